@@ -0,0 +1,272 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+
+	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+)
+
+const requestTimeout = 5 * time.Second
+
+func main() {
+	var (
+		socketDir, socket string
+		jsonOutput        bool
+	)
+
+	flag.StringVar(&socketDir, "socket-dir", dpapi.IntrospectionSocketDir, "directory of the device plugin introspection sockets, all of them are queried")
+	flag.StringVar(&socket, "socket", "", "introspection socket of a single device plugin, e.g. "+dpapi.IntrospectionSocket(dpapi.IntrospectionSocketDir, "gpu.intel.com"))
+	flag.BoolVar(&jsonOutput, "json", false, "print the raw JSON responses")
+
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		log.Fatal("Please provide command: devices, allocations, capacity, explain <pod file>")
+	}
+
+	sockets := []string{socket}
+
+	if socket == "" {
+		var err error
+
+		sockets, err = filepath.Glob(filepath.Join(socketDir, "*.sock"))
+		if err != nil || len(sockets) == 0 {
+			log.Fatalf("No introspection sockets found in %s", socketDir)
+		}
+	}
+
+	var err error
+
+	switch cmd := flag.Arg(0); cmd {
+	case "devices":
+		err = forEachSocket(sockets, dpapi.IntrospectionDevicesPath, jsonOutput, printDevices)
+	case "allocations":
+		err = forEachSocket(sockets, dpapi.IntrospectionAllocationsPath, jsonOutput, printAllocations)
+	case "capacity":
+		err = forEachSocket(sockets, dpapi.IntrospectionCapacityPath, jsonOutput, printCapacity)
+	case "explain":
+		err = explainPod(sockets, flag.Arg(1))
+	default:
+		err = errors.Errorf("unknown command %s", cmd)
+	}
+
+	if err != nil {
+		log.Fatalf("%+v", err)
+	}
+}
+
+func query(socket, path string) ([]byte, error) {
+	client := &http.Client{
+		Timeout: requestTimeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+
+	resp, err := client.Get("http://localhost" + path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to query %s", socket)
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("%s responded with %s", socket, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+
+	return data, errors.WithStack(err)
+}
+
+func forEachSocket(sockets []string, path string, jsonOutput bool, printFunc func(io.Writer, []byte) error) error {
+	for _, socket := range sockets {
+		data, err := query(socket, path)
+		if err != nil {
+			return err
+		}
+
+		if jsonOutput {
+			fmt.Println(string(data))
+
+			continue
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+
+		if err := printFunc(w, data); err != nil {
+			return errors.Wrapf(err, "unexpected response from %s", socket)
+		}
+
+		if err := w.Flush(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	return nil
+}
+
+func printDevices(w io.Writer, data []byte) error {
+	resources := map[string][]dpapi.DeviceState{}
+	if err := json.Unmarshal(data, &resources); err != nil {
+		return errors.WithStack(err)
+	}
+
+	names := make([]string, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "RESOURCE\tID\tHEALTH\tNUMA\tDEVICE NODES\tCDI DEVICES")
+
+	for _, name := range names {
+		for _, dev := range resources[name] {
+			numa := []string{}
+
+			if dev.Topology != nil {
+				for _, node := range dev.Topology.Nodes {
+					numa = append(numa, fmt.Sprint(node.ID))
+				}
+			}
+
+			nodes := []string{}
+			for _, node := range dev.Nodes {
+				nodes = append(nodes, node.HostPath)
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", name, dev.ID, dev.Health,
+				orNone(numa), orNone(nodes), orNone(dev.CDIDevices))
+		}
+	}
+
+	return nil
+}
+
+func printAllocations(w io.Writer, data []byte) error {
+	allocations := []dpapi.AllocateRecord{}
+	if err := json.Unmarshal(data, &allocations); err != nil {
+		return errors.WithStack(err)
+	}
+
+	fmt.Fprintln(w, "TIME\tRESOURCE\tDEVICES\tRESULT")
+
+	for _, allocation := range allocations {
+		devices := []string{}
+
+		if allocation.Request != nil {
+			for _, crqt := range allocation.Request.ContainerRequests {
+				devices = append(devices, strings.Join(crqt.DevicesIDs, ","))
+			}
+		}
+
+		result := "ok"
+		if allocation.Error != "" {
+			result = allocation.Error
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", allocation.Timestamp.Format(time.RFC3339),
+			allocation.ResourceName, orNone(devices), result)
+	}
+
+	return nil
+}
+
+func printCapacity(w io.Writer, data []byte) error {
+	capacity := map[string]dpapi.CapacitySummary{}
+	if err := json.Unmarshal(data, &capacity); err != nil {
+		return errors.WithStack(err)
+	}
+
+	names := make([]string, 0, len(capacity))
+	for name := range capacity {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "RESOURCE\tTOTAL\tHEALTHY\tALLOCATED\tSHARING")
+
+	for _, name := range names {
+		summary := capacity[name]
+
+		allocated := "-"
+		if summary.Allocated != nil {
+			allocated = fmt.Sprint(*summary.Allocated)
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%d\n", name, summary.Total, summary.Healthy, allocated, summary.SharingFactor)
+	}
+
+	return nil
+}
+
+func explainPod(sockets []string, podPath string) error {
+	if podPath == "" {
+		return errors.New("please provide the pod file, or - for stdin")
+	}
+
+	pod, err := readPod(podPath)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+
+	fits, err := explain(w, sockets, pod)
+	if err != nil {
+		return err
+	}
+
+	if err := w.Flush(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if !fits {
+		log.Fatalf("Pod %s does not fit the devices of this node", pod.Name)
+	}
+
+	return nil
+}
+
+func orNone(values []string) string {
+	if len(values) == 0 {
+		return "-"
+	}
+
+	return strings.Join(values, " ")
+}
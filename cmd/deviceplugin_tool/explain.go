@@ -0,0 +1,182 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+
+	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+)
+
+const intelDomain = ".intel.com"
+
+// readPod reads a pod from a YAML or JSON file, or from stdin when the path is "-".
+func readPod(path string) (*corev1.Pod, error) {
+	var (
+		data []byte
+		err  error
+	)
+
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	pod := &corev1.Pod{}
+	if err := yaml.UnmarshalStrict(data, pod); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode pod %s", path)
+	}
+
+	// Like the API server, default the missing requests to the limits.
+	for _, containers := range [][]corev1.Container{pod.Spec.InitContainers, pod.Spec.Containers} {
+		for i := range containers {
+			resources := &containers[i].Resources
+
+			for resourceName, quantity := range resources.Limits {
+				if _, ok := resources.Requests[resourceName]; !ok {
+					if resources.Requests == nil {
+						resources.Requests = corev1.ResourceList{}
+					}
+
+					resources.Requests[resourceName] = quantity
+				}
+			}
+		}
+	}
+
+	return pod, nil
+}
+
+// explainFit tells whether the requested number of devices fits the capacity
+// of a resource, and why not.
+func explainFit(requested int64, capacity dpapi.CapacitySummary) (string, bool) {
+	shared := ""
+	if capacity.SharingFactor > 1 {
+		shared = fmt.Sprintf(", each device is shared by %d containers", capacity.SharingFactor)
+	}
+
+	switch {
+	case requested > int64(capacity.Total):
+		return fmt.Sprintf("the node has only %d devices%s", capacity.Total, shared), false
+	case requested > int64(capacity.Healthy):
+		return fmt.Sprintf("only %d of the %d devices are healthy", capacity.Healthy, capacity.Total), false
+	case capacity.Allocated == nil:
+		return "fits the healthy devices, allocations are unknown", true
+	case requested > int64(capacity.Healthy-*capacity.Allocated):
+		return fmt.Sprintf("only %d of the %d healthy devices are free%s",
+			max(capacity.Healthy-*capacity.Allocated, 0), capacity.Healthy, shared), false
+	}
+
+	return "fits", true
+}
+
+// explain prints whether the devices a pod requests fit the capacity served
+// on the introspection sockets, and returns false when they don't.
+func explain(w io.Writer, sockets []string, pod *corev1.Pod) (bool, error) {
+	capacity := map[string]dpapi.CapacitySummary{}
+	requests := map[string]int64{}
+	namespaces := map[string]bool{}
+
+	for _, socket := range sockets {
+		data, err := query(socket, dpapi.IntrospectionCapacityPath)
+		if err != nil {
+			return false, err
+		}
+
+		if err := json.Unmarshal(data, &capacity); err != nil {
+			return false, errors.Wrapf(err, "unexpected response from %s", socket)
+		}
+
+		namespaces[strings.TrimSuffix(filepath.Base(socket), ".sock")] = true
+	}
+
+	// The resources of the plugins missing from the node are not advertised,
+	// but they are requested all the same.
+	for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		for resourceName := range container.Resources.Limits {
+			namespace, _, _ := strings.Cut(string(resourceName), "/")
+			if strings.HasSuffix(namespace, intelDomain) {
+				namespaces[namespace] = true
+			}
+		}
+	}
+
+	for namespace := range namespaces {
+		podRqts, err := dpapi.RequestedDevices(pod, namespace)
+		if err != nil {
+			return false, errors.Wrapf(err, "invalid resources in pod %s", pod.Name)
+		}
+
+		for resourceName, quantity := range podRqts {
+			requests[resourceName] = quantity
+		}
+	}
+
+	if len(requests) == 0 {
+		fmt.Fprintf(w, "Pod %s requests no devices of the plugins on this node\n", pod.Name)
+
+		return true, nil
+	}
+
+	names := make([]string, 0, len(requests))
+	for name := range requests {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	fmt.Fprintln(w, "RESOURCE\tREQUESTED\tTOTAL\tHEALTHY\tALLOCATED\tSHARING\tRESULT")
+
+	fitsAll := true
+
+	for _, name := range names {
+		summary, ok := capacity[name]
+		if !ok {
+			fitsAll = false
+
+			fmt.Fprintf(w, "%s\t%d\t-\t-\t-\t-\tnot advertised on this node\n", name, requests[name])
+
+			continue
+		}
+
+		reason, fits := explainFit(requests[name], summary)
+		fitsAll = fitsAll && fits
+
+		allocated := "-"
+		if summary.Allocated != nil {
+			allocated = fmt.Sprint(*summary.Allocated)
+		}
+
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%s\t%d\t%s\n", name, requests[name],
+			summary.Total, summary.Healthy, allocated, summary.SharingFactor, reason)
+	}
+
+	return fitsAll, nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+)
+
+func TestExplainFit(t *testing.T) {
+	allocated := 2
+
+	tcases := []struct {
+		name      string
+		reason    string
+		capacity  dpapi.CapacitySummary
+		requested int64
+		fits      bool
+	}{
+		{
+			name:      "more than total",
+			capacity:  dpapi.CapacitySummary{Total: 4, Healthy: 4, SharingFactor: 2},
+			requested: 5,
+			reason:    "the node has only 4 devices, each device is shared by 2 containers",
+		},
+		{
+			name:      "unhealthy",
+			capacity:  dpapi.CapacitySummary{Total: 4, Healthy: 1, SharingFactor: 1},
+			requested: 2,
+			reason:    "only 1 of the 4 devices are healthy",
+		},
+		{
+			name:      "allocated",
+			capacity:  dpapi.CapacitySummary{Total: 4, Healthy: 3, SharingFactor: 1, Allocated: &allocated},
+			requested: 2,
+			reason:    "only 1 of the 3 healthy devices are free",
+		},
+		{
+			name:      "fits",
+			capacity:  dpapi.CapacitySummary{Total: 4, Healthy: 4, SharingFactor: 1, Allocated: &allocated},
+			requested: 2,
+			reason:    "fits",
+			fits:      true,
+		},
+		{
+			name:      "allocations unknown",
+			capacity:  dpapi.CapacitySummary{Total: 4, Healthy: 4, SharingFactor: 1},
+			requested: 4,
+			reason:    "allocations are unknown",
+			fits:      true,
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			reason, fits := explainFit(tc.requested, tc.capacity)
+			if fits != tc.fits || !strings.Contains(reason, tc.reason) {
+				t.Errorf("expected %q (fits: %v), got %q (fits: %v)", tc.reason, tc.fits, reason, fits)
+			}
+		})
+	}
+}
+
+func TestExplainMissingPlugin(t *testing.T) {
+	resources := func(count string) corev1.ResourceRequirements {
+		list := corev1.ResourceList{"gpu.intel.com/i915": resource.MustParse(count)}
+
+		return corev1.ResourceRequirements{Limits: list, Requests: list}
+	}
+
+	pod := &corev1.Pod{}
+	pod.Name = "test"
+	pod.Spec.InitContainers = []corev1.Container{{Name: "init", Resources: resources("1")}}
+	pod.Spec.Containers = []corev1.Container{
+		{Name: "first", Resources: resources("2")},
+		{Name: "second", Resources: resources("2")},
+	}
+
+	out := &strings.Builder{}
+
+	fits, err := explain(out, nil, pod)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	// The sum of the containers wins over the largest init container.
+	if fits || !strings.Contains(out.String(), "gpu.intel.com/i915\t4\t-\t-\t-\t-\tnot advertised on this node") {
+		t.Errorf("unexpected explanation (fits %v):\n%s", fits, out.String())
+	}
+}
@@ -15,10 +15,10 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"path/filepath"
 	"reflect"
-	"time"
 
 	"k8s.io/klog/v2"
 
@@ -33,13 +33,11 @@ const (
 	deviceTypePF        = "pf"
 	deviceTypeVF        = "vf"
 	sysfsDir            = "/sys/class/dlb2"
-	// Period of device scans.
-	scanPeriod = 5 * time.Second
 )
 
 type DevicePlugin struct {
-	scanTicker *time.Ticker
-	scanDone   chan bool
+	scanWatcher *dpapi.DeviceWatcher
+	scanDone    chan bool
 
 	dlbDeviceFilePathReg string
 	sysfsDir             string
@@ -49,13 +47,22 @@ func NewDevicePlugin(dlbDeviceFilePathReg string, sysfsDir string) *DevicePlugin
 	return &DevicePlugin{
 		dlbDeviceFilePathReg: dlbDeviceFilePathReg,
 		sysfsDir:             sysfsDir,
-		scanTicker:           time.NewTicker(scanPeriod),
+		scanWatcher:          dpapi.NewDeviceWatcher(dpapi.WithSubsystems("dlb2", "pci"), dpapi.WithWatchPaths(filepath.Dir(dlbDeviceFilePathReg))),
 		scanDone:             make(chan bool, 1), // buffered as we may send to it before Scan starts receiving from it
 	}
 }
 
+// StopScan makes Scan() return. It implements dpapi.ScanStopper.
+func (dp *DevicePlugin) StopScan() {
+	select {
+	case dp.scanDone <- true:
+	default:
+	}
+}
+
 func (dp *DevicePlugin) Scan(notifier dpapi.Notifier) error {
-	defer dp.scanTicker.Stop()
+	dp.scanWatcher.Start()
+	defer dp.scanWatcher.Stop()
 
 	var prevDevTree dpapi.DeviceTree
 
@@ -63,7 +70,7 @@ func (dp *DevicePlugin) Scan(notifier dpapi.Notifier) error {
 		devTree := dp.scan()
 
 		if !reflect.DeepEqual(prevDevTree, devTree) {
-			klog.V(1).Info("DLB scan update: pf: ", len(devTree[deviceTypePF]), " / vf: ", len(devTree[deviceTypeVF]))
+			klog.V(1).InfoS("DLB scan update", "pf", len(devTree[deviceTypePF]), "vf", len(devTree[deviceTypeVF]))
 			prevDevTree = devTree
 		}
 
@@ -72,7 +79,7 @@ func (dp *DevicePlugin) Scan(notifier dpapi.Notifier) error {
 		select {
 		case <-dp.scanDone:
 			return nil
-		case <-dp.scanTicker.C:
+		case <-dp.scanWatcher.C():
 		}
 	}
 }
@@ -91,6 +98,7 @@ func (dp *DevicePlugin) scan() dpapi.DeviceTree {
 		deviceInfo := dpapi.NewDeviceInfo(pluginapi.Healthy, devs, nil, nil, nil, nil)
 
 		sysfsDev := filepath.Join(dp.sysfsDir, filepath.Base(file))
+		deviceInfo.SetSysfsDevice(filepath.Join(sysfsDev, "device"))
 		sriovNumVFs := pluginutils.GetSriovNumVFs(sysfsDev)
 
 		switch sriovNumVFs {
@@ -107,10 +115,23 @@ func (dp *DevicePlugin) scan() dpapi.DeviceTree {
 }
 
 func main() {
+	managerFlags := pluginutils.AddManagerFlags(flag.CommandLine)
 	flag.Parse()
-	klog.V(1).Infof("DLB device plugin started")
+
+	if err := managerFlags.SetupLogging(); err != nil {
+		klog.Fatalf("%+v", err)
+	}
+
+	managerOpts, err := managerFlags.ManagerOptions()
+	if err != nil {
+		klog.Fatalf("%+v", err)
+	}
+
+	klog.V(1).InfoS("DLB device plugin started")
 
 	plugin := NewDevicePlugin(dlbDeviceFilePathRE, sysfsDir)
-	manager := dpapi.NewManager(namespace, plugin)
-	manager.Run()
+	manager := dpapi.NewManager(namespace, plugin, managerOpts...)
+	if err := manager.Run(context.Background()); err != nil {
+		klog.Fatalf("%+v", err)
+	}
 }
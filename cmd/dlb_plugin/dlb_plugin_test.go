@@ -19,8 +19,10 @@ import (
 	"os"
 	"path"
 	"testing"
+	"time"
 
 	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+	dptesting "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin/testing"
 	"github.com/pkg/errors"
 )
 
@@ -147,3 +149,64 @@ func TestScan(t *testing.T) {
 		})
 	}
 }
+
+// ueventNotifier adds a device and injects a uevent after the first scan.
+type ueventNotifier struct {
+	plugin   *DevicePlugin
+	newDev   string
+	devCount []int
+}
+
+func (n *ueventNotifier) Notify(newDeviceTree dpapi.DeviceTree) {
+	n.devCount = append(n.devCount, len(newDeviceTree[deviceTypePF])+len(newDeviceTree[deviceTypeVF]))
+
+	if len(n.devCount) > 1 {
+		n.plugin.scanDone <- true
+		return
+	}
+
+	if err := os.MkdirAll(n.newDev, 0750); err != nil {
+		panic(err)
+	}
+
+	n.plugin.scanWatcher.Inject(dpapi.UEvent{Action: "add", Subsystem: "dlb2"})
+}
+
+func TestScanOnUEvent(t *testing.T) {
+	root := t.TempDir()
+	devfs := path.Join(root, "dev")
+	sysfs := path.Join(root, sysfsDir)
+
+	if err := createTestFiles(devfs, []string{"dlb0"}, sysfs, []string{"dlb0"}, []string{"0"}); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	plugin := NewDevicePlugin(path.Join(devfs, "dlb*"), sysfs)
+	plugin.scanWatcher = dpapi.NewDeviceWatcher(dpapi.WithSubsystems("dlb2"), dpapi.WithDebounce(time.Millisecond), dpapi.WithResyncPeriod(time.Hour))
+
+	notifier := &ueventNotifier{plugin: plugin, newDev: path.Join(devfs, "dlb1")}
+
+	if err := plugin.Scan(notifier); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if len(notifier.devCount) != 2 || notifier.devCount[0] != 1 || notifier.devCount[1] != 2 {
+		t.Errorf("expected the uevent to trigger a rescan finding 2 devices, got %v", notifier.devCount)
+	}
+}
+
+func TestConformance(t *testing.T) {
+	root := t.TempDir()
+	devfs := path.Join(root, "dev")
+	sysfs := path.Join(root, sysfsDir)
+
+	if err := createTestFiles(devfs, []string{"dlb0", "dlb1", "dlb2"}, sysfs, []string{"dlb0"}, []string{"0"}); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	dptesting.Run(t, dptesting.Config{
+		Plugin:      NewDevicePlugin(path.Join(devfs, "dlb*"), sysfs),
+		Namespace:   namespace,
+		DeviceTypes: []string{deviceTypePF, deviceTypeVF},
+	})
+}
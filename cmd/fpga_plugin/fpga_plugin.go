@@ -15,17 +15,18 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
 	"path"
 	"regexp"
-	"time"
 
 	"k8s.io/klog/v2"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 	cdispec "tags.cncf.io/container-device-interface/specs-go"
 
+	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/pluginutils"
 	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
 	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/fpga"
 	"github.com/pkg/errors"
@@ -49,9 +50,6 @@ const (
 	unhealthyAfuID       = "ffffffffffffffffffffffffffffffff"
 	unhealthyInterfaceID = "ffffffffffffffffffffffffffffffff"
 
-	// Period of device scans.
-	scanPeriod = 5 * time.Second
-
 	// CDI hook attributes.
 	CDIClass = "fpga"
 	HookName = "createRuntime"
@@ -89,7 +87,10 @@ func getRegionDevelTree(devices []device) dpapi.DeviceTree {
 				Permissions:   "rw",
 			}
 
-			regionTree.AddDevice(devType, region.id, dpapi.NewDeviceInfo(health, devNodes, nil, nil, nil, nil))
+			deviceInfo := dpapi.NewDeviceInfo(health, devNodes, nil, nil, nil, nil)
+			deviceInfo.SetSysfsDevice(dev.sysfsPath)
+
+			regionTree.AddDevice(devType, region.id, deviceInfo)
 		}
 	}
 
@@ -136,7 +137,10 @@ func getRegionTree(devices []device) dpapi.DeviceTree {
 				}
 			}
 
-			regionTree.AddDevice(devType, region.id, dpapi.NewDeviceInfo(health, devNodes, nil, nil, nil, cdiSpec))
+			deviceInfo := dpapi.NewDeviceInfo(health, devNodes, nil, nil, nil, cdiSpec)
+			deviceInfo.SetSysfsDevice(dev.sysfsPath)
+
+			regionTree.AddDevice(devType, region.id, deviceInfo)
 		}
 	}
 
@@ -157,7 +161,7 @@ func getAfuTree(devices []device) dpapi.DeviceTree {
 
 				devType, err := fpga.GetAfuDevType(region.interfaceID, afu.afuID)
 				if err != nil {
-					klog.Warningf("failed to get devtype: %+v", err)
+					klog.ErrorS(err, "Failed to get devtype", "afu", afu.afuID)
 					continue
 				}
 
@@ -168,7 +172,10 @@ func getAfuTree(devices []device) dpapi.DeviceTree {
 						Permissions:   "rw",
 					},
 				}
-				afuTree.AddDevice(devType, afu.id, dpapi.NewDeviceInfo(health, devNodes, nil, nil, nil, nil))
+				deviceInfo := dpapi.NewDeviceInfo(health, devNodes, nil, nil, nil, nil)
+				deviceInfo.SetSysfsDevice(dev.sysfsPath)
+
+				afuTree.AddDevice(devType, afu.id, deviceInfo)
 			}
 		}
 	}
@@ -190,8 +197,10 @@ type region struct {
 }
 
 type device struct {
-	name    string
-	regions []region
+	name string
+	// sysfsPath is the sysfs directory of the device, see DeviceInfo.SetSysfsDevice().
+	sysfsPath string
+	regions   []region
 }
 
 type devicePlugin struct {
@@ -206,8 +215,8 @@ type devicePlugin struct {
 	getDevTree getDevTreeFunc
 	newPort    newPortFunc
 
-	scanTicker *time.Ticker
-	scanDone   chan bool
+	scanWatcher *dpapi.DeviceWatcher
+	scanDone    chan bool
 
 	annotationValue string
 }
@@ -238,7 +247,7 @@ func newDevicePlugin(mode string, rootPath string) (*devicePlugin, error) {
 	}
 
 	dp.newPort = fpga.NewPort
-	dp.scanTicker = time.NewTicker(scanPeriod)
+	dp.scanWatcher = dpapi.NewDeviceWatcher(dpapi.WithSubsystems("pci", "fpga", "fpga_region", "dfl"), dpapi.WithWatchPaths(devfsPath))
 	dp.scanDone = make(chan bool, 1) // buffered as we may send to it before Scan starts receiving from it
 
 	return dp, nil
@@ -257,9 +266,18 @@ func (dp *devicePlugin) PostAllocate(response *pluginapi.AllocateResponse) error
 	return nil
 }
 
+// StopScan makes Scan() return. It implements dpapi.ScanStopper.
+func (dp *devicePlugin) StopScan() {
+	select {
+	case dp.scanDone <- true:
+	default:
+	}
+}
+
 // Scan starts scanning FPGA devices on the host.
 func (dp *devicePlugin) Scan(notifier dpapi.Notifier) error {
-	defer dp.scanTicker.Stop()
+	dp.scanWatcher.Start()
+	defer dp.scanWatcher.Stop()
 
 	for {
 		devTree, err := dp.scanFPGAs()
@@ -272,7 +290,7 @@ func (dp *devicePlugin) Scan(notifier dpapi.Notifier) error {
 		select {
 		case <-dp.scanDone:
 			return nil
-		case <-dp.scanTicker.C:
+		case <-dp.scanWatcher.C():
 		}
 	}
 }
@@ -317,7 +335,7 @@ func (dp *devicePlugin) getRegions(deviceFiles []os.DirEntry) ([]region, error)
 func (dp *devicePlugin) scanFPGAs() (dpapi.DeviceTree, error) {
 	files, err := os.ReadDir(dp.sysfsDir)
 	if err != nil {
-		klog.Warningf("Can't read folder %s. Kernel driver not loaded?", dp.sysfsDir)
+		klog.ErrorS(err, "Can't read folder. Kernel driver not loaded?", "path", dp.sysfsDir)
 		return dp.getDevTree([]device{}), nil
 	}
 
@@ -341,7 +359,7 @@ func (dp *devicePlugin) scanFPGAs() (dpapi.DeviceTree, error) {
 		}
 
 		if len(regions) > 0 {
-			devices = append(devices, device{name: devName, regions: regions})
+			devices = append(devices, device{name: devName, sysfsPath: path.Join(dp.sysfsDir, devName), regions: regions})
 		}
 	}
 
@@ -383,19 +401,27 @@ func main() {
 	flag.StringVar(&nodename, "node-name", os.Getenv("NODE_NAME"), "node name in the cluster to query mode annotation from")
 	flag.StringVar(&mode, "mode", string(afMode),
 		fmt.Sprintf("device plugin mode: '%s' (default), '%s' or '%s'", afMode, regionMode, regionDevelMode))
+	managerFlags := pluginutils.AddManagerFlags(flag.CommandLine)
 	flag.Parse()
 
+	if err := managerFlags.SetupLogging(); err != nil {
+		klog.Fatalf("%+v", err)
+	}
+
+	managerOpts, err := managerFlags.ManagerOptions()
+	if err != nil {
+		klog.Fatalf("%+v", err)
+	}
+
 	nodeMode, err := getModeOverrideFromCluster(nodename, kubeconfig, master, mode)
 	if err != nil {
-		klog.Warningf("could not get mode override from cluster: %+v", err)
+		klog.ErrorS(err, "Could not get mode override from cluster")
 	}
 
-	var modeMessage string
+	modeSource := "command line"
 	if mode != nodeMode {
-		modeMessage = fmt.Sprintf(" (override from %s node annotation)", nodename)
+		modeSource = fmt.Sprintf("%s node annotation", nodename)
 		mode = nodeMode
-	} else {
-		modeMessage = ""
 	}
 
 	plugin, err := newDevicePlugin(mode, "")
@@ -403,7 +429,9 @@ func main() {
 		klog.Fatalf("%+v", err)
 	}
 
-	klog.V(1).Infof("FPGA device plugin (%s) started in %s mode%s", plugin.name, mode, modeMessage)
-	manager := dpapi.NewManager(namespace, plugin)
-	manager.Run()
+	klog.V(1).InfoS("FPGA device plugin started", "name", plugin.name, "mode", mode, "modeSource", modeSource)
+	manager := dpapi.NewManager(namespace, plugin, managerOpts...)
+	if err := manager.Run(context.Background()); err != nil {
+		klog.Fatalf("%+v", err)
+	}
 }
@@ -25,6 +25,8 @@ import (
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 
 	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+	dptesting "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin/testing"
+	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/fpga"
 )
 
 func init() {
@@ -262,3 +264,45 @@ func TestScan(t *testing.T) {
 		})
 	}
 }
+
+func TestConformance(t *testing.T) {
+	root := t.TempDir()
+	sysfs := path.Join(root, "sys")
+	dev := path.Join(root, "dev")
+	port := "devices/pci0000:00/0000:00:03.2/0000:06:00.0"
+
+	err := createTestDirs(dev, sysfs,
+		[]string{"intel-fpga-fme.0", "intel-fpga-port.0"},
+		[]string{
+			"class/fpga/intel-fpga-dev.0/intel-fpga-port.0",
+			port + "/fpga/intel-fpga-dev.0/intel-fpga-port.0",
+			port + "/fpga/intel-fpga-dev.0/intel-fpga-fme.0/pr",
+		},
+		map[string][]byte{
+			port + "/fpga/intel-fpga-dev.0/intel-fpga-port.0/afu_id":         []byte("d8424dc4a4a3c413f89e433683f9040b\n"),
+			port + "/fpga/intel-fpga-dev.0/intel-fpga-fme.0/pr/interface_id": []byte("69528db6eb31577a8c3668f9faa081f6\n"),
+		})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	plugin, err := newDevicePlugin(afMode, root)
+	if err != nil {
+		t.Fatalf("failed to create a device plugin: %+v", err)
+	}
+
+	plugin.newPort = genNewIntelFpgaPort(sysfs, dev, map[string][]string{
+		"intel-fpga-port.0": {port, "intel-fpga-fme.0", port},
+	})
+
+	devType, err := fpga.GetAfuDevType("69528db6eb31577a8c3668f9faa081f6", "d8424dc4a4a3c413f89e433683f9040b")
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	dptesting.Run(t, dptesting.Config{
+		Plugin:      plugin,
+		Namespace:   namespace,
+		DeviceTypes: []string{devType},
+	})
+}
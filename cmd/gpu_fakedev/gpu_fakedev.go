@@ -20,6 +20,9 @@
 // sys/class/drm/cardX/device/
 // sys/class/drm/cardX/device/vendor (0x8086)
 // sys/class/drm/cardX/device/sriov_numvfs (PF only, number of VF GPUs, number)
+// sys/class/drm/cardX/device/sriov_totalvfs (PF only, max number of VF GPUs, number)
+// sys/class/drm/cardX/device/physfn (VF only, symlink to PF device)
+// sys/class/drm/cardX/iov/vfN/gt0/*_quota (PF only, VF resource quotas, number)
 // sys/class/drm/cardX/device/drm/
 // sys/class/drm/cardX/device/drm/cardX/
 // sys/class/drm/cardX/device/drm/renderD1XX/
@@ -133,14 +136,10 @@ func addSysfsDriTree(root string, opts *genOptions, i int) error {
 	}
 	opts.files++
 
-	if opts.VfsPerPf > 0 && i%(opts.VfsPerPf+1) == 0 {
-		data = []byte(strconv.Itoa(opts.VfsPerPf))
-		file = filepath.Join(base, "device", "sriov_numvfs")
-
-		if err := os.WriteFile(file, data, fileMode); err != nil {
+	if opts.VfsPerPf > 0 {
+		if err := addSriovFiles(base, opts, i); err != nil {
 			return err
 		}
-		opts.files++
 	}
 
 	for tile := 0; tile < opts.TilesPerDev; tile++ {
@@ -154,6 +153,49 @@ func addSysfsDriTree(root string, opts *genOptions, i int) error {
 	return nil
 }
 
+// vfQuotas are the VF resource quota files of the PF, for each VF and GT.
+var vfQuotas = []string{"contexts_quota", "doorbells_quota", "ggtt_quota", "lmem_quota"}
+
+// addSriovFiles adds the SR-IOV files of a PF, or the PF link of a VF.
+func addSriovFiles(base string, opts *genOptions, i int) error {
+	pf := i - i%(opts.VfsPerPf+1)
+
+	if i != pf {
+		target := filepath.Join("..", "..", fmt.Sprintf("card%d", cardBase+pf), "device")
+		if err := os.Symlink(target, filepath.Join(base, "device", "physfn")); err != nil {
+			return err
+		}
+		opts.files++
+
+		return nil
+	}
+
+	for _, name := range []string{"sriov_numvfs", "sriov_totalvfs"} {
+		data := []byte(strconv.Itoa(opts.VfsPerPf))
+		if err := os.WriteFile(filepath.Join(base, "device", name), data, fileMode); err != nil {
+			return err
+		}
+		opts.files++
+	}
+
+	for vf := 1; vf <= opts.VfsPerPf; vf++ {
+		path := filepath.Join(base, "iov", fmt.Sprintf("vf%d", vf), "gt0")
+		if err := os.MkdirAll(path, dirMode); err != nil {
+			return err
+		}
+		opts.dirs++
+
+		for _, quota := range vfQuotas {
+			if err := os.WriteFile(filepath.Join(path, quota), []byte("0"), fileMode); err != nil {
+				return err
+			}
+			opts.files++
+		}
+	}
+
+	return nil
+}
+
 func addSysfsBusTree(root string, opts *genOptions, i int) error {
 	pciName := fmt.Sprintf("0000:00:0%d.0", i)
 	base := filepath.Join(root, "bus", "pci", "drivers", "i915", pciName)
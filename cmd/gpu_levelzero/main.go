@@ -16,24 +16,124 @@ package main
 
 // #cgo CFLAGS: "-I/usr/include/level_zero" "-Wall" "-Wextra" "-O2"
 // #cgo LDFLAGS: "-lze_loader"
+// #include <stdlib.h>
 // #include "ze.h"
 import "C"
 
 import (
 	"context"
 	"flag"
+	"fmt"
+	"math"
 	"net"
 	"os"
 	"strconv"
+	"sync"
+	"time"
 	"unsafe"
 
 	levelzero "github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/levelzero"
+	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
 	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
 	"k8s.io/klog/v2"
 )
 
+const (
+	// sampleInterval is the time between the two samples of the counters
+	// of a device on the first request of their rates.
+	sampleInterval = 100 * time.Millisecond
+	// bytesPerMicrosecond converts bytes per microsecond to bytes per second.
+	bytesPerMicrosecond = 1e6
+	// defaultWatchInterval is the interval of sampling the health of the
+	// devices when the watch request has none, and minWatchInterval limits
+	// the sampling of the watches.
+	defaultWatchInterval = 5 * time.Second
+	minWatchInterval     = time.Second
+	// maxListenTimeout limits the time listening to the health events, so
+	// that cancelled watches are noticed.
+	maxListenTimeout = time.Second
+)
+
+var (
+	// engineGroups are the groups of engine_activity_t.
+	engineGroups = [C.ENGINE_GROUP_COUNT]string{"all", "compute", "render", "media", "copy"}
+	// throttleReasons are the zes_freq_throttle_reason_flags_t bits in order.
+	throttleReasons = []string{"average-power", "burst-power", "current", "thermal", "psu-alert", "software-range", "hardware-range"}
+)
+
 type server struct {
 	levelzero.UnimplementedLevelzeroServer
+	// samples are the previous samples of the counters of the devices by
+	// BDF address and counter, for the rates since the previous request.
+	samples map[string]counterSample
+	// listenMutex is held by the watch listening to the health events,
+	// which are cleared when they are read. The other watches sample the
+	// health at their intervals.
+	listenMutex  sync.Mutex
+	mutex        sync.Mutex
+	eventsOnce   sync.Once
+	healthEvents bool
+}
+
+// counterSample has cumulative counters, and the times they were read at in
+// microseconds.
+type counterSample struct {
+	counters   []uint64
+	timestamps []uint64
+}
+
+// rate returns the change of a counter per microsecond since a previous
+// sample. The rate is zero when the counter was reset.
+func (cs counterSample) rate(previous counterSample, i int) float64 {
+	if i >= len(previous.counters) || cs.timestamps[i] <= previous.timestamps[i] || cs.counters[i] < previous.counters[i] {
+		return 0
+	}
+
+	return float64(cs.counters[i]-previous.counters[i]) / float64(cs.timestamps[i]-previous.timestamps[i])
+}
+
+// samplePair returns the previous and the current sample of the counters of
+// a device. Without a previous sample, the counters are sampled twice.
+func (s *server) samplePair(key string, read func() (counterSample, bool)) (counterSample, counterSample, bool) {
+	s.mutex.Lock()
+	previous, ok := s.samples[key]
+	s.mutex.Unlock()
+
+	if !ok {
+		if previous, ok = read(); !ok {
+			return counterSample{}, counterSample{}, false
+		}
+
+		time.Sleep(sampleInterval)
+	}
+
+	current, ok := read()
+	if !ok {
+		return counterSample{}, counterSample{}, false
+	}
+
+	s.mutex.Lock()
+
+	if s.samples == nil {
+		s.samples = make(map[string]counterSample)
+	}
+
+	s.samples[key] = current
+
+	s.mutex.Unlock()
+
+	return previous, current, true
+}
+
+func statusError(errorVal uint32) *levelzero.Error {
+	var err levelzero.Error
+	if errorVal != 0 {
+		err.Errorcode = errorVal
+		err.Description = retrieveStatusDescription(errorVal)
+	}
+
+	return &err
 }
 
 func retrieveStatusDescription(code uint32) string {
@@ -51,7 +151,7 @@ func retrieveStatusDescription(code uint32) string {
 }
 
 func (s *server) GetDeviceHealth(c context.Context, deviceid *levelzero.DeviceId) (*levelzero.DeviceHealth, error) {
-	klog.V(3).Infof("Retrieve device health for %s", deviceid.BdfAddress)
+	klog.V(3).InfoS("Retrieve device health", dpapi.LogKeyBDF, deviceid.BdfAddress)
 
 	var errorVal uint32 = 0
 
@@ -59,12 +159,12 @@ func (s *server) GetDeviceHealth(c context.Context, deviceid *levelzero.DeviceId
 
 	memHealth := bool(C.zes_device_memory_is_healthy(cBdfAddress, (*C.uint32_t)(unsafe.Pointer(&errorVal))))
 	if errorVal != 0 {
-		klog.Warningf("device memory health read returned an error: 0x%X", errorVal)
+		klog.InfoS("Device memory health read returned an error", dpapi.LogKeyBDF, deviceid.BdfAddress, "errorCode", fmt.Sprintf("0x%X", errorVal))
 	}
 
 	busHealth := bool(C.zes_device_bus_is_healthy(cBdfAddress, (*C.uint32_t)(unsafe.Pointer(&errorVal))))
 	if errorVal != 0 {
-		klog.Warningf("device bus health read returned an error: 0x%X", errorVal)
+		klog.InfoS("Device bus health read returned an error", dpapi.LogKeyBDF, deviceid.BdfAddress, "errorCode", fmt.Sprintf("0x%X", errorVal))
 	}
 
 	var err levelzero.Error
@@ -72,7 +172,7 @@ func (s *server) GetDeviceHealth(c context.Context, deviceid *levelzero.DeviceId
 		err.Errorcode = errorVal
 		err.Description = retrieveStatusDescription(errorVal)
 	} else {
-		klog.V(3).Infof("Health for %s: Memory=%t, Bus=%t", deviceid.BdfAddress, memHealth, busHealth)
+		klog.V(3).InfoS("Device health", dpapi.LogKeyBDF, deviceid.BdfAddress, "memory", memHealth, "bus", busHealth)
 	}
 
 	health := &levelzero.DeviceHealth{
@@ -86,7 +186,7 @@ func (s *server) GetDeviceHealth(c context.Context, deviceid *levelzero.DeviceId
 }
 
 func (s *server) GetDeviceTemperature(c context.Context, deviceid *levelzero.DeviceId) (*levelzero.DeviceTemperature, error) {
-	klog.V(3).Infof("Retrieve device temperature for %s", deviceid.BdfAddress)
+	klog.V(3).InfoS("Retrieve device temperature", dpapi.LogKeyBDF, deviceid.BdfAddress)
 
 	var errorVal uint32 = 0
 
@@ -94,17 +194,17 @@ func (s *server) GetDeviceTemperature(c context.Context, deviceid *levelzero.Dev
 
 	globalTemp := float64(C.zes_device_temp_max(cBdfAddress, C.CString("global"), (*C.uint32_t)(unsafe.Pointer(&errorVal))))
 	if errorVal != 0 {
-		klog.Warningf("global temperature read returned an error: 0x%X", errorVal)
+		klog.InfoS("Global temperature read returned an error", dpapi.LogKeyBDF, deviceid.BdfAddress, "errorCode", fmt.Sprintf("0x%X", errorVal))
 	}
 
 	gpuTemp := float64(C.zes_device_temp_max(cBdfAddress, C.CString("gpu"), (*C.uint32_t)(unsafe.Pointer(&errorVal))))
 	if errorVal != 0 {
-		klog.Warningf("gpu temperature read returned an error: 0x%X", errorVal)
+		klog.InfoS("Gpu temperature read returned an error", dpapi.LogKeyBDF, deviceid.BdfAddress, "errorCode", fmt.Sprintf("0x%X", errorVal))
 	}
 
 	memTemp := float64(C.zes_device_temp_max(cBdfAddress, C.CString("memory"), (*C.uint32_t)(unsafe.Pointer(&errorVal))))
 	if errorVal != 0 {
-		klog.Warningf("memory temperature read returned an error: 0x%X", errorVal)
+		klog.InfoS("Memory temperature read returned an error", dpapi.LogKeyBDF, deviceid.BdfAddress, "errorCode", fmt.Sprintf("0x%X", errorVal))
 	}
 
 	var err levelzero.Error
@@ -112,7 +212,7 @@ func (s *server) GetDeviceTemperature(c context.Context, deviceid *levelzero.Dev
 		err.Errorcode = errorVal
 		err.Description = retrieveStatusDescription(errorVal)
 	} else {
-		klog.V(3).Infof("Temperatures for %s: Memory=%.1fC, GPU=%.1fC, Global=%.1fC", deviceid.BdfAddress, memTemp, gpuTemp, globalTemp)
+		klog.V(3).InfoS("Device temperatures", dpapi.LogKeyBDF, deviceid.BdfAddress, "memory", memTemp, "gpu", gpuTemp, "global", globalTemp)
 	}
 
 	temps := &levelzero.DeviceTemperature{
@@ -126,7 +226,7 @@ func (s *server) GetDeviceTemperature(c context.Context, deviceid *levelzero.Dev
 }
 
 func (s *server) GetIntelIndices(c context.Context, m *levelzero.GetIntelIndicesMessage) (*levelzero.DeviceIndices, error) {
-	klog.V(3).Infof("Retrieve Intel indices")
+	klog.V(3).InfoS("Retrieve Intel indices")
 
 	errorVal := uint32(0)
 
@@ -151,14 +251,14 @@ func (s *server) GetIntelIndices(c context.Context, m *levelzero.GetIntelIndices
 }
 
 func (s *server) GetDeviceMemoryAmount(c context.Context, deviceid *levelzero.DeviceId) (*levelzero.DeviceMemoryAmount, error) {
-	klog.V(3).Infof("Retrieve device memory amount for %s", deviceid.BdfAddress)
+	klog.V(3).InfoS("Retrieve device memory amount", dpapi.LogKeyBDF, deviceid.BdfAddress)
 
 	errorVal := uint32(0)
 
 	memSize := C.zes_device_memory_amount(C.CString(deviceid.BdfAddress), (*C.uint32_t)(unsafe.Pointer(&errorVal)))
 
 	if errorVal != 0 {
-		klog.Warningf("device memory amount read returned an error: 0x%X", errorVal)
+		klog.InfoS("Device memory amount read returned an error", dpapi.LogKeyBDF, deviceid.BdfAddress, "errorCode", fmt.Sprintf("0x%X", errorVal))
 	}
 
 	description := retrieveStatusDescription(errorVal)
@@ -177,6 +277,330 @@ func (s *server) GetDeviceMemoryAmount(c context.Context, deviceid *levelzero.De
 	return &ret, nil
 }
 
+func (s *server) GetDeviceUtilization(c context.Context, deviceid *levelzero.DeviceId) (*levelzero.DeviceUtilization, error) {
+	klog.V(3).InfoS("Retrieve device utilization", dpapi.LogKeyBDF, deviceid.BdfAddress)
+
+	errorVal := uint32(0)
+
+	cBdfAddress := C.CString(deviceid.BdfAddress)
+	defer C.free(unsafe.Pointer(cBdfAddress))
+
+	engines := [C.ENGINE_GROUP_COUNT]uint32{}
+
+	previous, current, ok := s.samplePair(deviceid.BdfAddress+"/engines", func() (counterSample, bool) {
+		var activity C.engine_activity_t
+
+		if !bool(C.zes_device_engine_activity(cBdfAddress, &activity, (*C.uint32_t)(unsafe.Pointer(&errorVal)))) {
+			return counterSample{}, false
+		}
+
+		sample := counterSample{counters: make([]uint64, C.ENGINE_GROUP_COUNT), timestamps: make([]uint64, C.ENGINE_GROUP_COUNT)}
+
+		for i := range engines {
+			sample.counters[i] = uint64(activity.active_time[i])
+			sample.timestamps[i] = uint64(activity.timestamp[i])
+			engines[i] = uint32(activity.engines[i])
+		}
+
+		return sample, true
+	})
+	if !ok && errorVal != 0 {
+		klog.InfoS("Engine activity read returned an error", dpapi.LogKeyBDF, deviceid.BdfAddress, "errorCode", fmt.Sprintf("0x%X", errorVal))
+	}
+
+	ret := levelzero.DeviceUtilization{
+		Error: statusError(errorVal),
+	}
+
+	if ok {
+		for i, group := range engineGroups {
+			if engines[i] == 0 {
+				continue
+			}
+
+			// The busy time per time of the engines, in percent.
+			ret.Engines = append(ret.Engines, &levelzero.EngineUtilization{
+				Group:       group,
+				Utilization: current.rate(previous, i) * 100,
+			})
+		}
+	}
+
+	return &ret, nil
+}
+
+func (s *server) GetDevicePower(c context.Context, deviceid *levelzero.DeviceId) (*levelzero.DevicePower, error) {
+	klog.V(3).InfoS("Retrieve device power", dpapi.LogKeyBDF, deviceid.BdfAddress)
+
+	errorVal := uint32(0)
+	tdp := C.double(0)
+
+	cBdfAddress := C.CString(deviceid.BdfAddress)
+	defer C.free(unsafe.Pointer(cBdfAddress))
+
+	previous, current, ok := s.samplePair(deviceid.BdfAddress+"/energy", func() (counterSample, bool) {
+		var energy, timestamp C.uint64_t
+
+		if !bool(C.zes_device_energy(cBdfAddress, &energy, &timestamp, &tdp, (*C.uint32_t)(unsafe.Pointer(&errorVal)))) {
+			return counterSample{}, false
+		}
+
+		return counterSample{counters: []uint64{uint64(energy)}, timestamps: []uint64{uint64(timestamp)}}, true
+	})
+	if !ok && errorVal != 0 {
+		klog.InfoS("Energy read returned an error", dpapi.LogKeyBDF, deviceid.BdfAddress, "errorCode", fmt.Sprintf("0x%X", errorVal))
+	}
+
+	ret := levelzero.DevicePower{
+		Tdp:   float64(tdp),
+		Error: statusError(errorVal),
+	}
+
+	// Microjoules per microsecond are watts.
+	if ok {
+		ret.Power = current.rate(previous, 0)
+	}
+
+	return &ret, nil
+}
+
+func (s *server) GetDeviceFrequency(c context.Context, deviceid *levelzero.DeviceId) (*levelzero.DeviceFrequency, error) {
+	klog.V(3).InfoS("Retrieve device frequency", dpapi.LogKeyBDF, deviceid.BdfAddress)
+
+	errorVal := uint32(0)
+
+	var (
+		actual, requested, maxFreq C.double
+		reasons                    C.uint32_t
+	)
+
+	cBdfAddress := C.CString(deviceid.BdfAddress)
+	defer C.free(unsafe.Pointer(cBdfAddress))
+
+	ok := bool(C.zes_device_frequency(cBdfAddress, &actual, &requested, &maxFreq, &reasons, (*C.uint32_t)(unsafe.Pointer(&errorVal))))
+	if !ok && errorVal != 0 {
+		klog.InfoS("Frequency read returned an error", dpapi.LogKeyBDF, deviceid.BdfAddress, "errorCode", fmt.Sprintf("0x%X", errorVal))
+	}
+
+	ret := levelzero.DeviceFrequency{
+		Actual:    float64(actual),
+		Requested: float64(requested),
+		Max:       float64(maxFreq),
+		Error:     statusError(errorVal),
+	}
+
+	for bit, reason := range throttleReasons {
+		if uint32(reasons)&(1<<bit) != 0 {
+			ret.ThrottleReasons = append(ret.ThrottleReasons, reason)
+		}
+	}
+
+	return &ret, nil
+}
+
+func (s *server) GetDeviceErrors(c context.Context, deviceid *levelzero.DeviceId) (*levelzero.DeviceErrors, error) {
+	klog.V(3).InfoS("Retrieve device errors", dpapi.LogKeyBDF, deviceid.BdfAddress)
+
+	errorVal := uint32(0)
+
+	var correctable, uncorrectable C.uint64_t
+
+	cBdfAddress := C.CString(deviceid.BdfAddress)
+	defer C.free(unsafe.Pointer(cBdfAddress))
+
+	ok := bool(C.zes_device_ras_errors(cBdfAddress, &correctable, &uncorrectable, (*C.uint32_t)(unsafe.Pointer(&errorVal))))
+	if !ok && errorVal != 0 {
+		klog.InfoS("RAS errors read returned an error", dpapi.LogKeyBDF, deviceid.BdfAddress, "errorCode", fmt.Sprintf("0x%X", errorVal))
+	}
+
+	return &levelzero.DeviceErrors{
+		Correctable:   uint64(correctable),
+		Uncorrectable: uint64(uncorrectable),
+		Error:         statusError(errorVal),
+	}, nil
+}
+
+func (s *server) GetDeviceMemoryBandwidth(c context.Context, deviceid *levelzero.DeviceId) (*levelzero.DeviceMemoryBandwidth, error) {
+	klog.V(3).InfoS("Retrieve device memory bandwidth", dpapi.LogKeyBDF, deviceid.BdfAddress)
+
+	errorVal := uint32(0)
+	maxBandwidth := C.uint64_t(0)
+
+	cBdfAddress := C.CString(deviceid.BdfAddress)
+	defer C.free(unsafe.Pointer(cBdfAddress))
+
+	previous, current, ok := s.samplePair(deviceid.BdfAddress+"/bandwidth", func() (counterSample, bool) {
+		var read, write, timestamp C.uint64_t
+
+		if !bool(C.zes_device_memory_bandwidth(cBdfAddress, &read, &write, &maxBandwidth, &timestamp, (*C.uint32_t)(unsafe.Pointer(&errorVal)))) {
+			return counterSample{}, false
+		}
+
+		return counterSample{
+			counters:   []uint64{uint64(read), uint64(write)},
+			timestamps: []uint64{uint64(timestamp), uint64(timestamp)},
+		}, true
+	})
+	if !ok && errorVal != 0 {
+		klog.InfoS("Memory bandwidth read returned an error", dpapi.LogKeyBDF, deviceid.BdfAddress, "errorCode", fmt.Sprintf("0x%X", errorVal))
+	}
+
+	ret := levelzero.DeviceMemoryBandwidth{
+		Max:   float64(maxBandwidth),
+		Error: statusError(errorVal),
+	}
+
+	if ok {
+		ret.Read = current.rate(previous, 0) * bytesPerMicrosecond
+		ret.Write = current.rate(previous, 1) * bytesPerMicrosecond
+	}
+
+	return &ret, nil
+}
+
+// watchInterval returns the sampling interval of a watch request.
+func watchInterval(intervalMs uint32) time.Duration {
+	if intervalMs == 0 {
+		return defaultWatchInterval
+	}
+
+	return max(time.Duration(intervalMs)*time.Millisecond, minWatchInterval)
+}
+
+// healthChanged tells whether the health of a device differs from the one
+// sent before. The temperatures change constantly, so they count only when
+// they change by a degree or more.
+func healthChanged(previous, current *levelzero.DeviceHealthEvent) bool {
+	if previous == nil || !proto.Equal(previous.Health, current.Health) || !proto.Equal(previous.Error, current.Error) ||
+		!proto.Equal(previous.Temperature.GetError(), current.Temperature.GetError()) {
+		return true
+	}
+
+	pt, ct := previous.Temperature, current.Temperature
+
+	return math.Abs(pt.GetGlobal()-ct.GetGlobal()) >= 1 || math.Abs(pt.GetGpu()-ct.GetGpu()) >= 1 ||
+		math.Abs(pt.GetMemory()-ct.GetMemory()) >= 1
+}
+
+// deviceBdfAddresses returns the BDF addresses of the devices.
+func deviceBdfAddresses() ([]string, uint32) {
+	errorVal := uint32(0)
+
+	count := uint32(C.zes_device_count((*C.uint32_t)(unsafe.Pointer(&errorVal))))
+	if errorVal != 0 {
+		return nil, errorVal
+	}
+
+	bdfAddresses := make([]string, 0, count)
+	b := make([]byte, 32)
+
+	for i := uint32(0); i < count; i++ {
+		written := int(C.zes_device_bdf_address(C.uint32_t(i), (*C.char)(unsafe.Pointer(&b[0])), C.uint32_t(len(b)), (*C.uint32_t)(unsafe.Pointer(&errorVal))))
+		if written > 0 && written < len(b) {
+			bdfAddresses = append(bdfAddresses, string(b[0:written]))
+		}
+	}
+
+	return bdfAddresses, 0
+}
+
+// deviceHealthEvents returns the health of the devices, or an event with
+// the error when the devices can't be enumerated.
+func (s *server) deviceHealthEvents(ctx context.Context) []*levelzero.DeviceHealthEvent {
+	bdfAddresses, errorVal := deviceBdfAddresses()
+	if errorVal != 0 {
+		klog.InfoS("Device enumeration returned an error", "errorCode", fmt.Sprintf("0x%X", errorVal))
+
+		return []*levelzero.DeviceHealthEvent{{Error: statusError(errorVal)}}
+	}
+
+	events := make([]*levelzero.DeviceHealthEvent, 0, len(bdfAddresses))
+
+	for _, bdfAddress := range bdfAddresses {
+		deviceid := &levelzero.DeviceId{BdfAddress: bdfAddress}
+
+		health, _ := s.GetDeviceHealth(ctx, deviceid)
+		temps, _ := s.GetDeviceTemperature(ctx, deviceid)
+
+		events = append(events, &levelzero.DeviceHealthEvent{
+			BdfAddress:  bdfAddress,
+			Health:      health,
+			Temperature: temps,
+			Error:       statusError(0),
+		})
+	}
+
+	return events
+}
+
+// waitHealthEvents waits for the health events of the driver, or for the
+// interval when the driver has none or another watch listens to them. It
+// returns false when the watch is cancelled.
+func (s *server) waitHealthEvents(ctx context.Context, interval time.Duration) bool {
+	s.eventsOnce.Do(func() {
+		errorVal := uint32(0)
+
+		s.healthEvents = bool(C.zes_register_health_events((*C.uint32_t)(unsafe.Pointer(&errorVal))))
+		klog.V(2).InfoS("Health events", "supported", s.healthEvents)
+	})
+
+	deadline := time.Now().Add(interval)
+
+	if s.healthEvents && s.listenMutex.TryLock() {
+		defer s.listenMutex.Unlock()
+
+		for remaining := interval; remaining > 0 && ctx.Err() == nil; remaining = time.Until(deadline) {
+			errorVal := uint32(0)
+
+			count := int(C.zes_listen_health_events(C.uint32_t(min(remaining, maxListenTimeout).Milliseconds()), (*C.uint32_t)(unsafe.Pointer(&errorVal))))
+			if count > 0 {
+				return true
+			}
+
+			if count < 0 {
+				klog.InfoS("Health event listening returned an error", "errorCode", fmt.Sprintf("0x%X", errorVal))
+
+				break
+			}
+		}
+	}
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(time.Until(deadline)):
+		return true
+	}
+}
+
+func (s *server) WatchDeviceHealth(req *levelzero.WatchDeviceHealthRequest, stream levelzero.Levelzero_WatchDeviceHealthServer) error {
+	interval := watchInterval(req.IntervalMs)
+	ctx := stream.Context()
+	sent := map[string]*levelzero.DeviceHealthEvent{}
+
+	klog.V(2).InfoS("Watching device health", "interval", interval)
+
+	for {
+		for _, event := range s.deviceHealthEvents(ctx) {
+			if !healthChanged(sent[event.BdfAddress], event) {
+				continue
+			}
+
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+
+			sent[event.BdfAddress] = event
+		}
+
+		if !s.waitHealthEvents(ctx, interval) {
+			klog.V(2).InfoS("Device health watch ended")
+
+			return nil
+		}
+	}
+}
+
 func main() {
 	klog.InitFlags(nil)
 
@@ -221,7 +645,7 @@ func main() {
 
 	levelzero.RegisterLevelzeroServer(s, &server{})
 
-	klog.Infof("server listening at %v", lis.Addr())
+	klog.InfoS("Server listening", "address", lis.Addr())
 
 	if err := s.Serve(lis); err != nil {
 		klog.Fatalf("failed to serve: %v", err)
@@ -17,10 +17,28 @@ package main
 import (
 	"context"
 	"testing"
+	"time"
 
 	levelzero "github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/levelzero"
+	"google.golang.org/grpc"
 )
 
+type fakeHealthStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	events []*levelzero.DeviceHealthEvent
+}
+
+func (f *fakeHealthStream) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeHealthStream) Send(event *levelzero.DeviceHealthEvent) error {
+	f.events = append(f.events, event)
+
+	return nil
+}
+
 func TestErrorConversion(t *testing.T) {
 	t.Run("Known conversion(s)", func(t *testing.T) {
 		desc := retrieveStatusDescription(0)
@@ -85,4 +103,131 @@ func TestCallingMethods(t *testing.T) {
 			t.Log("Received an error")
 		}
 	})
+
+	t.Run("Call get utilization", func(t *testing.T) {
+		utilization, err := s.GetDeviceUtilization(context.Background(), &levelzero.DeviceId{BdfAddress: "0000:00:01.0"})
+
+		if len(utilization.Engines) > 0 {
+			t.Log("Received engine utilization")
+		}
+		if err != nil {
+			t.Log("Received an error")
+		}
+	})
+
+	t.Run("Call get power", func(t *testing.T) {
+		power, err := s.GetDevicePower(context.Background(), &levelzero.DeviceId{BdfAddress: "0000:00:01.0"})
+
+		if power.Tdp > 0 {
+			t.Log("Received TDP")
+		}
+		if err != nil {
+			t.Log("Received an error")
+		}
+	})
+
+	t.Run("Call get frequency", func(t *testing.T) {
+		freq, err := s.GetDeviceFrequency(context.Background(), &levelzero.DeviceId{BdfAddress: "0000:00:01.0"})
+
+		if len(freq.ThrottleReasons) > 0 {
+			t.Log("Frequency is throttled")
+		}
+		if err != nil {
+			t.Log("Received an error")
+		}
+	})
+
+	t.Run("Call get errors", func(t *testing.T) {
+		errs, err := s.GetDeviceErrors(context.Background(), &levelzero.DeviceId{BdfAddress: "0000:00:01.0"})
+
+		if errs.Uncorrectable > 0 {
+			t.Log("Received uncorrectable errors")
+		}
+		if err != nil {
+			t.Log("Received an error")
+		}
+	})
+
+	t.Run("Call get memory bandwidth", func(t *testing.T) {
+		bandwidth, err := s.GetDeviceMemoryBandwidth(context.Background(), &levelzero.DeviceId{BdfAddress: "0000:00:01.0"})
+
+		if bandwidth.Max > 0 {
+			t.Log("Received max bandwidth")
+		}
+		if err != nil {
+			t.Log("Received an error")
+		}
+	})
+
+	t.Run("Call watch health", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		stream := &fakeHealthStream{ctx: ctx}
+
+		if err := s.WatchDeviceHealth(&levelzero.WatchDeviceHealthRequest{IntervalMs: 1000}, stream); err != nil {
+			t.Error("cancelled watch returned an error:", err)
+		}
+
+		if len(stream.events) > 0 {
+			t.Log("Received health events")
+		}
+	})
+}
+
+func TestWatchInterval(t *testing.T) {
+	if interval := watchInterval(0); interval != defaultWatchInterval {
+		t.Errorf("expected the default interval, got %v", interval)
+	}
+
+	if interval := watchInterval(10); interval != minWatchInterval {
+		t.Errorf("expected the minimum interval, got %v", interval)
+	}
+
+	if interval := watchInterval(2000); interval != 2*time.Second {
+		t.Errorf("expected 2s, got %v", interval)
+	}
+}
+
+func TestHealthChanged(t *testing.T) {
+	event := func(memoryOk bool, gpuTemp float64) *levelzero.DeviceHealthEvent {
+		return &levelzero.DeviceHealthEvent{
+			BdfAddress:  "0000:03:00.0",
+			Health:      &levelzero.DeviceHealth{MemoryOk: memoryOk, BusOk: true, SocOk: true},
+			Temperature: &levelzero.DeviceTemperature{Global: 40, Gpu: gpuTemp, Memory: 40},
+		}
+	}
+
+	for name, tc := range map[string]struct {
+		previous *levelzero.DeviceHealthEvent
+		current  *levelzero.DeviceHealthEvent
+		expected bool
+	}{
+		"first":               {previous: nil, current: event(true, 40), expected: true},
+		"unchanged":           {previous: event(true, 40), current: event(true, 40.5), expected: false},
+		"health changed":      {previous: event(true, 40), current: event(false, 40), expected: true},
+		"temperature changed": {previous: event(true, 40), current: event(true, 41.5), expected: true},
+	} {
+		if changed := healthChanged(tc.previous, tc.current); changed != tc.expected {
+			t.Errorf("%s: expected %t, got %t", name, tc.expected, changed)
+		}
+	}
+}
+
+func TestCounterSampleRate(t *testing.T) {
+	previous := counterSample{counters: []uint64{1000, 500}, timestamps: []uint64{10000, 10000}}
+	current := counterSample{counters: []uint64{1500, 100}, timestamps: []uint64{11000, 11000}}
+
+	if rate := current.rate(previous, 0); rate != 0.5 {
+		t.Errorf("expected rate 0.5, got %f", rate)
+	}
+
+	// A reset counter has no rate.
+	if rate := current.rate(previous, 1); rate != 0 {
+		t.Errorf("expected rate 0 for a reset counter, got %f", rate)
+	}
+
+	if rate := current.rate(counterSample{}, 0); rate != 0 {
+		t.Errorf("expected rate 0 without a previous sample, got %f", rate)
+	}
 }
@@ -20,6 +20,17 @@
 #define VENDOR_ID_INTEL 0x8086
 #define TEMP_ERROR_RET_VAL -999.0
 
+// Engine groups of engine_activity_t: all, compute, render, media and copy.
+#define ENGINE_GROUP_COUNT 5
+
+// Cumulative busy times of the engines of each group and the times they were
+// read at, in microseconds. The times of the engines of a group are summed up.
+typedef struct {
+    uint64_t active_time[ENGINE_GROUP_COUNT];
+    uint64_t timestamp[ENGINE_GROUP_COUNT];
+    uint32_t engines[ENGINE_GROUP_COUNT];
+} engine_activity_t;
+
 void zes_set_verbosity(const int level);
 
 bool ze_try_initialize(void);
@@ -32,3 +43,12 @@ uint64_t zes_device_memory_amount(char* bdf_address, uint32_t* error);
 bool zes_device_memory_is_healthy(char* bdf_address, uint32_t* error);
 bool zes_device_bus_is_healthy(char* bdf_address, uint32_t* error);
 double zes_device_temp_max(char* bdf_address, char* sensor, uint32_t* error);
+bool zes_device_engine_activity(char* bdf_address, engine_activity_t* activity, uint32_t* error);
+bool zes_device_energy(char* bdf_address, uint64_t* energy, uint64_t* timestamp, double* tdp, uint32_t* error);
+bool zes_device_frequency(char* bdf_address, double* actual, double* requested, double* max, uint32_t* throttle_reasons, uint32_t* error);
+bool zes_device_ras_errors(char* bdf_address, uint64_t* correctable, uint64_t* uncorrectable, uint32_t* error);
+bool zes_device_memory_bandwidth(char* bdf_address, uint64_t* read, uint64_t* write, uint64_t* max, uint64_t* timestamp, uint32_t* error);
+uint32_t zes_device_count(uint32_t* error);
+int zes_device_bdf_address(uint32_t index, char* out, uint32_t out_size, uint32_t* error);
+bool zes_register_health_events(uint32_t* error);
+int zes_listen_health_events(uint32_t timeout_ms, uint32_t* error);
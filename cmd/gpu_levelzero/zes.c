@@ -32,6 +32,8 @@ zes_device_handle_t* zes_handles = NULL;
 struct device_info* bdf_addresses = NULL;
 uint32_t zes_handles_count = 0;
 
+static zes_driver_handle_t zes_driver = NULL;
+
 static bool device_enumerated = false;
 
 typedef enum {
@@ -125,6 +127,7 @@ static ze_result_t enumerate_zes_devices(void)
     }
 
     zes_handles_count = count;
+    zes_driver = handle;
 
     bdf_addresses = (struct device_info*) calloc(count,sizeof(struct device_info));
     if (bdf_addresses == NULL) {
@@ -439,3 +442,490 @@ double zes_device_temp_max(char* bdf_address, char* sensor, uint32_t* error)
 
     return TEMP_ERROR_RET_VAL;
 }
+
+/// @brief Retrieves the handle of a device, enumerating the devices first
+/// @param bdf_address
+/// @return handle of the device, or 0 with the error set
+static zes_device_handle_t device_handle(char* bdf_address, uint32_t* error)
+{
+    if (!device_enumerated) {
+        ze_result_t res = enumerate_zes_devices();
+        if (res != ZE_RESULT_SUCCESS) {
+            *error = res;
+
+            return 0;
+        }
+    }
+
+    zes_device_handle_t handle = retrieve_handle_for_bdf(bdf_address);
+    if (handle == 0) {
+        *error = ZE_RESULT_ERROR_UNKNOWN;
+    }
+
+    return handle;
+}
+
+static int engine_group_index(zes_engine_group_t type)
+{
+    switch (type) {
+    case ZES_ENGINE_GROUP_ALL:
+        return 0;
+    case ZES_ENGINE_GROUP_COMPUTE_ALL:
+        return 1;
+    case ZES_ENGINE_GROUP_RENDER_ALL:
+        return 2;
+    case ZES_ENGINE_GROUP_MEDIA_ALL:
+        return 3;
+    case ZES_ENGINE_GROUP_COPY_ALL:
+        return 4;
+    default:
+        return -1;
+    }
+}
+
+/// @brief Retrieves the busy times of the engine groups of a device
+/// @param bdf_address
+/// @param activity - busy times of the groups, see engine_activity_t
+/// @return true when the busy times were read
+bool zes_device_engine_activity(char* bdf_address, engine_activity_t* activity, uint32_t* error)
+{
+    if (getenv("UNITTEST") != NULL) {
+        return false;
+    }
+
+    print_log(LOG_DEBUG, "Fetch engine activity for %s\n", bdf_address);
+
+    memset(activity, 0, sizeof(*activity));
+
+    zes_device_handle_t handle = device_handle(bdf_address, error);
+    if (handle == 0) {
+        return false;
+    }
+
+    uint32_t count = 0;
+    ze_result_t res = zesDeviceEnumEngineGroups(handle, &count, NULL);
+    if (res != ZE_RESULT_SUCCESS || count == 0) {
+        *error = res == ZE_RESULT_SUCCESS ? ZE_RESULT_ERROR_NOT_AVAILABLE : res;
+
+        return false;
+    }
+
+    zes_engine_handle_t engine_handles[count];
+    res = zesDeviceEnumEngineGroups(handle, &count, engine_handles);
+    if (res != ZE_RESULT_SUCCESS) {
+        *error = res;
+
+        return false;
+    }
+
+    for (uint32_t i = 0; i < count; ++i) {
+        zes_engine_properties_t props = {
+            .stype = ZES_STRUCTURE_TYPE_ENGINE_PROPERTIES,
+        };
+
+        if (zesEngineGetProperties(engine_handles[i], &props) != ZE_RESULT_SUCCESS) {
+            continue;
+        }
+
+        int group = engine_group_index(props.type);
+        if (group < 0) {
+            continue;
+        }
+
+        zes_engine_stats_t stats;
+        if (zesEngineGetActivity(engine_handles[i], &stats) != ZE_RESULT_SUCCESS) {
+            continue;
+        }
+
+        activity->active_time[group] += stats.activeTime;
+        activity->timestamp[group] += stats.timestamp;
+        activity->engines[group]++;
+    }
+
+    return true;
+}
+
+/// @brief Retrieves the energy counter of the card's power domain and its TDP
+/// @param bdf_address
+/// @param energy - energy in microjoules
+/// @param timestamp - time of the counter in microseconds
+/// @param tdp - the default power limit in watts, or 0 when not known
+/// @return true when the energy counter was read
+bool zes_device_energy(char* bdf_address, uint64_t* energy, uint64_t* timestamp, double* tdp, uint32_t* error)
+{
+    if (getenv("UNITTEST") != NULL) {
+        return false;
+    }
+
+    print_log(LOG_DEBUG, "Fetch energy for %s\n", bdf_address);
+
+    zes_device_handle_t handle = device_handle(bdf_address, error);
+    if (handle == 0) {
+        return false;
+    }
+
+    uint32_t count = 0;
+    ze_result_t res = zesDeviceEnumPowerDomains(handle, &count, NULL);
+    if (res != ZE_RESULT_SUCCESS || count == 0) {
+        *error = res == ZE_RESULT_SUCCESS ? ZE_RESULT_ERROR_NOT_AVAILABLE : res;
+
+        return false;
+    }
+
+    zes_pwr_handle_t power_handles[count];
+    res = zesDeviceEnumPowerDomains(handle, &count, power_handles);
+    if (res != ZE_RESULT_SUCCESS) {
+        *error = res;
+
+        return false;
+    }
+
+    for (uint32_t i = 0; i < count; ++i) {
+        zes_power_properties_t props = {
+            .stype = ZES_STRUCTURE_TYPE_POWER_PROPERTIES,
+        };
+
+        // The domains of the tiles are skipped, the card's domain covers them.
+        if (zesPowerGetProperties(power_handles[i], &props) != ZE_RESULT_SUCCESS || props.onSubdevice) {
+            continue;
+        }
+
+        zes_power_energy_counter_t counter;
+        res = zesPowerGetEnergyCounter(power_handles[i], &counter);
+        if (res != ZE_RESULT_SUCCESS) {
+            *error = res;
+
+            return false;
+        }
+
+        *energy = counter.energy;
+        *timestamp = counter.timestamp;
+        *tdp = props.defaultLimit > 0 ? props.defaultLimit / 1000.0 : 0.0;
+
+        print_log(LOG_DEBUG, "> Energy: %lu uJ, TDP: %.1f W\n", *energy, *tdp);
+
+        return true;
+    }
+
+    *error = ZE_RESULT_ERROR_NOT_AVAILABLE;
+
+    return false;
+}
+
+/// @brief Retrieves the GPU frequency of a device. With several GPU frequency
+/// domains, i.e. tiles, the most throttled one is returned.
+/// @param bdf_address
+/// @param actual, requested, max - frequencies in MHz
+/// @param throttle_reasons - zes_freq_throttle_reason_flags_t of the domain
+/// @return true when the frequency was read
+bool zes_device_frequency(char* bdf_address, double* actual, double* requested, double* max, uint32_t* throttle_reasons, uint32_t* error)
+{
+    if (getenv("UNITTEST") != NULL) {
+        return false;
+    }
+
+    print_log(LOG_DEBUG, "Fetch frequency for %s\n", bdf_address);
+
+    zes_device_handle_t handle = device_handle(bdf_address, error);
+    if (handle == 0) {
+        return false;
+    }
+
+    uint32_t count = 0;
+    ze_result_t res = zesDeviceEnumFrequencyDomains(handle, &count, NULL);
+    if (res != ZE_RESULT_SUCCESS || count == 0) {
+        *error = res == ZE_RESULT_SUCCESS ? ZE_RESULT_ERROR_NOT_AVAILABLE : res;
+
+        return false;
+    }
+
+    zes_freq_handle_t freq_handles[count];
+    res = zesDeviceEnumFrequencyDomains(handle, &count, freq_handles);
+    if (res != ZE_RESULT_SUCCESS) {
+        *error = res;
+
+        return false;
+    }
+
+    bool found = false;
+
+    for (uint32_t i = 0; i < count; ++i) {
+        zes_freq_properties_t props = {
+            .stype = ZES_STRUCTURE_TYPE_FREQ_PROPERTIES,
+        };
+
+        if (zesFrequencyGetProperties(freq_handles[i], &props) != ZE_RESULT_SUCCESS || props.type != ZES_FREQ_DOMAIN_GPU) {
+            continue;
+        }
+
+        zes_freq_state_t state = {
+            .stype = ZES_STRUCTURE_TYPE_FREQ_STATE,
+        };
+
+        if (zesFrequencyGetState(freq_handles[i], &state) != ZE_RESULT_SUCCESS) {
+            continue;
+        }
+
+        if (!found || state.actual < *actual) {
+            *actual = state.actual;
+            *requested = state.request;
+            *max = props.max;
+            *throttle_reasons = state.throttleReasons;
+
+            found = true;
+        }
+    }
+
+    if (!found) {
+        *error = ZE_RESULT_ERROR_NOT_AVAILABLE;
+
+        return false;
+    }
+
+    print_log(LOG_DEBUG, "> Frequency: %.0f MHz (requested %.0f MHz), throttle reasons: 0x%X\n", *actual, *requested, *throttle_reasons);
+
+    return true;
+}
+
+/// @brief Retrieves the RAS error counts of a device, summed up over the
+/// categories and the tiles
+/// @param bdf_address
+/// @return true when the error counts were read
+bool zes_device_ras_errors(char* bdf_address, uint64_t* correctable, uint64_t* uncorrectable, uint32_t* error)
+{
+    if (getenv("UNITTEST") != NULL) {
+        return false;
+    }
+
+    print_log(LOG_DEBUG, "Fetch RAS errors for %s\n", bdf_address);
+
+    zes_device_handle_t handle = device_handle(bdf_address, error);
+    if (handle == 0) {
+        return false;
+    }
+
+    uint32_t count = 0;
+    ze_result_t res = zesDeviceEnumRasErrorSets(handle, &count, NULL);
+    if (res != ZE_RESULT_SUCCESS || count == 0) {
+        *error = res == ZE_RESULT_SUCCESS ? ZE_RESULT_ERROR_NOT_AVAILABLE : res;
+
+        return false;
+    }
+
+    zes_ras_handle_t ras_handles[count];
+    res = zesDeviceEnumRasErrorSets(handle, &count, ras_handles);
+    if (res != ZE_RESULT_SUCCESS) {
+        *error = res;
+
+        return false;
+    }
+
+    *correctable = 0;
+    *uncorrectable = 0;
+
+    for (uint32_t i = 0; i < count; ++i) {
+        zes_ras_properties_t props = {
+            .stype = ZES_STRUCTURE_TYPE_RAS_PROPERTIES,
+        };
+
+        if (zesRasGetProperties(ras_handles[i], &props) != ZE_RESULT_SUCCESS) {
+            continue;
+        }
+
+        zes_ras_state_t state = {
+            .stype = ZES_STRUCTURE_TYPE_RAS_STATE,
+        };
+
+        // The counters are not cleared, they are shared with other tools.
+        if (zesRasGetState(ras_handles[i], 0, &state) != ZE_RESULT_SUCCESS) {
+            continue;
+        }
+
+        uint64_t total = 0;
+        for (uint32_t category = 0; category < ZES_MAX_RAS_ERROR_CATEGORY_COUNT; ++category) {
+            total += state.category[category];
+        }
+
+        if (props.type == ZES_RAS_ERROR_TYPE_CORRECTABLE) {
+            *correctable += total;
+        } else {
+            *uncorrectable += total;
+        }
+    }
+
+    print_log(LOG_DEBUG, "> RAS errors: %lu correctable, %lu uncorrectable\n", *correctable, *uncorrectable);
+
+    return true;
+}
+
+/// @brief Retrieves the memory bandwidth counters of a device, summed up over
+/// the memory modules
+/// @param bdf_address
+/// @param read, write - bytes read and written
+/// @param max - the maximum bandwidth in bytes per second
+/// @param timestamp - time of the counters in microseconds
+/// @return true when the counters were read
+bool zes_device_memory_bandwidth(char* bdf_address, uint64_t* read, uint64_t* write, uint64_t* max, uint64_t* timestamp, uint32_t* error)
+{
+    if (getenv("UNITTEST") != NULL) {
+        return false;
+    }
+
+    print_log(LOG_DEBUG, "Fetch memory bandwidth for %s\n", bdf_address);
+
+    zes_device_handle_t handle = device_handle(bdf_address, error);
+    if (handle == 0) {
+        return false;
+    }
+
+    // Levelzero does not provide memory details for integrated
+    if (is_integrated(handle)) {
+        *error = ZE_RESULT_ERROR_NOT_AVAILABLE;
+
+        return false;
+    }
+
+    uint32_t count = 0;
+    ze_result_t res = zesDeviceEnumMemoryModules(handle, &count, NULL);
+    if (res != ZE_RESULT_SUCCESS || count == 0) {
+        *error = res == ZE_RESULT_SUCCESS ? ZE_RESULT_ERROR_NOT_AVAILABLE : res;
+
+        return false;
+    }
+
+    zes_mem_handle_t mem_handles[count];
+    res = zesDeviceEnumMemoryModules(handle, &count, mem_handles);
+    if (res != ZE_RESULT_SUCCESS) {
+        *error = res;
+
+        return false;
+    }
+
+    *read = 0;
+    *write = 0;
+    *max = 0;
+    *timestamp = 0;
+
+    for (uint32_t i = 0; i < count; ++i) {
+        zes_mem_bandwidth_t bandwidth;
+
+        res = zesMemoryGetBandwidth(mem_handles[i], &bandwidth);
+        if (res != ZE_RESULT_SUCCESS) {
+            *error = res;
+
+            return false;
+        }
+
+        *read += bandwidth.readCounter;
+        *write += bandwidth.writeCounter;
+        *max += bandwidth.maxBandwidth;
+
+        if (bandwidth.timestamp > *timestamp) {
+            *timestamp = bandwidth.timestamp;
+        }
+    }
+
+    return true;
+}
+
+/// @brief Retrieves the number of the devices
+/// @return the number of the devices
+uint32_t zes_device_count(uint32_t* error)
+{
+    if (getenv("UNITTEST") != NULL) {
+        return 0;
+    }
+
+    if (!device_enumerated) {
+        ze_result_t res = enumerate_zes_devices();
+        if (res != ZE_RESULT_SUCCESS) {
+            *error = res;
+
+            return 0;
+        }
+    }
+
+    return zes_handles_count;
+}
+
+/// @brief Retrieves the BDF address of a device
+/// @param index - index of the device, less than zes_device_count()
+/// @return the length of the address written to out
+int zes_device_bdf_address(uint32_t index, char* out, uint32_t out_size, uint32_t* error)
+{
+    if (index >= zes_handles_count) {
+        *error = ZE_RESULT_ERROR_INVALID_ARGUMENT;
+
+        return 0;
+    }
+
+    return snprintf(out, out_size, "%s", bdf_addresses[index].bdf);
+}
+
+/// @brief Registers the health events of all the devices: memory health,
+/// critical temperature, uncorrectable errors, reset and detach
+/// @return false when the driver doesn't support the events
+bool zes_register_health_events(uint32_t* error)
+{
+    if (getenv("UNITTEST") != NULL) {
+        return false;
+    }
+
+    if (!device_enumerated) {
+        ze_result_t res = enumerate_zes_devices();
+        if (res != ZE_RESULT_SUCCESS) {
+            *error = res;
+
+            return false;
+        }
+    }
+
+    zes_event_type_flags_t events = ZES_EVENT_TYPE_FLAG_MEM_HEALTH | ZES_EVENT_TYPE_FLAG_TEMP_CRITICAL |
+        ZES_EVENT_TYPE_FLAG_RAS_UNCORRECTABLE_ERRORS | ZES_EVENT_TYPE_FLAG_DEVICE_RESET_REQUIRED |
+        ZES_EVENT_TYPE_FLAG_DEVICE_DETACH;
+
+    for (uint32_t i = 0; i < zes_handles_count; ++i) {
+        ze_result_t res = zesDeviceEventRegister(zes_handles[i], events);
+        if (res != ZE_RESULT_SUCCESS) {
+            print_log(LOG_INFO, "Health events are not supported for %s: 0x%X\n", bdf_addresses[i].bdf, res);
+
+            *error = res;
+
+            return false;
+        }
+    }
+
+    return true;
+}
+
+/// @brief Waits for the health events registered with
+/// zes_register_health_events(), which clears them
+/// @param timeout_ms - the maximum time to wait
+/// @return the number of the devices with events, zero on timeout and -1 on
+/// errors
+int zes_listen_health_events(uint32_t timeout_ms, uint32_t* error)
+{
+    if (getenv("UNITTEST") != NULL || zes_driver == NULL) {
+        *error = ZE_RESULT_ERROR_UNINITIALIZED;
+
+        return -1;
+    }
+
+    zes_event_type_flags_t events[zes_handles_count];
+    uint32_t num_device_events = 0;
+
+    ze_result_t res = zesDriverEventListen((ze_driver_handle_t)zes_driver, timeout_ms, zes_handles_count, zes_handles, &num_device_events, events);
+    if (res != ZE_RESULT_SUCCESS) {
+        *error = res;
+
+        return -1;
+    }
+
+    for (uint32_t i = 0; i < zes_handles_count && num_device_events > 0; ++i) {
+        if (events[i] != 0) {
+            print_log(LOG_DEBUG, "Health events 0x%X for %s\n", events[i], bdf_addresses[i].bdf);
+        }
+    }
+
+    return (int)num_device_events;
+}
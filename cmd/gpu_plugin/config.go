@@ -0,0 +1,143 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"slices"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/gpu_plugin/rm"
+	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/pluginutils"
+	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+)
+
+const configSection = "gpu"
+
+var allocationPolicies = []string{"balanced", "packed", "topology", "none"}
+
+// gpuConfig is the "gpu" section of the config file. The settings are
+// overridden by the corresponding flags given on the command line.
+type gpuConfig struct {
+	SharedDevNum     *int    `json:"sharedDevNum,omitempty"`     // -shared-dev-num
+	TemperatureLimit *int    `json:"temperatureLimit,omitempty"` // -temp-limit
+	AllocationPolicy *string `json:"allocationPolicy,omitempty"` // -allocation-policy
+	// LevelzeroUnreachable is the health of the GPUs when the Level-Zero
+	// sidecar is unreachable.
+	LevelzeroUnreachable *string `json:"levelzeroUnreachable,omitempty"` // -levelzero-unreachable
+	// SRIOV makes the plugin create the SR-IOV VFs of the GPUs. There's
+	// no flag for it.
+	SRIOV *sriovProfile `json:"sriov,omitempty"`
+	// HealthThresholds make the cards unhealthy on the values Level-Zero
+	// reports, with -health-management. There's no flag for them.
+	HealthThresholds *healthThresholds `json:"healthThresholds,omitempty"`
+}
+
+func validateOptions(opts cliOptions) error {
+	if opts.sharedDevNum < 1 {
+		return errors.New("The number of containers sharing the same GPU must greater than zero")
+	}
+
+	if opts.sharedDevNum == 1 && opts.resourceManagement {
+		return errors.New("Trying to use fractional resources with shared-dev-num 1 is pointless")
+	}
+
+	if opts.memoryUnitSize > 0 && (opts.resourceManagement || opts.sharedDevNum > 1) {
+		return errors.New("Memory units can't be used with fractional resources or shared-dev-num")
+	}
+
+	if opts.tileResources && (opts.resourceManagement || opts.sharedDevNum > 1 || opts.memoryUnitSize > 0) {
+		return errors.New("Tile resources can't be used with fractional resources, shared-dev-num or memory units")
+	}
+
+	if opts.tileResources && !rm.IsHierarchyMode(opts.tileHierarchy) {
+		return errors.Errorf("invalid tile hierarchy mode %q, the valid modes: FLAT, COMPOSITE and COMBINED", opts.tileHierarchy)
+	}
+
+	if !slices.Contains(allocationPolicies, opts.preferredAllocationPolicy) {
+		return errors.Errorf("invalid value for preferredAllocationPolicy, the valid values: %v", allocationPolicies)
+	}
+
+	if opts.healthManagement && !slices.Contains(unreachablePolicies, opts.levelzeroUnreachable) {
+		return errors.Errorf("invalid value for levelzeroUnreachable, the valid values: %v", unreachablePolicies)
+	}
+
+	return nil
+}
+
+// ConfigSection implements the Configurable interface.
+func (dp *devicePlugin) ConfigSection() string {
+	return configSection
+}
+
+// ApplyConfig implements the Configurable interface. The new settings are
+// applied on a rescan of the devices.
+func (dp *devicePlugin) ApplyConfig(section []byte) error {
+	cfg := gpuConfig{}
+	if err := dpapi.DecodeConfigSection(section, &cfg); err != nil {
+		return err
+	}
+
+	opts := dp.flagOptions
+
+	pluginutils.OverrideWithConfig(&opts.sharedDevNum, cfg.SharedDevNum, "shared-dev-num", dp.commandLine)
+	pluginutils.OverrideWithConfig(&opts.temperatureLimit, cfg.TemperatureLimit, "temp-limit", dp.commandLine)
+	pluginutils.OverrideWithConfig(&opts.preferredAllocationPolicy, cfg.AllocationPolicy, "allocation-policy", dp.commandLine)
+	pluginutils.OverrideWithConfig(&opts.levelzeroUnreachable, cfg.LevelzeroUnreachable, "levelzero-unreachable", dp.commandLine)
+
+	if err := validateOptions(opts); err != nil {
+		return err
+	}
+
+	if cfg.SRIOV != nil {
+		if err := cfg.SRIOV.validate(); err != nil {
+			return errors.Wrap(err, "invalid SR-IOV profile")
+		}
+	}
+
+	if cfg.HealthThresholds != nil {
+		if err := cfg.HealthThresholds.validate(); err != nil {
+			return errors.Wrap(err, "invalid health thresholds")
+		}
+	}
+
+	if err := dp.checkSriovProfile(cfg.SRIOV); err != nil {
+		return err
+	}
+
+	klog.V(1).InfoS("GPU config", "sharedDevNum", opts.sharedDevNum, "temperatureLimit", opts.temperatureLimit,
+		"allocationPolicy", opts.preferredAllocationPolicy)
+
+	dp.mutex.Lock()
+
+	if dp.options.sriov != nil && cfg.SRIOV == nil {
+		klog.InfoS("SR-IOV profile removed, the existing VFs are left in place, set numVFs to 0 to remove them")
+	}
+
+	dp.options.sharedDevNum = opts.sharedDevNum
+	dp.options.temperatureLimit = opts.temperatureLimit
+	dp.options.preferredAllocationPolicy = opts.preferredAllocationPolicy
+	dp.options.levelzeroUnreachable = opts.levelzeroUnreachable
+	dp.options.sriov = cfg.SRIOV
+	dp.options.healthThresholds = cfg.HealthThresholds
+	dp.policy = dp.allocationPolicy(opts.preferredAllocationPolicy)
+
+	dp.mutex.Unlock()
+
+	dp.scanWatcher.Rescan()
+
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+)
+
+func TestApplyConfig(t *testing.T) {
+	tcases := []struct {
+		commandLine map[string]bool
+		name        string
+		section     string
+		expected    cliOptions
+		options     cliOptions
+		expectedErr bool
+	}{
+		{
+			name:     "empty section keeps the flags",
+			options:  cliOptions{sharedDevNum: 2, temperatureLimit: 100, preferredAllocationPolicy: "none"},
+			expected: cliOptions{sharedDevNum: 2, temperatureLimit: 100, preferredAllocationPolicy: "none"},
+		},
+		{
+			name:     "config overrides flag defaults",
+			section:  `{"sharedDevNum": 4, "temperatureLimit": 90, "allocationPolicy": "packed"}`,
+			options:  cliOptions{sharedDevNum: 1, temperatureLimit: 100, preferredAllocationPolicy: "none"},
+			expected: cliOptions{sharedDevNum: 4, temperatureLimit: 90, preferredAllocationPolicy: "packed"},
+		},
+		{
+			name:        "command line overrides config",
+			section:     `{"sharedDevNum": 4, "temperatureLimit": 90}`,
+			commandLine: map[string]bool{"shared-dev-num": true},
+			options:     cliOptions{sharedDevNum: 2, temperatureLimit: 100, preferredAllocationPolicy: "none"},
+			expected:    cliOptions{sharedDevNum: 2, temperatureLimit: 90, preferredAllocationPolicy: "none"},
+		},
+		{
+			name:     "config enables fractional resources",
+			section:  `{"sharedDevNum": 4}`,
+			options:  cliOptions{sharedDevNum: 1, resourceManagement: true, preferredAllocationPolicy: "none"},
+			expected: cliOptions{sharedDevNum: 4, resourceManagement: true, preferredAllocationPolicy: "none"},
+		},
+		{
+			name:        "invalid share count",
+			section:     `{"sharedDevNum": 0}`,
+			options:     cliOptions{sharedDevNum: 2, preferredAllocationPolicy: "none"},
+			expected:    cliOptions{sharedDevNum: 2, preferredAllocationPolicy: "none"},
+			expectedErr: true,
+		},
+		{
+			name:        "invalid policy",
+			section:     `{"allocationPolicy": "random"}`,
+			options:     cliOptions{sharedDevNum: 2, preferredAllocationPolicy: "none"},
+			expected:    cliOptions{sharedDevNum: 2, preferredAllocationPolicy: "none"},
+			expectedErr: true,
+		},
+		{
+			name:        "unknown setting",
+			section:     `{"sharedDeviceNum": 4}`,
+			options:     cliOptions{sharedDevNum: 2, preferredAllocationPolicy: "none"},
+			expected:    cliOptions{sharedDevNum: 2, preferredAllocationPolicy: "none"},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			plugin := &devicePlugin{
+				options:     tc.options,
+				flagOptions: tc.options,
+				commandLine: tc.commandLine,
+				scanWatcher: newScanWatcher(t.TempDir(), tc.options),
+				policy:      nonePolicy,
+			}
+
+			err := plugin.ApplyConfig([]byte(tc.section))
+			if tc.expectedErr != (err != nil) {
+				t.Fatalf("unexpected error state: %+v", err)
+			}
+
+			if options := plugin.currentOptions(); options != tc.expected {
+				t.Errorf("expected options %+v, got %+v", tc.expected, options)
+			}
+
+			if plugin.policy != plugin.allocationPolicy(tc.expected.preferredAllocationPolicy) {
+				t.Error("unexpected allocation policy")
+			}
+		})
+	}
+}
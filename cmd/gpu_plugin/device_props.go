@@ -15,7 +15,9 @@
 package main
 
 import (
+	"path/filepath"
 	"slices"
+	"strconv"
 
 	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/labeler"
 	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/pluginutils"
@@ -23,10 +25,12 @@ import (
 )
 
 type DeviceProperties struct {
-	currentDriver string
 	drmDrivers    map[string]bool
-	tileCounts    []uint64
+	tileCounts    map[string][]uint64 // DRM driver -> tile counts of its cards
+	currentDriver string
+	currentTiles  uint64
 	isPfWithVfs   bool
+	isVf          bool
 }
 
 type invalidTileCountErr struct {
@@ -36,47 +40,66 @@ type invalidTileCountErr struct {
 func newDeviceProperties() *DeviceProperties {
 	return &DeviceProperties{
 		drmDrivers: make(map[string]bool),
+		tileCounts: make(map[string][]uint64),
 	}
 }
 
 func (d *DeviceProperties) fetch(cardPath string) {
 	d.isPfWithVfs = pluginutils.IsSriovPFwithVFs(cardPath)
+	d.isVf = pluginutils.IsSriovVF(cardPath)
 
-	d.tileCounts = append(d.tileCounts, labeler.GetTileCount(cardPath))
+	d.currentTiles = labeler.GetTileCount(cardPath)
 
 	driverName, err := pluginutils.ReadDeviceDriver(cardPath)
 	if err != nil {
-		klog.Warningf("card (%s) doesn't have driver, using default: %s", cardPath, deviceTypeDefault)
+		klog.InfoS("Card doesn't have a driver, using the default", "card", filepath.Base(cardPath), "driver", deviceTypeDefault)
 
 		driverName = deviceTypeDefault
 	}
 
 	d.currentDriver = driverName
 	d.drmDrivers[d.currentDriver] = true
-}
-
-func (d *DeviceProperties) drmDriverCount() int {
-	return len(d.drmDrivers)
+	d.tileCounts[d.currentDriver] = append(d.tileCounts[d.currentDriver], d.currentTiles)
 }
 
 func (d *DeviceProperties) driver() string {
 	return d.currentDriver
 }
 
+// attributes returns the inventory attributes of the last fetched card.
+func (d *DeviceProperties) attributes(cardPath string) map[string]string {
+	attributes := map[string]string{
+		"driver": d.currentDriver,
+	}
+
+	if d.currentTiles > 0 {
+		attributes["tiles"] = strconv.FormatUint(d.currentTiles, 10)
+	}
+
+	if id, err := pciDeviceIDForCard(cardPath); err == nil {
+		attributes["pci-device-id"] = id
+	}
+
+	return attributes
+}
+
 func (d *DeviceProperties) monitorResource() string {
 	return d.currentDriver + monitorSuffix
 }
 
-func (d *DeviceProperties) maxTileCount() (uint64, error) {
-	if len(d.tileCounts) == 0 {
+// maxTileCount returns the tile count of the cards using the driver, which
+// must be the same for all of them.
+func (d *DeviceProperties) maxTileCount(driver string) (uint64, error) {
+	tileCounts := d.tileCounts[driver]
+	if len(tileCounts) == 0 {
 		return 0, invalidTileCountErr{}
 	}
 
-	minCount := slices.Min(d.tileCounts)
-	maxCount := slices.Max(d.tileCounts)
+	minCount := slices.Min(tileCounts)
+	maxCount := slices.Max(tileCounts)
 
 	if minCount != maxCount {
-		klog.Warningf("Node's GPUs are heterogenous (min: %d, max: %d tiles)", minCount, maxCount)
+		klog.InfoS("Node's GPUs are heterogenous", "driver", driver, "minTiles", minCount, "maxTiles", maxCount)
 
 		return 0, invalidTileCountErr{}
 	}
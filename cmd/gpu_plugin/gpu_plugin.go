@@ -15,6 +15,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"io/fs"
@@ -22,9 +23,9 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
-	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -36,6 +37,7 @@ import (
 	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/gpu_plugin/rm"
 	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/labeler"
 	gpulevelzero "github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/levelzero"
+	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/pluginutils"
 	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
 	cdispec "tags.cncf.io/container-device-interface/specs-go"
 )
@@ -63,7 +65,7 @@ const (
 	monitorSuffix = "_monitoring"
 	monitorID     = "all"
 
-	// Period of device scans.
+	// Period of WSL device scans and device health checks.
 	scanPeriod = 5 * time.Second
 
 	// Labeler's max update interval, 5min.
@@ -71,146 +73,37 @@ const (
 )
 
 type cliOptions struct {
+	sriov                     *sriovProfile     // from the config file only
+	healthThresholds          *healthThresholds // from the config file only
 	preferredAllocationPolicy string
+	tileHierarchy             string
+	levelzeroUnreachable      string
+	memoryUnitSize            uint64
 	sharedDevNum              int
 	temperatureLimit          int
 	enableMonitoring          bool
 	resourceManagement        bool
 	wslScan                   bool
 	healthManagement          bool
+	tileResources             bool
 }
 
-type rmWithMultipleDriversErr struct {
-	error
-}
-
-type preferredAllocationPolicyFunc func(*pluginapi.ContainerPreferredAllocationRequest) []string
-
-// nonePolicy is used for allocating GPU devices randomly, while trying
-// to select as many individual GPU devices as requested.
-func nonePolicy(req *pluginapi.ContainerPreferredAllocationRequest) []string {
-	klog.V(2).Info("Select nonePolicy for GPU device allocation")
-
-	devices := make(map[string]bool)
-	selected := make(map[string]bool)
-	neededCount := req.AllocationSize
-
-	// When shared-dev-num is greater than 1, try to find as
-	// many independent GPUs as possible, to satisfy the request.
-
-	for _, deviceID := range req.AvailableDeviceIDs {
-		device := strings.Split(deviceID, "-")[0]
-
-		if _, found := devices[device]; !found {
-			devices[device] = true
-			selected[deviceID] = true
-			neededCount--
-
-			if neededCount == 0 {
-				break
-			}
-		}
+var (
+	// nonePolicy tries to select as many individual GPU devices as requested,
+	// in the order given by kubelet.
+	nonePolicy = dpapi.NewAllocationPolicy(dpapi.Spread(dpapi.ParentKey("-")))
+	// balancedPolicy spreads the allocations among the GPU devices.
+	balancedPolicy = dpapi.NewAllocationPolicy(dpapi.Balance(dpapi.ParentKey("-")), dpapi.ByID)
+	// packedPolicy fills one GPU device fully before moving to the next.
+	packedPolicy = dpapi.NewAllocationPolicy(dpapi.Pack(dpapi.ParentKey("-")), dpapi.ByID)
+	// advertisedDeviceTypes are the device types the plugin may advertise.
+	advertisedDeviceTypes = []string{
+		deviceTypeI915, deviceTypeXe, deviceTypeMemory,
+		deviceTypeI915 + vfSuffix, deviceTypeXe + vfSuffix,
+		deviceTypeI915 + tileSuffix, deviceTypeXe + tileSuffix,
+		deviceTypeXe + monitorSuffix, deviceTypeI915 + monitorSuffix,
 	}
-
-	// If there were not enough independent GPUs, use remaining untaken deviceIDs.
-
-	if neededCount > 0 {
-		for _, deviceID := range req.AvailableDeviceIDs {
-			if _, found := selected[deviceID]; !found {
-				selected[deviceID] = true
-				neededCount--
-
-				if neededCount == 0 {
-					break
-				}
-			}
-		}
-	}
-
-	// Convert selected map into an array
-
-	deviceIDs := []string{}
-
-	for deviceID := range selected {
-		deviceIDs = append(deviceIDs, deviceID)
-	}
-
-	klog.V(2).Infof("Allocate deviceIds: %q", deviceIDs)
-
-	return deviceIDs
-}
-
-// balancedPolicy is used for allocating GPU devices in balance.
-func balancedPolicy(req *pluginapi.ContainerPreferredAllocationRequest) []string {
-	klog.V(2).Info("Select balancedPolicy for GPU device allocation")
-
-	// Save the shared-devices list of each physical GPU.
-	Card := make(map[string][]string)
-	// Save the available shared-nums of each physical GPU.
-	Count := make(map[string]int)
-
-	for _, deviceID := range req.AvailableDeviceIDs {
-		device := strings.Split(deviceID, "-")
-		Card[device[0]] = append(Card[device[0]], deviceID)
-		Count[device[0]]++
-	}
-
-	// Save the physical GPUs in order.
-	Index := make([]string, 0)
-	for key := range Count {
-		Index = append(Index, key)
-		sort.Strings(Card[key])
-	}
-
-	sort.Strings(Index)
-
-	need := req.AllocationSize
-
-	var deviceIds []string
-
-	// We choose one device ID from the GPU card that has most shared gpu IDs each time.
-	for {
-		var (
-			allocateCard string
-			max          int
-		)
-
-		for _, key := range Index {
-			if Count[key] > max {
-				max = Count[key]
-				allocateCard = key
-			}
-		}
-
-		deviceIds = append(deviceIds, Card[allocateCard][0])
-		need--
-
-		if need == 0 {
-			break
-		}
-
-		// Update Maps
-		Card[allocateCard] = Card[allocateCard][1:]
-		Count[allocateCard]--
-	}
-
-	klog.V(2).Infof("Allocate deviceIds: %q", deviceIds)
-
-	return deviceIds
-}
-
-// packedPolicy is used for allocating GPU devices one by one.
-func packedPolicy(req *pluginapi.ContainerPreferredAllocationRequest) []string {
-	klog.V(2).Info("Select packedPolicy for GPU device allocation")
-
-	deviceIds := req.AvailableDeviceIDs
-	sort.Strings(deviceIds)
-	deviceIds = deviceIds[:req.AllocationSize]
-
-	klog.V(2).Infof("Allocate deviceIds: %q", deviceIds)
-
-	return deviceIds
-}
+)
 
 func (dp *devicePlugin) pciAddressForCard(cardPath, cardName string) (string, error) {
 	linkPath, err := os.Readlink(cardPath)
@@ -225,7 +118,7 @@ func (dp *devicePlugin) pciAddressForCard(cardPath, cardName string) (string, er
 	pciAddress := filepath.Base(strings.TrimSuffix(linkPath, filepath.Join("drm", cardName)))
 
 	if !dp.pciAddressReg.MatchString(pciAddress) {
-		klog.Warningf("Invalid PCI address for %s: %s", cardPath, pciAddress)
+		klog.InfoS("Invalid PCI address", "card", cardName, dpapi.LogKeyBDF, pciAddress)
 
 		return "", os.ErrInvalid
 	}
@@ -250,7 +143,7 @@ func pciDeviceIDForCard(cardPath string) (string, error) {
 func (dp *devicePlugin) bypathMountsForPci(pciAddress, bypathDir string) []pluginapi.Mount {
 	files, err := os.ReadDir(bypathDir)
 	if err != nil {
-		klog.Warningf("Failed to read by-path directory: %+v", err)
+		klog.ErrorS(err, "Failed to read by-path directory", "path", bypathDir)
 
 		return nil
 	}
@@ -279,39 +172,81 @@ type devicePlugin struct {
 	controlDeviceReg *regexp.Regexp
 	pciAddressReg    *regexp.Regexp
 
-	scanTicker    *time.Ticker
+	scanWatcher   *dpapi.DeviceWatcher
 	scanDone      chan bool
 	scanResources chan bool
 
 	resMan           rm.ResourceManager
 	levelzeroService levelzeroservice.LevelzeroService
+	journal          dpapi.AllocationJournal // nil without the allocation journal
 
-	sysfsDir       string
-	devfsDir       string
-	bypathDir      string
-	healthStatuses map[string]string
+	sysfsDir  string
+	devfsDir  string
+	bypathDir string
+
+	// commandLine has the flags set on the command line, which override
+	// the config file, see ApplyConfig().
+	commandLine map[string]bool
 
 	// Note: If restarting the plugin with a new policy, the allocations for existing pods remain with old policy.
-	policy  preferredAllocationPolicyFunc
-	options cliOptions
+	policy *dpapi.AllocationPolicy
+
+	// memoryCards are the GPUs of the memory units by name, see -memory-unit-size.
+	memoryCards map[string]cardDevices
+	// tileCards are the GPUs of the tiles by name, see -tile-resources.
+	tileCards map[string]cardDevices
+
+	// levelzeroHealth is the health of the cards by BDF address from the
+	// health watch of the Level-Zero sidecar, see watchHealth().
+	levelzeroHealth map[string]levelzeroservice.DeviceHealth
+	// healthChanged notifies the health checks of the changes of
+	// levelzeroHealth and healthWatchState.
+	healthChanged chan struct{}
+
+	// flagOptions are the options before the config file is applied.
+	flagOptions cliOptions
+	options     cliOptions
+
+	// mutex protects the options the config file can change, policy, memoryCards, tileCards, journal,
+	// levelzeroHealth and healthWatchState.
+	mutex sync.RWMutex
+
+	healthWatchState watchState
 
 	bypathFound bool
 }
 
+// newScanWatcher returns a watcher which triggers scans on DRM device and PCI
+// driver changes. WSL devices are only available by polling, so the scans are
+// then triggered with scanPeriod as well.
+func newScanWatcher(devfsDir string, options cliOptions) *dpapi.DeviceWatcher {
+	resyncPeriod := dpapi.DefaultScanResyncPeriod
+	if options.wslScan {
+		resyncPeriod = scanPeriod
+	}
+
+	return dpapi.NewDeviceWatcher(
+		dpapi.WithSubsystems("drm", "pci"),
+		dpapi.WithWatchPaths(devfsDir),
+		dpapi.WithResyncPeriod(resyncPeriod))
+}
+
 func newDevicePlugin(sysfsDir, devfsDir string, options cliOptions) *devicePlugin {
 	dp := &devicePlugin{
 		sysfsDir:         sysfsDir,
 		devfsDir:         devfsDir,
 		bypathDir:        path.Join(devfsDir, "/by-path"),
 		options:          options,
+		flagOptions:      options,
 		gpuDeviceReg:     regexp.MustCompile(gpuDeviceRE),
 		controlDeviceReg: regexp.MustCompile(controlDeviceRE),
 		pciAddressReg:    regexp.MustCompile(pciAddressRE),
-		scanTicker:       time.NewTicker(scanPeriod),
+		scanWatcher:      newScanWatcher(devfsDir, options),
 		scanDone:         make(chan bool, 1), // buffered as we may send to it before Scan starts receiving from it
 		bypathFound:      true,
 		scanResources:    make(chan bool, 1),
-		healthStatuses:   make(map[string]string),
+		levelzeroHealth:  make(map[string]levelzeroservice.DeviceHealth),
+		healthChanged:    make(chan struct{}, 1),
 	}
 
 	if options.resourceManagement {
@@ -323,23 +258,16 @@ func newDevicePlugin(sysfsDir, devfsDir string, options cliOptions) *devicePlugi
 				namespace + "/" + deviceTypeXe,
 			})
 		if err != nil {
-			klog.Errorf("Failed to create resource manager: %+v", err)
+			klog.ErrorS(err, "Failed to create resource manager")
 			return nil
 		}
 	}
 
-	switch options.preferredAllocationPolicy {
-	case "balanced":
-		dp.policy = balancedPolicy
-	case "packed":
-		dp.policy = packedPolicy
-	default:
-		dp.policy = nonePolicy
-	}
+	dp.policy = dp.allocationPolicy(options.preferredAllocationPolicy)
 
 	if !options.wslScan {
 		if _, err := os.ReadDir(dp.bypathDir); err != nil {
-			klog.Warningf("failed to read by-path dir: %+v", err)
+			klog.ErrorS(err, "Failed to read by-path directory", "path", dp.bypathDir)
 
 			dp.bypathFound = false
 		}
@@ -348,72 +276,190 @@ func newDevicePlugin(sysfsDir, devfsDir string, options cliOptions) *devicePlugi
 	return dp
 }
 
-func logHealthStatusChange(card, newStatus string, statuses map[string]string) {
-	prevState, found := statuses[card]
-	if !found {
-		klog.V(2).Infof("%s: new => %s", card, newStatus)
+// allocationPolicy returns the preferred allocation policy of the given name.
+func (dp *devicePlugin) allocationPolicy(name string) *dpapi.AllocationPolicy {
+	switch name {
+	case "balanced":
+		return balancedPolicy
+	case "packed":
+		return packedPolicy
+	case "topology":
+		return dpapi.NewTopologyPolicy(func(id string) string {
+			return path.Join(dp.sysfsDir, strings.Split(id, "-")[0])
+		})
+	default:
+		return nonePolicy
+	}
+}
+
+// currentOptions returns the options with the config file applied.
+func (dp *devicePlugin) currentOptions() cliOptions {
+	dp.mutex.RLock()
+	defer dp.mutex.RUnlock()
+
+	return dp.options
+}
+
+// HealthCheckInterval implements the HealthChecker interface.
+func (dp *devicePlugin) HealthCheckInterval() time.Duration {
+	if !dp.options.healthManagement {
+		return 0
+	}
+
+	return scanPeriod
+}
+
+// CheckHealth implements the HealthChecker interface. All the shared
+// devices of a card get the health result of the card.
+func (dp *devicePlugin) CheckHealth(devices map[string][]string) map[string]map[string]dpapi.HealthResult {
+	results := make(map[string]map[string]dpapi.HealthResult)
+	cards := make(map[string]dpapi.HealthResult)
+
+	for devType, ids := range devices {
+		if devType != deviceTypeI915 && devType != deviceTypeXe && devType != deviceTypeMemory &&
+			!strings.HasSuffix(devType, vfSuffix) && !strings.HasSuffix(devType, tileSuffix) {
+			continue
+		}
+
+		results[devType] = make(map[string]dpapi.HealthResult, len(ids))
 
-		statuses[card] = newStatus
-	} else if prevState != newStatus {
-		klog.V(2).Infof("%s: %s => %s", card, prevState, newStatus)
+		for _, id := range ids {
+			card, _, _ := strings.Cut(id, "-")
 
-		statuses[card] = newStatus
+			result, ok := cards[card]
+			if !ok {
+				if result, ok = dp.healthResultForCard(path.Join(dp.sysfsDir, card)); !ok {
+					// The devices keep their health.
+					continue
+				}
+
+				cards[card] = result
+			}
+
+			results[devType][id] = result
+		}
 	}
+
+	return results
 }
 
-func (dp *devicePlugin) healthStatusForCard(cardPath string) string {
+// healthResultForCard checks the health of a card with Level-Zero, when
+// available, and with the error counters of its driver in sysfs. The counters
+// also cover the xe GPUs without Level-Zero health indicators. False is
+// returned when the health of the card is unknown.
+func (dp *devicePlugin) healthResultForCard(cardPath string) (dpapi.HealthResult, bool) {
+	sysfsResult, hasCounters := sysfsHealthResult(cardPath)
+
 	if dp.levelzeroService == nil {
-		return pluginapi.Healthy
+		if !hasCounters {
+			return dpapi.HealthResult{
+				Timestamp: time.Now(),
+				State:     pluginapi.Healthy,
+				Source:    "sysfs",
+				Reason:    "health not available",
+			}, true
+		}
+
+		return sysfsResult, true
 	}
 
-	link, err := os.Readlink(filepath.Join(cardPath, "device"))
-	if err != nil {
-		klog.Warning("couldn't read device link for", cardPath)
+	result, ok := dp.levelzeroHealthResult(cardPath)
+	if !ok {
+		// Only the fatal errors of the counters are known to change
+		// the health.
+		return sysfsResult, hasCounters && sysfsResult.State != pluginapi.Healthy
+	}
 
-		return pluginapi.Healthy
+	// The counters are used when they find fatal errors, or when Level-Zero
+	// could not tell the health, e.g. "health not available".
+	if hasCounters && result.State == pluginapi.Healthy && (sysfsResult.State != pluginapi.Healthy || result.Reason != "") {
+		return sysfsResult, true
 	}
 
-	health := pluginapi.Healthy
+	return result, true
+}
+
+// levelzeroHealthResult returns the health of a card from the health watch
+// of the Level-Zero sidecar, and false when it's unknown.
+func (dp *devicePlugin) levelzeroHealthResult(cardPath string) (dpapi.HealthResult, bool) {
+	result := dpapi.HealthResult{
+		Timestamp: time.Now(),
+		State:     pluginapi.Healthy,
+		Source:    "levelzero",
+	}
+
+	link, err := os.Readlink(filepath.Join(cardPath, "device"))
+	if err != nil {
+		klog.ErrorS(err, "Couldn't read device link", "card", filepath.Base(cardPath))
 
-	// Check status changes after the function exits
-	defer func() { logHealthStatusChange(cardPath, health, dp.healthStatuses) }()
+		result.Reason = "device link not found"
+
+		return result, true
+	}
 
 	bdfAddr := filepath.Base(link)
 
-	dh, err := dp.levelzeroService.GetDeviceHealth(bdfAddr)
-	if err != nil {
-		klog.Warningf("Device health retrieval failed: %v", err)
+	dp.mutex.RLock()
+	dh, found := dp.levelzeroHealth[bdfAddr]
+	state, limit, policy := dp.healthWatchState, float64(dp.options.temperatureLimit), dp.options.levelzeroUnreachable
+	dp.mutex.RUnlock()
 
-		return health
+	switch {
+	case state == watchConnecting:
+		return result, false
+	case state == watchUnreachable:
+		return unreachableResult(result, policy)
+	case !found:
+		result.Reason = "health not available"
+
+		return result, true
 	}
 
 	// Direct Health indicators
-	klog.V(4).Infof("Health indicators: Memory=%t, Bus=%t, SoC=%t", dh.Memory, dh.Bus, dh.SoC)
+	klog.V(4).InfoS("Health indicators", dpapi.LogKeyBDF, bdfAddr, "memory", dh.Memory, "bus", dh.Bus, "soc", dh.SoC)
 
-	if !dh.Memory || !dh.Bus || !dh.SoC {
-		health = pluginapi.Unhealthy
+	result.Reason = levelzeroHealthReason(dh, limit)
+	if result.Reason != "" {
+		result.State = pluginapi.Unhealthy
+	}
 
-		return health
+	if !dh.Memory || !dh.Bus || !dh.SoC {
+		return result, true
 	}
 
-	dt, err := dp.levelzeroService.GetDeviceTemperature(bdfAddr)
-	// In case of any errors, return the current health status
-	if err != nil {
-		klog.Warningf("Device temperature retrieval failed: %v", err)
+	// Temperatures for different areas
+	klog.V(4).InfoS("Temperatures", dpapi.LogKeyBDF, bdfAddr, "memory", dh.MemoryTemperature, "gpu", dh.GPUTemperature, "global", dh.GlobalTemperature)
 
-		return health
+	result.Metrics = map[string]float64{
+		"temperatureGlobal": dh.GlobalTemperature,
+		"temperatureGPU":    dh.GPUTemperature,
+		"temperatureMemory": dh.MemoryTemperature,
 	}
 
-	limit := float64(dp.options.temperatureLimit)
+	dp.checkHealthThresholds(bdfAddr, &result)
 
-	// Temperatures for different areas
-	klog.V(4).Infof("Temperatures: Memory=%.1fC, GPU=%.1fC, Global=%.1fC", dh.MemoryTemperature, dh.GPUTemperature, dh.GlobalTemperature)
+	return result, true
+}
 
-	if dt.GPU > limit || dt.Global > limit || dt.Memory > limit {
-		health = pluginapi.Unhealthy
+// checkHealthThresholds marks the result unhealthy when the card crosses the
+// thresholds of the config file.
+func (dp *devicePlugin) checkHealthThresholds(bdfAddr string, result *dpapi.HealthResult) {
+	thresholds := dp.currentOptions().healthThresholds
+	if thresholds == nil {
+		return
+	}
+
+	reasons := thresholds.check(dp.levelzeroService, bdfAddr, result.Metrics)
+	if len(reasons) == 0 {
+		return
+	}
+
+	if result.Reason != "" {
+		reasons = append([]string{result.Reason}, reasons...)
 	}
 
-	return health
+	result.State = pluginapi.Unhealthy
+	result.Reason = strings.Join(reasons, ", ")
 }
 
 // Implement the PreferredAllocator interface.
@@ -422,32 +468,27 @@ func (dp *devicePlugin) GetPreferredAllocation(rqt *pluginapi.PreferredAllocatio
 		return dp.resMan.GetPreferredFractionalAllocation(rqt)
 	}
 
-	response := &pluginapi.PreferredAllocationResponse{}
-
-	for _, req := range rqt.ContainerRequests {
-		klog.V(3).Infof("AvailableDeviceIDs: %q", req.AvailableDeviceIDs)
-		klog.V(3).Infof("MustIncludeDeviceIDs: %q", req.MustIncludeDeviceIDs)
-		klog.V(3).Infof("AllocationSize: %d", req.AllocationSize)
-
-		// Add a security check here. This should never happen unless there occurs error in kubelet device plugin manager.
-		if req.AllocationSize > int32(len(req.AvailableDeviceIDs)) {
-			klog.V(3).Info("req.AllocationSize must be not greater than len(req.AvailableDeviceIDs).")
-
-			var err = errors.Errorf("AllocationSize (%d) is greater than the number of available device IDs (%d)", req.AllocationSize, len(req.AvailableDeviceIDs))
+	if len(rqt.ContainerRequests) > 0 && dp.isMemoryRequest(rqt.ContainerRequests[0].AvailableDeviceIDs) {
+		return memoryPolicy.SelectPreferred(rqt, nil)
+	}
 
-			return nil, err
-		}
+	if len(rqt.ContainerRequests) > 0 && dp.isTileRequest(rqt.ContainerRequests[0].AvailableDeviceIDs) {
+		return tilePolicy.SelectPreferred(rqt, nil)
+	}
 
-		IDs := dp.policy(req)
+	dp.mutex.RLock()
+	policy := dp.policy
+	dp.mutex.RUnlock()
 
-		resp := &pluginapi.ContainerPreferredAllocationResponse{
-			DeviceIDs: IDs,
-		}
+	return policy.SelectPreferred(rqt, nil)
+}
 
-		response.ContainerResponses = append(response.ContainerResponses, resp)
+// StopScan makes Scan() return. It implements dpapi.ScanStopper.
+func (dp *devicePlugin) StopScan() {
+	select {
+	case dp.scanDone <- true:
+	default:
 	}
-
-	return response, nil
 }
 
 func (dp *devicePlugin) Scan(notifier dpapi.Notifier) error {
@@ -459,9 +500,10 @@ func (dp *devicePlugin) Scan(notifier dpapi.Notifier) error {
 }
 
 func (dp *devicePlugin) wslGpuScan(notifier dpapi.Notifier) error {
-	defer dp.scanTicker.Stop()
+	dp.scanWatcher.Start()
+	defer dp.scanWatcher.Stop()
 
-	klog.V(1).Infof("GPU (%s) resource share count = %d", deviceTypeDxg, dp.options.sharedDevNum)
+	klog.V(1).InfoS("GPU resource share count", dpapi.LogKeyResource, namespace+"/"+deviceTypeDxg, "sharedDevNum", dp.currentOptions().sharedDevNum)
 
 	devSpecs := []pluginapi.DeviceSpec{
 		{
@@ -482,9 +524,10 @@ func (dp *devicePlugin) wslGpuScan(notifier dpapi.Notifier) error {
 	for {
 		indices, err := dp.levelzeroService.GetIntelIndices()
 		if err == nil {
-			klog.V(4).Info("Intel Level-Zero indices: ", indices)
+			klog.V(4).InfoS("Intel Level-Zero indices", "indices", indices)
 
 			devTree := dpapi.NewDeviceTree()
+			sharedDevNum := dp.currentOptions().sharedDevNum
 
 			for _, index := range indices {
 				envs := map[string]string{
@@ -493,7 +536,7 @@ func (dp *devicePlugin) wslGpuScan(notifier dpapi.Notifier) error {
 
 				deviceInfo := dpapi.NewDeviceInfo(pluginapi.Healthy, devSpecs, mounts, envs, nil, nil)
 
-				for i := 0; i < dp.options.sharedDevNum; i++ {
+				for i := 0; i < sharedDevNum; i++ {
 					devID := fmt.Sprintf("card%d-%d", index, i)
 					devTree.AddDevice(deviceTypeDxg, devID, deviceInfo)
 				}
@@ -501,35 +544,34 @@ func (dp *devicePlugin) wslGpuScan(notifier dpapi.Notifier) error {
 
 			notifier.Notify(devTree)
 		} else {
-			klog.Warning("Failed to get Intel indices from Level-Zero")
+			klog.ErrorS(err, "Failed to get Intel indices from Level-Zero")
 		}
 
 		select {
 		case <-dp.scanDone:
 			return nil
-		case <-dp.scanTicker.C:
+		case <-dp.scanWatcher.C():
 		}
 	}
 }
 
 func (dp *devicePlugin) sysFsGpuScan(notifier dpapi.Notifier) error {
-	defer dp.scanTicker.Stop()
+	dp.scanWatcher.Start()
+	defer dp.scanWatcher.Stop()
 
-	klog.V(1).Infof("GPU (%s/%s) resource share count = %d", deviceTypeI915, deviceTypeXe, dp.options.sharedDevNum)
+	klog.V(1).InfoS("GPU resource share count", dpapi.LogKeyResource, []string{namespace + "/" + deviceTypeI915, namespace + "/" + deviceTypeXe}, "sharedDevNum", dp.currentOptions().sharedDevNum)
 
-	previousCount := map[string]int{
-		deviceTypeI915: 0, deviceTypeXe: 0,
-		deviceTypeXe + monitorSuffix:   0,
-		deviceTypeI915 + monitorSuffix: 0}
+	previousCount := map[string]int{}
+	for _, devType := range advertisedDeviceTypes {
+		previousCount[devType] = 0
+	}
 
 	for {
+		dp.provisionVFs()
+
 		devTree, err := dp.scan()
 		if err != nil {
-			if errors.Is(err, rmWithMultipleDriversErr{}) {
-				return err
-			}
-
-			klog.Warning("Failed to scan: ", err)
+			klog.ErrorS(err, "Failed to scan")
 		}
 
 		countChanged := false
@@ -537,7 +579,7 @@ func (dp *devicePlugin) sysFsGpuScan(notifier dpapi.Notifier) error {
 		for name, prev := range previousCount {
 			count := devTree.DeviceTypeCount(name)
 			if count != prev {
-				klog.V(1).Infof("GPU scan update: %d->%d '%s' resources found", prev, count, name)
+				klog.V(1).InfoS("GPU scan update", dpapi.LogKeyResource, namespace+"/"+name, "previous", prev, "count", count)
 
 				previousCount[name] = count
 
@@ -555,25 +597,25 @@ func (dp *devicePlugin) sysFsGpuScan(notifier dpapi.Notifier) error {
 		select {
 		case <-dp.scanDone:
 			return nil
-		case <-dp.scanTicker.C:
+		case <-dp.scanWatcher.C():
 		}
 	}
 }
 
 func (dp *devicePlugin) isCompatibleDevice(name string) bool {
 	if !dp.gpuDeviceReg.MatchString(name) {
-		klog.V(4).Info("Not compatible device: ", name)
+		klog.V(4).InfoS("Not compatible device", "card", name)
 		return false
 	}
 
 	dat, err := os.ReadFile(path.Join(dp.sysfsDir, name, "device/vendor"))
 	if err != nil {
-		klog.Warning("Skipping. Can't read vendor file: ", err)
+		klog.ErrorS(err, "Skipping, can't read vendor file", "card", name)
 		return false
 	}
 
 	if strings.TrimSpace(string(dat)) != vendorString {
-		klog.V(4).Info("Non-Intel GPU: ", name)
+		klog.V(4).InfoS("Non-Intel GPU", "card", name)
 		return false
 	}
 
@@ -633,7 +675,7 @@ func (dp *devicePlugin) createDeviceSpecsFromDrmFiles(cardPath string) []plugina
 			continue
 		}
 
-		klog.V(4).Infof("Adding %s to GPU %s", devPath, filepath.Base(cardPath))
+		klog.V(4).InfoS("Adding device node to GPU", "card", filepath.Base(cardPath), "node", devPath)
 
 		specs = append(specs, devSpec)
 	}
@@ -689,8 +731,11 @@ func (dp *devicePlugin) scan() (dpapi.DeviceTree, error) {
 	monitor := make(map[string][]pluginapi.DeviceSpec, 0)
 
 	devTree := dpapi.NewDeviceTree()
-	rmDevInfos := rm.NewDeviceInfoMap()
+	rmDevInfos := map[string]rm.DeviceInfoMap{deviceTypeI915: rm.NewDeviceInfoMap(), deviceTypeXe: rm.NewDeviceInfoMap()}
 	devProps := newDeviceProperties()
+	options := dp.currentOptions()
+	memoryCards := make(map[string]cardDevices)
+	tileCards := make(map[string]cardDevices)
 
 	for _, f := range dp.filterOutInvalidCards(files) {
 		name := f.Name()
@@ -710,20 +755,39 @@ func (dp *devicePlugin) scan() (dpapi.DeviceTree, error) {
 
 		mounts, cdiDevices := dp.createMountsAndCDIDevices(cardPath, name, devSpecs)
 
-		health := dp.healthStatusForCard(cardPath)
+		attributes := devProps.attributes(cardPath)
 
-		deviceInfo := dpapi.NewDeviceInfo(health, devSpecs, mounts, nil, nil, cdiDevices)
+		deviceInfo := dpapi.NewDeviceInfo(pluginapi.Healthy, devSpecs, mounts, nil, nil, cdiDevices)
+		deviceInfo.SetSysfsDevice(path.Join(cardPath, "device"))
+		deviceInfo.SetAttributes(attributes)
 
-		for i := 0; i < dp.options.sharedDevNum; i++ {
-			devID := fmt.Sprintf("%s-%d", name, i)
-			devTree.AddDevice(devProps.driver(), devID, deviceInfo)
+		// With the SR-IOV profile, the VFs are a resource of their own.
+		devType := devProps.driver()
+		if devProps.isVf && options.sriov != nil {
+			devType += vfSuffix
+		}
 
-			rmDevInfos[devID] = rm.NewDeviceInfo(devSpecs, mounts, nil)
+		switch {
+		case options.memoryUnitSize > 0:
+			if dp.addMemoryUnits(devTree, name, deviceInfo, options.memoryUnitSize) {
+				memoryCards[name] = cardDevices{nodes: devSpecs, mounts: mounts}
+			}
+		case options.tileResources && !strings.HasSuffix(devType, vfSuffix):
+			tileCards[name] = dp.addTiles(devTree, devType+tileSuffix, name, devSpecs, mounts, cdiDevices, attributes)
+		default:
+			for i := 0; i < options.sharedDevNum; i++ {
+				devID := fmt.Sprintf("%s-%d", name, i)
+				devTree.AddDevice(devType, devID, deviceInfo)
+
+				if infos, ok := rmDevInfos[devType]; ok {
+					infos[devID] = rm.NewDeviceInfo(devSpecs, mounts, nil)
+				}
+			}
 		}
 
-		if dp.options.enableMonitoring {
+		if options.enableMonitoring {
 			res := devProps.monitorResource()
-			klog.V(4).Infof("For %s/%s, adding nodes: %+v", res, monitorID, devSpecs)
+			klog.V(4).InfoS("Adding nodes to the monitoring device", dpapi.LogKeyResource, namespace+"/"+res, dpapi.LogKeyDeviceID, monitorID, "nodes", devSpecs)
 
 			monitor[res] = append(monitor[res], devSpecs...)
 		}
@@ -737,19 +801,22 @@ func (dp *devicePlugin) scan() (dpapi.DeviceTree, error) {
 		}
 	}
 
-	if dp.resMan != nil {
-		if devProps.drmDriverCount() <= 1 {
-			dp.resMan.SetDevInfos(rmDevInfos)
+	dp.mutex.Lock()
 
-			if tileCount, err := devProps.maxTileCount(); err == nil {
-				dp.resMan.SetTileCountPerCard(tileCount)
-			}
-		} else {
-			klog.Warning("Plugin with RM doesn't support multiple DRM drivers:", devProps.drmDrivers)
+	dp.memoryCards = memoryCards
+	dp.tileCards = tileCards
 
-			err := rmWithMultipleDriversErr{}
+	dp.mutex.Unlock()
 
-			return nil, err
+	// The fractional resources of the GPUs of each driver are managed
+	// separately, so that nodes can have both i915 and xe GPUs.
+	if dp.resMan != nil {
+		for driver, infos := range rmDevInfos {
+			dp.resMan.SetDevInfos(driver, infos)
+
+			if tileCount, err := devProps.maxTileCount(driver); err == nil {
+				dp.resMan.SetTileCountPerCard(driver, tileCount)
+			}
 		}
 	}
 
@@ -761,13 +828,43 @@ func (dp *devicePlugin) Allocate(request *pluginapi.AllocateRequest) (*pluginapi
 		return dp.resMan.CreateFractionalResourceResponse(request)
 	}
 
+	if len(request.ContainerRequests) > 0 && dp.isMemoryRequest(request.ContainerRequests[0].DevicesIDs) {
+		return dp.allocateMemory(request)
+	}
+
+	if len(request.ContainerRequests) > 0 && dp.isTileRequest(request.ContainerRequests[0].DevicesIDs) {
+		return dp.allocateTiles(request)
+	}
+
 	return nil, &dpapi.UseDefaultMethodError{}
 }
 
+// MaxConcurrentAllocations serializes the allocations when the resource
+// manager is used, as it matches each allocation to a pending pod.
+func (dp *devicePlugin) MaxConcurrentAllocations(devType string) int {
+	if dp.resMan != nil {
+		return 1
+	}
+
+	return 0
+}
+
+// SharingFactor returns the number of containers sharing a GPU, see
+// -shared-dev-num. The monitoring resources, the memory units and the tiles
+// are not shared.
+func (dp *devicePlugin) SharingFactor(devType string) int {
+	if strings.HasSuffix(devType, monitorSuffix) || devType == deviceTypeMemory || strings.HasSuffix(devType, tileSuffix) {
+		return 1
+	}
+
+	return dp.currentOptions().sharedDevNum
+}
+
 func main() {
 	var (
-		prefix string
-		opts   cliOptions
+		prefix         string
+		memoryUnitSize string
+		opts           cliOptions
 	)
 
 	flag.StringVar(&prefix, "prefix", "", "Prefix for devfs & sysfs paths")
@@ -777,28 +874,39 @@ func main() {
 	flag.BoolVar(&opts.wslScan, "wsl", false, "scan for / use WSL devices")
 	flag.IntVar(&opts.sharedDevNum, "shared-dev-num", 1, "number of containers sharing the same GPU device")
 	flag.IntVar(&opts.temperatureLimit, "temp-limit", 100, "temperature limit at which device is marked unhealthy")
-	flag.StringVar(&opts.preferredAllocationPolicy, "allocation-policy", "none", "modes of allocating GPU devices: balanced, packed and none")
+	flag.StringVar(&opts.preferredAllocationPolicy, "allocation-policy", "none", "modes of allocating GPU devices: balanced, packed, topology and none")
+	flag.StringVar(&memoryUnitSize, "memory-unit-size", "", "advertise the GPU memory as "+namespace+"/"+deviceTypeMemory+" units of the given size, e.g. 1Gi (disabled when empty)")
+	flag.BoolVar(&opts.tileResources, "tile-resources", false, "advertise the tiles of the GPUs as "+namespace+"/<driver>"+tileSuffix+" resources")
+	flag.StringVar(&opts.tileHierarchy, "tile-hierarchy", rm.DefaultHierarchyMode, "Level-Zero hierarchy mode of the containers allocated tiles: FLAT, COMPOSITE or COMBINED")
+	flag.StringVar(&opts.levelzeroUnreachable, "levelzero-unreachable", unreachableUnknown, "health of the GPUs when the Level-Zero sidecar is unreachable: unknown, healthy or unhealthy")
+	managerFlags := pluginutils.AddManagerFlags(flag.CommandLine)
 	flag.Parse()
 
-	if opts.sharedDevNum < 1 {
-		klog.Error("The number of containers sharing the same GPU must greater than zero")
-		os.Exit(1)
+	if err := managerFlags.SetupLogging(); err != nil {
+		klog.Fatalf("%+v", err)
+	}
+
+	managerOpts, err := managerFlags.ManagerOptions()
+	if err != nil {
+		klog.Fatalf("%+v", err)
 	}
 
-	if opts.sharedDevNum == 1 && opts.resourceManagement {
-		klog.Error("Trying to use fractional resources with shared-dev-num 1 is pointless")
-		os.Exit(1)
+	if opts.memoryUnitSize, err = parseMemoryUnitSize(memoryUnitSize); err != nil {
+		klog.Fatalf("%+v", err)
 	}
 
-	var str = opts.preferredAllocationPolicy
-	if !(str == "balanced" || str == "packed" || str == "none") {
-		klog.Error("invalid value for preferredAllocationPolicy, the valid values: balanced, packed, none")
-		os.Exit(1)
+	// With a config file, the options are validated once the file is applied.
+	if managerFlags.ConfigFile() == "" {
+		if err := validateOptions(opts); err != nil {
+			klog.Error(err)
+			os.Exit(1)
+		}
 	}
 
-	klog.V(1).Infof("GPU device plugin started with %s preferred allocation policy", opts.preferredAllocationPolicy)
+	klog.V(1).InfoS("GPU device plugin started", "preferredAllocationPolicy", opts.preferredAllocationPolicy)
 
 	plugin := newDevicePlugin(prefix+sysfsDrmDirectory, prefix+devfsDriDirectory, opts)
+	plugin.commandLine = pluginutils.CommandLineFlags(flag.CommandLine)
 
 	if plugin.options.wslScan {
 		klog.Info("WSL mode requested")
@@ -820,28 +928,56 @@ func main() {
 
 			os.Exit(1)
 		}
+
+		if plugin.options.memoryUnitSize > 0 {
+			klog.Error("Memory units are not supported within WSL. Please disable memory units.")
+
+			os.Exit(1)
+		}
+
+		if plugin.options.tileResources {
+			klog.Error("Tile resources are not supported within WSL. Please disable tile resources.")
+
+			os.Exit(1)
+		}
 	}
 
 	if plugin.options.healthManagement || plugin.options.wslScan {
 		plugin.levelzeroService = levelzeroservice.NewLevelzero(gpulevelzero.DefaultUnixSocketPath)
 
 		go plugin.levelzeroService.Run(true)
+
+		if plugin.options.healthManagement {
+			go plugin.watchHealth(context.Background())
+		}
 	}
 
+	labelerCtx, stopLabeler := context.WithCancel(context.Background())
+	labelerDone := make(chan struct{})
+
 	if plugin.options.resourceManagement {
 		// Start labeler to export labels file for NFD.
 		nfdFeatureFile := path.Join(nfdFeatureDir, resourceFilename)
 
-		klog.V(2).Infof("NFD feature file location: %s", nfdFeatureFile)
+		klog.V(2).InfoS("NFD feature file location", "path", nfdFeatureFile)
 
-		// Labeler catches OS signals and calls os.Exit() after receiving any.
-		go labeler.Run(prefix+sysfsDrmDirectory, nfdFeatureFile,
-			labelerMaxInterval, plugin.scanResources, plugin.levelzeroService, func() {
-				// Exit the whole app when labeler exits
-				os.Exit(0)
-			})
+		// The labeler removes the label file when the plugin stops.
+		go func() {
+			labeler.Run(labelerCtx, prefix+sysfsDrmDirectory, nfdFeatureFile,
+				labelerMaxInterval, plugin.scanResources, plugin.levelzeroService)
+			close(labelerDone)
+		}()
+	} else {
+		close(labelerDone)
 	}
 
-	manager := dpapi.NewManager(namespace, plugin)
-	manager.Run()
+	manager := dpapi.NewManager(namespace, plugin, managerOpts...)
+	err = manager.Run(context.Background())
+
+	stopLabeler()
+	<-labelerDone
+
+	if err != nil {
+		klog.Fatalf("%+v", err)
+	}
 }
@@ -15,6 +15,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 	"path"
@@ -22,6 +23,7 @@ import (
 	"reflect"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
@@ -30,6 +32,7 @@ import (
 	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/gpu_plugin/levelzeroservice"
 	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/gpu_plugin/rm"
 	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+	dptesting "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin/testing"
 	cdispec "tags.cncf.io/container-device-interface/specs-go"
 )
 
@@ -59,20 +62,31 @@ func (n *mockNotifier) Notify(newDeviceTree dpapi.DeviceTree) {
 }
 
 type mockResourceManager struct {
-	tileCount uint64
+	devInfos   map[string]rm.DeviceInfoMap
+	tileCounts map[string]uint64
 }
 
 func (m *mockResourceManager) CreateFractionalResourceResponse(*v1beta1.AllocateRequest) (*v1beta1.AllocateResponse, error) {
 	return &v1beta1.AllocateResponse{}, &dpapi.UseDefaultMethodError{}
 }
-func (m *mockResourceManager) SetDevInfos(rm.DeviceInfoMap) {}
+func (m *mockResourceManager) SetDevInfos(driver string, infos rm.DeviceInfoMap) {
+	if m.devInfos == nil {
+		m.devInfos = make(map[string]rm.DeviceInfoMap)
+	}
+
+	m.devInfos[driver] = infos
+}
 
 func (m *mockResourceManager) GetPreferredFractionalAllocation(*v1beta1.PreferredAllocationRequest) (*v1beta1.PreferredAllocationResponse, error) {
 	return &v1beta1.PreferredAllocationResponse{}, &dpapi.UseDefaultMethodError{}
 }
 
-func (m *mockResourceManager) SetTileCountPerCard(count uint64) {
-	m.tileCount = count
+func (m *mockResourceManager) SetTileCountPerCard(driver string, count uint64) {
+	if m.tileCounts == nil {
+		m.tileCounts = make(map[string]uint64)
+	}
+
+	m.tileCounts[driver] = count
 }
 
 type mockL0Service struct {
@@ -114,6 +128,56 @@ func (m *mockL0Service) GetDeviceMemoryAmount(bdfAddress string) (uint64, error)
 
 	return m.memSize, nil
 }
+func (m *mockL0Service) GetDeviceUtilization(bdfAddress string) (levelzeroservice.DeviceUtilization, error) {
+	if m.fail {
+		return levelzeroservice.DeviceUtilization{}, errors.Errorf("error, error")
+	}
+
+	return levelzeroservice.DeviceUtilization{"all": 10.0}, nil
+}
+func (m *mockL0Service) GetDevicePower(bdfAddress string) (levelzeroservice.DevicePower, error) {
+	if m.fail {
+		return levelzeroservice.DevicePower{}, errors.Errorf("error, error")
+	}
+
+	return levelzeroservice.DevicePower{Power: 50.0, TDP: 300.0}, nil
+}
+func (m *mockL0Service) GetDeviceFrequency(bdfAddress string) (levelzeroservice.DeviceFrequency, error) {
+	if m.fail {
+		return levelzeroservice.DeviceFrequency{}, errors.Errorf("error, error")
+	}
+
+	return levelzeroservice.DeviceFrequency{Actual: 1000.0, Requested: 1000.0, Max: 2000.0}, nil
+}
+func (m *mockL0Service) GetDeviceErrors(bdfAddress string) (levelzeroservice.DeviceErrors, error) {
+	if m.fail {
+		return levelzeroservice.DeviceErrors{}, errors.Errorf("error, error")
+	}
+
+	return levelzeroservice.DeviceErrors{}, nil
+}
+func (m *mockL0Service) GetDeviceMemoryBandwidth(bdfAddress string) (levelzeroservice.DeviceMemoryBandwidth, error) {
+	if m.fail {
+		return levelzeroservice.DeviceMemoryBandwidth{}, errors.Errorf("error, error")
+	}
+
+	return levelzeroservice.DeviceMemoryBandwidth{Max: 1e9}, nil
+}
+func (m *mockL0Service) WatchDeviceHealth(ctx context.Context, interval time.Duration, handler func(levelzeroservice.HealthEvent)) {
+	if m.fail {
+		handler(levelzeroservice.HealthEvent{Err: errors.Errorf("error, error")})
+
+		return
+	}
+
+	handler(levelzeroservice.HealthEvent{
+		BdfAddress: "0000:00:00.0",
+		Health: levelzeroservice.DeviceHealth{
+			Memory: m.healthy, Bus: m.healthy, SoC: m.healthy,
+			GlobalTemperature: 35.0, GPUTemperature: 35.0, MemoryTemperature: 35.0,
+		},
+	})
+}
 
 type TestCaseDetails struct {
 	// possible mock l0 service
@@ -281,6 +345,53 @@ func TestGetPreferredAllocation(t *testing.T) {
 	}
 }
 
+func TestGetPreferredAllocationTopology(t *testing.T) {
+	root := t.TempDir()
+	sysfs := filepath.Join(root, "class", "drm")
+
+	// card0 and card2 are behind the same PCIe switch.
+	cards := map[string]string{
+		"card0": "pci0000:00/0000:00:01.0/0000:01:00.0/0000:02:01.0/0000:03:00.0",
+		"card1": "pci0000:00/0000:00:02.0/0000:04:00.0",
+		"card2": "pci0000:00/0000:00:01.0/0000:01:00.0/0000:02:02.0/0000:05:00.0",
+	}
+
+	if err := os.MkdirAll(sysfs, 0750); err != nil {
+		t.Fatal(err)
+	}
+
+	for card, pci := range cards {
+		dir := filepath.Join(root, "devices", pci, "drm", card)
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Symlink(dir, filepath.Join(sysfs, card)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rqt := &v1beta1.PreferredAllocationRequest{
+		ContainerRequests: []*v1beta1.ContainerPreferredAllocationRequest{
+			{
+				AvailableDeviceIDs: []string{"card0-0", "card1-0", "card2-0"},
+				AllocationSize:     2,
+			},
+		},
+	}
+
+	plugin := newDevicePlugin(sysfs, "", cliOptions{sharedDevNum: 1, preferredAllocationPolicy: "topology"})
+
+	response, err := plugin.GetPreferredAllocation(rqt)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if !reflect.DeepEqual(response.ContainerResponses[0].DeviceIDs, []string{"card0-0", "card2-0"}) {
+		t.Error("Unexpected return value for topology preferred allocation", response.ContainerResponses[0].DeviceIDs)
+	}
+}
+
 func TestAllocate(t *testing.T) {
 	plugin := newDevicePlugin("", "", cliOptions{sharedDevNum: 2, resourceManagement: false})
 
@@ -289,6 +400,10 @@ func TestAllocate(t *testing.T) {
 		t.Errorf("Unexpected return value: %+v", err)
 	}
 
+	if limit := plugin.MaxConcurrentAllocations("i915"); limit != 0 {
+		t.Errorf("Unexpected allocation limit %d without resource manager", limit)
+	}
+
 	// mock the rm
 	plugin.resMan = &mockResourceManager{}
 
@@ -296,6 +411,18 @@ func TestAllocate(t *testing.T) {
 	if _, ok := err.(*dpapi.UseDefaultMethodError); !ok {
 		t.Errorf("Unexpected return value: %+v", err)
 	}
+
+	if limit := plugin.MaxConcurrentAllocations("i915"); limit != 1 {
+		t.Errorf("Allocations should be serialized with resource manager, got limit %d", limit)
+	}
+
+	if factor := plugin.SharingFactor("i915"); factor != 2 {
+		t.Errorf("Unexpected sharing factor %d, expected 2", factor)
+	}
+
+	if factor := plugin.SharingFactor("i915" + monitorSuffix); factor != 1 {
+		t.Errorf("Monitoring resource should not be shared, got sharing factor %d", factor)
+	}
 }
 
 func TestScan(t *testing.T) {
@@ -631,6 +758,158 @@ func TestScanWithHealth(t *testing.T) {
 	}
 }
 
+func TestCheckHealth(t *testing.T) {
+	tcases := []struct {
+		l0mock         *mockL0Service
+		name           string
+		unreachable    string
+		expectedState  string
+		expectedReason string
+	}{
+		{
+			name:          "healthy",
+			l0mock:        &mockL0Service{healthy: true},
+			expectedState: v1beta1.Healthy,
+		},
+		{
+			name:           "unhealthy",
+			l0mock:         &mockL0Service{healthy: false},
+			expectedState:  v1beta1.Unhealthy,
+			expectedReason: "memory, bus, SoC unhealthy",
+		},
+		{
+			name:        "unreachable with unknown health",
+			l0mock:      &mockL0Service{fail: true},
+			unreachable: unreachableUnknown,
+		},
+		{
+			name:           "unreachable with healthy devices",
+			l0mock:         &mockL0Service{fail: true},
+			unreachable:    unreachableHealthy,
+			expectedState:  v1beta1.Healthy,
+			expectedReason: "Level-Zero unreachable",
+		},
+		{
+			name:           "unreachable with unhealthy devices",
+			l0mock:         &mockL0Service{fail: true},
+			unreachable:    unreachableUnhealthy,
+			expectedState:  v1beta1.Unhealthy,
+			expectedReason: "Level-Zero unreachable",
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			sysfs, _, err := createTestFiles(t.TempDir(), TestCaseDetails{
+				pciAddresses: map[string]string{"0000:00:00.0": "card0"},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			plugin := newDevicePlugin(sysfs, t.TempDir(), cliOptions{
+				healthManagement: true, temperatureLimit: 100, levelzeroUnreachable: tc.unreachable,
+			})
+
+			if plugin.HealthCheckInterval() != scanPeriod {
+				t.Error("health checks should be enabled")
+			}
+
+			// Without levelzero, only the error counters in sysfs are checked.
+			if result := plugin.CheckHealth(map[string][]string{deviceTypeI915: {"card0-0"}})[deviceTypeI915]["card0-0"]; result.Source != "sysfs" || result.State != v1beta1.Healthy {
+				t.Errorf("unexpected result without levelzero: %+v", result)
+			}
+
+			plugin.levelzeroService = tc.l0mock
+
+			// The health is unknown until the watch tells it.
+			if results := plugin.CheckHealth(map[string][]string{deviceTypeI915: {"card0-0"}}); len(results[deviceTypeI915]) != 0 {
+				t.Errorf("unexpected results before the health watch: %+v", results)
+			}
+
+			plugin.watchHealth(context.Background())
+
+			select {
+			case <-plugin.HealthChanged():
+			default:
+				t.Error("the health watch should notify the health checks")
+			}
+
+			results := plugin.CheckHealth(map[string][]string{
+				deviceTypeI915:                 {"card0-0", "card0-1"},
+				deviceTypeI915 + monitorSuffix: {monitorID},
+			})
+
+			if _, ok := results[deviceTypeI915+monitorSuffix]; ok {
+				t.Error("monitoring resource should not be checked")
+			}
+
+			if tc.expectedState == "" {
+				if len(results[deviceTypeI915]) != 0 {
+					t.Errorf("the devices should keep their health: %+v", results)
+				}
+
+				return
+			}
+
+			for _, id := range []string{"card0-0", "card0-1"} {
+				result := results[deviceTypeI915][id]
+				if result.State != tc.expectedState || result.Reason != tc.expectedReason || result.Source != "levelzero" {
+					t.Errorf("unexpected result for %s: %+v", id, result)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckHealthSysfs(t *testing.T) {
+	sysfs, _, err := createTestFiles(t.TempDir(), TestCaseDetails{
+		sysfsdirs: []string{"card0/device/tile0/gt0/error", "card0/device/tile1/gt1/error", "card1/gt/gt0/error", "card1/device"},
+		sysfsfiles: map[string][]byte{
+			"card0/device/tile0/gt0/error/fatal_guc":            []byte("0"),
+			"card0/device/tile0/gt0/error/correctable_eu_grf":   []byte("3"),
+			"card0/device/tile1/gt1/error/fatal_array_bist":     []byte("1"),
+			"card0/device/tile1/gt1/error/non_fatal_gsc":        []byte("2"),
+			"card1/gt/gt0/error/fatal_sram_ecc":                 []byte("0"),
+			"card1/gt/gt0/error/correctable_subslice_broadcast": []byte("5"),
+		},
+		symlinkfiles: map[string]string{
+			"card0/device/driver": "drivers/xe",
+			"card1/device/driver": "drivers/i915",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	plugin := newDevicePlugin(sysfs, t.TempDir(), cliOptions{healthManagement: true})
+
+	results := plugin.CheckHealth(map[string][]string{
+		deviceTypeXe:   {"card0-0"},
+		deviceTypeI915: {"card1-0"},
+	})
+
+	xe := results[deviceTypeXe]["card0-0"]
+	if xe.State != v1beta1.Unhealthy || xe.Reason != "fatal errors: tile1/gt1/fatal_array_bist=1" || xe.Source != "sysfs" {
+		t.Errorf("unexpected result for the xe card: %+v", xe)
+	}
+
+	if xe.Metrics["fatalErrors"] != 1 || xe.Metrics["nonFatalErrors"] != 5 {
+		t.Errorf("unexpected metrics for the xe card: %v", xe.Metrics)
+	}
+
+	if i915 := results[deviceTypeI915]["card1-0"]; i915.State != v1beta1.Healthy || i915.Metrics["nonFatalErrors"] != 5 {
+		t.Errorf("unexpected result for the i915 card: %+v", i915)
+	}
+
+	// Fatal errors make a card unhealthy even when levelzero reports it healthy.
+	plugin.levelzeroService = &mockL0Service{healthy: true}
+
+	if xe := plugin.CheckHealth(map[string][]string{deviceTypeXe: {"card0-0"}})[deviceTypeXe]["card0-0"]; xe.State != v1beta1.Unhealthy {
+		t.Errorf("unexpected result for the xe card with levelzero: %+v", xe)
+	}
+}
+
 func TestScanWsl(t *testing.T) {
 	tcases := []TestCaseDetails{
 		{
@@ -689,10 +968,10 @@ func TestScanWsl(t *testing.T) {
 	}
 }
 
-func TestScanFails(t *testing.T) {
+func TestScanWithRmAndMixedDrivers(t *testing.T) {
 	tc := TestCaseDetails{
-		name:      "xe and i915 devices with rm will fail",
-		sysfsdirs: []string{"card0/device/drm/card0", "card0/device/drm/controlD64", "card1/device/drm/card1"},
+		name:      "xe and i915 devices with rm",
+		sysfsdirs: []string{"card0/device/drm/card0", "card0/device/drm/controlD64", "card1/device/drm/card1", "card0/device/tile0", "card0/device/tile1"},
 		sysfsfiles: map[string][]byte{
 			"card0/device/vendor": []byte("0x8086"),
 			"card1/device/vendor": []byte("0x8086"),
@@ -720,17 +999,29 @@ func TestScanFails(t *testing.T) {
 			t.Errorf("Unexpected error: %+v", err)
 		}
 
-		plugin := newDevicePlugin(sysfs, devfs, tc.options)
+		plugin := newDevicePlugin(sysfs, devfs, cliOptions{sharedDevNum: 1})
 
-		plugin.resMan = &mockResourceManager{}
+		resMan := &mockResourceManager{}
+		plugin.resMan = resMan
 
 		notifier := &mockNotifier{
 			scanDone: plugin.scanDone,
 		}
 
-		err = plugin.Scan(notifier)
-		if err == nil {
-			t.Error("Unexpected nil error")
+		if err = plugin.Scan(notifier); err != nil {
+			t.Errorf("Unexpected error: %+v", err)
+		}
+
+		if notifier.xeCount != 1 || notifier.i915Count != 1 {
+			t.Errorf("Unexpected device counts, xe: %d, i915: %d", notifier.xeCount, notifier.i915Count)
+		}
+
+		if _, ok := resMan.devInfos[deviceTypeXe]["card0-0"]; !ok || len(resMan.devInfos[deviceTypeI915]) != 1 {
+			t.Errorf("Unexpected devices of the drivers: %v", resMan.devInfos)
+		}
+
+		if resMan.tileCounts[deviceTypeXe] != 2 || resMan.tileCounts[deviceTypeI915] != 1 {
+			t.Errorf("Unexpected tile counts of the drivers: %v", resMan.tileCounts)
 		}
 	})
 }
@@ -813,7 +1104,7 @@ func TestScanWithRmAndTiles(t *testing.T) {
 			if err != nil {
 				t.Error("Unexpected error")
 			}
-			if rm.tileCount != expectedTileCounts[i] {
+			if rm.tileCounts[deviceTypeXe] != expectedTileCounts[i] {
 				t.Error("Unexpected tilecount for RM")
 			}
 		})
@@ -1198,6 +1489,19 @@ func TestCDIDeviceInclusion(t *testing.T) {
 		},
 	}))
 
+	// The plugin records the sysfs devices of the cards for device selectors
+	// and their attributes for the node inventory.
+	for devType, card := range map[string]string{"i915": "card0", "xe": "card1"} {
+		info := refTree[devType][card+"-0"]
+		info.SetSysfsDevice(sysfs + "/class/drm/" + card + "/device")
+		info.SetAttributes(map[string]string{
+			"driver":        devType,
+			"tiles":         "1",
+			"pci-device-id": map[string]string{"card0": "0x9a49", "card1": "0x9a48"}[card],
+		})
+		refTree[devType][card+"-0"] = info
+	}
+
 	if !reflect.DeepEqual(tree, refTree) {
 		t.Error("Received device tree isn't expected\n", tree, "\n", refTree)
 	}
@@ -1209,3 +1513,32 @@ func TestCDIDeviceInclusion(t *testing.T) {
 		t.Error("Invalid count for device (xe)")
 	}
 }
+
+func TestConformance(t *testing.T) {
+	sysfs, devfs, err := createTestFiles(t.TempDir(), TestCaseDetails{
+		sysfsdirs: []string{"card0/device/drm/card0", "card0/device/drm/controlD64", "card1/device/drm/card1"},
+		sysfsfiles: map[string][]byte{
+			"card0/device/vendor": []byte("0x8086"),
+			"card1/device/vendor": []byte("0x8086"),
+		},
+		devfsdirs: []string{
+			"card0",
+			"by-path/pci-0000:00:00.0-card",
+			"by-path/pci-0000:00:00.0-render",
+			"card1",
+			"by-path/pci-0000:00:01.0-card",
+			"by-path/pci-0000:00:01.0-render",
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	options := cliOptions{sharedDevNum: 2, enableMonitoring: true, preferredAllocationPolicy: "balanced"}
+
+	dptesting.Run(t, dptesting.Config{
+		Plugin:      newDevicePlugin(sysfs, devfs, options),
+		Namespace:   namespace,
+		DeviceTypes: []string{deviceTypeI915, deviceTypeI915 + monitorSuffix},
+	})
+}
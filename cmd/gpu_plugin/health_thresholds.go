@@ -0,0 +1,237 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/gpu_plugin/levelzeroservice"
+	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+)
+
+// throttleReasons are the frequency throttle reasons the Level-Zero sidecar
+// reports.
+var throttleReasons = []string{"average-power", "burst-power", "current", "thermal", "psu-alert", "software-range", "hardware-range"}
+
+// healthThresholds is the "healthThresholds" setting of the config file. A
+// card is unhealthy when a value Level-Zero reports for it crosses its
+// threshold. Only the values with a threshold are requested, and the
+// thresholds are not used without Level-Zero.
+type healthThresholds struct {
+	// EngineUtilization is the busy percentage of any engine group, e.g.
+	// "compute", since the previous health check.
+	EngineUtilization *float64 `json:"engineUtilization,omitempty"`
+	// PowerOfTDP is the average power since the previous health check,
+	// in percent of the TDP.
+	PowerOfTDP *float64 `json:"powerOfTDP,omitempty"`
+	// CorrectableErrors and UncorrectableErrors are the RAS error counts
+	// over which the card is unhealthy. Zero makes any error count.
+	CorrectableErrors   *uint64 `json:"correctableErrors,omitempty"`
+	UncorrectableErrors *uint64 `json:"uncorrectableErrors,omitempty"`
+	// MemoryBandwidth is the read and write bandwidth since the previous
+	// health check, in percent of the maximum bandwidth.
+	MemoryBandwidth *float64 `json:"memoryBandwidth,omitempty"`
+	// ThrottleReasons are the frequency throttle reasons which make the
+	// card unhealthy, e.g. "thermal".
+	ThrottleReasons []string `json:"throttleReasons,omitempty"`
+}
+
+func validatePercent(name string, value *float64, limit float64) error {
+	if value != nil && (*value <= 0 || *value > limit) {
+		return errors.Errorf("invalid %s threshold %g, expected a percentage between 0 and %g", name, *value, limit)
+	}
+
+	return nil
+}
+
+func (t *healthThresholds) validate() error {
+	// The power can exceed the TDP for short periods.
+	for _, err := range []error{
+		validatePercent("engine utilization", t.EngineUtilization, 100),
+		validatePercent("power", t.PowerOfTDP, 200),
+		validatePercent("memory bandwidth", t.MemoryBandwidth, 100),
+	} {
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, reason := range t.ThrottleReasons {
+		if !slices.Contains(throttleReasons, reason) {
+			return errors.Errorf("invalid throttle reason %q, the valid reasons: %v", reason, throttleReasons)
+		}
+	}
+
+	return nil
+}
+
+// checkUtilization returns the reason for the engine utilization threshold
+// being crossed, or "" when it's not.
+func (t *healthThresholds) checkUtilization(l0 levelzeroservice.LevelzeroService, bdfAddr string, metrics map[string]float64) string {
+	if t.EngineUtilization == nil {
+		return ""
+	}
+
+	util, err := l0.GetDeviceUtilization(bdfAddr)
+	if err != nil {
+		klog.ErrorS(err, "Device utilization retrieval failed", dpapi.LogKeyBDF, bdfAddr)
+
+		return ""
+	}
+
+	busy := []string{}
+
+	for group, percent := range util {
+		if group == "" {
+			continue
+		}
+
+		metrics["utilization"+strings.ToUpper(group[:1])+group[1:]] = percent
+
+		if percent > *t.EngineUtilization {
+			busy = append(busy, group)
+		}
+	}
+
+	if len(busy) == 0 {
+		return ""
+	}
+
+	slices.Sort(busy)
+
+	return fmt.Sprintf("%s utilization over %g%%", strings.Join(busy, ", "), *t.EngineUtilization)
+}
+
+func (t *healthThresholds) checkPower(l0 levelzeroservice.LevelzeroService, bdfAddr string, metrics map[string]float64) string {
+	if t.PowerOfTDP == nil {
+		return ""
+	}
+
+	power, err := l0.GetDevicePower(bdfAddr)
+	if err != nil {
+		klog.ErrorS(err, "Device power retrieval failed", dpapi.LogKeyBDF, bdfAddr)
+
+		return ""
+	}
+
+	metrics["power"] = power.Power
+	metrics["powerTDP"] = power.TDP
+
+	if power.TDP > 0 && power.Power > power.TDP*(*t.PowerOfTDP)/100 {
+		return fmt.Sprintf("power over %g%% of TDP", *t.PowerOfTDP)
+	}
+
+	return ""
+}
+
+func (t *healthThresholds) checkFrequency(l0 levelzeroservice.LevelzeroService, bdfAddr string, metrics map[string]float64) string {
+	if len(t.ThrottleReasons) == 0 {
+		return ""
+	}
+
+	freq, err := l0.GetDeviceFrequency(bdfAddr)
+	if err != nil {
+		klog.ErrorS(err, "Device frequency retrieval failed", dpapi.LogKeyBDF, bdfAddr)
+
+		return ""
+	}
+
+	metrics["frequencyActual"] = freq.Actual
+	metrics["frequencyMax"] = freq.Max
+
+	throttled := []string{}
+
+	for _, reason := range freq.ThrottleReasons {
+		if slices.Contains(t.ThrottleReasons, reason) {
+			throttled = append(throttled, reason)
+		}
+	}
+
+	if len(throttled) == 0 {
+		return ""
+	}
+
+	return "frequency throttled: " + strings.Join(throttled, ", ")
+}
+
+func (t *healthThresholds) checkErrors(l0 levelzeroservice.LevelzeroService, bdfAddr string, metrics map[string]float64) string {
+	if t.CorrectableErrors == nil && t.UncorrectableErrors == nil {
+		return ""
+	}
+
+	errs, err := l0.GetDeviceErrors(bdfAddr)
+	if err != nil {
+		klog.ErrorS(err, "Device RAS errors retrieval failed", dpapi.LogKeyBDF, bdfAddr)
+
+		return ""
+	}
+
+	metrics["correctableErrors"] = float64(errs.Correctable)
+	metrics["uncorrectableErrors"] = float64(errs.Uncorrectable)
+
+	if t.UncorrectableErrors != nil && errs.Uncorrectable > *t.UncorrectableErrors {
+		return fmt.Sprintf("%d uncorrectable errors", errs.Uncorrectable)
+	}
+
+	if t.CorrectableErrors != nil && errs.Correctable > *t.CorrectableErrors {
+		return fmt.Sprintf("%d correctable errors", errs.Correctable)
+	}
+
+	return ""
+}
+
+func (t *healthThresholds) checkMemoryBandwidth(l0 levelzeroservice.LevelzeroService, bdfAddr string, metrics map[string]float64) string {
+	if t.MemoryBandwidth == nil {
+		return ""
+	}
+
+	bw, err := l0.GetDeviceMemoryBandwidth(bdfAddr)
+	if err != nil {
+		klog.ErrorS(err, "Device memory bandwidth retrieval failed", dpapi.LogKeyBDF, bdfAddr)
+
+		return ""
+	}
+
+	metrics["memoryBandwidthRead"] = bw.Read
+	metrics["memoryBandwidthWrite"] = bw.Write
+
+	if bw.Max > 0 && bw.Read+bw.Write > bw.Max*(*t.MemoryBandwidth)/100 {
+		return fmt.Sprintf("memory bandwidth over %g%%", *t.MemoryBandwidth)
+	}
+
+	return ""
+}
+
+// check adds the values with thresholds to the metrics, and returns the
+// reasons for the thresholds crossed. The values which can't be retrieved
+// are skipped.
+func (t *healthThresholds) check(l0 levelzeroservice.LevelzeroService, bdfAddr string, metrics map[string]float64) []string {
+	reasons := []string{}
+
+	for _, check := range []func(levelzeroservice.LevelzeroService, string, map[string]float64) string{
+		t.checkUtilization, t.checkPower, t.checkFrequency, t.checkErrors, t.checkMemoryBandwidth,
+	} {
+		if reason := check(l0, bdfAddr, metrics); reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+
+	return reasons
+}
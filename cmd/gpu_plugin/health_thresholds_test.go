@@ -0,0 +1,122 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/gpu_plugin/levelzeroservice"
+	lz "github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/levelzero"
+	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/levelzero/fakeserver"
+)
+
+func TestHealthThresholdsValidate(t *testing.T) {
+	for name, section := range map[string]string{
+		"utilization":     `{"healthThresholds": {"engineUtilization": 101}}`,
+		"power":           `{"healthThresholds": {"powerOfTDP": 0}}`,
+		"throttle reason": `{"healthThresholds": {"throttleReasons": ["slow"]}}`,
+	} {
+		plugin := newDevicePlugin(t.TempDir(), t.TempDir(), cliOptions{sharedDevNum: 1, preferredAllocationPolicy: "none"})
+
+		if err := plugin.ApplyConfig([]byte(section)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestHealthThresholds(t *testing.T) {
+	sysfs, _, err := createTestFiles(t.TempDir(), TestCaseDetails{
+		pciAddresses: map[string]string{"0000:00:00.0": "card0", "0000:00:01.0": "card1"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	server := fakeserver.New()
+
+	healthy := &fakeserver.Device{
+		Health:          &lz.DeviceHealth{MemoryOk: true, BusOk: true, SocOk: true},
+		Temperature:     &lz.DeviceTemperature{Global: 40, Gpu: 40, Memory: 40},
+		Utilization:     &lz.DeviceUtilization{Engines: []*lz.EngineUtilization{{Group: "compute", Utilization: 20}}},
+		Power:           &lz.DevicePower{Power: 100, Tdp: 300},
+		Frequency:       &lz.DeviceFrequency{Actual: 2000, Requested: 2000, Max: 2000},
+		Errors:          &lz.DeviceErrors{},
+		MemoryBandwidth: &lz.DeviceMemoryBandwidth{Read: 10e9, Write: 10e9, Max: 500e9},
+	}
+
+	server.SetDevice("0000:00:00.0", healthy)
+	server.SetDevice("0000:00:01.0", &fakeserver.Device{
+		Health:          healthy.Health,
+		Temperature:     healthy.Temperature,
+		Utilization:     &lz.DeviceUtilization{Engines: []*lz.EngineUtilization{{Group: "compute", Utilization: 99}}},
+		Power:           &lz.DevicePower{Power: 290, Tdp: 300},
+		Frequency:       &lz.DeviceFrequency{Actual: 800, Requested: 2000, Max: 2000, ThrottleReasons: []string{"thermal"}},
+		Errors:          &lz.DeviceErrors{Correctable: 2, Uncorrectable: 1},
+		MemoryBandwidth: &lz.DeviceMemoryBandwidth{Read: 300e9, Write: 200e9, Max: 500e9},
+	})
+
+	sockPath := filepath.Join(t.TempDir(), "levelzero.sock")
+	if err := server.Start(sockPath); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	defer server.Stop()
+
+	plugin := newDevicePlugin(sysfs, t.TempDir(), cliOptions{
+		healthManagement: true, temperatureLimit: 100, sharedDevNum: 1, preferredAllocationPolicy: "none", levelzeroUnreachable: unreachableUnknown,
+	})
+	plugin.levelzeroService = levelzeroservice.NewLevelzero(sockPath)
+	plugin.levelzeroService.Run(false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go plugin.watchHealth(ctx)
+
+	waitHealthWatch(t, plugin, 2)
+
+	devices := map[string][]string{deviceTypeI915: {"card0-0", "card1-0"}}
+
+	// Without thresholds, only the health indicators and the temperatures
+	// count.
+	if result := plugin.CheckHealth(devices)[deviceTypeI915]["card1-0"]; result.State != v1beta1.Healthy {
+		t.Errorf("unexpected result without thresholds: %+v", result)
+	}
+
+	if err := plugin.ApplyConfig([]byte(`{"healthThresholds": {"engineUtilization": 95, "powerOfTDP": 90,
+		"throttleReasons": ["thermal"], "uncorrectableErrors": 0, "memoryBandwidth": 90}}`)); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	results := plugin.CheckHealth(devices)[deviceTypeI915]
+
+	if result := results["card0-0"]; result.State != v1beta1.Healthy || result.Metrics["utilizationCompute"] != 20 || result.Metrics["power"] != 100 {
+		t.Errorf("unexpected result of card0: %+v", result)
+	}
+
+	expected := "compute utilization over 95%, power over 90% of TDP, frequency throttled: thermal, 1 uncorrectable errors, memory bandwidth over 90%"
+	if result := results["card1-0"]; result.State != v1beta1.Unhealthy || result.Reason != expected {
+		t.Errorf("unexpected result of card1: %+v", result)
+	}
+
+	// Both error counts are reported with a threshold for one of them.
+	if _, ok := results["card1-0"].Metrics["correctableErrors"]; !ok {
+		t.Errorf("missing the error metrics: %v", results["card1-0"].Metrics)
+	}
+}
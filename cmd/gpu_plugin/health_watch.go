@@ -0,0 +1,146 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/klog/v2"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/gpu_plugin/levelzeroservice"
+	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+)
+
+// The health of the cards when the Level-Zero sidecar is unreachable, see
+// -levelzero-unreachable.
+const (
+	// unreachableUnknown keeps the previous health of the cards.
+	unreachableUnknown   = "unknown"
+	unreachableHealthy   = "healthy"
+	unreachableUnhealthy = "unhealthy"
+)
+
+var unreachablePolicies = []string{unreachableUnknown, unreachableHealthy, unreachableUnhealthy}
+
+// watchState is the state of the health watch of the Level-Zero sidecar.
+type watchState int
+
+const (
+	// watchConnecting is the state until the first event of the watch.
+	watchConnecting watchState = iota
+	watchConnected
+	watchUnreachable
+)
+
+// levelzeroHealthReason returns the reason for a card being unhealthy, or ""
+// when it's healthy.
+func levelzeroHealthReason(dh levelzeroservice.DeviceHealth, limit float64) string {
+	failed := []string{}
+
+	for _, indicator := range []struct {
+		name string
+		ok   bool
+	}{{"memory", dh.Memory}, {"bus", dh.Bus}, {"SoC", dh.SoC}} {
+		if !indicator.ok {
+			failed = append(failed, indicator.name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return strings.Join(failed, ", ") + " unhealthy"
+	}
+
+	if dh.GPUTemperature > limit || dh.GlobalTemperature > limit || dh.MemoryTemperature > limit {
+		return fmt.Sprintf("temperature over the limit of %.0fC", limit)
+	}
+
+	return ""
+}
+
+// HealthChanged implements the HealthNotifier interface. The health watch
+// tells about the health changes of the cards, and about losing the sidecar.
+func (dp *devicePlugin) HealthChanged() <-chan struct{} {
+	return dp.healthChanged
+}
+
+// watchHealth keeps the health of the cards up to date from the health
+// watch of the Level-Zero sidecar until ctx is done.
+func (dp *devicePlugin) watchHealth(ctx context.Context) {
+	dp.levelzeroService.WatchDeviceHealth(ctx, scanPeriod, dp.handleHealthEvent)
+}
+
+// handleHealthEvent stores the health of a card from the health watch, and
+// notifies the health checks when it changes the health of the card or the
+// state of the watch.
+func (dp *devicePlugin) handleHealthEvent(event levelzeroservice.HealthEvent) {
+	dp.mutex.Lock()
+
+	previousState := dp.healthWatchState
+	changed := false
+
+	if event.Err != nil {
+		// The health is sent again when the watch is restarted.
+		dp.healthWatchState = watchUnreachable
+		dp.levelzeroHealth = make(map[string]levelzeroservice.DeviceHealth)
+	} else {
+		limit := float64(dp.options.temperatureLimit)
+		previous, ok := dp.levelzeroHealth[event.BdfAddress]
+
+		changed = !ok || levelzeroHealthReason(previous, limit) != levelzeroHealthReason(event.Health, limit)
+
+		dp.healthWatchState = watchConnected
+		dp.levelzeroHealth[event.BdfAddress] = event.Health
+	}
+
+	state, policy := dp.healthWatchState, dp.options.levelzeroUnreachable
+
+	dp.mutex.Unlock()
+
+	if state != previousState {
+		changed = true
+
+		if state == watchUnreachable {
+			klog.ErrorS(event.Err, "Level-Zero sidecar is unreachable", "health", policy)
+		} else {
+			klog.InfoS("Watching device health with Level-Zero")
+		}
+	}
+
+	if changed {
+		select {
+		case dp.healthChanged <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// unreachableResult returns the health of a card when the Level-Zero sidecar
+// is unreachable, and false when the health is unknown.
+func unreachableResult(result dpapi.HealthResult, policy string) (dpapi.HealthResult, bool) {
+	switch policy {
+	case unreachableHealthy:
+		result.Reason = "Level-Zero unreachable"
+	case unreachableUnhealthy:
+		result.State = pluginapi.Unhealthy
+		result.Reason = "Level-Zero unreachable"
+	default:
+		return result, false
+	}
+
+	return result, true
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/gpu_plugin/levelzeroservice"
+)
+
+// waitHealthWatch waits until the health watch has told the health of the
+// given number of cards.
+func waitHealthWatch(t *testing.T, plugin *devicePlugin, cards int) {
+	t.Helper()
+
+	timeout := time.After(10 * time.Second)
+
+	for {
+		plugin.mutex.RLock()
+		known := len(plugin.levelzeroHealth)
+		plugin.mutex.RUnlock()
+
+		if known >= cards {
+			return
+		}
+
+		select {
+		case <-plugin.HealthChanged():
+		case <-time.After(100 * time.Millisecond):
+		case <-timeout:
+			t.Fatalf("timeout waiting for the health of %d cards", cards)
+		}
+	}
+}
+
+func TestHandleHealthEvent(t *testing.T) {
+	plugin := newDevicePlugin(t.TempDir(), t.TempDir(), cliOptions{healthManagement: true, temperatureLimit: 100})
+
+	healthy := levelzeroservice.DeviceHealth{Memory: true, Bus: true, SoC: true, GlobalTemperature: 40}
+	warmer := healthy
+	warmer.GlobalTemperature = 60
+	hot := healthy
+	hot.GlobalTemperature = 110
+
+	for _, step := range []struct {
+		name     string
+		event    levelzeroservice.HealthEvent
+		expected watchState
+		notified bool
+	}{
+		{name: "first event", event: levelzeroservice.HealthEvent{BdfAddress: "0000:00:00.0", Health: healthy}, expected: watchConnected, notified: true},
+		{name: "same health", event: levelzeroservice.HealthEvent{BdfAddress: "0000:00:00.0", Health: warmer}, expected: watchConnected},
+		{name: "over the limit", event: levelzeroservice.HealthEvent{BdfAddress: "0000:00:00.0", Health: hot}, expected: watchConnected, notified: true},
+		{name: "another card", event: levelzeroservice.HealthEvent{BdfAddress: "0000:00:01.0", Health: healthy}, expected: watchConnected, notified: true},
+		{name: "unreachable", event: levelzeroservice.HealthEvent{Err: errors.New("failed")}, expected: watchUnreachable, notified: true},
+		{name: "unreachable again", event: levelzeroservice.HealthEvent{Err: errors.New("failed")}, expected: watchUnreachable},
+		{name: "reconnected", event: levelzeroservice.HealthEvent{BdfAddress: "0000:00:00.0", Health: healthy}, expected: watchConnected, notified: true},
+	} {
+		plugin.handleHealthEvent(step.event)
+
+		notified := false
+
+		select {
+		case <-plugin.HealthChanged():
+			notified = true
+		default:
+		}
+
+		if notified != step.notified || plugin.healthWatchState != step.expected {
+			t.Errorf("%s: unexpected notification %v and state %v", step.name, notified, plugin.healthWatchState)
+		}
+	}
+
+	// The health of the cards is told again after reconnecting.
+	if len(plugin.levelzeroHealth) != 1 {
+		t.Errorf("unexpected health after reconnecting: %v", plugin.levelzeroHealth)
+	}
+}
+
+func TestLevelzeroUnreachableOption(t *testing.T) {
+	sysfs, _, err := createTestFiles(t.TempDir(), TestCaseDetails{
+		pciAddresses: map[string]string{"0000:00:00.0": "card0"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	plugin := newDevicePlugin(sysfs, t.TempDir(), cliOptions{
+		healthManagement: true, sharedDevNum: 1, preferredAllocationPolicy: "none", levelzeroUnreachable: unreachableUnknown,
+	})
+	plugin.levelzeroService = &mockL0Service{}
+
+	if err := plugin.ApplyConfig([]byte(`{"levelzeroUnreachable": "maybe"}`)); err == nil {
+		t.Error("expected an error for an invalid policy")
+	}
+
+	if err := plugin.ApplyConfig([]byte(`{"levelzeroUnreachable": "unhealthy"}`)); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	plugin.handleHealthEvent(levelzeroservice.HealthEvent{Err: errors.New("failed")})
+
+	if result := plugin.CheckHealth(map[string][]string{deviceTypeI915: {"card0-0"}})[deviceTypeI915]["card0-0"]; result.State != v1beta1.Unhealthy {
+		t.Errorf("unexpected result with the policy of the config file: %+v", result)
+	}
+}
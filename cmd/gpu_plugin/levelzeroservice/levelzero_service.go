@@ -16,8 +16,13 @@ package levelzeroservice
 
 import (
 	"context"
+	"fmt"
+	"io"
+	"time"
 
 	lz "github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/levelzero"
+	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+	"github.com/pkg/errors"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
@@ -30,8 +35,21 @@ type LevelzeroService interface {
 	GetDeviceHealth(bdfAddress string) (DeviceHealth, error)
 	GetDeviceTemperature(bdfAddress string) (DeviceTemperature, error)
 	GetDeviceMemoryAmount(bdfAddress string) (uint64, error)
+	GetDeviceUtilization(bdfAddress string) (DeviceUtilization, error)
+	GetDevicePower(bdfAddress string) (DevicePower, error)
+	GetDeviceFrequency(bdfAddress string) (DeviceFrequency, error)
+	GetDeviceErrors(bdfAddress string) (DeviceErrors, error)
+	GetDeviceMemoryBandwidth(bdfAddress string) (DeviceMemoryBandwidth, error)
+	WatchDeviceHealth(ctx context.Context, interval time.Duration, handler func(HealthEvent))
 }
 
+const (
+	// The backoff between the attempts to watch the health of the devices
+	// doubles from initialWatchBackoff to maxWatchBackoff.
+	initialWatchBackoff = time.Second
+	maxWatchBackoff     = time.Minute
+)
+
 type DeviceHealth struct {
 	Memory            bool
 	Bus               bool
@@ -47,6 +65,47 @@ type DeviceTemperature struct {
 	Memory float64
 }
 
+// DeviceUtilization has the busy time of the engine groups in percent since
+// the previous request, by group, e.g. "compute".
+type DeviceUtilization map[string]float64
+
+// DevicePower has the average power in watts since the previous request.
+type DevicePower struct {
+	Power float64
+	TDP   float64
+}
+
+// DeviceFrequency has the frequencies in MHz, and the reasons the frequency
+// is throttled, e.g. "thermal".
+type DeviceFrequency struct {
+	ThrottleReasons []string
+	Actual          float64
+	Requested       float64
+	Max             float64
+}
+
+// DeviceErrors has the RAS error counts.
+type DeviceErrors struct {
+	Correctable   uint64
+	Uncorrectable uint64
+}
+
+// DeviceMemoryBandwidth has the memory bandwidth in bytes per second since
+// the previous request.
+type DeviceMemoryBandwidth struct {
+	Read  float64
+	Write float64
+	Max   float64
+}
+
+// HealthEvent is the health of a device from WatchDeviceHealth, or the error
+// which interrupted the watch.
+type HealthEvent struct {
+	Err        error
+	BdfAddress string
+	Health     DeviceHealth
+}
+
 type clientNotReadyErr struct{}
 
 func (e *clientNotReadyErr) Error() string {
@@ -55,28 +114,32 @@ func (e *clientNotReadyErr) Error() string {
 
 func NewLevelzero(socket string) LevelzeroService {
 	return &levelzero{
-		socketPath: socket,
-		ctx:        context.Background(),
-		conn:       nil,
-		client:     nil,
+		socketPath:     socket,
+		ctx:            context.Background(),
+		conn:           nil,
+		client:         nil,
+		initialBackoff: initialWatchBackoff,
+		maxBackoff:     maxWatchBackoff,
 	}
 }
 
 type levelzero struct {
-	client     lz.LevelzeroClient
-	ctx        context.Context
-	conn       *grpc.ClientConn
-	socketPath string
+	client         lz.LevelzeroClient
+	ctx            context.Context
+	conn           *grpc.ClientConn
+	socketPath     string
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
 }
 
 func (l *levelzero) Run(keep bool) {
 	url := "unix://" + l.socketPath
 
-	klog.V(3).Info("Starting Level-Zero client. Connecting to: ", url)
+	klog.V(3).InfoS("Starting Level-Zero client", "url", url)
 
 	conn, err := grpc.NewClient(url, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	if err != nil {
-		klog.Error("Failed to connect to socket", err)
+		klog.ErrorS(err, "Failed to connect to socket", "url", url)
 
 		return
 	}
@@ -92,7 +155,7 @@ func (l *levelzero) Run(keep bool) {
 		}
 
 		if state == connectivity.Ready {
-			klog.V(2).Info("Connection ready")
+			klog.V(2).InfoS("Connection ready")
 
 			l.client = lz.NewLevelzeroClient(conn)
 
@@ -124,7 +187,7 @@ func (l *levelzero) GetIntelIndices() ([]uint32, error) {
 	}
 
 	if indices.Error != nil && indices.Error.Errorcode != 0 {
-		klog.Warningf("indices request returned internal error: 0x%X (%s)", indices.Error.Errorcode, indices.Error.Description)
+		klog.InfoS("Indices request returned an internal error", "errorCode", fmt.Sprintf("0x%X", indices.Error.Errorcode), "description", indices.Error.Description)
 	}
 
 	return indices.Indices, nil
@@ -147,7 +210,7 @@ func (l *levelzero) GetDeviceHealth(bdfAddress string) (DeviceHealth, error) {
 	}
 
 	if health.Error != nil && health.Error.Errorcode != 0 {
-		klog.Warningf("health request returned internal error: 0x%X (%s)", health.Error.Errorcode, health.Error.Description)
+		klog.InfoS("Health request returned an internal error", dpapi.LogKeyBDF, bdfAddress, "errorCode", fmt.Sprintf("0x%X", health.Error.Errorcode), "description", health.Error.Description)
 	}
 
 	return DeviceHealth{
@@ -174,7 +237,7 @@ func (l *levelzero) GetDeviceTemperature(bdfAddress string) (DeviceTemperature,
 	}
 
 	if temps.Error != nil && temps.Error.Errorcode != 0 {
-		klog.Warningf("temperature request returned internal error: 0x%X (%s)", temps.Error.Errorcode, temps.Error.Description)
+		klog.InfoS("Temperature request returned an internal error", dpapi.LogKeyBDF, bdfAddress, "errorCode", fmt.Sprintf("0x%X", temps.Error.Errorcode), "description", temps.Error.Description)
 	}
 
 	return DeviceTemperature{
@@ -201,8 +264,229 @@ func (l *levelzero) GetDeviceMemoryAmount(bdfAddress string) (uint64, error) {
 	}
 
 	if memSize.Error != nil && memSize.Error.Errorcode != 0 {
-		klog.Warningf("memory request returned internal error: 0x%X (%s)", memSize.Error.Errorcode, memSize.Error.Description)
+		klog.InfoS("Memory request returned an internal error", dpapi.LogKeyBDF, bdfAddress, "errorCode", fmt.Sprintf("0x%X", memSize.Error.Errorcode), "description", memSize.Error.Description)
 	}
 
 	return memSize.MemorySize, nil
 }
+
+func (l *levelzero) GetDeviceUtilization(bdfAddress string) (DeviceUtilization, error) {
+	if !l.isClientReady() {
+		return DeviceUtilization{}, &clientNotReadyErr{}
+	}
+
+	cli := l.client
+
+	did := lz.DeviceId{
+		BdfAddress: bdfAddress,
+	}
+
+	util, err := cli.GetDeviceUtilization(l.ctx, &did)
+	if err != nil || util == nil {
+		return DeviceUtilization{}, err
+	}
+
+	if util.Error != nil && util.Error.Errorcode != 0 {
+		klog.InfoS("Utilization request returned an internal error", dpapi.LogKeyBDF, bdfAddress, "errorCode", fmt.Sprintf("0x%X", util.Error.Errorcode), "description", util.Error.Description)
+	}
+
+	utilization := DeviceUtilization{}
+
+	for _, engine := range util.Engines {
+		utilization[engine.Group] = engine.Utilization
+	}
+
+	return utilization, nil
+}
+
+func (l *levelzero) GetDevicePower(bdfAddress string) (DevicePower, error) {
+	if !l.isClientReady() {
+		return DevicePower{}, &clientNotReadyErr{}
+	}
+
+	cli := l.client
+
+	did := lz.DeviceId{
+		BdfAddress: bdfAddress,
+	}
+
+	power, err := cli.GetDevicePower(l.ctx, &did)
+	if err != nil || power == nil {
+		return DevicePower{}, err
+	}
+
+	if power.Error != nil && power.Error.Errorcode != 0 {
+		klog.InfoS("Power request returned an internal error", dpapi.LogKeyBDF, bdfAddress, "errorCode", fmt.Sprintf("0x%X", power.Error.Errorcode), "description", power.Error.Description)
+	}
+
+	return DevicePower{
+		Power: power.Power,
+		TDP:   power.Tdp,
+	}, nil
+}
+
+func (l *levelzero) GetDeviceFrequency(bdfAddress string) (DeviceFrequency, error) {
+	if !l.isClientReady() {
+		return DeviceFrequency{}, &clientNotReadyErr{}
+	}
+
+	cli := l.client
+
+	did := lz.DeviceId{
+		BdfAddress: bdfAddress,
+	}
+
+	freq, err := cli.GetDeviceFrequency(l.ctx, &did)
+	if err != nil || freq == nil {
+		return DeviceFrequency{}, err
+	}
+
+	if freq.Error != nil && freq.Error.Errorcode != 0 {
+		klog.InfoS("Frequency request returned an internal error", dpapi.LogKeyBDF, bdfAddress, "errorCode", fmt.Sprintf("0x%X", freq.Error.Errorcode), "description", freq.Error.Description)
+	}
+
+	return DeviceFrequency{
+		Actual:          freq.Actual,
+		Requested:       freq.Requested,
+		Max:             freq.Max,
+		ThrottleReasons: freq.ThrottleReasons,
+	}, nil
+}
+
+func (l *levelzero) GetDeviceErrors(bdfAddress string) (DeviceErrors, error) {
+	if !l.isClientReady() {
+		return DeviceErrors{}, &clientNotReadyErr{}
+	}
+
+	cli := l.client
+
+	did := lz.DeviceId{
+		BdfAddress: bdfAddress,
+	}
+
+	errs, err := cli.GetDeviceErrors(l.ctx, &did)
+	if err != nil || errs == nil {
+		return DeviceErrors{}, err
+	}
+
+	if errs.Error != nil && errs.Error.Errorcode != 0 {
+		klog.InfoS("Errors request returned an internal error", dpapi.LogKeyBDF, bdfAddress, "errorCode", fmt.Sprintf("0x%X", errs.Error.Errorcode), "description", errs.Error.Description)
+	}
+
+	return DeviceErrors{
+		Correctable:   errs.Correctable,
+		Uncorrectable: errs.Uncorrectable,
+	}, nil
+}
+
+func (l *levelzero) GetDeviceMemoryBandwidth(bdfAddress string) (DeviceMemoryBandwidth, error) {
+	if !l.isClientReady() {
+		return DeviceMemoryBandwidth{}, &clientNotReadyErr{}
+	}
+
+	cli := l.client
+
+	did := lz.DeviceId{
+		BdfAddress: bdfAddress,
+	}
+
+	bw, err := cli.GetDeviceMemoryBandwidth(l.ctx, &did)
+	if err != nil || bw == nil {
+		return DeviceMemoryBandwidth{}, err
+	}
+
+	if bw.Error != nil && bw.Error.Errorcode != 0 {
+		klog.InfoS("Memory bandwidth request returned an internal error", dpapi.LogKeyBDF, bdfAddress, "errorCode", fmt.Sprintf("0x%X", bw.Error.Errorcode), "description", bw.Error.Description)
+	}
+
+	return DeviceMemoryBandwidth{
+		Read:  bw.Read,
+		Write: bw.Write,
+		Max:   bw.Max,
+	}, nil
+}
+
+// WatchDeviceHealth streams the health of the devices to the handler until
+// the context is done. The sidecar samples the health at the interval, and
+// sends the changes. When the watch fails, the handler gets the error, and
+// the watch is retried with an exponential backoff.
+func (l *levelzero) WatchDeviceHealth(ctx context.Context, interval time.Duration, handler func(HealthEvent)) {
+	backoff := l.initialBackoff
+
+	for attempt := 1; ; attempt++ {
+		received, err := l.watchDeviceHealth(ctx, interval, handler)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if received {
+			backoff = l.initialBackoff
+		}
+
+		// The first attempt can be made before the client is connected.
+		var notReady *clientNotReadyErr
+		if attempt > 1 || !errors.As(err, &notReady) {
+			klog.ErrorS(err, "Health watch failed, retrying", "backoff", backoff)
+
+			handler(HealthEvent{Err: err})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff = min(2*backoff, l.maxBackoff)
+	}
+}
+
+// watchDeviceHealth streams the health events until the stream fails. It
+// tells whether any events were received.
+func (l *levelzero) watchDeviceHealth(ctx context.Context, interval time.Duration, handler func(HealthEvent)) (bool, error) {
+	if !l.isClientReady() {
+		return false, &clientNotReadyErr{}
+	}
+
+	stream, err := l.client.WatchDeviceHealth(ctx, &lz.WatchDeviceHealthRequest{IntervalMs: uint32(interval.Milliseconds())})
+	if err != nil {
+		return false, errors.Wrap(err, "failed to watch health")
+	}
+
+	klog.V(2).InfoS("Watching device health")
+
+	received := false
+
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return received, errors.New("health watch ended")
+		}
+
+		if err != nil {
+			return received, errors.Wrap(err, "health watch failed")
+		}
+
+		received = true
+
+		if event.Error != nil && event.Error.Errorcode != 0 {
+			klog.InfoS("Health watch returned an internal error", dpapi.LogKeyBDF, event.BdfAddress, "errorCode", fmt.Sprintf("0x%X", event.Error.Errorcode), "description", event.Error.Description)
+		}
+
+		if event.BdfAddress == "" {
+			continue
+		}
+
+		handler(HealthEvent{
+			BdfAddress: event.BdfAddress,
+			Health: DeviceHealth{
+				Memory:            event.Health.GetMemoryOk(),
+				Bus:               event.Health.GetBusOk(),
+				SoC:               event.Health.GetSocOk(),
+				GlobalTemperature: event.Temperature.GetGlobal(),
+				GPUTemperature:    event.Temperature.GetGpu(),
+				MemoryTemperature: event.Temperature.GetMemory(),
+			},
+		})
+	}
+}
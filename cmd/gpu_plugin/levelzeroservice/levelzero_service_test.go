@@ -21,9 +21,12 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
+	"time"
 
 	lz "github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/levelzero"
+	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/levelzero/fakeserver"
 	"google.golang.org/grpc"
 )
 
@@ -304,4 +307,147 @@ func TestAccessBeforeReady(t *testing.T) {
 	if err == nil {
 		t.Error("Got non-error for indices, expected error")
 	}
+
+	_, err = n.GetDeviceUtilization("")
+	if err == nil {
+		t.Error("Got non-error for utilization, expected error")
+	}
+}
+
+func TestDeviceCounters(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "server.sock")
+
+	server := fakeserver.New()
+	server.SetDevice("0000:03:00.0", &fakeserver.Device{
+		Utilization: &lz.DeviceUtilization{Engines: []*lz.EngineUtilization{
+			{Group: "all", Utilization: 60}, {Group: "compute", Utilization: 50},
+		}},
+		Power:           &lz.DevicePower{Power: 120, Tdp: 300},
+		Frequency:       &lz.DeviceFrequency{Actual: 900, Requested: 1600, Max: 2100, ThrottleReasons: []string{"thermal"}},
+		Errors:          &lz.DeviceErrors{Correctable: 3, Uncorrectable: 1},
+		MemoryBandwidth: &lz.DeviceMemoryBandwidth{Read: 1e9, Write: 2e9, Max: 500e9},
+	})
+
+	if err := server.Start(sockPath); err != nil {
+		t.Fatal("failed to start the fake server:", err)
+	}
+
+	defer server.Stop()
+
+	n := NewLevelzero(sockPath)
+	n.Run(false)
+
+	util, err := n.GetDeviceUtilization("0000:03:00.0")
+	if err != nil || !reflect.DeepEqual(util, DeviceUtilization{"all": 60, "compute": 50}) {
+		t.Error("unexpected utilization", util, err)
+	}
+
+	power, err := n.GetDevicePower("0000:03:00.0")
+	if err != nil || power != (DevicePower{Power: 120, TDP: 300}) {
+		t.Error("unexpected power", power, err)
+	}
+
+	freq, err := n.GetDeviceFrequency("0000:03:00.0")
+	if err != nil || freq.Actual != 900 || freq.Max != 2100 || !reflect.DeepEqual(freq.ThrottleReasons, []string{"thermal"}) {
+		t.Error("unexpected frequency", freq, err)
+	}
+
+	errs, err := n.GetDeviceErrors("0000:03:00.0")
+	if err != nil || errs != (DeviceErrors{Correctable: 3, Uncorrectable: 1}) {
+		t.Error("unexpected errors", errs, err)
+	}
+
+	bw, err := n.GetDeviceMemoryBandwidth("0000:03:00.0")
+	if err != nil || bw != (DeviceMemoryBandwidth{Read: 1e9, Write: 2e9, Max: 500e9}) {
+		t.Error("unexpected memory bandwidth", bw, err)
+	}
+
+	// Internal errors of the unknown devices return zero values.
+	power, err = n.GetDevicePower("0000:04:00.0")
+	if err != nil || power != (DevicePower{}) {
+		t.Error("unexpected power of an unknown device", power, err)
+	}
+
+	server.SetFailing(true)
+
+	if _, err := n.GetDeviceErrors("0000:03:00.0"); err == nil {
+		t.Error("GetDeviceErrors returned nil and expected error")
+	}
+}
+
+func TestWatchDeviceHealth(t *testing.T) {
+	sockPath := filepath.Join(t.TempDir(), "server.sock")
+
+	healthy := &fakeserver.Device{
+		Health:      &lz.DeviceHealth{MemoryOk: true, BusOk: true, SocOk: true},
+		Temperature: &lz.DeviceTemperature{Global: 40, Gpu: 45, Memory: 40},
+	}
+
+	server := fakeserver.New()
+	server.SetDevice("0000:03:00.0", healthy)
+
+	if err := server.Start(sockPath); err != nil {
+		t.Fatal("failed to start the fake server:", err)
+	}
+
+	defer server.Stop()
+
+	n := NewLevelzero(sockPath)
+	n.(*levelzero).initialBackoff = 10 * time.Millisecond
+	n.Run(false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events := make(chan HealthEvent, 10)
+	done := make(chan struct{})
+
+	go func() {
+		n.WatchDeviceHealth(ctx, 10*time.Millisecond, func(event HealthEvent) { events <- event })
+		close(done)
+	}()
+
+	next := func() HealthEvent {
+		t.Helper()
+
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("no health event received")
+		}
+
+		return HealthEvent{}
+	}
+
+	if event := next(); event.Err != nil || event.BdfAddress != "0000:03:00.0" || !event.Health.Memory || event.Health.GPUTemperature != 45 {
+		t.Errorf("unexpected first event %+v", event)
+	}
+
+	server.SetDevice("0000:03:00.0", &fakeserver.Device{
+		Health:      &lz.DeviceHealth{MemoryOk: false, BusOk: true, SocOk: true},
+		Temperature: healthy.Temperature,
+	})
+
+	if event := next(); event.Err != nil || event.Health.Memory {
+		t.Errorf("expected an unhealthy event, got %+v", event)
+	}
+
+	server.SetFailing(true)
+
+	if event := next(); event.Err == nil {
+		t.Errorf("expected an error event, got %+v", event)
+	}
+
+	// The watch is restarted and gets the health again.
+	server.SetFailing(false)
+
+	for event := next(); event.Err != nil; event = next() {
+	}
+
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("the watch didn't end with the context")
+	}
 }
@@ -0,0 +1,176 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog/v2"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/labeler"
+	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+)
+
+const (
+	// deviceTypeMemory is the device type of the memory units, see -memory-unit-size.
+	deviceTypeMemory = "memory-units"
+	// memoryLimitEnvVar has the memory allocated to a container in bytes, and
+	// with the card name appended, e.g. INTEL_GPU_MEMORY_LIMIT_CARD0, the
+	// memory allocated on that GPU.
+	memoryLimitEnvVar = "INTEL_GPU_MEMORY_LIMIT"
+	// maxMemoryUnits is the maximum number of memory units advertised per GPU.
+	maxMemoryUnits = 1024
+)
+
+// memoryPolicy keeps the memory units of a container on one GPU when they
+// fit, and the free units of the GPUs unfragmented.
+var memoryPolicy = dpapi.NewAllocationPolicy(dpapi.Pack(dpapi.ParentKey("-")), dpapi.ByID)
+
+// cardDevices has the devices and mounts given to the containers allocated
+// the memory units or the tiles of a GPU.
+type cardDevices struct {
+	nodes  []pluginapi.DeviceSpec
+	mounts []pluginapi.Mount
+	tiles  int // the number of tiles, see -tile-resources
+}
+
+// addTo adds the devices and mounts of the GPU to a container's response.
+func (c *cardDevices) addTo(cresp *pluginapi.ContainerAllocateResponse) {
+	for i := range c.nodes {
+		cresp.Devices = append(cresp.Devices, &c.nodes[i])
+	}
+
+	for i := range c.mounts {
+		cresp.Mounts = append(cresp.Mounts, &c.mounts[i])
+	}
+}
+
+// parseMemoryUnitSize parses the -memory-unit-size flag, e.g. "1Gi". Memory
+// units are disabled when it's empty.
+func parseMemoryUnitSize(value string) (uint64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid memory unit size %q", value)
+	}
+
+	size, ok := quantity.AsInt64()
+	if !ok || size < 0 {
+		return 0, errors.Errorf("invalid memory unit size %q", value)
+	}
+
+	return uint64(size), nil
+}
+
+// memoryUnits returns the number of memory units of a GPU.
+func (dp *devicePlugin) memoryUnits(card string, unitSize uint64) int {
+	amount := labeler.MemoryAmount(dp.sysfsDir, card, labeler.GetTileCount(path.Join(dp.sysfsDir, card)), dp.levelzeroService)
+	units := amount / unitSize
+
+	if units > maxMemoryUnits {
+		klog.InfoS("Too many memory units, limiting them", "card", card, "memory", amount, "units", units, "limit", maxMemoryUnits)
+
+		return maxMemoryUnits
+	}
+
+	return int(units)
+}
+
+// addMemoryUnits adds the memory units of a GPU to the device tree, and
+// returns false when the GPU has too little memory for one unit.
+func (dp *devicePlugin) addMemoryUnits(devTree dpapi.DeviceTree, name string, deviceInfo dpapi.DeviceInfo, unitSize uint64) bool {
+	units := dp.memoryUnits(name, unitSize)
+	if units == 0 {
+		klog.InfoS("GPU memory is not known or smaller than one unit, set GPU_MEMORY_OVERRIDE for integrated GPUs",
+			"card", name, "unitSize", unitSize)
+
+		return false
+	}
+
+	for i := 0; i < units; i++ {
+		devTree.AddDevice(deviceTypeMemory, fmt.Sprintf("%s-%d", name, i), deviceInfo)
+	}
+
+	return true
+}
+
+// isMemoryRequest tells whether the device IDs are memory units, i.e. the
+// memory units are enabled and the IDs are not of the monitoring resource.
+func (dp *devicePlugin) isMemoryRequest(ids []string) bool {
+	return dp.currentOptions().memoryUnitSize > 0 && !slices.Contains(ids, monitorID)
+}
+
+// allocateMemory gives the containers the devices of the GPUs of their memory
+// units, their memory limit in INTEL_GPU_MEMORY_LIMIT and the limit on each
+// GPU in INTEL_GPU_MEMORY_LIMIT_<CARD>, as the units may span several GPUs.
+func (dp *devicePlugin) allocateMemory(request *pluginapi.AllocateRequest) (*pluginapi.AllocateResponse, error) {
+	dp.mutex.RLock()
+	cards, unitSize := dp.memoryCards, dp.options.memoryUnitSize
+	dp.mutex.RUnlock()
+
+	response := &pluginapi.AllocateResponse{}
+
+	for _, crqt := range request.ContainerRequests {
+		cresp := &pluginapi.ContainerAllocateResponse{
+			Envs: map[string]string{
+				memoryLimitEnvVar: strconv.FormatUint(uint64(len(crqt.DevicesIDs))*unitSize, 10),
+			},
+		}
+
+		names := []string{}
+		units := map[string]uint64{}
+
+		for _, id := range crqt.DevicesIDs {
+			name, _, _ := strings.Cut(id, "-")
+			if units[name] == 0 {
+				names = append(names, name)
+			}
+
+			units[name]++
+		}
+
+		sort.Strings(names)
+
+		if len(names) > 1 {
+			klog.InfoS("Memory units of a container are on several GPUs", dpapi.LogKeyDeviceID, crqt.DevicesIDs)
+		}
+
+		for _, name := range names {
+			card, ok := cards[name]
+			if !ok {
+				return nil, errors.Errorf("memory units of unknown GPU %s", name)
+			}
+
+			card.addTo(cresp)
+
+			cresp.Envs[memoryLimitEnvVar+"_"+strings.ToUpper(name)] = strconv.FormatUint(units[name]*unitSize, 10)
+		}
+
+		response.ContainerResponses = append(response.ContainerResponses, cresp)
+	}
+
+	return response, nil
+}
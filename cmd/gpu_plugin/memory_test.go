@@ -0,0 +1,163 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"path"
+	"reflect"
+	"testing"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+)
+
+func TestParseMemoryUnitSize(t *testing.T) {
+	for value, expected := range map[string]uint64{"": 0, "1Gi": 1 << 30, "512Mi": 512 << 20} {
+		if size, err := parseMemoryUnitSize(value); err != nil || size != expected {
+			t.Errorf("%q: expected %d, got %d (%+v)", value, expected, size, err)
+		}
+	}
+
+	for _, value := range []string{"1G1", "-1Gi", "0.5"} {
+		if _, err := parseMemoryUnitSize(value); err == nil {
+			t.Errorf("%q: expected an error", value)
+		}
+	}
+}
+
+func TestValidateMemoryOptions(t *testing.T) {
+	opts := cliOptions{memoryUnitSize: 1 << 30, sharedDevNum: 1, preferredAllocationPolicy: "none"}
+	if err := validateOptions(opts); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+
+	opts.sharedDevNum = 2
+	if err := validateOptions(opts); err == nil {
+		t.Error("memory units should not be shared")
+	}
+}
+
+func TestMemoryUnits(t *testing.T) {
+	tc := TestCaseDetails{
+		sysfsdirs: []string{"card0/device/drm/card0", "card1/device/drm/card1", "card2/device/drm/card2"},
+		sysfsfiles: map[string][]byte{
+			"card0/device/vendor":    []byte("0x8086"),
+			"card0/lmem_total_bytes": []byte("4294967296"),
+			"card1/device/vendor":    []byte("0x8086"),
+			"card1/lmem_total_bytes": []byte("3221225471"),
+			"card2/device/vendor":    []byte("0x8086"),
+		},
+		devfsdirs: []string{"card0", "card1", "card2"},
+	}
+
+	sysfs, devfs, err := createTestFiles(t.TempDir(), tc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %+v", err)
+	}
+
+	t.Setenv("GPU_MEMORY_OVERRIDE", "")
+
+	plugin := newDevicePlugin(sysfs, devfs, cliOptions{memoryUnitSize: 1 << 30, sharedDevNum: 1, enableMonitoring: true})
+
+	tree, err := plugin.scan()
+	if err != nil {
+		t.Fatalf("Unexpected error: %+v", err)
+	}
+
+	// card1 has a byte short of 3 units, and the memory of card2 is not known.
+	if len(tree[deviceTypeMemory]) != 6 || len(tree[deviceTypeI915]) != 0 {
+		t.Fatalf("Unexpected devices %v", tree)
+	}
+
+	if _, ok := tree[deviceTypeMemory]["card1-1"]; !ok {
+		t.Errorf("Missing memory unit of card1: %v", tree[deviceTypeMemory])
+	}
+
+	if factor := plugin.SharingFactor(deviceTypeMemory); factor != 1 {
+		t.Errorf("Memory units should not be shared, got sharing factor %d", factor)
+	}
+
+	// The units of a container are kept on the GPU that fits them tightest.
+	for size, expected := range map[int32][]string{
+		2: {"card1-0", "card1-1"},
+		3: {"card0-0", "card0-1", "card0-2"},
+	} {
+		resp, err := plugin.GetPreferredAllocation(&v1beta1.PreferredAllocationRequest{
+			ContainerRequests: []*v1beta1.ContainerPreferredAllocationRequest{{
+				AvailableDeviceIDs: []string{"card0-0", "card1-0", "card0-1", "card1-1", "card0-2", "card0-3"},
+				AllocationSize:     size,
+			}},
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %+v", err)
+		}
+
+		if ids := resp.ContainerResponses[0].DeviceIDs; !reflect.DeepEqual(ids, expected) {
+			t.Errorf("Expected %v, got %v", expected, ids)
+		}
+	}
+
+	resp, err := plugin.Allocate(&v1beta1.AllocateRequest{
+		ContainerRequests: []*v1beta1.ContainerAllocateRequest{{DevicesIDs: []string{"card0-0", "card0-1"}}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %+v", err)
+	}
+
+	cresp := resp.ContainerResponses[0]
+	if len(cresp.Devices) != 1 || cresp.Devices[0].HostPath != path.Join(devfs, "card0") || cresp.Envs[memoryLimitEnvVar] != "2147483648" ||
+		cresp.Envs[memoryLimitEnvVar+"_CARD0"] != "2147483648" {
+		t.Errorf("Unexpected response %+v", cresp)
+	}
+
+	// The units spanning several GPUs are limited on each of them.
+	resp, err = plugin.Allocate(&v1beta1.AllocateRequest{
+		ContainerRequests: []*v1beta1.ContainerAllocateRequest{{DevicesIDs: []string{"card1-0", "card0-0", "card1-1"}}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %+v", err)
+	}
+
+	expectedEnvs := map[string]string{
+		memoryLimitEnvVar:            "3221225472",
+		memoryLimitEnvVar + "_CARD0": "1073741824",
+		memoryLimitEnvVar + "_CARD1": "2147483648",
+	}
+
+	cresp = resp.ContainerResponses[0]
+	if len(cresp.Devices) != 2 || !reflect.DeepEqual(cresp.Envs, expectedEnvs) {
+		t.Errorf("Unexpected response %+v", cresp)
+	}
+
+	if _, err := plugin.Allocate(&v1beta1.AllocateRequest{
+		ContainerRequests: []*v1beta1.ContainerAllocateRequest{{DevicesIDs: []string{"card2-0"}}},
+	}); err == nil {
+		t.Error("Expected an error for the memory units of an unknown GPU")
+	}
+
+	// The monitoring resource is allocated as usual.
+	if _, err := plugin.Allocate(&v1beta1.AllocateRequest{
+		ContainerRequests: []*v1beta1.ContainerAllocateRequest{{DevicesIDs: []string{monitorID}}},
+	}); !isUseDefaultMethodError(err) {
+		t.Errorf("Unexpected error for the monitoring resource: %+v", err)
+	}
+}
+
+func isUseDefaultMethodError(err error) bool {
+	_, ok := err.(*dpapi.UseDefaultMethodError)
+
+	return ok
+}
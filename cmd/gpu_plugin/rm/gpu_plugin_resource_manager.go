@@ -52,12 +52,16 @@ const (
 	gasTileAnnotation = "gas-container-tiles"
 
 	LevelzeroAffinityMaskEnvVar = "ZE_AFFINITY_MASK"
-	levelzeroHierarchyEnvVar    = "ZE_FLAT_DEVICE_HIERARCHY"
+	LevelzeroHierarchyEnvVar    = "ZE_FLAT_DEVICE_HIERARCHY"
 
 	hierarchyModeComposite = "COMPOSITE"
 	hierarchyModeFlat      = "FLAT"
 	hierarchyModeCombined  = "COMBINED"
 
+	// DefaultHierarchyMode is the hierarchy mode of Level-Zero when
+	// ZE_FLAT_DEVICE_HIERARCHY is not set.
+	DefaultHierarchyMode = hierarchyModeFlat
+
 	grpcAddress    = "unix:///var/lib/kubelet/pod-resources/kubelet.sock"
 	grpcBufferSize = 4 * 1024 * 1024
 	grpcTimeout    = 5 * time.Second
@@ -103,8 +107,15 @@ type getClientFunc func(string, time.Duration, int) (podresourcesv1.PodResources
 type ResourceManager interface {
 	CreateFractionalResourceResponse(*pluginapi.AllocateRequest) (*pluginapi.AllocateResponse, error)
 	GetPreferredFractionalAllocation(*pluginapi.PreferredAllocationRequest) (*pluginapi.PreferredAllocationResponse, error)
-	SetDevInfos(DeviceInfoMap)
-	SetTileCountPerCard(count uint64)
+	SetDevInfos(driver string, deviceInfos DeviceInfoMap)
+	SetTileCountPerCard(driver string, count uint64)
+}
+
+// driverState has the devices of the GPUs using one DRM driver, as the GPUs
+// of a node may use different drivers, e.g. i915 and xe.
+type driverState struct {
+	deviceInfos      DeviceInfoMap
+	tileCountPerCard uint64
 }
 
 type containerAssignments struct {
@@ -118,7 +129,7 @@ type podAssignmentDetails struct {
 
 type resourceManager struct {
 	clientset         kubernetes.Interface
-	deviceInfos       DeviceInfoMap
+	drivers           map[string]*driverState // DRM driver -> devices of its GPUs
 	prGetClientFunc   getClientFunc
 	assignments       map[string]podAssignmentDetails // pod name -> assignment details
 	nodeName          string
@@ -129,8 +140,8 @@ type resourceManager struct {
 	cleanupInterval   time.Duration
 	mutex             sync.RWMutex // for devTree updates during scan
 	cleanupMutex      sync.RWMutex // for assignment details during cleanup
+	allocationMutex   sync.Mutex   // serializes the allocations of the resources of all drivers
 	useKubelet        bool
-	tileCountPerCard  uint64
 }
 
 // NewDeviceInfo creates a new DeviceInfo.
@@ -171,17 +182,17 @@ func NewResourceManager(skipID string, fullResourceNames []string) (ResourceMana
 		useKubelet:        true,
 	}
 
-	klog.Info("GPU device plugin resource manager enabled")
+	klog.InfoS("GPU device plugin resource manager enabled")
 
 	// Try listing Pods once to detect if Kubelet API works
 	_, err = rm.listPodsFromKubelet()
 
 	if err != nil {
-		klog.V(2).Info("Not using Kubelet API")
+		klog.V(2).InfoS("Not using Kubelet API")
 
 		rm.useKubelet = false
 	} else {
-		klog.V(2).Info("Using Kubelet API")
+		klog.V(2).InfoS("Using Kubelet API")
 	}
 
 	go func() {
@@ -196,7 +207,7 @@ func NewResourceManager(skipID string, fullResourceNames []string) (ResourceMana
 		ticker := time.NewTicker(getRandDuration())
 
 		for range ticker.C {
-			klog.V(4).Info("Running cleanup")
+			klog.V(4).InfoS("Running cleanup")
 
 			ticker.Reset(getRandDuration())
 
@@ -211,13 +222,13 @@ func NewResourceManager(skipID string, fullResourceNames []string) (ResourceMana
 
 				for podName := range rm.assignments {
 					if _, found := running[podName]; !found {
-						klog.V(4).Info("Removing from assignments: ", podName)
+						klog.V(4).InfoS("Removing from assignments", dpapi.LogKeyPod, podName)
 						delete(rm.assignments, podName)
 					}
 				}
 			}()
 
-			klog.V(4).Info("Cleanup done")
+			klog.V(4).InfoS("Cleanup done")
 		}
 	}()
 
@@ -241,14 +252,14 @@ func (rm *resourceManager) listPodsFromAPIServer() (*v1.PodList, error) {
 		return &v1.PodList{}, err
 	}
 
-	klog.V(4).Info("Requesting pods from API server")
+	klog.V(4).InfoS("Requesting pods from API server")
 
 	podList, err := rm.clientset.CoreV1().Pods(v1.NamespaceAll).List(context.Background(), metav1.ListOptions{
 		FieldSelector: selector.String(),
 	})
 
 	if err != nil {
-		klog.Error("pod listing failed:", err)
+		klog.ErrorS(err, "Pod listing failed")
 
 		if err != nil {
 			return &v1.PodList{}, err
@@ -265,14 +276,14 @@ func (rm *resourceManager) listPodsFromKubelet() (*v1.PodList, error) {
 
 	token, err := os.ReadFile(serviceAccountTokenPath)
 	if err != nil {
-		klog.Warning("Failed to read token for kubelet API access: ", err)
+		klog.ErrorS(err, "Failed to read token for kubelet API access")
 
 		return &podList, err
 	}
 
 	kubeletCert, err := os.ReadFile(kubeletHTTPSCertPath)
 	if err != nil {
-		klog.Warning("Failed to read kubelet cert: ", err)
+		klog.ErrorS(err, "Failed to read kubelet cert")
 
 		return &podList, err
 	}
@@ -289,7 +300,7 @@ func (rm *resourceManager) listPodsFromKubelet() (*v1.PodList, error) {
 
 	req, err := http.NewRequestWithContext(context.Background(), "GET", kubeletURL, nil)
 	if err != nil {
-		klog.Warning("Failure creating new request: ", err)
+		klog.ErrorS(err, "Failed to create new request")
 
 		return &podList, err
 	}
@@ -308,18 +319,18 @@ func (rm *resourceManager) listPodsFromKubelet() (*v1.PodList, error) {
 		Transport: tr,
 	}
 
-	klog.V(4).Infof("Requesting pods from kubelet (%s)", kubeletURL)
+	klog.V(4).InfoS("Requesting pods from kubelet", "url", kubeletURL)
 
 	resp, err := (*client).Do(req)
 	if err != nil {
-		klog.Warning("Failed to read pods from kubelet API: ", err)
+		klog.ErrorS(err, "Failed to read pods from kubelet API")
 
 		return &podList, err
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		klog.Warning("Failed to read http response body: ", err)
+		klog.ErrorS(err, "Failed to read http response body")
 
 		return &podList, err
 	}
@@ -328,7 +339,7 @@ func (rm *resourceManager) listPodsFromKubelet() (*v1.PodList, error) {
 
 	err = json.Unmarshal(body, &podList)
 	if err != nil {
-		klog.Warning("Failed to unmarshal PodList from response: ", err)
+		klog.ErrorS(err, "Failed to unmarshal PodList from response")
 
 		return &podList, err
 	}
@@ -352,7 +363,7 @@ func (rm *resourceManager) listPods() (*v1.PodList, error) {
 			break
 		}
 
-		klog.Warning("Stopping Kubelet API use due to error/timeout")
+		klog.InfoS("Stopping Kubelet API use due to error/timeout")
 
 		rm.useKubelet = false
 	}
@@ -365,7 +376,7 @@ func (rm *resourceManager) listPodsOnNodeWithStates(states []string) map[string]
 
 	podList, err := rm.listPods()
 	if err != nil {
-		klog.Error("pod listing failed:", err)
+		klog.ErrorS(err, "Pod listing failed")
 
 		return pods
 	}
@@ -391,11 +402,16 @@ func (rm *resourceManager) CreateFractionalResourceResponse(request *pluginapi.A
 		return nil, &dpapi.UseDefaultMethodError{}
 	}
 
-	klog.V(4).Info("Proposed device ids: ", request.ContainerRequests[0].DevicesIDs)
+	// The resources of each driver are allocated by their own servers, but
+	// the pod candidates are the same for all.
+	rm.allocationMutex.Lock()
+	defer rm.allocationMutex.Unlock()
+
+	klog.V(4).InfoS("Proposed device IDs", dpapi.LogKeyDeviceID, request.ContainerRequests[0].DevicesIDs)
 
 	podCandidate, err := rm.findAllocationPodCandidate()
 	if errors.Is(err, &retryErr{}) {
-		klog.Warning("retrying POD resolving after sleeping")
+		klog.InfoS("Retrying pod resolving after sleeping")
 		time.Sleep(rm.retryTimeout)
 
 		podCandidate, err = rm.findAllocationPodCandidate()
@@ -403,7 +419,7 @@ func (rm *resourceManager) CreateFractionalResourceResponse(request *pluginapi.A
 
 	if err != nil {
 		if !errors.Is(err, &zeroPendingErr{}) {
-			klog.Error("allocation candidate not found, perhaps the GPU scheduler extender is not called, err:", err)
+			klog.ErrorS(err, "Allocation candidate not found, perhaps the GPU scheduler extender is not called")
 		}
 		// it is better to leave allocated gpu devices as is and return
 		return nil, &dpapi.UseDefaultMethodError{}
@@ -416,7 +432,7 @@ func (rm *resourceManager) CreateFractionalResourceResponse(request *pluginapi.A
 	assignment, found := rm.assignments[getPodKey(pod)]
 	if !found {
 		rm.cleanupMutex.Unlock()
-		klog.Error("couldn't find allocation info from assignments:", getPodKey(pod))
+		klog.ErrorS(nil, "Couldn't find allocation info from assignments", dpapi.LogKeyPod, klog.KObj(pod))
 
 		return nil, &dpapi.UseDefaultMethodError{}
 	}
@@ -432,17 +448,16 @@ func (rm *resourceManager) CreateFractionalResourceResponse(request *pluginapi.A
 
 	// Check if all the preferred devices were also used
 	if len(devIds) != len(getPrefDevices) {
-		klog.Warningf("Allocate called with odd number of device IDs: %d vs %d", len(devIds), len(getPrefDevices))
+		klog.InfoS("Allocate called with odd number of device IDs", dpapi.LogKeyPod, klog.KObj(pod), "allocated", len(devIds), "preferred", len(getPrefDevices))
 	}
 
 	for _, devID := range devIds {
 		if _, found := getPrefDevices[devID]; !found {
-			klog.Warningf("Not preferred device used in Allocate: %s (%v)", devID, getPrefDevices)
+			klog.InfoS("Not preferred device used in Allocate", dpapi.LogKeyPod, klog.KObj(pod), dpapi.LogKeyDeviceID, devID, "preferred", getPrefDevices)
 		}
 	}
 
-	klog.V(4).Info("Allocate affinity mask: ", affinityMask)
-	klog.V(4).Info("Allocate device ids: ", devIds)
+	klog.V(4).InfoS("Allocate", dpapi.LogKeyPod, klog.KObj(pod), "containerIndex", containerIndex, dpapi.LogKeyDeviceID, devIds, "affinityMask", affinityMask)
 
 	return rm.createAllocateResponse(devIds, affinityMask)
 }
@@ -454,11 +469,14 @@ func (rm *resourceManager) GetPreferredFractionalAllocation(request *pluginapi.P
 		return &pluginapi.PreferredAllocationResponse{}, nil
 	}
 
-	klog.V(4).Info("GetPreferredAllocation request: ", request)
+	rm.allocationMutex.Lock()
+	defer rm.allocationMutex.Unlock()
+
+	klog.V(4).InfoS("GetPreferredAllocation request", "request", request)
 
 	podCandidate, err := rm.findAllocationPodCandidate()
 	if errors.Is(err, &retryErr{}) {
-		klog.Warning("retrying POD resolving after sleeping")
+		klog.InfoS("Retrying pod resolving after sleeping")
 		time.Sleep(rm.retryTimeout)
 
 		podCandidate, err = rm.findAllocationPodCandidate()
@@ -466,7 +484,7 @@ func (rm *resourceManager) GetPreferredFractionalAllocation(request *pluginapi.P
 
 	if err != nil {
 		if !errors.Is(err, &zeroPendingErr{}) {
-			klog.Error("allocation candidate not found, perhaps the GPU scheduler extender is not called, err:", err)
+			klog.ErrorS(err, "Allocation candidate not found, perhaps the GPU scheduler extender is not called")
 		}
 
 		// Return empty response as returning an error causes
@@ -477,13 +495,13 @@ func (rm *resourceManager) GetPreferredFractionalAllocation(request *pluginapi.P
 	pod := podCandidate.pod
 	containerIndex := podCandidate.allocatedContainerCount
 	cards := containerCards(pod, containerIndex)
-	affinityMask := containerTileAffinityMask(pod, containerIndex, int(rm.tileCountPerCard))
+	affinityMask := containerTileAffinityMask(pod, containerIndex, int(rm.tileCountPerCard(cards)))
 	podKey := getPodKey(pod)
 
 	creq := request.ContainerRequests[0]
 
-	klog.V(4).Info("Get preferred fractional allocation: ",
-		podKey, creq.AllocationSize, creq.MustIncludeDeviceIDs, creq.AvailableDeviceIDs)
+	klog.V(4).InfoS("Get preferred fractional allocation", dpapi.LogKeyPod, podKey, "size", creq.AllocationSize,
+		"mustInclude", creq.MustIncludeDeviceIDs, "available", creq.AvailableDeviceIDs)
 
 	deviceIds := selectDeviceIDsForContainer(
 		int(creq.AllocationSize), cards, creq.AvailableDeviceIDs, creq.MustIncludeDeviceIDs)
@@ -509,7 +527,7 @@ func (rm *resourceManager) GetPreferredFractionalAllocation(request *pluginapi.P
 
 	rm.cleanupMutex.Unlock()
 
-	klog.V(4).Info("Selected devices for container: ", deviceIds)
+	klog.V(4).InfoS("Selected devices for container", dpapi.LogKeyDeviceID, deviceIds)
 
 	response := pluginapi.PreferredAllocationResponse{
 		ContainerResponses: []*pluginapi.ContainerPreferredAllocationResponse{
@@ -528,12 +546,12 @@ func selectDeviceIDsForContainer(requestedCount int, cards, deviceIds, mustHaveD
 	}
 
 	if requestedCount < len(cards) {
-		klog.Warningf("Requested count is less than card count: %d vs %d.", requestedCount, len(cards))
+		klog.InfoS("Requested count is less than card count", "requested", requestedCount, "cards", len(cards))
 		cards = cards[0:requestedCount]
 	}
 
 	if requestedCount > len(cards) {
-		klog.Warningf("Requested count is higher than card count: %d vs %d.", requestedCount, len(cards))
+		klog.InfoS("Requested count is higher than card count", "requested", requestedCount, "cards", len(cards))
 	}
 
 	// map of cardX -> device id list
@@ -559,7 +577,7 @@ func selectDeviceIDsForContainer(requestedCount int, cards, deviceIds, mustHaveD
 
 		availableDevices, found := available[card]
 		if !found {
-			klog.Warningf("card %s is not found from known devices: %v", card, available)
+			klog.InfoS("Card is not found from known devices", "card", card, "available", available)
 			continue
 		}
 
@@ -576,7 +594,7 @@ func selectDeviceIDsForContainer(requestedCount int, cards, deviceIds, mustHaveD
 func isAllocateRequestOk(rqt *pluginapi.AllocateRequest, skipID string) bool {
 	// so far kubelet calls allocate for each container separately. If that changes, we need to refine our logic.
 	if len(rqt.ContainerRequests) != 1 {
-		klog.Warning("multi-container allocation request not supported")
+		klog.InfoS("Multi-container allocation request not supported")
 		return false
 	}
 
@@ -593,7 +611,7 @@ func isAllocateRequestOk(rqt *pluginapi.AllocateRequest, skipID string) bool {
 func isPreferredAllocationRequestOk(rqt *pluginapi.PreferredAllocationRequest, skipID string) bool {
 	// so far kubelet calls allocate for each container separately. If that changes, we need to refine our logic.
 	if len(rqt.ContainerRequests) != 1 {
-		klog.Warning("multi-container allocation request not supported")
+		klog.InfoS("Multi-container allocation request not supported")
 		return false
 	}
 
@@ -635,14 +653,14 @@ func (rm *resourceManager) findAllocationPodCandidate() (*podCandidate, error) {
 	switch numCandidates {
 	case 0:
 		// fine, this typically happens when deployment is deleted before PODs start
-		klog.V(4).Info("zero pending pods")
+		klog.V(4).InfoS("Zero pending pods")
 		return nil, &zeroPendingErr{}
 	case 1:
 		// perfect, only one option
-		klog.V(4).Info("only one pending pod")
+		klog.V(4).InfoS("Only one pending pod")
 
 		if _, ok := candidates[0].pod.Annotations[gasCardAnnotation]; !ok {
-			klog.Warningf("Pending POD annotations from scheduler not yet visible for pod %q", candidates[0].pod.Name)
+			klog.InfoS("Pending pod annotations from scheduler not yet visible", dpapi.LogKeyPod, klog.KObj(candidates[0].pod))
 			return nil, &retryErr{}
 		}
 
@@ -650,7 +668,7 @@ func (rm *resourceManager) findAllocationPodCandidate() (*podCandidate, error) {
 
 	default: // > 1 candidates, not good, need to pick the best
 		// look for scheduler timestamps and sort by them
-		klog.V(4).Infof("%v pods pending, picking oldest", numCandidates)
+		klog.V(4).InfoS("Pods pending, picking oldest", "pending", numCandidates)
 
 		timestampedCandidates := []podCandidate{}
 
@@ -668,7 +686,7 @@ func (rm *resourceManager) findAllocationPodCandidate() (*podCandidate, error) {
 			})
 
 		if len(timestampedCandidates) == 0 {
-			klog.Warning("Pending POD annotations from scheduler not yet visible")
+			klog.InfoS("Pending pod annotations from scheduler not yet visible")
 			return nil, &retryErr{}
 		}
 
@@ -744,16 +762,65 @@ func (rm *resourceManager) findAllocationPodCandidates(pendingPods map[string]*v
 	return candidates, nil
 }
 
-func (rm *resourceManager) SetDevInfos(deviceInfos DeviceInfoMap) {
+// driver returns the state of a DRM driver. rm.mutex must be held.
+func (rm *resourceManager) driver(name string) *driverState {
+	if rm.drivers == nil {
+		rm.drivers = make(map[string]*driverState)
+	}
+
+	state, ok := rm.drivers[name]
+	if !ok {
+		state = &driverState{deviceInfos: NewDeviceInfoMap()}
+		rm.drivers[name] = state
+	}
+
+	return state
+}
+
+// SetDevInfos sets the devices of the GPUs using the driver.
+func (rm *resourceManager) SetDevInfos(driver string, deviceInfos DeviceInfoMap) {
 	rm.mutex.Lock()
 	defer rm.mutex.Unlock()
-	rm.deviceInfos = deviceInfos
+	rm.driver(driver).deviceInfos = deviceInfos
 }
 
-func (rm *resourceManager) SetTileCountPerCard(count uint64) {
+// SetTileCountPerCard sets the tile count of the GPUs using the driver.
+func (rm *resourceManager) SetTileCountPerCard(driver string, count uint64) {
 	rm.mutex.Lock()
 	defer rm.mutex.Unlock()
-	rm.tileCountPerCard = count
+	rm.driver(driver).tileCountPerCard = count
+}
+
+// tileCountPerCard returns the tile count of the driver of the cards, or 0
+// when the driver of the cards is not known.
+func (rm *resourceManager) tileCountPerCard(cards []string) uint64 {
+	rm.mutex.RLock()
+	defer rm.mutex.RUnlock()
+
+	if len(cards) == 0 {
+		return 0
+	}
+
+	for _, state := range rm.drivers {
+		for devID := range state.deviceInfos {
+			if strings.HasPrefix(devID, cards[0]+"-") {
+				return state.tileCountPerCard
+			}
+		}
+	}
+
+	return 0
+}
+
+// deviceInfo returns the device of the ID from the driver having it.
+func (rm *resourceManager) deviceInfo(devID string) (*DeviceInfo, bool) {
+	for _, state := range rm.drivers {
+		if dev, ok := state.deviceInfos[devID]; ok {
+			return dev, true
+		}
+	}
+
+	return nil, false
 }
 
 func (rm *resourceManager) createAllocateResponse(deviceIds []string, tileAffinityMask string) (*pluginapi.AllocateResponse, error) {
@@ -764,9 +831,9 @@ func (rm *resourceManager) createAllocateResponse(deviceIds []string, tileAffini
 	cresp := pluginapi.ContainerAllocateResponse{}
 
 	for _, devID := range deviceIds {
-		dev, ok := rm.deviceInfos[devID]
+		dev, ok := rm.deviceInfo(devID)
 		if !ok {
-			klog.Warningf("No device info for %q, using default allocation method devices", devID)
+			klog.InfoS("No device info, using default allocation method devices", dpapi.LogKeyDeviceID, devID)
 			return nil, &dpapi.UseDefaultMethodError{}
 		}
 
@@ -828,7 +895,7 @@ func numGPUUsingContainers(pod *v1.Pod, fullResourceNames []string) int {
 func containerCards(pod *v1.Pod, gpuUsingContainerIndex int) []string {
 	fullAnnotation := pod.Annotations[gasCardAnnotation]
 	cardLists := strings.Split(fullAnnotation, "|")
-	klog.V(3).Infof("%s:%v", fullAnnotation, cardLists)
+	klog.V(3).InfoS("Card annotation", "annotation", fullAnnotation, "cards", cardLists)
 
 	i := 0
 
@@ -836,7 +903,7 @@ func containerCards(pod *v1.Pod, gpuUsingContainerIndex int) []string {
 		cards := strings.Split(cardList, ",")
 		if len(cards) > 0 && len(cardList) > 0 {
 			if gpuUsingContainerIndex == i {
-				klog.V(3).Infof("Cards for container nr %v in pod %v are %v", gpuUsingContainerIndex, getPodKey(pod), cards)
+				klog.V(3).InfoS("Cards for container", dpapi.LogKeyPod, klog.KObj(pod), "containerIndex", gpuUsingContainerIndex, "cards", cards)
 				return cards
 			}
 
@@ -844,7 +911,7 @@ func containerCards(pod *v1.Pod, gpuUsingContainerIndex int) []string {
 		}
 	}
 
-	klog.Warningf("couldn't find cards for gpu using container index %v", gpuUsingContainerIndex)
+	klog.InfoS("Couldn't find cards for GPU using container", dpapi.LogKeyPod, klog.KObj(pod), "containerIndex", gpuUsingContainerIndex)
 
 	return nil
 }
@@ -852,22 +919,17 @@ func containerCards(pod *v1.Pod, gpuUsingContainerIndex int) []string {
 // Guesses level zero hierarchy mode for the container. Defaults to the new "flat" mode
 // if no mode is set in the container's env variables.
 func guessLevelzeroHierarchyMode(pod *v1.Pod, containerIndex int) string {
-	klog.V(4).Infof("Checking pod %s envs", pod.Name)
+	klog.V(4).InfoS("Checking pod envs", dpapi.LogKeyPod, klog.KObj(pod))
 
 	if containerIndex < len(pod.Spec.Containers) {
 		c := pod.Spec.Containers[containerIndex]
 
 		if c.Env != nil {
 			for _, env := range c.Env {
-				if env.Name == levelzeroHierarchyEnvVar {
-					switch env.Value {
+				if env.Name == LevelzeroHierarchyEnvVar {
 					// Check that the value is valid.
-					case hierarchyModeComposite:
-						fallthrough
-					case hierarchyModeFlat:
-						fallthrough
-					case hierarchyModeCombined:
-						klog.V(4).Infof("Returning %s hierarchy", env.Value)
+					if IsHierarchyMode(env.Value) {
+						klog.V(4).InfoS("Returning hierarchy", "hierarchy", env.Value)
 						return env.Value
 					}
 
@@ -877,11 +939,25 @@ func guessLevelzeroHierarchyMode(pod *v1.Pod, containerIndex int) string {
 		}
 	}
 
-	klog.V(4).Infof("Returning default %s hierarchy", hierarchyModeFlat)
+	klog.V(4).InfoS("Returning default hierarchy", "hierarchy", hierarchyModeFlat)
 
 	return hierarchyModeFlat
 }
 
+// IsHierarchyMode tells whether the mode is a valid Level-Zero hierarchy mode
+// for ZE_FLAT_DEVICE_HIERARCHY: FLAT, COMPOSITE or COMBINED.
+func IsHierarchyMode(mode string) bool {
+	return mode == hierarchyModeFlat || mode == hierarchyModeComposite || mode == hierarchyModeCombined
+}
+
+// TileAffinityMask returns the ZE_AFFINITY_MASK of the tiles of a container
+// in the given hierarchy mode. The tiles are listed per card in the order the
+// container sees the cards, e.g. "card0:gt0+gt1,card1:gt0". An empty mask is
+// returned for invalid tiles.
+func TileAffinityMask(tileInfo string, tilesPerCard int, hierarchyMode string) string {
+	return convertTileInfoToEnvMask(tileInfo, tilesPerCard, hierarchyMode)
+}
+
 func convertTileInfoToEnvMask(tileInfo string, tilesPerCard int, hierarchyMode string) string {
 	cards := strings.Split(tileInfo, ",")
 
@@ -890,7 +966,7 @@ func convertTileInfoToEnvMask(tileInfo string, tilesPerCard int, hierarchyMode s
 	for i, cardTileCombos := range cards {
 		cardTileSplit := strings.Split(cardTileCombos, ":")
 		if len(cardTileSplit) != 2 {
-			klog.Warningf("invalid card tile combo string (%v)", cardTileCombos)
+			klog.InfoS("Invalid card tile combo string", "combo", cardTileCombos)
 			return ""
 		}
 
@@ -900,7 +976,7 @@ func convertTileInfoToEnvMask(tileInfo string, tilesPerCard int, hierarchyMode s
 
 		for _, tile := range tiles {
 			if !strings.HasPrefix(tile, "gt") {
-				klog.Warningf("invalid tile syntax (%v)", tile)
+				klog.InfoS("Invalid tile syntax", "tile", tile)
 				return ""
 			}
 
@@ -908,7 +984,7 @@ func convertTileInfoToEnvMask(tileInfo string, tilesPerCard int, hierarchyMode s
 			tileNo, err := strconv.ParseInt(tileNoStr, 10, 16)
 
 			if err != nil {
-				klog.Warningf("invalid tile syntax (%v)", tile)
+				klog.InfoS("Invalid tile syntax", "tile", tile)
 				return ""
 			}
 
@@ -949,7 +1025,7 @@ func containerTileAffinityMask(pod *v1.Pod, gpuUsingContainerIndex, tilesPerCard
 	}
 
 	tileLists := strings.Split(fullAnnotation, "|")
-	klog.Infof("%s:%v", fullAnnotation, tileLists)
+	klog.InfoS("Tile annotation", "annotation", fullAnnotation, "tiles", tileLists)
 
 	i := 0
 
@@ -965,7 +1041,7 @@ func containerTileAffinityMask(pod *v1.Pod, gpuUsingContainerIndex, tilesPerCard
 		i++
 	}
 
-	klog.Warningf("couldn't find tile info for gpu using container index %v", gpuUsingContainerIndex)
+	klog.InfoS("Couldn't find tile info for GPU using container", dpapi.LogKeyPod, klog.KObj(pod), "containerIndex", gpuUsingContainerIndex)
 
 	return ""
 }
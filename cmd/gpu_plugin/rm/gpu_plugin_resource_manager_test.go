@@ -126,7 +126,7 @@ func newMockResourceManager(pods []v1.Pod) ResourceManager {
 		map[string]string{"more": "coverage"})
 	deviceInfoMap["card1-0"] = NewDeviceInfo([]v1beta1.DeviceSpec{{}}, nil, nil)
 	deviceInfoMap["card2-0"] = NewDeviceInfo([]v1beta1.DeviceSpec{{}}, nil, nil)
-	rm.SetDevInfos(deviceInfoMap)
+	rm.SetDevInfos("i915", deviceInfoMap)
 
 	return &rm
 }
@@ -419,7 +419,7 @@ func TestCreateFractionalResourceResponse(t *testing.T) {
 
 	for _, tCase := range testCases {
 		rm := newMockResourceManager(tCase.pods)
-		rm.SetTileCountPerCard(uint64(1))
+		rm.SetTileCountPerCard("i915", uint64(1))
 
 		_, perr := rm.GetPreferredFractionalAllocation(&v1beta1.PreferredAllocationRequest{
 			ContainerRequests: tCase.prefContainerRequests,
@@ -472,7 +472,7 @@ func TestCreateFractionalResourceResponseWithOneCardTwoTiles(t *testing.T) {
 					},
 					Env: []v1.EnvVar{
 						{
-							Name:  levelzeroHierarchyEnvVar,
+							Name:  LevelzeroHierarchyEnvVar,
 							Value: hierarchyModeComposite,
 						},
 					},
@@ -501,7 +501,7 @@ func TestCreateFractionalResourceResponseWithOneCardTwoTiles(t *testing.T) {
 	}
 
 	rm := newMockResourceManager(tCase.pods)
-	rm.SetTileCountPerCard(uint64(2))
+	rm.SetTileCountPerCard("i915", uint64(2))
 
 	_, perr := rm.GetPreferredFractionalAllocation(&v1beta1.PreferredAllocationRequest{
 		ContainerRequests: tCase.prefContainerRequests,
@@ -545,7 +545,7 @@ func TestCreateFractionalResourceResponseWithTwoCardsOneTile(t *testing.T) {
 					},
 					Env: []v1.EnvVar{
 						{
-							Name:  levelzeroHierarchyEnvVar,
+							Name:  LevelzeroHierarchyEnvVar,
 							Value: hierarchyModeComposite,
 						},
 					},
@@ -574,7 +574,7 @@ func TestCreateFractionalResourceResponseWithTwoCardsOneTile(t *testing.T) {
 	}
 
 	rm := newMockResourceManager(tCase.pods)
-	rm.SetTileCountPerCard(uint64(5))
+	rm.SetTileCountPerCard("i915", uint64(5))
 
 	_, perr := rm.GetPreferredFractionalAllocation(&v1beta1.PreferredAllocationRequest{
 		ContainerRequests: tCase.prefContainerRequests,
@@ -623,7 +623,7 @@ func TestCreateFractionalResourceResponseWithThreeCardsTwoTiles(t *testing.T) {
 					},
 					Env: []v1.EnvVar{
 						{
-							Name:  levelzeroHierarchyEnvVar,
+							Name:  LevelzeroHierarchyEnvVar,
 							Value: hierarchyModeComposite,
 						},
 					},
@@ -652,7 +652,7 @@ func TestCreateFractionalResourceResponseWithThreeCardsTwoTiles(t *testing.T) {
 	}
 
 	rm := newMockResourceManager(tCase.pods)
-	rm.SetTileCountPerCard(uint64(5))
+	rm.SetTileCountPerCard("i915", uint64(5))
 
 	_, perr := rm.GetPreferredFractionalAllocation(&v1beta1.PreferredAllocationRequest{
 		ContainerRequests: tCase.prefContainerRequests,
@@ -701,7 +701,7 @@ func TestCreateFractionalResourceResponseWithMultipleContainersTileEach(t *testi
 					},
 					Env: []v1.EnvVar{
 						{
-							Name:  levelzeroHierarchyEnvVar,
+							Name:  LevelzeroHierarchyEnvVar,
 							Value: hierarchyModeComposite,
 						},
 					},
@@ -714,7 +714,7 @@ func TestCreateFractionalResourceResponseWithMultipleContainersTileEach(t *testi
 					},
 					Env: []v1.EnvVar{
 						{
-							Name:  levelzeroHierarchyEnvVar,
+							Name:  LevelzeroHierarchyEnvVar,
 							Value: hierarchyModeComposite,
 						},
 					},
@@ -747,7 +747,7 @@ func TestCreateFractionalResourceResponseWithMultipleContainersTileEach(t *testi
 	}
 
 	rm := newMockResourceManager(tCase.pods)
-	rm.SetTileCountPerCard(uint64(2))
+	rm.SetTileCountPerCard("i915", uint64(2))
 
 	_, perr := rm.GetPreferredFractionalAllocation(&v1beta1.PreferredAllocationRequest{
 		ContainerRequests: properPrefContainerRequests,
@@ -945,7 +945,7 @@ func TestTileAnnotationParsing(t *testing.T) {
 				if i < len(pt.hierarchys) {
 					pod.Spec.Containers[i].Env = []v1.EnvVar{
 						{
-							Name:  levelzeroHierarchyEnvVar,
+							Name:  LevelzeroHierarchyEnvVar,
 							Value: pt.hierarchys[i],
 						},
 					}
@@ -1018,6 +1018,31 @@ func TestSelectDeviceIDsForContainerSingleCard(t *testing.T) {
 	}
 }
 
+func TestMixedDrivers(t *testing.T) {
+	rm, _ := newMockResourceManager(nil).(*resourceManager)
+	rm.SetTileCountPerCard("i915", 1)
+
+	xeInfos := NewDeviceInfoMap()
+	xeInfos["card3-0"] = NewDeviceInfo([]v1beta1.DeviceSpec{{HostPath: "/dev/dri/card3"}}, nil, nil)
+	rm.SetDevInfos("xe", xeInfos)
+	rm.SetTileCountPerCard("xe", 2)
+
+	for card, expected := range map[string]uint64{"card0": 1, "card3": 2, "card9": 0} {
+		if count := rm.tileCountPerCard([]string{card}); count != expected {
+			t.Errorf("%s: expected %d tiles, got %d", card, expected, count)
+		}
+	}
+
+	resp, err := rm.createAllocateResponse([]string{"card1-0", "card3-0"}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if devices := resp.ContainerResponses[0].Devices; len(devices) != 2 || devices[1].HostPath != "/dev/dri/card3" {
+		t.Errorf("unexpected devices: %v", devices)
+	}
+}
+
 func expectTruef(predicate bool, t *testing.T, testName, format string, args ...interface{}) {
 	if !predicate {
 		t.Helper()
@@ -0,0 +1,377 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/pluginutils"
+	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+)
+
+// vfSuffix is appended to the device type of the VFs when the SR-IOV profile
+// is set, e.g. "i915-vf".
+const vfSuffix = "-vf"
+
+var (
+	quotaNameRE   = regexp.MustCompile(`^[a-z0-9_]+$`)
+	pciDeviceIDRE = regexp.MustCompile(`^0x[0-9a-f]{4}$`)
+)
+
+// sriovProfile is the "sriov" setting of the config file. It declares the
+// SR-IOV VFs of the node's GPUs, which the plugin then creates.
+type sriovProfile struct {
+	// Quotas are written to the attributes of the same name of each VF,
+	// e.g. "lmem_quota", in the iov/vfN/ and iov/vfN/gt*/ directories of
+	// the PF. The quotas are skipped when the driver has no iov/ directory.
+	Quotas map[string]uint64 `json:"quotas,omitempty"`
+	// DeviceIDs are the PCI device IDs of the PFs to provision, e.g.
+	// "0x56c0". All the PFs supporting SR-IOV are provisioned when empty.
+	DeviceIDs []string `json:"deviceIDs,omitempty"`
+	// NumVFs is the number of VFs of each PF. Zero removes the VFs.
+	NumVFs int `json:"numVFs"`
+}
+
+func (p *sriovProfile) validate() error {
+	if p.NumVFs < 0 {
+		return errors.Errorf("invalid number of VFs %d", p.NumVFs)
+	}
+
+	for name := range p.Quotas {
+		if !quotaNameRE.MatchString(name) {
+			return errors.Errorf("invalid VF quota name %q", name)
+		}
+	}
+
+	for _, id := range p.DeviceIDs {
+		if !pciDeviceIDRE.MatchString(id) {
+			return errors.Errorf("invalid PCI device ID %q, expected e.g. 0x56c0", id)
+		}
+	}
+
+	return nil
+}
+
+// SetAllocationJournal implements the AllocationQuerier interface. The
+// journal tells whether the VFs can be changed.
+func (dp *devicePlugin) SetAllocationJournal(journal dpapi.AllocationJournal) {
+	dp.mutex.Lock()
+
+	dp.journal = journal
+
+	dp.mutex.Unlock()
+}
+
+func readSysfsInt(filePath string) (int, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+
+	value, err := strconv.Atoi(strings.TrimSpace(string(data)))
+
+	return value, errors.Wrapf(err, "invalid value in %s", filePath)
+}
+
+func writeSysfs(filePath, value string) error {
+	return errors.WithStack(os.WriteFile(filePath, []byte(value), 0600))
+}
+
+// sriovPFs returns the sysfs directories of the PFs supporting SR-IOV which
+// the profile selects.
+func (dp *devicePlugin) sriovPFs(profile *sriovProfile) ([]string, error) {
+	files, err := os.ReadDir(dp.sysfsDir)
+	if err != nil {
+		return nil, errors.Wrap(err, "Can't read sysfs folder")
+	}
+
+	pfs := []string{}
+
+	for _, f := range dp.filterOutInvalidCards(files) {
+		cardPath := filepath.Join(dp.sysfsDir, f.Name())
+
+		if pluginutils.IsSriovVF(cardPath) {
+			continue
+		}
+
+		if _, err := os.Stat(filepath.Join(cardPath, "device/sriov_totalvfs")); err != nil {
+			continue
+		}
+
+		if len(profile.DeviceIDs) > 0 {
+			if id, err := pciDeviceIDForCard(cardPath); err != nil || !slices.Contains(profile.DeviceIDs, id) {
+				continue
+			}
+		}
+
+		pfs = append(pfs, cardPath)
+	}
+
+	return pfs, nil
+}
+
+// vfCards returns the names of the VF cards of a PF.
+func (dp *devicePlugin) vfCards(pfPath string) []string {
+	pfDevice, err := filepath.EvalSymlinks(filepath.Join(pfPath, "device"))
+	if err != nil {
+		return nil
+	}
+
+	files, err := os.ReadDir(dp.sysfsDir)
+	if err != nil {
+		return nil
+	}
+
+	cards := []string{}
+
+	for _, f := range dp.filterOutInvalidCards(files) {
+		physfn, err := filepath.EvalSymlinks(filepath.Join(dp.sysfsDir, f.Name(), "device/physfn"))
+		if err == nil && physfn == pfDevice {
+			cards = append(cards, f.Name())
+		}
+	}
+
+	return cards
+}
+
+// allocatedCards returns the cards of a PF and of its VFs allocated to
+// containers as any of the advertised device types.
+func (dp *devicePlugin) allocatedCards(pfPath string, journal dpapi.AllocationJournal) []string {
+	cards := append(dp.vfCards(pfPath), filepath.Base(pfPath))
+	allocated := []string{}
+
+	for _, devType := range advertisedDeviceTypes {
+		for _, allocation := range journal.Allocations(namespace + "/" + devType) {
+			for _, id := range allocation.DeviceIDs {
+				card, _, _ := strings.Cut(id, "-")
+				if slices.Contains(cards, card) && !slices.Contains(allocated, card) {
+					allocated = append(allocated, card)
+				}
+			}
+		}
+	}
+
+	sort.Strings(allocated)
+
+	return allocated
+}
+
+// checkUnallocated returns an error when a PF or its VFs may be allocated,
+// e.g. the PF itself before its VFs are created. Without the allocation
+// journal, that can't be told.
+func (dp *devicePlugin) checkUnallocated(pfPath string, journal dpapi.AllocationJournal) error {
+	if journal == nil {
+		return errors.Errorf("refusing to change the VFs of %s, their allocations are not known without the allocation journal", filepath.Base(pfPath))
+	}
+
+	if allocated := dp.allocatedCards(pfPath, journal); len(allocated) > 0 {
+		return errors.Errorf("refusing to change the VFs of %s, cards %v are allocated", filepath.Base(pfPath), allocated)
+	}
+
+	return nil
+}
+
+// vfQuotaFiles returns the files of a quota of a VF. Zero files are returned
+// when the driver has no such quota.
+func vfQuotaFiles(pfPath string, vf int, name string) []string {
+	vfDir := filepath.Join(pfPath, "iov", fmt.Sprintf("vf%d", vf))
+
+	files, _ := filepath.Glob(filepath.Join(vfDir, name))
+	gtFiles, _ := filepath.Glob(filepath.Join(vfDir, "gt[0-9]*", name))
+
+	return append(files, gtFiles...)
+}
+
+func hasIovDir(pfPath string) bool {
+	_, err := os.Stat(filepath.Join(pfPath, "iov"))
+
+	return err == nil
+}
+
+// vfQuotasMatch tells whether the quotas of the VFs read back from sysfs are
+// the ones of the profile.
+func vfQuotasMatch(pfPath string, profile *sriovProfile) bool {
+	if !hasIovDir(pfPath) {
+		return true
+	}
+
+	for vf := 1; vf <= profile.NumVFs; vf++ {
+		for name, value := range profile.Quotas {
+			for _, file := range vfQuotaFiles(pfPath, vf, name) {
+				if current, err := readSysfsInt(file); err != nil || uint64(current) != value {
+					return false
+				}
+			}
+		}
+	}
+
+	return true
+}
+
+// writeVFQuotas writes the quotas of the profile to the VFs of a PF, before
+// the VFs are enabled.
+func writeVFQuotas(pfPath string, profile *sriovProfile) error {
+	if len(profile.Quotas) == 0 {
+		return nil
+	}
+
+	if !hasIovDir(pfPath) {
+		klog.InfoS("Driver has no iov interface, VF quotas are not set", "card", filepath.Base(pfPath))
+
+		return nil
+	}
+
+	for vf := 1; vf <= profile.NumVFs; vf++ {
+		for name, value := range profile.Quotas {
+			files := vfQuotaFiles(pfPath, vf, name)
+			if len(files) == 0 {
+				return errors.Errorf("VF quota %s is not supported by %s", name, filepath.Base(pfPath))
+			}
+
+			for _, file := range files {
+				if err := writeSysfs(file, strconv.FormatUint(value, 10)); err != nil {
+					return errors.Wrapf(err, "failed to set VF quota %s", name)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// needsProvisioning returns the current number of VFs of a PF, and whether
+// the VFs differ from the profile.
+func needsProvisioning(pfPath string, profile *sriovProfile) (int, bool, error) {
+	current, err := readSysfsInt(filepath.Join(pfPath, "device/sriov_numvfs"))
+	if err != nil {
+		return 0, false, err
+	}
+
+	return current, current != profile.NumVFs || !vfQuotasMatch(pfPath, profile), nil
+}
+
+// provisionPF makes the VFs of a PF match the profile. The existing VFs are
+// removed first, and only when neither the PF nor its VFs are allocated.
+func (dp *devicePlugin) provisionPF(pfPath string, profile *sriovProfile, journal dpapi.AllocationJournal) error {
+	name := filepath.Base(pfPath)
+
+	total, err := readSysfsInt(filepath.Join(pfPath, "device/sriov_totalvfs"))
+	if err != nil {
+		return err
+	}
+
+	if profile.NumVFs > total {
+		return errors.Errorf("%s supports only %d VFs", name, total)
+	}
+
+	current, changed, err := needsProvisioning(pfPath, profile)
+	if err != nil || !changed {
+		return err
+	}
+
+	if err := dp.checkUnallocated(pfPath, journal); err != nil {
+		return err
+	}
+
+	numVFsPath := filepath.Join(pfPath, "device/sriov_numvfs")
+
+	if current > 0 {
+		klog.InfoS("Removing VFs", "card", name, "numVFs", current)
+
+		if err := writeSysfs(numVFsPath, "0"); err != nil {
+			return errors.Wrapf(err, "failed to remove the VFs of %s", name)
+		}
+	}
+
+	if profile.NumVFs == 0 {
+		return nil
+	}
+
+	if err := writeVFQuotas(pfPath, profile); err != nil {
+		return err
+	}
+
+	klog.InfoS("Creating VFs", "card", name, "numVFs", profile.NumVFs, "quotas", profile.Quotas)
+
+	return errors.Wrapf(writeSysfs(numVFsPath, strconv.Itoa(profile.NumVFs)), "failed to create the VFs of %s", name)
+}
+
+// provisionVFs provisions the PFs the SR-IOV profile selects. It's called
+// before each scan, and the failed PFs are retried on the next one.
+func (dp *devicePlugin) provisionVFs() {
+	dp.mutex.RLock()
+	profile, journal := dp.options.sriov, dp.journal
+	dp.mutex.RUnlock()
+
+	if profile == nil {
+		return
+	}
+
+	pfs, err := dp.sriovPFs(profile)
+	if err != nil {
+		klog.ErrorS(err, "Failed to find SR-IOV PFs")
+
+		return
+	}
+
+	for _, pfPath := range pfs {
+		if err := dp.provisionPF(pfPath, profile, journal); err != nil {
+			klog.ErrorS(err, "Failed to provision VFs", "card", filepath.Base(pfPath))
+		}
+	}
+}
+
+// checkSriovProfile returns an error when a new profile would change the VFs
+// of an allocated PF or allocated VFs. Until the allocation journal is set, the changes are left
+// for provisionVFs() to check.
+func (dp *devicePlugin) checkSriovProfile(profile *sriovProfile) error {
+	dp.mutex.RLock()
+	journal := dp.journal
+	dp.mutex.RUnlock()
+
+	if profile == nil || journal == nil {
+		return nil
+	}
+
+	pfs, err := dp.sriovPFs(profile)
+	if err != nil {
+		return err
+	}
+
+	for _, pfPath := range pfs {
+		_, changed, err := needsProvisioning(pfPath, profile)
+		if err != nil {
+			return err
+		}
+
+		if changed {
+			if err := dp.checkUnallocated(pfPath, journal); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
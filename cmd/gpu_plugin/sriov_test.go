@@ -0,0 +1,195 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+)
+
+type fakeJournal map[string][]dpapi.Allocation
+
+func (j fakeJournal) Allocations(resourceName string) []dpapi.Allocation {
+	return j[resourceName]
+}
+
+func TestSriovProfileValidate(t *testing.T) {
+	for name, profile := range map[string]sriovProfile{
+		"negative VFs":    {NumVFs: -1},
+		"quota path":      {NumVFs: 1, Quotas: map[string]uint64{"../sriov_numvfs": 1}},
+		"short device ID": {NumVFs: 1, DeviceIDs: []string{"56c0"}},
+	} {
+		if err := profile.validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	profile := sriovProfile{NumVFs: 2, Quotas: map[string]uint64{"lmem_quota": 1 << 30}, DeviceIDs: []string{"0x56c0"}}
+	if err := profile.validate(); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+}
+
+func readTestFile(t *testing.T, filePath string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	return strings.TrimSpace(string(data))
+}
+
+func TestProvisionVFs(t *testing.T) {
+	sysfs, devfs, err := createTestFiles(t.TempDir(), TestCaseDetails{
+		sysfsdirs: []string{
+			"card0/device/drm/card0", "card0/iov/vf1/gt0", "card0/iov/vf2/gt0",
+			"card1/device/drm/card1",
+		},
+		sysfsfiles: map[string][]byte{
+			"card0/device/vendor":          []byte("0x8086"),
+			"card0/device/device":          []byte("0x56c0"),
+			"card0/device/sriov_totalvfs":  []byte("2"),
+			"card0/device/sriov_numvfs":    []byte("0"),
+			"card0/iov/vf1/gt0/lmem_quota": []byte("0"),
+			"card0/iov/vf2/gt0/lmem_quota": []byte("0"),
+			"card1/device/vendor":          []byte("0x8086"),
+		},
+		symlinkfiles: map[string]string{
+			"card1/device/physfn": "card0/device",
+		},
+		devfsdirs: []string{"card0", "card1"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	numVFsPath := filepath.Join(sysfs, "card0/device/sriov_numvfs")
+
+	plugin := newDevicePlugin(sysfs, devfs, cliOptions{sharedDevNum: 1, preferredAllocationPolicy: "none"})
+	plugin.options.sriov = &sriovProfile{NumVFs: 2, Quotas: map[string]uint64{"lmem_quota": 1024}}
+
+	// The VFs are not created when the allocations of the PF are not known.
+	plugin.provisionVFs()
+
+	if numVFs := readTestFile(t, numVFsPath); numVFs != "0" {
+		t.Errorf("VFs of a PF with unknown allocations were created: %s", numVFs)
+	}
+
+	// Nor when the PF is allocated, which also rejects the config.
+	plugin.SetAllocationJournal(fakeJournal{
+		namespace + "/" + deviceTypeI915: {{DeviceIDs: []string{"card0-0"}}},
+	})
+
+	plugin.provisionVFs()
+
+	if numVFs := readTestFile(t, numVFsPath); numVFs != "0" {
+		t.Errorf("VFs of an allocated PF were created: %s", numVFs)
+	}
+
+	if err := plugin.ApplyConfig([]byte(`{"sriov": {"numVFs": 2}}`)); err == nil {
+		t.Error("expected an error for creating the VFs of an allocated PF")
+	}
+
+	plugin.SetAllocationJournal(fakeJournal{})
+
+	plugin.provisionVFs()
+
+	if numVFs := readTestFile(t, numVFsPath); numVFs != "2" {
+		t.Errorf("expected 2 VFs, got %s", numVFs)
+	}
+
+	for _, vf := range []string{"vf1", "vf2"} {
+		if quota := readTestFile(t, filepath.Join(sysfs, "card0/iov", vf, "gt0/lmem_quota")); quota != "1024" {
+			t.Errorf("unexpected quota of %s: %s", vf, quota)
+		}
+	}
+
+	tree, err := plugin.scan()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if _, ok := tree[deviceTypeI915+vfSuffix]["card1-0"]; !ok || len(tree[deviceTypeI915]) != 0 {
+		t.Errorf("expected the VF as %s and no PF: %v", deviceTypeI915+vfSuffix, tree)
+	}
+
+	// The VFs are not changed when their allocations are not known.
+	plugin.SetAllocationJournal(nil)
+
+	plugin.options.sriov = &sriovProfile{NumVFs: 1}
+
+	plugin.provisionVFs()
+
+	if numVFs := readTestFile(t, numVFsPath); numVFs != "2" {
+		t.Errorf("VFs with unknown allocations were changed to %s", numVFs)
+	}
+
+	// Nor when they are allocated, which also rejects the config.
+	plugin.SetAllocationJournal(fakeJournal{
+		namespace + "/" + deviceTypeI915 + vfSuffix: {{DeviceIDs: []string{"card1-0"}}},
+	})
+
+	plugin.provisionVFs()
+
+	if numVFs := readTestFile(t, numVFsPath); numVFs != "2" {
+		t.Errorf("allocated VFs were changed to %s", numVFs)
+	}
+
+	// Whatever the resource of the allocation.
+	plugin.SetAllocationJournal(fakeJournal{
+		namespace + "/" + deviceTypeXe + tileSuffix: {{DeviceIDs: []string{"card1-tile0"}}},
+	})
+
+	plugin.provisionVFs()
+
+	if numVFs := readTestFile(t, numVFsPath); numVFs != "2" {
+		t.Errorf("VFs with allocated tiles were changed to %s", numVFs)
+	}
+
+	plugin.SetAllocationJournal(fakeJournal{
+		namespace + "/" + deviceTypeI915 + vfSuffix: {{DeviceIDs: []string{"card1-0"}}},
+	})
+
+	if err := plugin.ApplyConfig([]byte(`{"sriov": {"numVFs": 1}}`)); err == nil {
+		t.Error("expected an error for changing allocated VFs")
+	}
+
+	plugin.SetAllocationJournal(fakeJournal{})
+
+	if err := plugin.ApplyConfig([]byte(`{"sriov": {"numVFs": 1}}`)); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+
+	plugin.provisionVFs()
+
+	if numVFs := readTestFile(t, numVFsPath); numVFs != "1" {
+		t.Errorf("expected 1 VF, got %s", numVFs)
+	}
+
+	// A profile for other GPUs leaves the VFs as they are.
+	plugin.options.sriov = &sriovProfile{NumVFs: 2, DeviceIDs: []string{"0x56c1"}}
+
+	plugin.provisionVFs()
+
+	if numVFs := readTestFile(t, numVFsPath); numVFs != "1" {
+		t.Errorf("VFs of other GPUs were changed to %s", numVFs)
+	}
+}
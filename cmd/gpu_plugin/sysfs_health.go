@@ -0,0 +1,120 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/pluginutils"
+	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+)
+
+const (
+	// fatalErrorPrefix is the name prefix of the fatal error counters.
+	fatalErrorPrefix = "fatal"
+	// maxListedErrors is the maximum number of error counters listed in
+	// the reason of a health result.
+	maxListedErrors = 3
+)
+
+// errorCounterGlobs are the error counter files of the DRM drivers relative to
+// the card directory: i915 has them per GT, and xe per GT of each tile.
+var errorCounterGlobs = map[string]string{
+	deviceTypeI915: "gt/gt[0-9]*/error/*",
+	deviceTypeXe:   "device/tile[0-9]*/gt[0-9]*/error/*",
+}
+
+// sysfsHealthResult returns the health of a card from the error counters of
+// its driver in sysfs. The card is unhealthy when a fatal error has been
+// counted. It returns false when the driver has no error counters.
+func sysfsHealthResult(cardPath string) (dpapi.HealthResult, bool) {
+	driver, err := pluginutils.ReadDeviceDriver(cardPath)
+	if err != nil {
+		driver = deviceTypeDefault
+	}
+
+	pattern, ok := errorCounterGlobs[driver]
+	if !ok {
+		return dpapi.HealthResult{}, false
+	}
+
+	files, _ := filepath.Glob(filepath.Join(cardPath, pattern))
+	if len(files) == 0 {
+		return dpapi.HealthResult{}, false
+	}
+
+	result := dpapi.HealthResult{
+		Timestamp: time.Now(),
+		State:     pluginapi.Healthy,
+		Source:    "sysfs",
+	}
+
+	fatal := []string{}
+	fatalCount, nonFatalCount := 0.0, 0.0
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+
+		count, err := strconv.ParseUint(strings.TrimSpace(string(data)), 10, 64)
+		if err != nil || count == 0 {
+			continue
+		}
+
+		if !strings.HasPrefix(filepath.Base(file), fatalErrorPrefix) {
+			nonFatalCount += float64(count)
+
+			continue
+		}
+
+		// e.g. "tile0/gt0/fatal_guc" of "device/tile0/gt0/error/fatal_guc"
+		rel, _ := filepath.Rel(cardPath, file)
+		name := strings.Replace(strings.TrimPrefix(rel, "device/"), "/error/", "/", 1)
+
+		fatal = append(fatal, fmt.Sprintf("%s=%d", name, count))
+		fatalCount += float64(count)
+	}
+
+	result.Metrics = map[string]float64{
+		"fatalErrors":    fatalCount,
+		"nonFatalErrors": nonFatalCount,
+	}
+
+	if len(fatal) > 0 {
+		sort.Strings(fatal)
+
+		if len(fatal) > maxListedErrors {
+			fatal = append(fatal[:maxListedErrors:maxListedErrors], "...")
+		}
+
+		klog.V(4).InfoS("Fatal errors counted", "card", filepath.Base(cardPath), "driver", driver, "errors", fatal)
+
+		result.State = pluginapi.Unhealthy
+		result.Reason = "fatal errors: " + strings.Join(fatal, ", ")
+	}
+
+	return result, true
+}
@@ -0,0 +1,187 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/gpu_plugin/rm"
+	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/labeler"
+	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+const (
+	// tileSuffix is appended to the device type of the tiles, see
+	// -tile-resources, e.g. "i915-tile".
+	tileSuffix = "-tile"
+	// tileIDPrefix separates the card and the tile index in the tile IDs,
+	// e.g. "card0-tile1".
+	tileIDPrefix = "-tile"
+)
+
+// tilePolicy keeps the tiles of a container on one GPU when they fit, and
+// the free tiles of the GPUs unfragmented.
+var tilePolicy = dpapi.NewAllocationPolicy(dpapi.Pack(dpapi.ParentKey("-")), dpapi.ByID)
+
+// tileTopology returns the topology hints of the tiles of a GPU. The tiles
+// are behind the PCI device of the GPU, so their NUMA node is the one of the
+// device.
+func tileTopology(sysfsDir, card string) *pluginapi.TopologyInfo {
+	node := labeler.GetNumaNode(sysfsDir, card)
+	if node < 0 {
+		return nil
+	}
+
+	return &pluginapi.TopologyInfo{Nodes: []*pluginapi.NUMANode{{ID: int64(node)}}}
+}
+
+// addTiles adds the tiles of a GPU to the device tree as devices of their
+// own, and returns the devices given to the containers allocated the tiles.
+func (dp *devicePlugin) addTiles(devTree dpapi.DeviceTree, devType, name string, devSpecs []pluginapi.DeviceSpec,
+	mounts []pluginapi.Mount, cdiSpec *cdispec.Spec, attributes map[string]string) cardDevices {
+	cardPath := path.Join(dp.sysfsDir, name)
+	tiles := int(labeler.GetTileCount(cardPath))
+	topology := tileTopology(dp.sysfsDir, name)
+
+	for i := 0; i < tiles; i++ {
+		deviceInfo := dpapi.NewDeviceInfoWithTopologyHints(pluginapi.Healthy, devSpecs, mounts, nil, nil, topology, cdiSpec)
+		deviceInfo.SetSysfsDevice(path.Join(cardPath, "device"))
+		deviceInfo.SetAttributes(attributes)
+
+		devTree.AddDevice(devType, fmt.Sprintf("%s%s%d", name, tileIDPrefix, i), deviceInfo)
+	}
+
+	return cardDevices{nodes: devSpecs, mounts: mounts, tiles: tiles}
+}
+
+// isTileRequest tells whether the device IDs are tiles.
+func (dp *devicePlugin) isTileRequest(ids []string) bool {
+	return dp.currentOptions().tileResources && len(ids) > 0 && strings.Contains(ids[0], tileIDPrefix)
+}
+
+// cardNumber returns the number of a card name, e.g. 1 of "card1".
+func cardNumber(name string) int {
+	number, err := strconv.Atoi(strings.TrimPrefix(name, "card"))
+	if err != nil {
+		return -1
+	}
+
+	return number
+}
+
+// containerTiles returns the cards of the tile IDs of a container in the
+// order of their numbers, which is the order the container sees the GPUs in,
+// and the tiles in the format of TileAffinityMask, e.g. "card0:gt0+gt1".
+func containerTiles(ids []string) ([]string, string, error) {
+	tiles := make(map[string][]int)
+	names := []string{}
+
+	for _, id := range ids {
+		name, tile, ok := strings.Cut(id, tileIDPrefix)
+
+		index, err := strconv.Atoi(tile)
+		if !ok || err != nil {
+			return nil, "", errors.Errorf("invalid tile ID %s", id)
+		}
+
+		if _, ok := tiles[name]; !ok {
+			names = append(names, name)
+		}
+
+		tiles[name] = append(tiles[name], index)
+	}
+
+	slices.SortFunc(names, func(a, b string) int { return cardNumber(a) - cardNumber(b) })
+
+	cardTiles := make([]string, len(names))
+
+	for i, name := range names {
+		slices.Sort(tiles[name])
+
+		gts := make([]string, len(tiles[name]))
+		for j, index := range tiles[name] {
+			gts[j] = "gt" + strconv.Itoa(index)
+		}
+
+		cardTiles[i] = name + ":" + strings.Join(gts, "+")
+	}
+
+	return names, strings.Join(cardTiles, ","), nil
+}
+
+// allocateTiles gives the containers the devices of the GPUs of their tiles,
+// and limits them to the tiles with ZE_AFFINITY_MASK. The mask is for the
+// hierarchy mode of -tile-hierarchy, which is set in ZE_FLAT_DEVICE_HIERARCHY
+// as well.
+func (dp *devicePlugin) allocateTiles(request *pluginapi.AllocateRequest) (*pluginapi.AllocateResponse, error) {
+	dp.mutex.RLock()
+	cards, hierarchyMode := dp.tileCards, dp.options.tileHierarchy
+	dp.mutex.RUnlock()
+
+	response := &pluginapi.AllocateResponse{}
+
+	for _, crqt := range request.ContainerRequests {
+		names, tileInfo, err := containerTiles(crqt.DevicesIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		cresp := &pluginapi.ContainerAllocateResponse{}
+		tilesPerCard := 0
+
+		for _, name := range names {
+			card, ok := cards[name]
+			if !ok {
+				return nil, errors.Errorf("tiles of unknown GPU %s", name)
+			}
+
+			// The mask indexes the tiles of all the cards with the
+			// same tile count.
+			if tilesPerCard != 0 && card.tiles != tilesPerCard {
+				return nil, errors.Errorf("tiles %v are on GPUs with different tile counts", crqt.DevicesIDs)
+			}
+
+			tilesPerCard = card.tiles
+
+			card.addTo(cresp)
+		}
+
+		// Like with the resource manager, the mask is not needed for
+		// 1-tile GPUs, which the devices of the container limit.
+		if tilesPerCard > 1 {
+			mask := rm.TileAffinityMask(tileInfo, tilesPerCard, hierarchyMode)
+			if mask == "" {
+				return nil, errors.Errorf("failed to create the affinity mask of tiles %v", crqt.DevicesIDs)
+			}
+
+			cresp.Envs = map[string]string{
+				rm.LevelzeroAffinityMaskEnvVar: mask,
+				rm.LevelzeroHierarchyEnvVar:    hierarchyMode,
+			}
+		}
+
+		response.ContainerResponses = append(response.ContainerResponses, cresp)
+	}
+
+	return response, nil
+}
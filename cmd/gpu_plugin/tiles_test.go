@@ -0,0 +1,171 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/gpu_plugin/rm"
+)
+
+func TestValidateTileOptions(t *testing.T) {
+	opts := cliOptions{tileResources: true, tileHierarchy: "COMPOSITE", sharedDevNum: 1, preferredAllocationPolicy: "none"}
+	if err := validateOptions(opts); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+
+	for name, change := range map[string]func(*cliOptions){
+		"shared":         func(o *cliOptions) { o.sharedDevNum = 2 },
+		"memory units":   func(o *cliOptions) { o.memoryUnitSize = 1 << 30 },
+		"hierarchy mode": func(o *cliOptions) { o.tileHierarchy = "flat" },
+	} {
+		invalid := opts
+		change(&invalid)
+
+		if err := validateOptions(invalid); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestContainerTiles(t *testing.T) {
+	names, tileInfo, err := containerTiles([]string{"card10-tile0", "card2-tile1", "card2-tile0"})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if !reflect.DeepEqual(names, []string{"card2", "card10"}) || tileInfo != "card2:gt0+gt1,card10:gt0" {
+		t.Errorf("unexpected cards %v and tiles %s", names, tileInfo)
+	}
+
+	if _, _, err := containerTiles([]string{"card0-0"}); err == nil {
+		t.Error("expected an error for a card ID")
+	}
+}
+
+func TestTiles(t *testing.T) {
+	sysfs, devfs, err := createTestFiles(t.TempDir(), TestCaseDetails{
+		sysfsdirs: []string{
+			"card0/device/drm/card0", "card0/device/tile0", "card0/device/tile1",
+			"card1/device/drm/card1", "card1/device/tile0", "card1/device/tile1",
+			"card2/device/drm/card2",
+		},
+		sysfsfiles: map[string][]byte{
+			"card0/device/vendor":    []byte("0x8086"),
+			"card0/device/numa_node": []byte("1"),
+			"card1/device/vendor":    []byte("0x8086"),
+			"card1/device/numa_node": []byte("-1"),
+			"card2/device/vendor":    []byte("0x8086"),
+		},
+		symlinkfiles: map[string]string{
+			"card0/device/driver": "drivers/xe",
+			"card1/device/driver": "drivers/xe",
+			"card2/device/driver": "drivers/xe",
+		},
+		devfsdirs: []string{"card0", "card1", "card2"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	plugin := newDevicePlugin(sysfs, devfs, cliOptions{
+		tileResources: true, tileHierarchy: rm.DefaultHierarchyMode, sharedDevNum: 1, preferredAllocationPolicy: "none",
+	})
+
+	tree, err := plugin.scan()
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if len(tree[deviceTypeXe+tileSuffix]) != 5 || len(tree[deviceTypeXe]) != 0 {
+		t.Fatalf("unexpected devices %v", tree)
+	}
+
+	if _, ok := tree[deviceTypeXe+tileSuffix]["card1-tile1"]; !ok {
+		t.Errorf("missing tile of card1: %v", tree[deviceTypeXe+tileSuffix])
+	}
+
+	if topology := tileTopology(sysfs, "card0"); topology == nil || len(topology.Nodes) != 1 || topology.Nodes[0].ID != 1 {
+		t.Errorf("unexpected topology of card0 tiles: %v", topology)
+	}
+
+	if topology := tileTopology(sysfs, "card1"); topology != nil {
+		t.Errorf("expected no topology without a NUMA node, got %v", topology)
+	}
+
+	// The tiles of a container are kept on one GPU when they fit.
+	resp, err := plugin.GetPreferredAllocation(&v1beta1.PreferredAllocationRequest{
+		ContainerRequests: []*v1beta1.ContainerPreferredAllocationRequest{{
+			AvailableDeviceIDs: []string{"card0-tile0", "card1-tile0", "card1-tile1", "card2-tile0"},
+			AllocationSize:     2,
+		}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if ids := resp.ContainerResponses[0].DeviceIDs; !reflect.DeepEqual(ids, []string{"card1-tile0", "card1-tile1"}) {
+		t.Errorf("unexpected preferred tiles %v", ids)
+	}
+
+	for _, tc := range []struct {
+		hierarchyMode string
+		expectedMask  string
+		ids           []string
+		expectedCards int
+	}{
+		{hierarchyMode: "FLAT", ids: []string{"card1-tile1"}, expectedMask: "1", expectedCards: 1},
+		{hierarchyMode: "FLAT", ids: []string{"card1-tile0", "card0-tile1"}, expectedMask: "1,2", expectedCards: 2},
+		{hierarchyMode: "COMPOSITE", ids: []string{"card1-tile0", "card0-tile1"}, expectedMask: "0.1,1.0", expectedCards: 2},
+		{hierarchyMode: "COMBINED", ids: []string{"card0-tile0", "card0-tile1"}, expectedMask: "0,1", expectedCards: 1},
+	} {
+		plugin.options.tileHierarchy = tc.hierarchyMode
+
+		resp, err := plugin.Allocate(&v1beta1.AllocateRequest{
+			ContainerRequests: []*v1beta1.ContainerAllocateRequest{{DevicesIDs: tc.ids}},
+		})
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %+v", tc.ids, err)
+		}
+
+		cresp := resp.ContainerResponses[0]
+		if cresp.Envs[rm.LevelzeroAffinityMaskEnvVar] != tc.expectedMask || cresp.Envs[rm.LevelzeroHierarchyEnvVar] != tc.hierarchyMode {
+			t.Errorf("%s %v: expected mask %s, got envs %v", tc.hierarchyMode, tc.ids, tc.expectedMask, cresp.Envs)
+		}
+
+		if len(cresp.Devices) != tc.expectedCards {
+			t.Errorf("%v: unexpected devices %v", tc.ids, cresp.Devices)
+		}
+	}
+
+	// The 1-tile GPUs need no mask.
+	resp2, err := plugin.Allocate(&v1beta1.AllocateRequest{
+		ContainerRequests: []*v1beta1.ContainerAllocateRequest{{DevicesIDs: []string{"card2-tile0"}}},
+	})
+	if err != nil || len(resp2.ContainerResponses[0].Envs) != 0 || len(resp2.ContainerResponses[0].Devices) != 1 {
+		t.Errorf("unexpected response %+v (%+v)", resp2, err)
+	}
+
+	for _, ids := range [][]string{{"card0-tile0", "card2-tile0"}, {"card3-tile0"}} {
+		if _, err := plugin.Allocate(&v1beta1.AllocateRequest{
+			ContainerRequests: []*v1beta1.ContainerAllocateRequest{{DevicesIDs: ids}},
+		}); err == nil {
+			t.Errorf("%v: expected an error", ids)
+		}
+	}
+}
@@ -15,9 +15,11 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"os"
 
+	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/pluginutils"
 	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
 	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/idxd"
 
@@ -29,27 +31,52 @@ const (
 	namespace = "iaa.intel.com"
 	// Device directories.
 	devDir = "/dev/iax"
+	// Directory of the work queue devices.
+	sysfsDir = "/sys/bus/dsa/devices"
 	// Glob pattern for the state sysfs entry.
 	statePattern = "/sys/bus/dsa/devices/iax*/wq*/state"
 )
 
 func main() {
-	var sharedDevNum int
+	var (
+		sharedDevNum     int
+		allocationPolicy string
+	)
 
 	flag.IntVar(&sharedDevNum, "shared-dev-num", 1, "number of containers sharing the same work queue")
+	flag.StringVar(&allocationPolicy, "allocation-policy", "none", "modes of allocating work queues: balanced, packed, topology and none")
+	managerFlags := pluginutils.AddManagerFlags(flag.CommandLine)
 	flag.Parse()
 
+	if err := managerFlags.SetupLogging(); err != nil {
+		klog.Fatalf("%+v", err)
+	}
+
+	managerOpts, err := managerFlags.ManagerOptions()
+	if err != nil {
+		klog.Fatalf("%+v", err)
+	}
+
 	if sharedDevNum < 1 {
 		klog.Warning("The number of containers sharing the same work queue must be greater than zero")
 		os.Exit(1)
 	}
 
-	plugin := idxd.NewDevicePlugin(statePattern, devDir, sharedDevNum)
+	policy, err := idxd.NewAllocationPolicy(allocationPolicy, sysfsDir)
+	if err != nil {
+		klog.Fatalf("%+v", err)
+	}
+
+	plugin := idxd.NewDevicePlugin(statePattern, devDir, sharedDevNum, policy)
 	if plugin == nil {
 		klog.Fatal("Cannot create device plugin, please check above error messages.")
 	}
 
-	manager := dpapi.NewManager(namespace, plugin)
+	plugin.EnableConfig("iaa", sysfsDir, pluginutils.CommandLineFlags(flag.CommandLine))
 
-	manager.Run()
+	manager := dpapi.NewManager(namespace, plugin, managerOpts...)
+
+	if err := manager.Run(context.Background()); err != nil {
+		klog.Fatalf("%+v", err)
+	}
 }
@@ -15,18 +15,18 @@
 package labeler
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"os"
-	"os/signal"
 	"path"
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
-	"syscall"
 	"time"
 
 	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/gpu_plugin/levelzeroservice"
@@ -42,6 +42,7 @@ const (
 	millicoreLabelName  = "millicores"
 	pciGroupLabelName   = "pci-groups"
 	tilesLabelName      = "tiles"
+	kmdLabelName        = "kmd"
 	numaMappingName     = "numa-gpu-map"
 	millicoresPerGPU    = 1000
 	memoryOverrideEnv   = "GPU_MEMORY_OVERRIDE"
@@ -169,24 +170,66 @@ func fallback() uint64 {
 func legacyFallback(sysfsDrmDir, gpuName string, numTiles uint64) uint64 {
 	reserved := getEnvVarNumber(memoryReservedEnv)
 
-	filePath := filepath.Join(sysfsDrmDir, gpuName, "lmem_total_bytes")
-
-	dat, err := os.ReadFile(filePath)
+	total, err := sysfsMemoryAmount(filepath.Join(sysfsDrmDir, gpuName), numTiles)
 	if err != nil {
-		klog.Warning("Can't read file: ", err)
+		klog.Warning("Can't read GPU memory amount: ", err)
 		return fallback()
 	}
 
-	totalPerTile, err := strconv.ParseUint(strings.TrimSpace(string(dat)), 0, 64)
-	if err != nil {
-		klog.Warning("Can't convert lmem_total_bytes: ", err)
-		return fallback()
+	if reserved > total {
+		klog.Warningf("%s is larger than the memory of %s", memoryReservedEnv, gpuName)
+		return 0
 	}
 
-	return totalPerTile*numTiles - reserved
+	return total - reserved
+}
+
+// sysfsMemoryAmount reads the local memory of a GPU from the per tile
+// lmem_total_bytes of i915, or from the physical_vram_size_bytes of each
+// tile of xe.
+func sysfsMemoryAmount(cardPath string, numTiles uint64) (uint64, error) {
+	dat, err := os.ReadFile(filepath.Join(cardPath, "lmem_total_bytes"))
+	if err == nil {
+		totalPerTile, err := strconv.ParseUint(strings.TrimSpace(string(dat)), 0, 64)
+		if err != nil {
+			return 0, errors.Wrap(err, "can't convert lmem_total_bytes")
+		}
+
+		return totalPerTile * numTiles, nil
+	}
+
+	paths, _ := filepath.Glob(filepath.Join(cardPath, "device/tile[0-9]*/physical_vram_size_bytes"))
+	if len(paths) == 0 {
+		return 0, errors.WithStack(err)
+	}
+
+	total := uint64(0)
+
+	for _, filePath := range paths {
+		dat, err := os.ReadFile(filePath)
+		if err != nil {
+			return 0, errors.WithStack(err)
+		}
+
+		tileTotal, err := strconv.ParseUint(strings.TrimSpace(string(dat)), 0, 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, "can't convert %s", filePath)
+		}
+
+		total += tileTotal
+	}
+
+	return total, nil
 }
 
 func (l *labeler) GetMemoryAmount(sysfsDrmDir, gpuName string, numTiles uint64) uint64 {
+	return MemoryAmount(sysfsDrmDir, gpuName, numTiles, l.levelzero)
+}
+
+// MemoryAmount returns the local memory of a GPU from Level-Zero when
+// available, and otherwise from sysfs minus GPU_MEMORY_RESERVED. It falls
+// back to GPU_MEMORY_OVERRIDE, e.g. for integrated GPUs.
+func MemoryAmount(sysfsDrmDir, gpuName string, numTiles uint64, levelzero levelzeroservice.LevelzeroService) uint64 {
 	link, err := os.Readlink(filepath.Join(sysfsDrmDir, gpuName, "device"))
 	if err != nil {
 		return legacyFallback(sysfsDrmDir, gpuName, numTiles)
@@ -194,8 +237,8 @@ func (l *labeler) GetMemoryAmount(sysfsDrmDir, gpuName string, numTiles uint64)
 
 	amount := uint64(0)
 
-	if l.levelzero != nil {
-		amount, err = l.levelzero.GetDeviceMemoryAmount(filepath.Base(link))
+	if levelzero != nil {
+		amount, err = levelzero.GetDeviceMemoryAmount(filepath.Base(link))
 		if amount == 0 || err != nil {
 			return legacyFallback(sysfsDrmDir, gpuName, numTiles)
 		}
@@ -213,7 +256,7 @@ func GetTileCount(cardPath string) (numTiles uint64) {
 	paths, _ := filepath.Glob(filepath.Join(cardPath, "gt/gt*")) // i915 driver
 	files = append(files, paths...)
 
-	paths, _ = filepath.Glob(filepath.Join(cardPath, "device/tile?")) // Xe driver
+	paths, _ = filepath.Glob(filepath.Join(cardPath, "device/tile[0-9]*")) // Xe driver
 	files = append(files, paths...)
 
 	klog.V(4).Info("tile files found:", files)
@@ -328,6 +371,7 @@ func (l *labeler) createLabels() error {
 	tileCount := 0
 
 	numaMapping := make(map[int][]string)
+	drivers := []string{}
 
 	for _, gpuName := range gpuNameList {
 		gpuNum := ""
@@ -340,6 +384,10 @@ func (l *labeler) createLabels() error {
 		numTiles := GetTileCount(filepath.Join(l.sysfsDRMDir, gpuName))
 		tileCount += int(numTiles)
 
+		if driver, err := pluginutils.ReadDeviceDriver(filepath.Join(l.sysfsDRMDir, gpuName)); err == nil && !slices.Contains(drivers, driver) {
+			drivers = append(drivers, driver)
+		}
+
 		memoryAmount := l.GetMemoryAmount(l.sysfsDRMDir, gpuName, numTiles)
 		gpuNumList = append(gpuNumList, gpuName[4:])
 
@@ -381,6 +429,13 @@ func (l *labeler) createLabels() error {
 		// all GPUs get default number of millicores (1000)
 		l.labels.addNumericLabel(labelNamespace+millicoreLabelName, int64(millicoresPerGPU*gpuCount))
 
+		// add kernel mode driver label (example: "i915.xe" for a node with both)
+		if len(drivers) > 0 {
+			sort.Strings(drivers)
+
+			l.labels[labelNamespace+kmdLabelName] = strings.Join(drivers, ".")
+		}
+
 		// aa pci-group label(s), (two group example: "1.2.3.4_5.6.7.8")
 		allPCIGroups := l.createPCIGroupLabel(gpuNumList)
 		if allPCIGroups != "" {
@@ -468,15 +523,12 @@ func CreateAndPrintLabels(sysfsDRMDir string) {
 }
 
 // Gathers node's GPU labels on channel trigger or timeout, and write them to a file.
-// The created label file is deleted on exit (process dying).
-func Run(sysfsDrmDir, nfdFeatureFile string, updateInterval time.Duration, scanResources chan bool, levelzero levelzeroservice.LevelzeroService, exitFunc func()) {
+// Run returns when ctx is done, and the created label file is deleted then.
+func Run(ctx context.Context, sysfsDrmDir, nfdFeatureFile string, updateInterval time.Duration, scanResources chan bool, levelzero levelzeroservice.LevelzeroService) {
 	l := newLabeler(sysfsDrmDir)
 
 	l.levelzero = levelzero
 
-	interruptChan := make(chan os.Signal, 1)
-	signal.Notify(interruptChan, syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP, syscall.SIGQUIT)
-
 	klog.V(1).Info("Starting GPU labeler")
 
 Loop:
@@ -486,9 +538,7 @@ Loop:
 		select {
 		case <-timeout:
 		case <-scanResources:
-		case interrupt := <-interruptChan:
-			klog.V(2).Infof("Interrupt %d received", interrupt)
-
+		case <-ctx.Done():
 			break Loop
 		}
 
@@ -513,8 +563,6 @@ Loop:
 		}
 	}
 
-	signal.Stop(interruptChan)
-
 	klog.V(2).Info("Removing label file")
 
 	err := os.Remove(nfdFeatureFile)
@@ -523,7 +571,4 @@ Loop:
 	}
 
 	klog.V(1).Info("Stopping GPU labeler")
-
-	// Call exitFunc that might exit the app
-	exitFunc()
 }
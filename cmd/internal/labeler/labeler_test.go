@@ -15,12 +15,12 @@
 package labeler
 
 import (
+	"context"
 	"os"
 	"path"
 	"path/filepath"
 	"reflect"
 	"strconv"
-	"syscall"
 	"testing"
 	"time"
 
@@ -56,6 +56,23 @@ func (m *mockL0Service) GetDeviceMemoryAmount(bdfAddress string) (uint64, error)
 
 	return m.memSize, nil
 }
+func (m *mockL0Service) GetDeviceUtilization(bdfAddress string) (levelzeroservice.DeviceUtilization, error) {
+	return levelzeroservice.DeviceUtilization{}, nil
+}
+func (m *mockL0Service) GetDevicePower(bdfAddress string) (levelzeroservice.DevicePower, error) {
+	return levelzeroservice.DevicePower{}, nil
+}
+func (m *mockL0Service) GetDeviceFrequency(bdfAddress string) (levelzeroservice.DeviceFrequency, error) {
+	return levelzeroservice.DeviceFrequency{}, nil
+}
+func (m *mockL0Service) GetDeviceErrors(bdfAddress string) (levelzeroservice.DeviceErrors, error) {
+	return levelzeroservice.DeviceErrors{}, nil
+}
+func (m *mockL0Service) GetDeviceMemoryBandwidth(bdfAddress string) (levelzeroservice.DeviceMemoryBandwidth, error) {
+	return levelzeroservice.DeviceMemoryBandwidth{}, nil
+}
+func (m *mockL0Service) WatchDeviceHealth(ctx context.Context, interval time.Duration, handler func(levelzeroservice.HealthEvent)) {
+}
 
 type testcase struct {
 	capabilityFile map[string][]byte
@@ -63,6 +80,7 @@ type testcase struct {
 	expectedLabels labelMap
 	name           string
 	sysfsfiles     map[string][]byte
+	symlinks       map[string]string
 	sysfsdirs      []string
 	memoryOverride uint64
 	memoryReserved uint64
@@ -605,6 +623,39 @@ func getTestCases() []testcase {
 				"gpu.intel.com/numa-gpu-map": "1-0.1",
 			},
 		},
+		{
+			sysfsdirs: []string{
+				"card0/device/drm/card0",
+				"card0/device/tile0/gt0",
+				"card0/device/tile1/gt1",
+				"card1/device/drm/card1",
+				"card1/gt/gt0",
+				"drivers/i915",
+				"drivers/xe",
+			},
+			sysfsfiles: map[string][]byte{
+				"card0/device/vendor":                         []byte("0x8086"),
+				"card0/device/tile0/physical_vram_size_bytes": []byte("0x1000"),
+				"card0/device/tile1/physical_vram_size_bytes": []byte("0x1000"),
+				"card1/device/vendor":                         []byte("0x8086"),
+				"card1/lmem_total_bytes":                      []byte("8000"),
+			},
+			symlinks: map[string]string{
+				"card0/device/driver": "../../drivers/xe",
+				"card1/device/driver": "../../drivers/i915",
+			},
+			memoryReserved: 96,
+			name:           "successful labeling of xe and i915 cards with xe memory per tile",
+			expectedRetval: nil,
+			expectedLabels: labelMap{
+				"gpu.intel.com/millicores":  "2000",
+				"gpu.intel.com/memory.max":  "16000",
+				"gpu.intel.com/gpu-numbers": "0.1",
+				"gpu.intel.com/cards":       "card0.card1",
+				"gpu.intel.com/tiles":       "3",
+				"gpu.intel.com/kmd":         "i915.xe",
+			},
+		},
 	}
 }
 
@@ -620,6 +671,12 @@ func (tc *testcase) createFiles(t *testing.T, sysfs string) {
 			t.Fatalf("Failed to create fake vendor file: %+v", err)
 		}
 	}
+
+	for link, target := range tc.symlinks {
+		if err := os.Symlink(target, path.Join(sysfs, link)); err != nil {
+			t.Fatalf("Failed to create fake symlink: %+v", err)
+		}
+	}
 }
 
 func TestSplit(t *testing.T) {
@@ -759,25 +816,24 @@ func TestCreateAndRun(t *testing.T) {
 		nfdLabelBase := "nfd-labelfile.txt"
 		nfdLabelFile := filepath.Join(root, nfdLabelBase)
 
-		go Run(sysfs, nfdLabelFile, time.Millisecond, c, nil, func() {})
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+
+		go func() {
+			Run(ctx, sysfs, nfdLabelFile, time.Millisecond, c, nil)
+			close(done)
+		}()
 
 		// Wait for the labeling timeout to trigger
 		if !waitForFileOp(root, nfdLabelBase, fsnotify.Create, time.Second*2) {
 			t.Error("Run didn't create label file")
 		}
 
-		err = syscall.Kill(syscall.Getpid(), syscall.SIGHUP)
-		if err != nil {
-			t.Error("Calling Kill failed")
-		}
+		cancel()
+		<-done
 
-		// Wait for the labeling timeout to trigger
-		if !waitForFileOp(root, nfdLabelBase, fsnotify.Remove, time.Second*2) {
-			// It's possible that removal happened before we started waiting.
-			// Only fail the test if the file still exists.
-			if _, err := os.Stat(nfdLabelFile); err == nil {
-				t.Error("Run didn't remove label file")
-			}
+		if _, err := os.Stat(nfdLabelFile); err == nil {
+			t.Error("Run didn't remove label file")
 		}
 	})
 }
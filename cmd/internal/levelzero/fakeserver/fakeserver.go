@@ -0,0 +1,269 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fakeserver implements the gRPC service of the GPU Level-Zero
+// sidecar with the device values set by the tests, so that the clients of
+// the sidecar can be tested without GPUs.
+package fakeserver
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	lz "github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/levelzero"
+)
+
+const (
+	// The Level-Zero result codes of the replies of unknown devices and of
+	// the values not set, like the sidecar returns them.
+	errorUnknown      = 0x7ffffffe // ZE_RESULT_ERROR_UNKNOWN
+	errorNotAvailable = 0x70010001 // ZE_RESULT_ERROR_NOT_AVAILABLE
+
+	// defaultWatchInterval is the interval of the health watches without
+	// one, like in the sidecar.
+	defaultWatchInterval = 5 * time.Second
+)
+
+// Device has the replies of the server for a device. The replies not set are
+// answered with the ZE_RESULT_ERROR_NOT_AVAILABLE error.
+type Device struct {
+	Health          *lz.DeviceHealth
+	Temperature     *lz.DeviceTemperature
+	Utilization     *lz.DeviceUtilization
+	Power           *lz.DevicePower
+	Frequency       *lz.DeviceFrequency
+	Errors          *lz.DeviceErrors
+	MemoryBandwidth *lz.DeviceMemoryBandwidth
+	MemorySize      uint64
+}
+
+// Server is a fake Level-Zero sidecar.
+type Server struct {
+	lz.UnimplementedLevelzeroServer
+	grpcServer *grpc.Server
+	devices    map[string]*Device
+	// bdfAddresses are the devices in the order they were added, which
+	// is the order of their indices.
+	bdfAddresses []string
+	mutex        sync.Mutex
+	failing      bool
+}
+
+// New returns a fake Level-Zero sidecar without devices.
+func New() *Server {
+	return &Server{devices: make(map[string]*Device)}
+}
+
+// SetDevice sets the replies for the device of a BDF address, e.g.
+// "0000:03:00.0". The replies must not be changed after they are set.
+func (s *Server) SetDevice(bdfAddress string, device *Device) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.devices[bdfAddress]; !ok {
+		s.bdfAddresses = append(s.bdfAddresses, bdfAddress)
+	}
+
+	s.devices[bdfAddress] = device
+}
+
+// SetFailing makes all the requests fail with a gRPC error, like when the
+// sidecar is not running.
+func (s *Server) SetFailing(failing bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.failing = failing
+}
+
+// Start serves the requests on a unix socket until Stop is called.
+func (s *Server) Start(socketPath string) error {
+	lis, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to listen")
+	}
+
+	s.grpcServer = grpc.NewServer()
+
+	lz.RegisterLevelzeroServer(s.grpcServer, s)
+
+	go func() {
+		_ = s.grpcServer.Serve(lis)
+	}()
+
+	return nil
+}
+
+// Stop stops serving the requests.
+func (s *Server) Stop() {
+	if s.grpcServer != nil {
+		s.grpcServer.Stop()
+	}
+}
+
+// reply returns a copy of the reply for a device. Without the device or the
+// reply, the reply of errorReply is returned with the error of Level-Zero.
+func reply[T proto.Message](s *Server, bdfAddress string, get func(*Device) T, errorReply func(*lz.Error) T) (T, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.failing {
+		var none T
+
+		return none, status.Error(codes.Unavailable, "fake server is failing")
+	}
+
+	device, ok := s.devices[bdfAddress]
+	if !ok {
+		return errorReply(&lz.Error{Errorcode: errorUnknown, Description: "unknown device"}), nil
+	}
+
+	r := get(device)
+	if !r.ProtoReflect().IsValid() {
+		return errorReply(&lz.Error{Errorcode: errorNotAvailable, Description: "not available"}), nil
+	}
+
+	return proto.Clone(r).(T), nil
+}
+
+func (s *Server) GetDeviceHealth(c context.Context, deviceid *lz.DeviceId) (*lz.DeviceHealth, error) {
+	return reply(s, deviceid.BdfAddress, func(d *Device) *lz.DeviceHealth { return d.Health },
+		func(e *lz.Error) *lz.DeviceHealth {
+			return &lz.DeviceHealth{MemoryOk: true, BusOk: true, SocOk: true, Error: e}
+		})
+}
+
+func (s *Server) GetDeviceTemperature(c context.Context, deviceid *lz.DeviceId) (*lz.DeviceTemperature, error) {
+	return reply(s, deviceid.BdfAddress, func(d *Device) *lz.DeviceTemperature { return d.Temperature },
+		func(e *lz.Error) *lz.DeviceTemperature {
+			return &lz.DeviceTemperature{Global: -999.0, Gpu: -999.0, Memory: -999.0, Error: e}
+		})
+}
+
+func (s *Server) GetDeviceMemoryAmount(c context.Context, deviceid *lz.DeviceId) (*lz.DeviceMemoryAmount, error) {
+	return reply(s, deviceid.BdfAddress, func(d *Device) *lz.DeviceMemoryAmount {
+		if d.MemorySize == 0 {
+			return nil
+		}
+
+		return &lz.DeviceMemoryAmount{MemorySize: d.MemorySize}
+	}, func(e *lz.Error) *lz.DeviceMemoryAmount { return &lz.DeviceMemoryAmount{Error: e} })
+}
+
+func (s *Server) GetDeviceUtilization(c context.Context, deviceid *lz.DeviceId) (*lz.DeviceUtilization, error) {
+	return reply(s, deviceid.BdfAddress, func(d *Device) *lz.DeviceUtilization { return d.Utilization },
+		func(e *lz.Error) *lz.DeviceUtilization { return &lz.DeviceUtilization{Error: e} })
+}
+
+func (s *Server) GetDevicePower(c context.Context, deviceid *lz.DeviceId) (*lz.DevicePower, error) {
+	return reply(s, deviceid.BdfAddress, func(d *Device) *lz.DevicePower { return d.Power },
+		func(e *lz.Error) *lz.DevicePower { return &lz.DevicePower{Error: e} })
+}
+
+func (s *Server) GetDeviceFrequency(c context.Context, deviceid *lz.DeviceId) (*lz.DeviceFrequency, error) {
+	return reply(s, deviceid.BdfAddress, func(d *Device) *lz.DeviceFrequency { return d.Frequency },
+		func(e *lz.Error) *lz.DeviceFrequency { return &lz.DeviceFrequency{Error: e} })
+}
+
+func (s *Server) GetDeviceErrors(c context.Context, deviceid *lz.DeviceId) (*lz.DeviceErrors, error) {
+	return reply(s, deviceid.BdfAddress, func(d *Device) *lz.DeviceErrors { return d.Errors },
+		func(e *lz.Error) *lz.DeviceErrors { return &lz.DeviceErrors{Error: e} })
+}
+
+func (s *Server) GetDeviceMemoryBandwidth(c context.Context, deviceid *lz.DeviceId) (*lz.DeviceMemoryBandwidth, error) {
+	return reply(s, deviceid.BdfAddress, func(d *Device) *lz.DeviceMemoryBandwidth { return d.MemoryBandwidth },
+		func(e *lz.Error) *lz.DeviceMemoryBandwidth { return &lz.DeviceMemoryBandwidth{Error: e} })
+}
+
+// WatchDeviceHealth sends the health of the devices when the watch starts,
+// and then the changed health at the interval of the request. Unlike the
+// sidecar's, the interval has no minimum. The watch fails when the server is
+// set failing.
+func (s *Server) WatchDeviceHealth(req *lz.WatchDeviceHealthRequest, stream lz.Levelzero_WatchDeviceHealthServer) error {
+	interval := time.Duration(req.IntervalMs) * time.Millisecond
+	if interval == 0 {
+		interval = defaultWatchInterval
+	}
+
+	sent := map[string]*lz.DeviceHealthEvent{}
+
+	for {
+		s.mutex.Lock()
+		bdfAddresses := append([]string{}, s.bdfAddresses...)
+		s.mutex.Unlock()
+
+		for _, bdfAddress := range bdfAddresses {
+			event, err := s.healthEvent(stream.Context(), bdfAddress)
+			if err != nil {
+				return err
+			}
+
+			if proto.Equal(sent[bdfAddress], event) {
+				continue
+			}
+
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+
+			sent[bdfAddress] = event
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (s *Server) healthEvent(ctx context.Context, bdfAddress string) (*lz.DeviceHealthEvent, error) {
+	deviceid := &lz.DeviceId{BdfAddress: bdfAddress}
+
+	health, err := s.GetDeviceHealth(ctx, deviceid)
+	if err != nil {
+		return nil, err
+	}
+
+	temps, err := s.GetDeviceTemperature(ctx, deviceid)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lz.DeviceHealthEvent{BdfAddress: bdfAddress, Health: health, Temperature: temps}, nil
+}
+
+func (s *Server) GetIntelIndices(c context.Context, m *lz.GetIntelIndicesMessage) (*lz.DeviceIndices, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.failing {
+		return nil, status.Error(codes.Unavailable, "fake server is failing")
+	}
+
+	indices := make([]uint32, len(s.bdfAddresses))
+	for i := range indices {
+		indices[i] = uint32(i)
+	}
+
+	return &lz.DeviceIndices{Indices: indices}, nil
+}
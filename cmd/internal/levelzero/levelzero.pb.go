@@ -14,8 +14,8 @@
 
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
-// 	protoc-gen-go v1.28.1
-// 	protoc        v3.12.4
+// 	protoc-gen-go v1.35.2
+// 	protoc        (unknown)
 // source: levelzero.proto
 
 package gpulevelzero
@@ -42,11 +42,9 @@ type GetIntelIndicesMessage struct {
 
 func (x *GetIntelIndicesMessage) Reset() {
 	*x = GetIntelIndicesMessage{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_levelzero_proto_msgTypes[0]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_levelzero_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *GetIntelIndicesMessage) String() string {
@@ -57,7 +55,7 @@ func (*GetIntelIndicesMessage) ProtoMessage() {}
 
 func (x *GetIntelIndicesMessage) ProtoReflect() protoreflect.Message {
 	mi := &file_levelzero_proto_msgTypes[0]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -82,11 +80,9 @@ type DeviceId struct {
 
 func (x *DeviceId) Reset() {
 	*x = DeviceId{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_levelzero_proto_msgTypes[1]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_levelzero_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *DeviceId) String() string {
@@ -97,7 +93,7 @@ func (*DeviceId) ProtoMessage() {}
 
 func (x *DeviceId) ProtoReflect() protoreflect.Message {
 	mi := &file_levelzero_proto_msgTypes[1]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -132,11 +128,9 @@ type DeviceHealth struct {
 
 func (x *DeviceHealth) Reset() {
 	*x = DeviceHealth{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_levelzero_proto_msgTypes[2]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_levelzero_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *DeviceHealth) String() string {
@@ -147,7 +141,7 @@ func (*DeviceHealth) ProtoMessage() {}
 
 func (x *DeviceHealth) ProtoReflect() protoreflect.Message {
 	mi := &file_levelzero_proto_msgTypes[2]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -203,11 +197,9 @@ type DeviceTemperature struct {
 
 func (x *DeviceTemperature) Reset() {
 	*x = DeviceTemperature{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_levelzero_proto_msgTypes[3]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_levelzero_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *DeviceTemperature) String() string {
@@ -218,7 +210,7 @@ func (*DeviceTemperature) ProtoMessage() {}
 
 func (x *DeviceTemperature) ProtoReflect() protoreflect.Message {
 	mi := &file_levelzero_proto_msgTypes[3]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -272,11 +264,9 @@ type DeviceIndices struct {
 
 func (x *DeviceIndices) Reset() {
 	*x = DeviceIndices{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_levelzero_proto_msgTypes[4]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_levelzero_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *DeviceIndices) String() string {
@@ -287,7 +277,7 @@ func (*DeviceIndices) ProtoMessage() {}
 
 func (x *DeviceIndices) ProtoReflect() protoreflect.Message {
 	mi := &file_levelzero_proto_msgTypes[4]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -327,11 +317,9 @@ type DeviceMemoryAmount struct {
 
 func (x *DeviceMemoryAmount) Reset() {
 	*x = DeviceMemoryAmount{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_levelzero_proto_msgTypes[5]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_levelzero_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *DeviceMemoryAmount) String() string {
@@ -342,7 +330,7 @@ func (*DeviceMemoryAmount) ProtoMessage() {}
 
 func (x *DeviceMemoryAmount) ProtoReflect() protoreflect.Message {
 	mi := &file_levelzero_proto_msgTypes[5]
-	if protoimpl.UnsafeEnabled && x != nil {
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -371,6 +359,507 @@ func (x *DeviceMemoryAmount) GetError() *Error {
 	return nil
 }
 
+// Busy time of the engines of a group in percent since the previous request,
+// the group being "all", "compute", "render", "media" or "copy".
+type EngineUtilization struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Group       string  `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	Utilization float64 `protobuf:"fixed64,2,opt,name=utilization,proto3" json:"utilization,omitempty"`
+}
+
+func (x *EngineUtilization) Reset() {
+	*x = EngineUtilization{}
+	mi := &file_levelzero_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EngineUtilization) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EngineUtilization) ProtoMessage() {}
+
+func (x *EngineUtilization) ProtoReflect() protoreflect.Message {
+	mi := &file_levelzero_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EngineUtilization.ProtoReflect.Descriptor instead.
+func (*EngineUtilization) Descriptor() ([]byte, []int) {
+	return file_levelzero_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *EngineUtilization) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+func (x *EngineUtilization) GetUtilization() float64 {
+	if x != nil {
+		return x.Utilization
+	}
+	return 0
+}
+
+type DeviceUtilization struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Engines []*EngineUtilization `protobuf:"bytes,1,rep,name=engines,proto3" json:"engines,omitempty"`
+	Error   *Error               `protobuf:"bytes,42,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *DeviceUtilization) Reset() {
+	*x = DeviceUtilization{}
+	mi := &file_levelzero_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeviceUtilization) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeviceUtilization) ProtoMessage() {}
+
+func (x *DeviceUtilization) ProtoReflect() protoreflect.Message {
+	mi := &file_levelzero_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeviceUtilization.ProtoReflect.Descriptor instead.
+func (*DeviceUtilization) Descriptor() ([]byte, []int) {
+	return file_levelzero_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DeviceUtilization) GetEngines() []*EngineUtilization {
+	if x != nil {
+		return x.Engines
+	}
+	return nil
+}
+
+func (x *DeviceUtilization) GetError() *Error {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+// Average power in watts since the previous request, and the TDP of the card.
+type DevicePower struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Power float64 `protobuf:"fixed64,1,opt,name=power,proto3" json:"power,omitempty"`
+	Tdp   float64 `protobuf:"fixed64,2,opt,name=tdp,proto3" json:"tdp,omitempty"`
+	Error *Error  `protobuf:"bytes,42,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *DevicePower) Reset() {
+	*x = DevicePower{}
+	mi := &file_levelzero_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DevicePower) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DevicePower) ProtoMessage() {}
+
+func (x *DevicePower) ProtoReflect() protoreflect.Message {
+	mi := &file_levelzero_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DevicePower.ProtoReflect.Descriptor instead.
+func (*DevicePower) Descriptor() ([]byte, []int) {
+	return file_levelzero_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DevicePower) GetPower() float64 {
+	if x != nil {
+		return x.Power
+	}
+	return 0
+}
+
+func (x *DevicePower) GetTdp() float64 {
+	if x != nil {
+		return x.Tdp
+	}
+	return 0
+}
+
+func (x *DevicePower) GetError() *Error {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+// Frequencies in MHz, and the reasons the actual frequency is throttled below
+// the requested one: "average-power", "burst-power", "current", "thermal",
+// "psu-alert", "software-range" or "hardware-range".
+type DeviceFrequency struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Actual          float64  `protobuf:"fixed64,1,opt,name=actual,proto3" json:"actual,omitempty"`
+	Requested       float64  `protobuf:"fixed64,2,opt,name=requested,proto3" json:"requested,omitempty"`
+	Max             float64  `protobuf:"fixed64,3,opt,name=max,proto3" json:"max,omitempty"`
+	ThrottleReasons []string `protobuf:"bytes,4,rep,name=throttle_reasons,json=throttleReasons,proto3" json:"throttle_reasons,omitempty"`
+	Error           *Error   `protobuf:"bytes,42,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *DeviceFrequency) Reset() {
+	*x = DeviceFrequency{}
+	mi := &file_levelzero_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeviceFrequency) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeviceFrequency) ProtoMessage() {}
+
+func (x *DeviceFrequency) ProtoReflect() protoreflect.Message {
+	mi := &file_levelzero_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeviceFrequency.ProtoReflect.Descriptor instead.
+func (*DeviceFrequency) Descriptor() ([]byte, []int) {
+	return file_levelzero_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DeviceFrequency) GetActual() float64 {
+	if x != nil {
+		return x.Actual
+	}
+	return 0
+}
+
+func (x *DeviceFrequency) GetRequested() float64 {
+	if x != nil {
+		return x.Requested
+	}
+	return 0
+}
+
+func (x *DeviceFrequency) GetMax() float64 {
+	if x != nil {
+		return x.Max
+	}
+	return 0
+}
+
+func (x *DeviceFrequency) GetThrottleReasons() []string {
+	if x != nil {
+		return x.ThrottleReasons
+	}
+	return nil
+}
+
+func (x *DeviceFrequency) GetError() *Error {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+// RAS error counts of all the categories.
+type DeviceErrors struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Correctable   uint64 `protobuf:"varint,1,opt,name=correctable,proto3" json:"correctable,omitempty"`
+	Uncorrectable uint64 `protobuf:"varint,2,opt,name=uncorrectable,proto3" json:"uncorrectable,omitempty"`
+	Error         *Error `protobuf:"bytes,42,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *DeviceErrors) Reset() {
+	*x = DeviceErrors{}
+	mi := &file_levelzero_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeviceErrors) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeviceErrors) ProtoMessage() {}
+
+func (x *DeviceErrors) ProtoReflect() protoreflect.Message {
+	mi := &file_levelzero_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeviceErrors.ProtoReflect.Descriptor instead.
+func (*DeviceErrors) Descriptor() ([]byte, []int) {
+	return file_levelzero_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *DeviceErrors) GetCorrectable() uint64 {
+	if x != nil {
+		return x.Correctable
+	}
+	return 0
+}
+
+func (x *DeviceErrors) GetUncorrectable() uint64 {
+	if x != nil {
+		return x.Uncorrectable
+	}
+	return 0
+}
+
+func (x *DeviceErrors) GetError() *Error {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+// Memory bandwidth in bytes per second since the previous request.
+type DeviceMemoryBandwidth struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Read  float64 `protobuf:"fixed64,1,opt,name=read,proto3" json:"read,omitempty"`
+	Write float64 `protobuf:"fixed64,2,opt,name=write,proto3" json:"write,omitempty"`
+	Max   float64 `protobuf:"fixed64,3,opt,name=max,proto3" json:"max,omitempty"`
+	Error *Error  `protobuf:"bytes,42,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *DeviceMemoryBandwidth) Reset() {
+	*x = DeviceMemoryBandwidth{}
+	mi := &file_levelzero_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeviceMemoryBandwidth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeviceMemoryBandwidth) ProtoMessage() {}
+
+func (x *DeviceMemoryBandwidth) ProtoReflect() protoreflect.Message {
+	mi := &file_levelzero_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeviceMemoryBandwidth.ProtoReflect.Descriptor instead.
+func (*DeviceMemoryBandwidth) Descriptor() ([]byte, []int) {
+	return file_levelzero_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *DeviceMemoryBandwidth) GetRead() float64 {
+	if x != nil {
+		return x.Read
+	}
+	return 0
+}
+
+func (x *DeviceMemoryBandwidth) GetWrite() float64 {
+	if x != nil {
+		return x.Write
+	}
+	return 0
+}
+
+func (x *DeviceMemoryBandwidth) GetMax() float64 {
+	if x != nil {
+		return x.Max
+	}
+	return 0
+}
+
+func (x *DeviceMemoryBandwidth) GetError() *Error {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
+// Interval of sampling the health of the devices in milliseconds. The health
+// is also read when the driver sends health events.
+type WatchDeviceHealthRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	IntervalMs uint32 `protobuf:"varint,1,opt,name=interval_ms,json=intervalMs,proto3" json:"interval_ms,omitempty"`
+}
+
+func (x *WatchDeviceHealthRequest) Reset() {
+	*x = WatchDeviceHealthRequest{}
+	mi := &file_levelzero_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchDeviceHealthRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchDeviceHealthRequest) ProtoMessage() {}
+
+func (x *WatchDeviceHealthRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_levelzero_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchDeviceHealthRequest.ProtoReflect.Descriptor instead.
+func (*WatchDeviceHealthRequest) Descriptor() ([]byte, []int) {
+	return file_levelzero_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *WatchDeviceHealthRequest) GetIntervalMs() uint32 {
+	if x != nil {
+		return x.IntervalMs
+	}
+	return 0
+}
+
+// Health and temperatures of a device. They are sent for all the devices when
+// the watch starts, and then for the devices whose health changes or whose
+// temperatures change by a degree or more.
+type DeviceHealthEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	BdfAddress  string             `protobuf:"bytes,1,opt,name=bdf_address,json=bdfAddress,proto3" json:"bdf_address,omitempty"`
+	Health      *DeviceHealth      `protobuf:"bytes,2,opt,name=health,proto3" json:"health,omitempty"`
+	Temperature *DeviceTemperature `protobuf:"bytes,3,opt,name=temperature,proto3" json:"temperature,omitempty"`
+	Error       *Error             `protobuf:"bytes,42,opt,name=error,proto3" json:"error,omitempty"`
+}
+
+func (x *DeviceHealthEvent) Reset() {
+	*x = DeviceHealthEvent{}
+	mi := &file_levelzero_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeviceHealthEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeviceHealthEvent) ProtoMessage() {}
+
+func (x *DeviceHealthEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_levelzero_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeviceHealthEvent.ProtoReflect.Descriptor instead.
+func (*DeviceHealthEvent) Descriptor() ([]byte, []int) {
+	return file_levelzero_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *DeviceHealthEvent) GetBdfAddress() string {
+	if x != nil {
+		return x.BdfAddress
+	}
+	return ""
+}
+
+func (x *DeviceHealthEvent) GetHealth() *DeviceHealth {
+	if x != nil {
+		return x.Health
+	}
+	return nil
+}
+
+func (x *DeviceHealthEvent) GetTemperature() *DeviceTemperature {
+	if x != nil {
+		return x.Temperature
+	}
+	return nil
+}
+
+func (x *DeviceHealthEvent) GetError() *Error {
+	if x != nil {
+		return x.Error
+	}
+	return nil
+}
+
 type Error struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -382,11 +871,9 @@ type Error struct {
 
 func (x *Error) Reset() {
 	*x = Error{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_levelzero_proto_msgTypes[6]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
-	}
+	mi := &file_levelzero_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
 }
 
 func (x *Error) String() string {
@@ -396,8 +883,8 @@ func (x *Error) String() string {
 func (*Error) ProtoMessage() {}
 
 func (x *Error) ProtoReflect() protoreflect.Message {
-	mi := &file_levelzero_proto_msgTypes[6]
-	if protoimpl.UnsafeEnabled && x != nil {
+	mi := &file_levelzero_proto_msgTypes[14]
+	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
 			ms.StoreMessageInfo(mi)
@@ -409,7 +896,7 @@ func (x *Error) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Error.ProtoReflect.Descriptor instead.
 func (*Error) Descriptor() ([]byte, []int) {
-	return file_levelzero_proto_rawDescGZIP(), []int{6}
+	return file_levelzero_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *Error) GetDescription() string {
@@ -459,11 +946,67 @@ var file_levelzero_proto_rawDesc = []byte{
 	0x69, 0x7a, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x6d, 0x65, 0x6d, 0x6f, 0x72,
 	0x79, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x1c, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x2a,
 	0x20, 0x01, 0x28, 0x0b, 0x32, 0x06, 0x2e, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x52, 0x05, 0x65, 0x72,
+	0x72, 0x6f, 0x72, 0x22, 0x4b, 0x0a, 0x11, 0x45, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x55, 0x74, 0x69,
+	0x6c, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x67, 0x72, 0x6f, 0x75,
+	0x70, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x12, 0x20,
+	0x0a, 0x0b, 0x75, 0x74, 0x69, 0x6c, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x01, 0x52, 0x0b, 0x75, 0x74, 0x69, 0x6c, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x22, 0x5f, 0x0a, 0x11, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x55, 0x74, 0x69, 0x6c, 0x69, 0x7a,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x2c, 0x0a, 0x07, 0x65, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x45, 0x6e, 0x67, 0x69, 0x6e, 0x65, 0x55,
+	0x74, 0x69, 0x6c, 0x69, 0x7a, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x07, 0x65, 0x6e, 0x67, 0x69,
+	0x6e, 0x65, 0x73, 0x12, 0x1c, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x2a, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x06, 0x2e, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f,
+	0x72, 0x22, 0x53, 0x0a, 0x0b, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x50, 0x6f, 0x77, 0x65, 0x72,
+	0x12, 0x14, 0x0a, 0x05, 0x70, 0x6f, 0x77, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52,
+	0x05, 0x70, 0x6f, 0x77, 0x65, 0x72, 0x12, 0x10, 0x0a, 0x03, 0x74, 0x64, 0x70, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x01, 0x52, 0x03, 0x74, 0x64, 0x70, 0x12, 0x1c, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f,
+	0x72, 0x18, 0x2a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x06, 0x2e, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x52,
+	0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0xa2, 0x01, 0x0a, 0x0f, 0x44, 0x65, 0x76, 0x69, 0x63,
+	0x65, 0x46, 0x72, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x79, 0x12, 0x16, 0x0a, 0x06, 0x61, 0x63,
+	0x74, 0x75, 0x61, 0x6c, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x06, 0x61, 0x63, 0x74, 0x75,
+	0x61, 0x6c, 0x12, 0x1c, 0x0a, 0x09, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x65, 0x64, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x09, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x65, 0x64,
+	0x12, 0x10, 0x0a, 0x03, 0x6d, 0x61, 0x78, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x03, 0x6d,
+	0x61, 0x78, 0x12, 0x29, 0x0a, 0x10, 0x74, 0x68, 0x72, 0x6f, 0x74, 0x74, 0x6c, 0x65, 0x5f, 0x72,
+	0x65, 0x61, 0x73, 0x6f, 0x6e, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x09, 0x52, 0x0f, 0x74, 0x68,
+	0x72, 0x6f, 0x74, 0x74, 0x6c, 0x65, 0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x73, 0x12, 0x1c, 0x0a,
+	0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x2a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x06, 0x2e, 0x45,
+	0x72, 0x72, 0x6f, 0x72, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x74, 0x0a, 0x0c, 0x44,
+	0x65, 0x76, 0x69, 0x63, 0x65, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x12, 0x20, 0x0a, 0x0b, 0x63,
+	0x6f, 0x72, 0x72, 0x65, 0x63, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x0b, 0x63, 0x6f, 0x72, 0x72, 0x65, 0x63, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x12, 0x24, 0x0a,
+	0x0d, 0x75, 0x6e, 0x63, 0x6f, 0x72, 0x72, 0x65, 0x63, 0x74, 0x61, 0x62, 0x6c, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x0d, 0x75, 0x6e, 0x63, 0x6f, 0x72, 0x72, 0x65, 0x63, 0x74, 0x61,
+	0x62, 0x6c, 0x65, 0x12, 0x1c, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x2a, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x06, 0x2e, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f,
+	0x72, 0x22, 0x71, 0x0a, 0x15, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x4d, 0x65, 0x6d, 0x6f, 0x72,
+	0x79, 0x42, 0x61, 0x6e, 0x64, 0x77, 0x69, 0x64, 0x74, 0x68, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x65,
+	0x61, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x01, 0x52, 0x04, 0x72, 0x65, 0x61, 0x64, 0x12, 0x14,
+	0x0a, 0x05, 0x77, 0x72, 0x69, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x01, 0x52, 0x05, 0x77,
+	0x72, 0x69, 0x74, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6d, 0x61, 0x78, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x01, 0x52, 0x03, 0x6d, 0x61, 0x78, 0x12, 0x1c, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18,
+	0x2a, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x06, 0x2e, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x52, 0x05, 0x65,
+	0x72, 0x72, 0x6f, 0x72, 0x22, 0x3b, 0x0a, 0x18, 0x57, 0x61, 0x74, 0x63, 0x68, 0x44, 0x65, 0x76,
+	0x69, 0x63, 0x65, 0x48, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x12, 0x1f, 0x0a, 0x0b, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x5f, 0x6d, 0x73, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x0a, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x4d,
+	0x73, 0x22, 0xaf, 0x01, 0x0a, 0x11, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x48, 0x65, 0x61, 0x6c,
+	0x74, 0x68, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x62, 0x64, 0x66, 0x5f, 0x61,
+	0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x62, 0x64,
+	0x66, 0x41, 0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x12, 0x25, 0x0a, 0x06, 0x68, 0x65, 0x61, 0x6c,
+	0x74, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x44, 0x65, 0x76, 0x69, 0x63,
+	0x65, 0x48, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x52, 0x06, 0x68, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x12,
+	0x34, 0x0a, 0x0b, 0x74, 0x65, 0x6d, 0x70, 0x65, 0x72, 0x61, 0x74, 0x75, 0x72, 0x65, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x54, 0x65, 0x6d,
+	0x70, 0x65, 0x72, 0x61, 0x74, 0x75, 0x72, 0x65, 0x52, 0x0b, 0x74, 0x65, 0x6d, 0x70, 0x65, 0x72,
+	0x61, 0x74, 0x75, 0x72, 0x65, 0x12, 0x1c, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x2a,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x06, 0x2e, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x52, 0x05, 0x65, 0x72,
 	0x72, 0x6f, 0x72, 0x22, 0x47, 0x0a, 0x05, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x20, 0x0a, 0x0b,
 	0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28,
 	0x09, 0x52, 0x0b, 0x64, 0x65, 0x73, 0x63, 0x72, 0x69, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x1c,
 	0x0a, 0x09, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x0d, 0x52, 0x09, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x63, 0x6f, 0x64, 0x65, 0x32, 0xec, 0x01, 0x0a,
+	0x0d, 0x52, 0x09, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x63, 0x6f, 0x64, 0x65, 0x32, 0xbf, 0x04, 0x0a,
 	0x09, 0x4c, 0x65, 0x76, 0x65, 0x6c, 0x7a, 0x65, 0x72, 0x6f, 0x12, 0x2d, 0x0a, 0x0f, 0x47, 0x65,
 	0x74, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x48, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x12, 0x09, 0x2e,
 	0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x49, 0x64, 0x1a, 0x0d, 0x2e, 0x44, 0x65, 0x76, 0x69, 0x63,
@@ -478,9 +1021,30 @@ var file_levelzero_proto_rawDesc = []byte{
 	0x12, 0x39, 0x0a, 0x15, 0x47, 0x65, 0x74, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x4d, 0x65, 0x6d,
 	0x6f, 0x72, 0x79, 0x41, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x09, 0x2e, 0x44, 0x65, 0x76, 0x69,
 	0x63, 0x65, 0x49, 0x64, 0x1a, 0x13, 0x2e, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x4d, 0x65, 0x6d,
-	0x6f, 0x72, 0x79, 0x41, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x00, 0x42, 0x0f, 0x5a, 0x0d, 0x67,
-	0x70, 0x75, 0x2e, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x7a, 0x65, 0x72, 0x6f, 0x62, 0x06, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x33,
+	0x6f, 0x72, 0x79, 0x41, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x00, 0x12, 0x37, 0x0a, 0x14, 0x47,
+	0x65, 0x74, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x55, 0x74, 0x69, 0x6c, 0x69, 0x7a, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x12, 0x09, 0x2e, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x49, 0x64, 0x1a, 0x12,
+	0x2e, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x55, 0x74, 0x69, 0x6c, 0x69, 0x7a, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x22, 0x00, 0x12, 0x2b, 0x0a, 0x0e, 0x47, 0x65, 0x74, 0x44, 0x65, 0x76, 0x69, 0x63,
+	0x65, 0x50, 0x6f, 0x77, 0x65, 0x72, 0x12, 0x09, 0x2e, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x49,
+	0x64, 0x1a, 0x0c, 0x2e, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x50, 0x6f, 0x77, 0x65, 0x72, 0x22,
+	0x00, 0x12, 0x33, 0x0a, 0x12, 0x47, 0x65, 0x74, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x46, 0x72,
+	0x65, 0x71, 0x75, 0x65, 0x6e, 0x63, 0x79, 0x12, 0x09, 0x2e, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65,
+	0x49, 0x64, 0x1a, 0x10, 0x2e, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x46, 0x72, 0x65, 0x71, 0x75,
+	0x65, 0x6e, 0x63, 0x79, 0x22, 0x00, 0x12, 0x2d, 0x0a, 0x0f, 0x47, 0x65, 0x74, 0x44, 0x65, 0x76,
+	0x69, 0x63, 0x65, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x73, 0x12, 0x09, 0x2e, 0x44, 0x65, 0x76, 0x69,
+	0x63, 0x65, 0x49, 0x64, 0x1a, 0x0d, 0x2e, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x45, 0x72, 0x72,
+	0x6f, 0x72, 0x73, 0x22, 0x00, 0x12, 0x3f, 0x0a, 0x18, 0x47, 0x65, 0x74, 0x44, 0x65, 0x76, 0x69,
+	0x63, 0x65, 0x4d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x42, 0x61, 0x6e, 0x64, 0x77, 0x69, 0x64, 0x74,
+	0x68, 0x12, 0x09, 0x2e, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x49, 0x64, 0x1a, 0x16, 0x2e, 0x44,
+	0x65, 0x76, 0x69, 0x63, 0x65, 0x4d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x42, 0x61, 0x6e, 0x64, 0x77,
+	0x69, 0x64, 0x74, 0x68, 0x22, 0x00, 0x12, 0x46, 0x0a, 0x11, 0x57, 0x61, 0x74, 0x63, 0x68, 0x44,
+	0x65, 0x76, 0x69, 0x63, 0x65, 0x48, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x12, 0x19, 0x2e, 0x57, 0x61,
+	0x74, 0x63, 0x68, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x48, 0x65, 0x61, 0x6c, 0x74, 0x68, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x44, 0x65, 0x76, 0x69, 0x63, 0x65, 0x48,
+	0x65, 0x61, 0x6c, 0x74, 0x68, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x22, 0x00, 0x30, 0x01, 0x42, 0x0f,
+	0x5a, 0x0d, 0x67, 0x70, 0x75, 0x2e, 0x6c, 0x65, 0x76, 0x65, 0x6c, 0x7a, 0x65, 0x72, 0x6f, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -495,34 +1059,63 @@ func file_levelzero_proto_rawDescGZIP() []byte {
 	return file_levelzero_proto_rawDescData
 }
 
-var file_levelzero_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
-var file_levelzero_proto_goTypes = []interface{}{
-	(*GetIntelIndicesMessage)(nil), // 0: GetIntelIndicesMessage
-	(*DeviceId)(nil),               // 1: DeviceId
-	(*DeviceHealth)(nil),           // 2: DeviceHealth
-	(*DeviceTemperature)(nil),      // 3: DeviceTemperature
-	(*DeviceIndices)(nil),          // 4: DeviceIndices
-	(*DeviceMemoryAmount)(nil),     // 5: DeviceMemoryAmount
-	(*Error)(nil),                  // 6: Error
+var file_levelzero_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_levelzero_proto_goTypes = []any{
+	(*GetIntelIndicesMessage)(nil),   // 0: GetIntelIndicesMessage
+	(*DeviceId)(nil),                 // 1: DeviceId
+	(*DeviceHealth)(nil),             // 2: DeviceHealth
+	(*DeviceTemperature)(nil),        // 3: DeviceTemperature
+	(*DeviceIndices)(nil),            // 4: DeviceIndices
+	(*DeviceMemoryAmount)(nil),       // 5: DeviceMemoryAmount
+	(*EngineUtilization)(nil),        // 6: EngineUtilization
+	(*DeviceUtilization)(nil),        // 7: DeviceUtilization
+	(*DevicePower)(nil),              // 8: DevicePower
+	(*DeviceFrequency)(nil),          // 9: DeviceFrequency
+	(*DeviceErrors)(nil),             // 10: DeviceErrors
+	(*DeviceMemoryBandwidth)(nil),    // 11: DeviceMemoryBandwidth
+	(*WatchDeviceHealthRequest)(nil), // 12: WatchDeviceHealthRequest
+	(*DeviceHealthEvent)(nil),        // 13: DeviceHealthEvent
+	(*Error)(nil),                    // 14: Error
 }
 var file_levelzero_proto_depIdxs = []int32{
-	6, // 0: DeviceHealth.error:type_name -> Error
-	6, // 1: DeviceTemperature.error:type_name -> Error
-	6, // 2: DeviceIndices.error:type_name -> Error
-	6, // 3: DeviceMemoryAmount.error:type_name -> Error
-	1, // 4: Levelzero.GetDeviceHealth:input_type -> DeviceId
-	1, // 5: Levelzero.GetDeviceTemperature:input_type -> DeviceId
-	0, // 6: Levelzero.GetIntelIndices:input_type -> GetIntelIndicesMessage
-	1, // 7: Levelzero.GetDeviceMemoryAmount:input_type -> DeviceId
-	2, // 8: Levelzero.GetDeviceHealth:output_type -> DeviceHealth
-	3, // 9: Levelzero.GetDeviceTemperature:output_type -> DeviceTemperature
-	4, // 10: Levelzero.GetIntelIndices:output_type -> DeviceIndices
-	5, // 11: Levelzero.GetDeviceMemoryAmount:output_type -> DeviceMemoryAmount
-	8, // [8:12] is the sub-list for method output_type
-	4, // [4:8] is the sub-list for method input_type
-	4, // [4:4] is the sub-list for extension type_name
-	4, // [4:4] is the sub-list for extension extendee
-	0, // [0:4] is the sub-list for field type_name
+	14, // 0: DeviceHealth.error:type_name -> Error
+	14, // 1: DeviceTemperature.error:type_name -> Error
+	14, // 2: DeviceIndices.error:type_name -> Error
+	14, // 3: DeviceMemoryAmount.error:type_name -> Error
+	6,  // 4: DeviceUtilization.engines:type_name -> EngineUtilization
+	14, // 5: DeviceUtilization.error:type_name -> Error
+	14, // 6: DevicePower.error:type_name -> Error
+	14, // 7: DeviceFrequency.error:type_name -> Error
+	14, // 8: DeviceErrors.error:type_name -> Error
+	14, // 9: DeviceMemoryBandwidth.error:type_name -> Error
+	2,  // 10: DeviceHealthEvent.health:type_name -> DeviceHealth
+	3,  // 11: DeviceHealthEvent.temperature:type_name -> DeviceTemperature
+	14, // 12: DeviceHealthEvent.error:type_name -> Error
+	1,  // 13: Levelzero.GetDeviceHealth:input_type -> DeviceId
+	1,  // 14: Levelzero.GetDeviceTemperature:input_type -> DeviceId
+	0,  // 15: Levelzero.GetIntelIndices:input_type -> GetIntelIndicesMessage
+	1,  // 16: Levelzero.GetDeviceMemoryAmount:input_type -> DeviceId
+	1,  // 17: Levelzero.GetDeviceUtilization:input_type -> DeviceId
+	1,  // 18: Levelzero.GetDevicePower:input_type -> DeviceId
+	1,  // 19: Levelzero.GetDeviceFrequency:input_type -> DeviceId
+	1,  // 20: Levelzero.GetDeviceErrors:input_type -> DeviceId
+	1,  // 21: Levelzero.GetDeviceMemoryBandwidth:input_type -> DeviceId
+	12, // 22: Levelzero.WatchDeviceHealth:input_type -> WatchDeviceHealthRequest
+	2,  // 23: Levelzero.GetDeviceHealth:output_type -> DeviceHealth
+	3,  // 24: Levelzero.GetDeviceTemperature:output_type -> DeviceTemperature
+	4,  // 25: Levelzero.GetIntelIndices:output_type -> DeviceIndices
+	5,  // 26: Levelzero.GetDeviceMemoryAmount:output_type -> DeviceMemoryAmount
+	7,  // 27: Levelzero.GetDeviceUtilization:output_type -> DeviceUtilization
+	8,  // 28: Levelzero.GetDevicePower:output_type -> DevicePower
+	9,  // 29: Levelzero.GetDeviceFrequency:output_type -> DeviceFrequency
+	10, // 30: Levelzero.GetDeviceErrors:output_type -> DeviceErrors
+	11, // 31: Levelzero.GetDeviceMemoryBandwidth:output_type -> DeviceMemoryBandwidth
+	13, // 32: Levelzero.WatchDeviceHealth:output_type -> DeviceHealthEvent
+	23, // [23:33] is the sub-list for method output_type
+	13, // [13:23] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
 }
 
 func init() { file_levelzero_proto_init() }
@@ -530,99 +1123,13 @@ func file_levelzero_proto_init() {
 	if File_levelzero_proto != nil {
 		return
 	}
-	if !protoimpl.UnsafeEnabled {
-		file_levelzero_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetIntelIndicesMessage); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_levelzero_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*DeviceId); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_levelzero_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*DeviceHealth); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_levelzero_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*DeviceTemperature); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_levelzero_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*DeviceIndices); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_levelzero_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*DeviceMemoryAmount); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-		file_levelzero_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Error); i {
-			case 0:
-				return &v.state
-			case 1:
-				return &v.sizeCache
-			case 2:
-				return &v.unknownFields
-			default:
-				return nil
-			}
-		}
-	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_levelzero_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   7,
+			NumMessages:   15,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -40,6 +40,12 @@ type LevelzeroClient interface {
 	GetDeviceTemperature(ctx context.Context, in *DeviceId, opts ...grpc.CallOption) (*DeviceTemperature, error)
 	GetIntelIndices(ctx context.Context, in *GetIntelIndicesMessage, opts ...grpc.CallOption) (*DeviceIndices, error)
 	GetDeviceMemoryAmount(ctx context.Context, in *DeviceId, opts ...grpc.CallOption) (*DeviceMemoryAmount, error)
+	GetDeviceUtilization(ctx context.Context, in *DeviceId, opts ...grpc.CallOption) (*DeviceUtilization, error)
+	GetDevicePower(ctx context.Context, in *DeviceId, opts ...grpc.CallOption) (*DevicePower, error)
+	GetDeviceFrequency(ctx context.Context, in *DeviceId, opts ...grpc.CallOption) (*DeviceFrequency, error)
+	GetDeviceErrors(ctx context.Context, in *DeviceId, opts ...grpc.CallOption) (*DeviceErrors, error)
+	GetDeviceMemoryBandwidth(ctx context.Context, in *DeviceId, opts ...grpc.CallOption) (*DeviceMemoryBandwidth, error)
+	WatchDeviceHealth(ctx context.Context, in *WatchDeviceHealthRequest, opts ...grpc.CallOption) (Levelzero_WatchDeviceHealthClient, error)
 }
 
 type levelzeroClient struct {
@@ -86,6 +92,83 @@ func (c *levelzeroClient) GetDeviceMemoryAmount(ctx context.Context, in *DeviceI
 	return out, nil
 }
 
+func (c *levelzeroClient) GetDeviceUtilization(ctx context.Context, in *DeviceId, opts ...grpc.CallOption) (*DeviceUtilization, error) {
+	out := new(DeviceUtilization)
+	err := c.cc.Invoke(ctx, "/Levelzero/GetDeviceUtilization", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *levelzeroClient) GetDevicePower(ctx context.Context, in *DeviceId, opts ...grpc.CallOption) (*DevicePower, error) {
+	out := new(DevicePower)
+	err := c.cc.Invoke(ctx, "/Levelzero/GetDevicePower", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *levelzeroClient) GetDeviceFrequency(ctx context.Context, in *DeviceId, opts ...grpc.CallOption) (*DeviceFrequency, error) {
+	out := new(DeviceFrequency)
+	err := c.cc.Invoke(ctx, "/Levelzero/GetDeviceFrequency", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *levelzeroClient) GetDeviceErrors(ctx context.Context, in *DeviceId, opts ...grpc.CallOption) (*DeviceErrors, error) {
+	out := new(DeviceErrors)
+	err := c.cc.Invoke(ctx, "/Levelzero/GetDeviceErrors", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *levelzeroClient) GetDeviceMemoryBandwidth(ctx context.Context, in *DeviceId, opts ...grpc.CallOption) (*DeviceMemoryBandwidth, error) {
+	out := new(DeviceMemoryBandwidth)
+	err := c.cc.Invoke(ctx, "/Levelzero/GetDeviceMemoryBandwidth", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *levelzeroClient) WatchDeviceHealth(ctx context.Context, in *WatchDeviceHealthRequest, opts ...grpc.CallOption) (Levelzero_WatchDeviceHealthClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Levelzero_ServiceDesc.Streams[0], "/Levelzero/WatchDeviceHealth", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &levelzeroWatchDeviceHealthClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Levelzero_WatchDeviceHealthClient interface {
+	Recv() (*DeviceHealthEvent, error)
+	grpc.ClientStream
+}
+
+type levelzeroWatchDeviceHealthClient struct {
+	grpc.ClientStream
+}
+
+func (x *levelzeroWatchDeviceHealthClient) Recv() (*DeviceHealthEvent, error) {
+	m := new(DeviceHealthEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // LevelzeroServer is the server API for Levelzero service.
 // All implementations must embed UnimplementedLevelzeroServer
 // for forward compatibility
@@ -94,6 +177,12 @@ type LevelzeroServer interface {
 	GetDeviceTemperature(context.Context, *DeviceId) (*DeviceTemperature, error)
 	GetIntelIndices(context.Context, *GetIntelIndicesMessage) (*DeviceIndices, error)
 	GetDeviceMemoryAmount(context.Context, *DeviceId) (*DeviceMemoryAmount, error)
+	GetDeviceUtilization(context.Context, *DeviceId) (*DeviceUtilization, error)
+	GetDevicePower(context.Context, *DeviceId) (*DevicePower, error)
+	GetDeviceFrequency(context.Context, *DeviceId) (*DeviceFrequency, error)
+	GetDeviceErrors(context.Context, *DeviceId) (*DeviceErrors, error)
+	GetDeviceMemoryBandwidth(context.Context, *DeviceId) (*DeviceMemoryBandwidth, error)
+	WatchDeviceHealth(*WatchDeviceHealthRequest, Levelzero_WatchDeviceHealthServer) error
 	mustEmbedUnimplementedLevelzeroServer()
 }
 
@@ -113,6 +202,24 @@ func (UnimplementedLevelzeroServer) GetIntelIndices(context.Context, *GetIntelIn
 func (UnimplementedLevelzeroServer) GetDeviceMemoryAmount(context.Context, *DeviceId) (*DeviceMemoryAmount, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetDeviceMemoryAmount not implemented")
 }
+func (UnimplementedLevelzeroServer) GetDeviceUtilization(context.Context, *DeviceId) (*DeviceUtilization, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDeviceUtilization not implemented")
+}
+func (UnimplementedLevelzeroServer) GetDevicePower(context.Context, *DeviceId) (*DevicePower, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDevicePower not implemented")
+}
+func (UnimplementedLevelzeroServer) GetDeviceFrequency(context.Context, *DeviceId) (*DeviceFrequency, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDeviceFrequency not implemented")
+}
+func (UnimplementedLevelzeroServer) GetDeviceErrors(context.Context, *DeviceId) (*DeviceErrors, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDeviceErrors not implemented")
+}
+func (UnimplementedLevelzeroServer) GetDeviceMemoryBandwidth(context.Context, *DeviceId) (*DeviceMemoryBandwidth, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetDeviceMemoryBandwidth not implemented")
+}
+func (UnimplementedLevelzeroServer) WatchDeviceHealth(*WatchDeviceHealthRequest, Levelzero_WatchDeviceHealthServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchDeviceHealth not implemented")
+}
 func (UnimplementedLevelzeroServer) mustEmbedUnimplementedLevelzeroServer() {}
 
 // UnsafeLevelzeroServer may be embedded to opt out of forward compatibility for this service.
@@ -198,6 +305,117 @@ func _Levelzero_GetDeviceMemoryAmount_Handler(srv interface{}, ctx context.Conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Levelzero_GetDeviceUtilization_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeviceId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LevelzeroServer).GetDeviceUtilization(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Levelzero/GetDeviceUtilization",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LevelzeroServer).GetDeviceUtilization(ctx, req.(*DeviceId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Levelzero_GetDevicePower_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeviceId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LevelzeroServer).GetDevicePower(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Levelzero/GetDevicePower",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LevelzeroServer).GetDevicePower(ctx, req.(*DeviceId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Levelzero_GetDeviceFrequency_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeviceId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LevelzeroServer).GetDeviceFrequency(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Levelzero/GetDeviceFrequency",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LevelzeroServer).GetDeviceFrequency(ctx, req.(*DeviceId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Levelzero_GetDeviceErrors_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeviceId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LevelzeroServer).GetDeviceErrors(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Levelzero/GetDeviceErrors",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LevelzeroServer).GetDeviceErrors(ctx, req.(*DeviceId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Levelzero_GetDeviceMemoryBandwidth_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeviceId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LevelzeroServer).GetDeviceMemoryBandwidth(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/Levelzero/GetDeviceMemoryBandwidth",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LevelzeroServer).GetDeviceMemoryBandwidth(ctx, req.(*DeviceId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Levelzero_WatchDeviceHealth_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchDeviceHealthRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(LevelzeroServer).WatchDeviceHealth(m, &levelzeroWatchDeviceHealthServer{stream})
+}
+
+type Levelzero_WatchDeviceHealthServer interface {
+	Send(*DeviceHealthEvent) error
+	grpc.ServerStream
+}
+
+type levelzeroWatchDeviceHealthServer struct {
+	grpc.ServerStream
+}
+
+func (x *levelzeroWatchDeviceHealthServer) Send(m *DeviceHealthEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 // Levelzero_ServiceDesc is the grpc.ServiceDesc for Levelzero service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -221,7 +439,33 @@ var Levelzero_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetDeviceMemoryAmount",
 			Handler:    _Levelzero_GetDeviceMemoryAmount_Handler,
 		},
+		{
+			MethodName: "GetDeviceUtilization",
+			Handler:    _Levelzero_GetDeviceUtilization_Handler,
+		},
+		{
+			MethodName: "GetDevicePower",
+			Handler:    _Levelzero_GetDevicePower_Handler,
+		},
+		{
+			MethodName: "GetDeviceFrequency",
+			Handler:    _Levelzero_GetDeviceFrequency_Handler,
+		},
+		{
+			MethodName: "GetDeviceErrors",
+			Handler:    _Levelzero_GetDeviceErrors_Handler,
+		},
+		{
+			MethodName: "GetDeviceMemoryBandwidth",
+			Handler:    _Levelzero_GetDeviceMemoryBandwidth_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchDeviceHealth",
+			Handler:       _Levelzero_WatchDeviceHealth_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "levelzero.proto",
 }
@@ -0,0 +1,54 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginutils
+
+import (
+	"flag"
+
+	"github.com/pkg/errors"
+	logsapi "k8s.io/component-base/logs/api/v1"
+	_ "k8s.io/component-base/logs/json/register" // registers the json format
+)
+
+// setupLogging switches klog to the given format, "text" or "json". The
+// text format is the default of klog and needs no setup.
+func setupLogging(format string) error {
+	switch format {
+	case logsapi.DefaultLogFormat:
+		return nil
+	case logsapi.JSONLogFormat:
+	default:
+		return errors.Errorf("unknown logging format %q, expected %s or %s", format, logsapi.DefaultLogFormat, logsapi.JSONLogFormat)
+	}
+
+	c := logsapi.NewLoggingConfiguration()
+	c.Format = format
+
+	// Applying the configuration resets the verbosity, keep the one given
+	// with the -v and -vmodule flags of klog.
+	if v := flag.Lookup("v"); v != nil {
+		if err := logsapi.VerbosityLevelPflag(&c.Verbosity).Set(v.Value.String()); err != nil {
+			return errors.Wrap(err, "invalid verbosity")
+		}
+	}
+
+	if vmodule := flag.Lookup("vmodule"); vmodule != nil && vmodule.Value.String() != "" {
+		if err := logsapi.VModuleConfigurationPflag(&c.VModule).Set(vmodule.Value.String()); err != nil {
+			return errors.Wrap(err, "invalid vmodule")
+		}
+	}
+
+	return errors.Wrap(logsapi.ValidateAndApply(c, nil), "failed to set up logging")
+}
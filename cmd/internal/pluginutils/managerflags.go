@@ -0,0 +1,146 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginutils
+
+import (
+	"flag"
+
+	"github.com/pkg/errors"
+
+	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+)
+
+// The rules of the reporter-role ClusterRole of the device plugins which
+// publish the inventory, report the node condition or the Node events.
+// +kubebuilder:rbac:groups=deviceplugin.intel.com,resources=nodeacceleratorinventories,verbs=get;create;update
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get
+// +kubebuilder:rbac:groups="",resources=nodes/status,verbs=patch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+// ManagerFlags holds the command line flags common to all device plugins.
+type ManagerFlags struct {
+	metricsAddr string
+	resourceAPI string
+	journalDir  string
+	debugAddr   string
+	socketDir   string
+	configFile  string
+	includeDevs string
+	excludeDevs string
+	logFormat   string
+	inventory   bool
+	condition   bool
+}
+
+// AddManagerFlags registers the command line flags common to all device plugins.
+func AddManagerFlags(fs *flag.FlagSet) *ManagerFlags {
+	f := &ManagerFlags{}
+
+	fs.StringVar(&f.metricsAddr, "metrics-bind-address", "", "address the metrics endpoint binds to, e.g. \":8080\" (disabled when empty)")
+	fs.StringVar(&f.debugAddr, "health-debug-bind-address", "", "address the device health debug endpoint binds to, e.g. \"127.0.0.1:8081\" (disabled when empty)")
+	fs.StringVar(&f.socketDir, "introspection-socket-dir", dpapi.IntrospectionSocketDir, "directory of the unix socket serving the advertised devices and recent allocations (disabled when empty)")
+	fs.StringVar(&f.journalDir, "allocation-journal-dir", "", "host directory for the journal of device allocations kept over plugin restarts (disabled when empty)")
+	fs.StringVar(&f.configFile, "config", "", "path of the device plugin config file, reloaded on changes; flags given on the command line override its settings (disabled when empty)")
+	fs.StringVar(&f.includeDevs, "include-devices", "", "advertise only the devices matching any of the matches separated by ';', each with properties separated by ',': pci, deviceid, driver, numa, node and attr:<sysfs attribute>, e.g. \"driver=i915,numa=0;pci=0000:03:00.0\" (overrides the config file)")
+	fs.StringVar(&f.excludeDevs, "exclude-devices", "", "don't advertise the devices matching any of the matches, see -include-devices (overrides the config file)")
+	fs.BoolVar(&f.inventory, "publish-inventory", false, "publish the devices in the NodeAcceleratorInventory of the node, requires the CRD and the NODE_NAME environment variable")
+	fs.BoolVar(&f.condition, "report-node-condition", false, "maintain a node condition, e.g. IntelGPUHealthy, summarizing the health of the devices, requires the NODE_NAME environment variable")
+	fs.StringVar(&f.logFormat, "logging-format", "text", "format of the log messages: text or json")
+	fs.StringVar(&f.resourceAPI, "resource-api", string(dpapi.ModeClassic), "kubelet API used to advertise devices: classic (device plugin API), dra (Dynamic Resource Allocation) or both")
+
+	return f
+}
+
+// SetupLogging switches klog to the format given with -logging-format. The
+// plugins call it right after parsing the flags, before logging anything.
+func (f *ManagerFlags) SetupLogging() error {
+	return setupLogging(f.logFormat)
+}
+
+// ManagerOptions converts the parsed flags to device plugin Manager options.
+func (f *ManagerFlags) ManagerOptions() ([]dpapi.ManagerOption, error) {
+	mode, err := dpapi.ParseMode(f.resourceAPI)
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := f.deviceSelector()
+	if err != nil {
+		return nil, err
+	}
+
+	return []dpapi.ManagerOption{
+		dpapi.WithMetrics(f.metricsAddr),
+		dpapi.WithMode(mode),
+		dpapi.WithAllocationJournal(f.journalDir),
+		dpapi.WithHealthDebug(f.debugAddr),
+		dpapi.WithIntrospection(f.socketDir),
+		dpapi.WithConfigFile(f.configFile),
+		dpapi.WithDeviceSelector(selector),
+		dpapi.WithInventory(f.inventory),
+		dpapi.WithNodeCondition(f.condition),
+	}, nil
+}
+
+// deviceSelector returns the device selector of the flags, nil when the
+// flags are not used.
+func (f *ManagerFlags) deviceSelector() (*dpapi.DeviceSelector, error) {
+	if f.includeDevs == "" && f.excludeDevs == "" {
+		return nil, nil
+	}
+
+	var (
+		selector dpapi.DeviceSelector
+		err      error
+	)
+
+	if selector.Include, err = dpapi.ParseDeviceMatches(f.includeDevs); err != nil {
+		return nil, errors.Wrap(err, "invalid -include-devices")
+	}
+
+	if selector.Exclude, err = dpapi.ParseDeviceMatches(f.excludeDevs); err != nil {
+		return nil, errors.Wrap(err, "invalid -exclude-devices")
+	}
+
+	if err := selector.Validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid device selector")
+	}
+
+	return &selector, nil
+}
+
+// ConfigFile returns the path of the config file, empty when not used.
+func (f *ManagerFlags) ConfigFile() string {
+	return f.configFile
+}
+
+// CommandLineFlags returns the names of the flags set on the command line.
+func CommandLineFlags(fs *flag.FlagSet) map[string]bool {
+	set := make(map[string]bool)
+
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	return set
+}
+
+// OverrideWithConfig sets dst to the config file value, unless the value is
+// nil or the flag is set on the command line, see CommandLineFlags().
+func OverrideWithConfig[T any](dst *T, value *T, flagName string, commandLine map[string]bool) {
+	if value != nil && !commandLine[flagName] {
+		*dst = *value
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pluginutils
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestManagerFlags(t *testing.T) {
+	tcases := []struct {
+		name        string
+		args        []string
+		expectedErr bool
+	}{
+		{name: "defaults"},
+		{name: "dra", args: []string{"-resource-api", "dra", "-metrics-bind-address", ":8080", "-allocation-journal-dir", "/var/lib/intel-device-plugins", "-health-debug-bind-address", "127.0.0.1:8081"}},
+		{name: "both", args: []string{"-resource-api", "both", "-introspection-socket-dir", ""}},
+		{name: "unknown resource API", args: []string{"-resource-api", "foo"}, expectedErr: true},
+		{name: "device selector", args: []string{"-include-devices", "driver=i915;driver=xe", "-exclude-devices", "pci=0000:00:02.0"}},
+		{name: "unknown device property", args: []string{"-exclude-devices", "bdf=0000:00:02.0"}, expectedErr: true},
+		{name: "invalid device pattern", args: []string{"-include-devices", "node=/dev/dri/card[0"}, expectedErr: true},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			fs := flag.NewFlagSet(tc.name, flag.ContinueOnError)
+			f := AddManagerFlags(fs)
+
+			if err := fs.Parse(tc.args); err != nil {
+				t.Fatalf("unexpected parse error: %+v", err)
+			}
+
+			opts, err := f.ManagerOptions()
+			if tc.expectedErr != (err != nil) {
+				t.Errorf("unexpected error state: %+v", err)
+			}
+
+			if !tc.expectedErr && len(opts) != 9 {
+				t.Errorf("expected 9 options, got %d", len(opts))
+			}
+		})
+	}
+}
+
+func TestSetupLogging(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	f := AddManagerFlags(fs)
+
+	if err := fs.Parse(nil); err != nil {
+		t.Fatalf("unexpected parse error: %+v", err)
+	}
+
+	if err := f.SetupLogging(); err != nil {
+		t.Errorf("unexpected error with the text format: %+v", err)
+	}
+
+	if err := fs.Parse([]string{"-logging-format", "yaml"}); err != nil {
+		t.Fatalf("unexpected parse error: %+v", err)
+	}
+
+	if err := f.SetupLogging(); err == nil {
+		t.Error("expected an error with an unknown logging format")
+	}
+}
+
+func TestOverrideWithConfig(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	sharedDevNum := fs.Int("shared-dev-num", 1, "")
+	tempLimit := fs.Int("temp-limit", 100, "")
+
+	if err := fs.Parse([]string{"-temp-limit", "90"}); err != nil {
+		t.Fatalf("unexpected parse error: %+v", err)
+	}
+
+	commandLine := CommandLineFlags(fs)
+	configured := 80
+
+	OverrideWithConfig(tempLimit, &configured, "temp-limit", commandLine)
+	OverrideWithConfig(sharedDevNum, nil, "shared-dev-num", commandLine)
+
+	if *tempLimit != 90 || *sharedDevNum != 1 {
+		t.Errorf("config should not override the command line nor the defaults: %d, %d", *tempLimit, *sharedDevNum)
+	}
+
+	OverrideWithConfig(sharedDevNum, &configured, "shared-dev-num", commandLine)
+
+	if *sharedDevNum != 80 {
+		t.Errorf("config should override the defaults, got %d", *sharedDevNum)
+	}
+}
@@ -26,6 +26,13 @@ func IsSriovPFwithVFs(pfpath string) bool {
 	return dat != "-1" && dat != "0"
 }
 
+// IsSriovVF returns true if the device with given path is a SR-IOV virtual function.
+func IsSriovVF(sysFSPath string) bool {
+	_, err := os.Lstat(path.Join(sysFSPath, "device/physfn"))
+
+	return err == nil
+}
+
 func GetSriovNumVFs(sysFSPath string) string {
 	dat, err := os.ReadFile(path.Join(sysFSPath, "device/sriov_numvfs"))
 	if err != nil {
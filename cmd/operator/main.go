@@ -32,6 +32,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 
 	devicepluginv1 "github.com/intel/intel-device-plugins-for-kubernetes/pkg/apis/deviceplugin/v1"
+	devicepluginv1alpha1 "github.com/intel/intel-device-plugins-for-kubernetes/pkg/apis/deviceplugin/v1alpha1"
 	fpgav2 "github.com/intel/intel-device-plugins-for-kubernetes/pkg/apis/fpga/v2"
 	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/controllers/dlb"
 	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/controllers/dsa"
@@ -56,6 +57,7 @@ func init() {
 	_ = clientgoscheme.AddToScheme(scheme)
 
 	_ = devicepluginv1.AddToScheme(scheme)
+	_ = devicepluginv1alpha1.AddToScheme(scheme)
 	_ = fpgav2.AddToScheme(scheme)
 	// +kubebuilder:scaffold:scheme
 }
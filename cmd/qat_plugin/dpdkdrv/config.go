@@ -0,0 +1,81 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dpdkdrv
+
+import (
+	"flag"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/pluginutils"
+	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+)
+
+// qatConfig is the "qat" section of the config file. The settings are
+// overridden by the corresponding flags given on the command line.
+type qatConfig struct {
+	MaxNumDevices    *int    `json:"maxNumDevices,omitempty"`    // -max-num-devices
+	AllocationPolicy *string `json:"allocationPolicy,omitempty"` // -allocation-policy
+}
+
+// settings returns the settings the config file can change.
+func (dp *DevicePlugin) settings() (int, *dpapi.AllocationPolicy) {
+	dp.mutex.RLock()
+	defer dp.mutex.RUnlock()
+
+	return dp.maxDevices, dp.policy
+}
+
+// ConfigSection implements the Configurable interface.
+func (dp *DevicePlugin) ConfigSection() string {
+	return "qat"
+}
+
+// ApplyConfig implements the Configurable interface. The new settings are
+// applied on a rescan of the devices.
+func (dp *DevicePlugin) ApplyConfig(section []byte) error {
+	cfg := qatConfig{}
+	if err := dpapi.DecodeConfigSection(section, &cfg); err != nil {
+		return err
+	}
+
+	commandLine := pluginutils.CommandLineFlags(flag.CommandLine)
+	maxDevices, policy := dp.flagMaxDevices, dp.flagPolicy
+
+	pluginutils.OverrideWithConfig(&maxDevices, cfg.MaxNumDevices, "max-num-devices", commandLine)
+
+	if maxDevices < 0 {
+		return errors.Errorf("wrong maximum number of devices: %d", maxDevices)
+	}
+
+	if cfg.AllocationPolicy != nil && !commandLine["allocation-policy"] {
+		if policy = allocationPolicyByName(*cfg.AllocationPolicy); policy == nil {
+			return errors.Errorf("wrong allocation policy: %s", *cfg.AllocationPolicy)
+		}
+	}
+
+	klog.V(1).Infof("QAT config: maximum number of devices = %d", maxDevices)
+
+	dp.mutex.Lock()
+
+	dp.maxDevices, dp.policy = maxDevices, policy
+
+	dp.mutex.Unlock()
+
+	dp.scanWatcher.Rescan()
+
+	return nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dpdkdrv
+
+import (
+	"testing"
+
+	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+)
+
+func TestApplyConfig(t *testing.T) {
+	tcases := []struct {
+		expectedPolicy     *dpapi.AllocationPolicy
+		name               string
+		section            string
+		expectedMaxDevices int
+		expectedErr        bool
+	}{
+		{
+			name:               "empty section keeps the flags",
+			expectedMaxDevices: 64,
+			expectedPolicy:     nonePolicy,
+		},
+		{
+			name:               "config overrides flag defaults",
+			section:            `{"maxNumDevices": 8, "allocationPolicy": "packed"}`,
+			expectedMaxDevices: 8,
+			expectedPolicy:     packedPolicy,
+		},
+		{
+			name:               "invalid policy",
+			section:            `{"maxNumDevices": 8, "allocationPolicy": "random"}`,
+			expectedMaxDevices: 64,
+			expectedPolicy:     nonePolicy,
+			expectedErr:        true,
+		},
+		{
+			name:               "invalid maximum number of devices",
+			section:            `{"maxNumDevices": -1}`,
+			expectedMaxDevices: 64,
+			expectedPolicy:     nonePolicy,
+			expectedErr:        true,
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			dp := newDevicePlugin(t.TempDir(), t.TempDir(), 64, []string{"4xxxvf"}, vfioPci, nonePolicy)
+
+			err := dp.ApplyConfig([]byte(tc.section))
+			if tc.expectedErr != (err != nil) {
+				t.Fatalf("unexpected error state: %+v", err)
+			}
+
+			if maxDevices, policy := dp.settings(); maxDevices != tc.expectedMaxDevices || policy != tc.expectedPolicy {
+				t.Errorf("expected %d devices with %p policy, got %d with %p", tc.expectedMaxDevices, tc.expectedPolicy, maxDevices, policy)
+			}
+		})
+	}
+}
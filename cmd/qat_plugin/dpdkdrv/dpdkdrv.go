@@ -21,9 +21,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-ini/ini"
@@ -50,11 +50,11 @@ const (
 	igbUio  = "igb_uio"
 	vfioPci = "vfio-pci"
 
-	// Period of device scans.
-	scanPeriod = 5 * time.Second
-
 	// Resource name to use when device capabilities are not available.
 	defaultCapabilities = "generic"
+
+	// Period of the PF heartbeat status checks.
+	healthCheckPeriod = 5 * time.Second
 )
 
 // QAT PCI VF Device ID -> kernel QAT VF device driver mappings.
@@ -71,59 +71,36 @@ var qatDeviceDriver = map[string]string{
 	"6f55": "d15xxvf",
 }
 
-// swapBDF returns ["C1:B1:A1", "C2:B2:A2"], when the given parameter is ["A1:B1:C1", "A2:B2:C2"].
-func swapBDF(devstrings []string) []string {
-	result := make([]string, len(devstrings))
-
-	for n, dev := range devstrings {
-		tmp := strings.Split(dev, ":")
-		result[n] = fmt.Sprintf("%v:%v:%v", tmp[2], tmp[1], tmp[0])
-	}
-
-	return result
-}
-
-type preferredAllocationPolicyFunc func(*pluginapi.ContainerPreferredAllocationRequest) []string
-
-// nonePolicy is used when no policy is specified.
-func nonePolicy(req *pluginapi.ContainerPreferredAllocationRequest) []string {
-	deviceIds := req.AvailableDeviceIDs
-
-	return deviceIds[:req.AllocationSize]
-}
-
-// balancedPolicy is used for allocating QAT devices in balance.
-func balancedPolicy(req *pluginapi.ContainerPreferredAllocationRequest) []string {
-	// make it "FDB" and string sort and change back to "BDF"
-	deviceIds := swapBDF(req.AvailableDeviceIDs)
-	sort.Strings(deviceIds)
-	deviceIds = swapBDF(deviceIds)
-
-	return deviceIds[:req.AllocationSize]
-}
-
-// packedPolicy is used for allocating QAT PF devices one by one.
-func packedPolicy(req *pluginapi.ContainerPreferredAllocationRequest) []string {
-	deviceIds := req.AvailableDeviceIDs
-	sort.Strings(deviceIds)
-	deviceIds = deviceIds[:req.AllocationSize]
-
-	return deviceIds
-}
+var (
+	// nonePolicy is used when no policy is specified.
+	nonePolicy = dpapi.NewAllocationPolicy()
+	// balancedPolicy is used for allocating QAT devices in balance.
+	balancedPolicy = dpapi.NewAllocationPolicy(dpapi.Balance(dpapi.PCIBusKey), dpapi.ByID)
+	// packedPolicy is used for allocating QAT PF devices one by one.
+	packedPolicy = dpapi.NewAllocationPolicy(dpapi.Pack(dpapi.PCIBusKey), dpapi.ByID)
+)
 
 // DevicePlugin represents vfio based QAT plugin.
 type DevicePlugin struct {
-	scanTicker *time.Ticker
-	scanDone   chan bool
+	scanWatcher *dpapi.DeviceWatcher
+	scanDone    chan bool
 
 	// Note: If restarting the plugin with a new policy, the allocations for existing pods remain with old policy.
-	policy preferredAllocationPolicyFunc
+	policy *dpapi.AllocationPolicy
+	// flagPolicy is the policy before the config file is applied.
+	flagPolicy *dpapi.AllocationPolicy
 
 	pciDriverDir    string
 	pciDeviceDir    string
 	dpdkDriver      string
 	kernelVfDrivers []string
 	maxDevices      int
+	// flagMaxDevices is the maximum number of devices before the config
+	// file is applied.
+	flagMaxDevices int
+
+	// mutex protects the settings the config file can change.
+	mutex sync.RWMutex
 }
 
 // NewDevicePlugin returns new instance of vfio based QAT plugin.
@@ -139,23 +116,35 @@ func NewDevicePlugin(maxDevices int, kernelVfDrivers string, dpdkDriver string,
 		}
 	}
 
-	allocationPolicyFunc := getAllocationPolicy(preferredAllocationPolicy)
-	if allocationPolicyFunc == nil {
+	policy := getAllocationPolicy(preferredAllocationPolicy)
+	if policy == nil {
 		return nil, errors.Errorf("wrong allocation policy: %s", preferredAllocationPolicy)
 	}
 
-	return newDevicePlugin(pciDriverDirectory, pciDeviceDirectory, maxDevices, kernelDrivers, dpdkDriver, allocationPolicyFunc), nil
+	return newDevicePlugin(pciDriverDirectory, pciDeviceDirectory, maxDevices, kernelDrivers, dpdkDriver, policy), nil
 }
 
-// getAllocationPolicy returns a func that fits the policy given as a parameter. It returns nonePolicy when the flag is not set, and it returns nil when the policy is not valid value.
-func getAllocationPolicy(preferredAllocationPolicy string) preferredAllocationPolicyFunc {
-	switch {
-	case !isFlagSet("allocation-policy"):
+// getAllocationPolicy returns the policy given as a parameter. It returns nonePolicy when the flag is not set, and it returns nil when the policy is not valid value.
+func getAllocationPolicy(preferredAllocationPolicy string) *dpapi.AllocationPolicy {
+	if !isFlagSet("allocation-policy") {
 		return nonePolicy
-	case preferredAllocationPolicy == "packed":
+	}
+
+	return allocationPolicyByName(preferredAllocationPolicy)
+}
+
+// allocationPolicyByName returns the policy of the given name, or nil when
+// the name is not valid.
+func allocationPolicyByName(name string) *dpapi.AllocationPolicy {
+	switch name {
+	case "packed":
 		return packedPolicy
-	case preferredAllocationPolicy == "balanced":
+	case "balanced":
 		return balancedPolicy
+	case "topology":
+		return dpapi.NewTopologyPolicy(func(id string) string {
+			return filepath.Join(pciDeviceDirectory, id)
+		})
 	default:
 		return nil
 	}
@@ -174,16 +163,18 @@ func isFlagSet(name string) bool {
 	return set
 }
 
-func newDevicePlugin(pciDriverDir, pciDeviceDir string, maxDevices int, kernelVfDrivers []string, dpdkDriver string, preferredAllocationPolicyFunc preferredAllocationPolicyFunc) *DevicePlugin {
+func newDevicePlugin(pciDriverDir, pciDeviceDir string, maxDevices int, kernelVfDrivers []string, dpdkDriver string, policy *dpapi.AllocationPolicy) *DevicePlugin {
 	return &DevicePlugin{
 		maxDevices:      maxDevices,
+		flagMaxDevices:  maxDevices,
 		pciDriverDir:    pciDriverDir,
 		pciDeviceDir:    pciDeviceDir,
 		kernelVfDrivers: kernelVfDrivers,
 		dpdkDriver:      dpdkDriver,
-		scanTicker:      time.NewTicker(scanPeriod),
+		scanWatcher:     dpapi.NewDeviceWatcher(dpapi.WithSubsystems("pci", "vfio", "uio"), dpapi.WithWatchPaths(vfioDevicePath, filepath.Dir(vfioDevicePath))),
 		scanDone:        make(chan bool, 1),
-		policy:          preferredAllocationPolicyFunc,
+		policy:          policy,
+		flagPolicy:      policy,
 	}
 }
 
@@ -204,9 +195,18 @@ func (dp *DevicePlugin) setupDeviceIDs() error {
 	return nil
 }
 
+// StopScan makes Scan() return. It implements dpapi.ScanStopper.
+func (dp *DevicePlugin) StopScan() {
+	select {
+	case dp.scanDone <- true:
+	default:
+	}
+}
+
 // Scan implements Scanner interface for vfio based QAT plugin.
 func (dp *DevicePlugin) Scan(notifier dpapi.Notifier) error {
-	defer dp.scanTicker.Stop()
+	dp.scanWatcher.Start()
+	defer dp.scanWatcher.Stop()
 
 	if err := dp.setupDeviceIDs(); err != nil {
 		return err
@@ -223,34 +223,16 @@ func (dp *DevicePlugin) Scan(notifier dpapi.Notifier) error {
 		select {
 		case <-dp.scanDone:
 			return nil
-		case <-dp.scanTicker.C:
+		case <-dp.scanWatcher.C():
 		}
 	}
 }
 
 // Implement the PreferredAllocator interface.
 func (dp *DevicePlugin) GetPreferredAllocation(rqt *pluginapi.PreferredAllocationRequest) (*pluginapi.PreferredAllocationResponse, error) {
-	response := &pluginapi.PreferredAllocationResponse{}
+	_, policy := dp.settings()
 
-	for _, req := range rqt.ContainerRequests {
-		// Add a security check here. This should never happen unless there occurs error in kubelet device plugin manager.
-		if req.AllocationSize > int32(len(req.AvailableDeviceIDs)) {
-			var err = errors.Errorf("AllocationSize (%d) is greater than the number of available device IDs (%d)", req.AllocationSize, len(req.AvailableDeviceIDs))
-			return nil, err
-		}
-
-		IDs := dp.policy(req)
-		klog.V(3).Infof("AvailableDeviceIDs: %q", req.AvailableDeviceIDs)
-		klog.V(3).Infof("AllocatedDeviceIDs: %q", IDs)
-
-		resp := &pluginapi.ContainerPreferredAllocationResponse{
-			DeviceIDs: IDs,
-		}
-
-		response.ContainerResponses = append(response.ContainerResponses, resp)
-	}
-
-	return response, nil
+	return policy.SelectPreferred(rqt, nil)
 }
 
 func (dp *DevicePlugin) getDpdkDevice(vfBdf string) (string, error) {
@@ -362,14 +344,14 @@ func (dp *DevicePlugin) getDpdkMounts(dpdkDeviceName string) []pluginapi.Mount {
 func readDeviceConfiguration(pfDev string) string {
 	qatState, err := os.ReadFile(filepath.Join(pfDev, "qat/state"))
 	if err != nil && !errors.Is(err, os.ErrNotExist) {
-		klog.Warningf("failed to read device state for %s: %q", filepath.Base(pfDev), err)
+		klog.ErrorS(err, "Failed to read device state", dpapi.LogKeyBDF, filepath.Base(pfDev))
 		return defaultCapabilities
 	}
 
 	if err == nil && strings.TrimSpace(string(qatState)) == "up" {
 		qatCfgServices, err2 := os.ReadFile(filepath.Join(pfDev, "qat/cfg_services"))
 		if err2 != nil && !errors.Is(err2, os.ErrNotExist) {
-			klog.Warningf("failed to read services config for %s: %q", filepath.Base(pfDev), err2)
+			klog.ErrorS(err2, "Failed to read services config", dpapi.LogKeyBDF, filepath.Base(pfDev))
 			return defaultCapabilities
 		}
 
@@ -387,20 +369,27 @@ func readDeviceConfiguration(pfDev string) string {
 
 	devCfg, err := ini.LoadSources(lOpts, devCfgPath)
 	if err != nil {
-		klog.Warningf("failed to read dev_cfg for %s: %q", filepath.Base(pfDev), err)
+		klog.ErrorS(err, "Failed to read dev_cfg", dpapi.LogKeyBDF, filepath.Base(pfDev))
 		return defaultCapabilities
 	}
 
 	return devCfg.Section("GENERAL").Key("ServicesEnabled").String()
 }
 
-func getDeviceHealthiness(device string, lookup map[string]string) string {
-	healthiness := pluginapi.Healthy
+func getDeviceHealthiness(device string, lookup map[string]dpapi.HealthResult) dpapi.HealthResult {
+	result := dpapi.HealthResult{
+		Timestamp: time.Now(),
+		State:     pluginapi.Healthy,
+		Source:    "heartbeat",
+	}
 
 	pfDev, err := filepath.EvalSymlinks(filepath.Join(device, "physfn"))
 	if err != nil {
-		klog.Warningf("failed to get PF device ID for %s: %q", filepath.Base(device), err)
-		return healthiness
+		klog.ErrorS(err, "Failed to get PF device ID", dpapi.LogKeyBDF, filepath.Base(device))
+
+		result.Reason = "PF not found"
+
+		return result
 	}
 
 	// VFs share one PF, so all the VFs should return the same result.
@@ -416,13 +405,41 @@ func getDeviceHealthiness(device string, lookup map[string]string) string {
 
 	// If status reads "-1", the device is considered bad:
 	// https://github.com/torvalds/linux/blob/v6.6-rc5/Documentation/ABI/testing/debugfs-driver-qat
-	if data, err := os.ReadFile(hbStatusFile); err == nil && strings.Split(string(data), "\n")[0] == "-1" {
-		healthiness = pluginapi.Unhealthy
+	data, err := os.ReadFile(hbStatusFile)
+
+	switch {
+	case err != nil:
+		result.Reason = "heartbeat status not available"
+	case strings.Split(string(data), "\n")[0] == "-1":
+		result.State = pluginapi.Unhealthy
+		result.Reason = fmt.Sprintf("PF %s heartbeat failed", filepath.Base(pfDev))
 	}
 
-	lookup[pfDev] = healthiness
+	lookup[pfDev] = result
+
+	return result
+}
+
+// HealthCheckInterval implements the HealthChecker interface.
+func (dp *DevicePlugin) HealthCheckInterval() time.Duration {
+	return healthCheckPeriod
+}
+
+// CheckHealth implements the HealthChecker interface. The VFs get the
+// heartbeat status of their PF.
+func (dp *DevicePlugin) CheckHealth(devices map[string][]string) map[string]map[string]dpapi.HealthResult {
+	results := make(map[string]map[string]dpapi.HealthResult, len(devices))
+	pfHealthLookup := map[string]dpapi.HealthResult{}
+
+	for devType, vfBdfs := range devices {
+		results[devType] = make(map[string]dpapi.HealthResult, len(vfBdfs))
+
+		for _, vfBdf := range vfBdfs {
+			results[devType][vfBdf] = getDeviceHealthiness(filepath.Join(dp.pciDeviceDir, vfBdf), pfHealthLookup)
+		}
+	}
 
-	return healthiness
+	return results
 }
 
 func getDeviceCapabilities(device string) (string, error) {
@@ -444,7 +461,7 @@ func getDeviceCapabilities(device string) (string, error) {
 
 	pfDev, err := filepath.EvalSymlinks(filepath.Join(device, "physfn"))
 	if err != nil {
-		klog.Warningf("failed to get PF device ID for %s: %q", filepath.Base(device), err)
+		klog.ErrorS(err, "Failed to get PF device ID", dpapi.LogKeyBDF, filepath.Base(device))
 		return defaultCapabilities, nil
 	}
 
@@ -517,7 +534,7 @@ func (dp *DevicePlugin) isValidVfDeviceID(vfDevID string) bool {
 		}
 	}
 
-	klog.V(3).Infof("device ID %s is not a QAT device or not enabled by kernelVfDrivers.", vfDevID)
+	klog.V(3).InfoS("Device ID is not a QAT device or not enabled by kernelVfDrivers", "deviceID", vfDevID)
 
 	return false
 }
@@ -545,14 +562,14 @@ func getPciDevicesWithPattern(pattern string) (pciDevices []string) {
 
 	devs, err := filepath.Glob(pattern)
 	if err != nil {
-		klog.Warningf("bad pattern: %s", pattern)
+		klog.ErrorS(err, "Bad pattern", "pattern", pattern)
 		return
 	}
 
 	for _, devBdf := range devs {
 		targetDev, err := filepath.EvalSymlinks(devBdf)
 		if err != nil {
-			klog.Warningf("unable to evaluate symlink: %s", devBdf)
+			klog.ErrorS(err, "Unable to evaluate symlink", "path", devBdf)
 			continue
 		}
 
@@ -563,6 +580,7 @@ func getPciDevicesWithPattern(pattern string) (pciDevices []string) {
 }
 
 func (dp *DevicePlugin) getVfDevices() []string {
+	maxDevices, _ := dp.settings()
 	qatPfDevices := make([]string, 0)
 	qatVfDevices := make([]string, 0)
 
@@ -580,8 +598,8 @@ func (dp *DevicePlugin) getVfDevices() []string {
 	}
 
 	if len(qatPfDevices) > 0 {
-		if len(qatVfDevices) >= dp.maxDevices {
-			return qatVfDevices[:dp.maxDevices]
+		if len(qatVfDevices) >= maxDevices {
+			return qatVfDevices[:maxDevices]
 		}
 
 		return qatVfDevices
@@ -592,7 +610,7 @@ func (dp *DevicePlugin) getVfDevices() []string {
 	for _, pciDev := range getPciDevicesWithPattern(pattern) {
 		devID, err := getDeviceID(pciDev)
 		if err != nil {
-			klog.Warningf("unable to read device id for device %s: %q", filepath.Base(pciDev), err)
+			klog.ErrorS(err, "Unable to read device id", dpapi.LogKeyBDF, filepath.Base(pciDev))
 			continue
 		}
 
@@ -601,8 +619,8 @@ func (dp *DevicePlugin) getVfDevices() []string {
 		}
 	}
 
-	if len(qatVfDevices) >= dp.maxDevices {
-		return qatVfDevices[:dp.maxDevices]
+	if len(qatVfDevices) >= maxDevices {
+		return qatVfDevices[:maxDevices]
 	}
 
 	return qatVfDevices
@@ -613,7 +631,7 @@ func getCurrentDriver(device string) string {
 
 	driver, err := filepath.EvalSymlinks(symlink)
 	if err != nil {
-		klog.Infof("no driver bound to device %q", filepath.Base(device))
+		klog.InfoS("No driver bound to device", dpapi.LogKeyBDF, filepath.Base(device))
 		return ""
 	}
 
@@ -624,7 +642,7 @@ func (dp *DevicePlugin) scan() (dpapi.DeviceTree, error) {
 	devTree := dpapi.NewDeviceTree()
 	n := 0
 
-	pfHealthLookup := map[string]string{}
+	pfHealthLookup := map[string]dpapi.HealthResult{}
 
 	for _, vfDevice := range dp.getVfDevices() {
 		vfBdf := filepath.Base(vfDevice)
@@ -653,9 +671,9 @@ func (dp *DevicePlugin) scan() (dpapi.DeviceTree, error) {
 			return nil, err
 		}
 
-		healthiness := getDeviceHealthiness(vfDevice, pfHealthLookup)
+		healthiness := getDeviceHealthiness(vfDevice, pfHealthLookup).State
 
-		klog.V(1).Infof("Device %s with %s capabilities found (%s)", vfBdf, cap, healthiness)
+		klog.V(1).InfoS("Device found", dpapi.LogKeyBDF, vfBdf, "capabilities", cap, dpapi.LogKeyHealth, healthiness)
 
 		n = n + 1
 		envs := map[string]string{
@@ -663,6 +681,11 @@ func (dp *DevicePlugin) scan() (dpapi.DeviceTree, error) {
 		}
 
 		devinfo := dpapi.NewDeviceInfo(healthiness, dp.getDpdkDeviceSpecs(dpdkDeviceName), dp.getDpdkMounts(dpdkDeviceName), envs, nil, nil)
+		devinfo.SetSysfsDevice(vfDevice)
+		devinfo.SetAttributes(map[string]string{
+			"driver":       dp.dpdkDriver,
+			"capabilities": cap,
+		})
 
 		devTree.AddDevice(cap, vfBdf, devinfo)
 	}
@@ -27,6 +27,7 @@ import (
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 
 	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+	dptesting "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin/testing"
 )
 
 func init() {
@@ -678,6 +679,26 @@ func TestScan(t *testing.T) {
 				t.Errorf("expected %d, but got %d unhealthy devices", tt.expectedUnhealthyNum, unhealtyNum)
 			}
 
+			devices := map[string][]string{}
+			for devType, resource := range fN.tree {
+				for id := range resource {
+					devices[devType] = append(devices[devType], id)
+				}
+			}
+
+			unhealthyNum := 0
+			for _, results := range dp.CheckHealth(devices) {
+				for _, result := range results {
+					if result.State == pluginapi.Unhealthy {
+						unhealthyNum++
+					}
+				}
+			}
+
+			if unhealthyNum != tt.expectedUnhealthyNum {
+				t.Errorf("expected %d, but got %d unhealthy devices from health check", tt.expectedUnhealthyNum, unhealthyNum)
+			}
+
 			if err = os.RemoveAll(tmpdir); err != nil {
 				t.Fatal(err)
 			}
@@ -742,3 +763,54 @@ func TestPostAllocate(t *testing.T) {
 		}
 	}
 }
+
+func TestConformance(t *testing.T) {
+	root := t.TempDir()
+
+	err := createTestFiles(root,
+		[]string{
+			"sys/bus/pci/drivers/4xxx",
+			"sys/bus/pci/drivers/vfio-pci",
+			"sys/devices/pci0000:02/0000:02:00.0/qat",
+			"sys/kernel/debug/qat_4xxx_0000:02:00.0/heartbeat",
+			"sys/bus/pci/devices/0000:02:00.1",
+			"sys/bus/pci/devices/0000:02:00.2",
+		},
+		map[string][]byte{
+			"sys/devices/pci0000:02/0000:02:00.0/device":              []byte("0x4940"),
+			"sys/devices/pci0000:02/0000:02:00.0/qat/state":           []byte("up"),
+			"sys/devices/pci0000:02/0000:02:00.0/qat/cfg_services":    []byte("sym;asym"),
+			"sys/bus/pci/devices/0000:02:00.1/device":                 []byte("0x4941"),
+			"sys/bus/pci/devices/0000:02:00.2/device":                 []byte("0x4941"),
+			"sys/kernel/debug/qat_4xxx_0000:02:00.0/heartbeat/status": []byte("0"),
+		},
+		map[string]string{
+			"sys/bus/pci/devices/0000:02:00.1/iommu_group": "sys/kernel/iommu_groups/vfiotestfile1",
+			"sys/bus/pci/devices/0000:02:00.1/physfn":      "sys/devices/pci0000:02/0000:02:00.0",
+			"sys/bus/pci/devices/0000:02:00.2/iommu_group": "sys/kernel/iommu_groups/vfiotestfile2",
+			"sys/bus/pci/devices/0000:02:00.2/physfn":      "sys/devices/pci0000:02/0000:02:00.0",
+			"sys/bus/pci/drivers/4xxx/0000:02:00.0":        "sys/devices/pci0000:02/0000:02:00.0",
+			"sys/bus/pci/devices/0000:02:00.0":             "sys/devices/pci0000:02/0000:02:00.0",
+			"sys/devices/pci0000:02/0000:02:00.0/virtfn0":  "sys/bus/pci/devices/0000:02:00.1",
+			"sys/devices/pci0000:02/0000:02:00.0/virtfn1":  "sys/bus/pci/devices/0000:02:00.2",
+			"sys/devices/pci0000:02/0000:02:00.0/driver":   "sys/bus/pci/drivers/4xxx",
+		})
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+
+	dp := newDevicePlugin(
+		path.Join(root, "sys/bus/pci/drivers"),
+		path.Join(root, "sys/bus/pci/devices"),
+		2,
+		[]string{"4xxxvf"},
+		"vfio-pci",
+		packedPolicy,
+	)
+
+	dptesting.Run(t, dptesting.Config{
+		Plugin:      dp,
+		Namespace:   "qat.intel.com",
+		DeviceTypes: []string{"cy"},
+	})
+}
@@ -24,7 +24,6 @@ import (
 	"path/filepath"
 	"regexp"
 	"strings"
-	"time"
 
 	"github.com/go-ini/ini"
 	"github.com/pkg/errors"
@@ -119,8 +118,10 @@ func getDevTree(sysfs string, qatDevs []device, config map[string]section) (dpap
 
 // DevicePlugin represents QAT plugin exploiting kernel driver.
 type DevicePlugin struct {
-	execer    utilsexec.Interface
-	configDir string
+	execer      utilsexec.Interface
+	scanWatcher *dpapi.DeviceWatcher
+	scanDone    chan bool
+	configDir   string
 }
 
 // NewDevicePlugin returns new instance of kernel based QAT plugin.
@@ -130,8 +131,10 @@ func NewDevicePlugin() *DevicePlugin {
 
 func newDevicePlugin(configDir string, execer utilsexec.Interface) *DevicePlugin {
 	return &DevicePlugin{
-		execer:    execer,
-		configDir: configDir,
+		execer:      execer,
+		scanWatcher: dpapi.NewDeviceWatcher(dpapi.WithSubsystems("pci", "uio"), dpapi.WithWatchPaths("/dev", configDir)),
+		scanDone:    make(chan bool, 1),
+		configDir:   configDir,
 	}
 }
 
@@ -162,7 +165,7 @@ func (dp *DevicePlugin) getOnlineDevices(iommuOn bool) ([]device, error) {
 
 		// Ignore devices which are on the denylist.
 		if _, ok := devicesDenyList[matches[1]]; ok {
-			klog.Warning("skip denylisted device ", matches[1])
+			klog.InfoS("Skip denylisted device", "devtype", matches[1])
 			continue
 		}
 
@@ -176,7 +179,7 @@ func (dp *DevicePlugin) getOnlineDevices(iommuOn bool) ([]device, error) {
 			devtype: matches[1],
 			bsf:     fmt.Sprintf("%s%s", matches[3], matches[4]),
 		})
-		klog.V(4).Info("New online device", devices[len(devices)-1])
+		klog.V(4).InfoS("New online device", dpapi.LogKeyDeviceID, devices[len(devices)-1].id, "devtype", devices[len(devices)-1].devtype, dpapi.LogKeyBDF, devices[len(devices)-1].bsf)
 	}
 
 	return devices, nil
@@ -188,7 +191,7 @@ func getUIODeviceListPath(sysfs, devtype, bsf string) string {
 
 func getUIODevices(sysfs, devtype, bsf string) ([]string, error) {
 	sysfsDir := getUIODeviceListPath(sysfs, devtype, bsf)
-	klog.V(4).Info("Path to uio devices:", sysfsDir)
+	klog.V(4).InfoS("Path to uio devices", "path", sysfsDir)
 
 	devFiles, err := os.ReadDir(sysfsDir)
 	if err != nil {
@@ -196,7 +199,7 @@ func getUIODevices(sysfs, devtype, bsf string) ([]string, error) {
 	}
 
 	if len(devFiles) == 0 {
-		klog.Warning("no uio devices listed in", sysfsDir)
+		klog.InfoS("No uio devices listed", "path", sysfsDir)
 	}
 
 	devices := []string{}
@@ -225,7 +228,7 @@ func (dp *DevicePlugin) parseConfigs(devices []device) (map[string]section, erro
 				continue
 			}
 
-			klog.V(4).Info(section.Name())
+			klog.V(4).InfoS("Parsing config section", dpapi.LogKeyDeviceID, dev.id, "section", section.Name())
 
 			if err := drvConfig.update(dev.id, section); err != nil {
 				return nil, err
@@ -322,8 +325,19 @@ func getIOMMUStatus() (bool, error) {
 	return false, nil
 }
 
+// StopScan makes Scan() return. It implements dpapi.ScanStopper.
+func (dp *DevicePlugin) StopScan() {
+	select {
+	case dp.scanDone <- true:
+	default:
+	}
+}
+
 // Scan implements Scanner interface for kernel based QAT plugin.
 func (dp *DevicePlugin) Scan(notifier dpapi.Notifier) error {
+	dp.scanWatcher.Start()
+	defer dp.scanWatcher.Stop()
+
 	for {
 		iommuOn, err := getIOMMUStatus()
 		if err != nil {
@@ -347,7 +361,11 @@ func (dp *DevicePlugin) Scan(notifier dpapi.Notifier) error {
 
 		notifier.Notify(devTree)
 
-		time.Sleep(5 * time.Second)
+		select {
+		case <-dp.scanDone:
+			return nil
+		case <-dp.scanWatcher.C():
+		}
 	}
 }
 
@@ -15,12 +15,14 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
 
 	"github.com/pkg/errors"
 
+	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/pluginutils"
 	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/qat_plugin/dpdkdrv"
 	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/qat_plugin/kerneldrv"
 	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
@@ -41,10 +43,20 @@ func main() {
 
 	dpdkDriver := flag.String("dpdk-driver", "vfio-pci", "DPDK Device driver for configuring the QAT device")
 	kernelVfDrivers := flag.String("kernel-vf-drivers", "4xxxvf,420xxvf", "Comma separated VF Device Driver of the QuickAssist Devices in the system. Devices supported: DH895xCC, C62x, C3xxx, C4xxx, 4xxx, 420xxx, and D15xx")
-	preferredAllocationPolicy := flag.String("allocation-policy", "", "Modes of allocating QAT devices: balanced and packed")
+	preferredAllocationPolicy := flag.String("allocation-policy", "", "Modes of allocating QAT devices: balanced, packed and topology")
 	maxNumDevices := flag.Int("max-num-devices", 64, "maximum number of QAT devices to be provided to the QuickAssist device plugin")
+	managerFlags := pluginutils.AddManagerFlags(flag.CommandLine)
 	flag.Parse()
 
+	if err := managerFlags.SetupLogging(); err != nil {
+		klog.Fatalf("%+v", err)
+	}
+
+	managerOpts, err := managerFlags.ManagerOptions()
+	if err != nil {
+		klog.Fatalf("%+v", err)
+	}
+
 	switch *mode {
 	case "dpdk":
 		plugin, err = dpdkdrv.NewDevicePlugin(*maxNumDevices, *kernelVfDrivers, *dpdkDriver, *preferredAllocationPolicy)
@@ -61,7 +73,9 @@ func main() {
 
 	klog.V(1).Infof("QAT device plugin started in '%s' mode", *mode)
 
-	manager := deviceplugin.NewManager(namespace, plugin)
+	manager := deviceplugin.NewManager(namespace, plugin, managerOpts...)
 
-	manager.Run()
+	if err := manager.Run(context.Background()); err != nil {
+		klog.Fatalf("%+v", err)
+	}
 }
@@ -15,6 +15,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"os"
@@ -22,6 +23,7 @@ import (
 	"runtime"
 	"strconv"
 
+	"github.com/intel/intel-device-plugins-for-kubernetes/cmd/internal/pluginutils"
 	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
 	"k8s.io/klog/v2"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
@@ -53,6 +55,14 @@ func newDevicePlugin(devfsDir string, nEnclave, nProvision uint) *devicePlugin {
 	}
 }
 
+// StopScan makes Scan() return. It implements dpapi.ScanStopper.
+func (dp *devicePlugin) StopScan() {
+	select {
+	case dp.scanDone <- true:
+	default:
+	}
+}
+
 func (dp *devicePlugin) Scan(notifier dpapi.Notifier) error {
 	devTree, err := dp.scan()
 	if err != nil {
@@ -75,12 +85,12 @@ func (dp *devicePlugin) scan() (dpapi.DeviceTree, error) {
 	sgxProvisionPath := path.Join(dp.devfsDir, "sgx_provision")
 
 	if _, err := os.Stat(sgxEnclavePath); err != nil {
-		klog.Error("No SGX enclave file available: ", err)
+		klog.ErrorS(err, "No SGX enclave file available")
 		return devTree, nil
 	}
 
 	if _, err := os.Stat(sgxProvisionPath); err != nil {
-		klog.Error("No SGX provision file available: ", err)
+		klog.ErrorS(err, "No SGX provision file available")
 		return devTree, nil
 	}
 
@@ -109,7 +119,7 @@ func getDefaultPodCount(nCPUs uint) uint {
 	if envPodsPerCore != "" {
 		tmp, err := strconv.ParseUint(envPodsPerCore, 10, 32)
 		if err != nil {
-			klog.Errorf("Error: failed to parse %s value as uint, using default value.", podsPerCoreEnvVariable)
+			klog.ErrorS(err, "Failed to parse value as uint, using default value", "variable", podsPerCoreEnvVariable)
 		} else {
 			return uint(tmp) * nCPUs
 		}
@@ -125,11 +135,23 @@ func main() {
 
 	flag.UintVar(&enclaveLimit, "enclave-limit", podCount, "Number of \"enclave\" resources")
 	flag.UintVar(&provisionLimit, "provision-limit", podCount, "Number of \"provision\" resources")
+	managerFlags := pluginutils.AddManagerFlags(flag.CommandLine)
 	flag.Parse()
 
-	klog.V(4).Infof("SGX device plugin started with %d \"%s/enclave\" resources and %d \"%s/provision\" resources.", enclaveLimit, namespace, provisionLimit, namespace)
+	if err := managerFlags.SetupLogging(); err != nil {
+		klog.Fatalf("%+v", err)
+	}
+
+	managerOpts, err := managerFlags.ManagerOptions()
+	if err != nil {
+		klog.Fatalf("%+v", err)
+	}
+
+	klog.V(4).InfoS("SGX device plugin started", "enclaveLimit", enclaveLimit, "provisionLimit", provisionLimit, dpapi.LogKeyResource, namespace)
 
 	plugin := newDevicePlugin(devicePath, enclaveLimit, provisionLimit)
-	manager := dpapi.NewManager(namespace, plugin)
-	manager.Run()
+	manager := dpapi.NewManager(namespace, plugin, managerOpts...)
+	if err := manager.Run(context.Background()); err != nil {
+		klog.Fatalf("%+v", err)
+	}
 }
@@ -21,6 +21,7 @@ import (
 	"testing"
 
 	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+	dptesting "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin/testing"
 )
 
 func init() {
@@ -178,3 +179,19 @@ func TestScan(t *testing.T) {
 		})
 	}
 }
+
+func TestConformance(t *testing.T) {
+	devfs := t.TempDir()
+
+	for _, name := range []string{"sgx_enclave", "sgx_provision"} {
+		if err := os.WriteFile(path.Join(devfs, name), []byte{}, 0600); err != nil {
+			t.Fatalf("Failed to create fake device file: %+v", err)
+		}
+	}
+
+	dptesting.Run(t, dptesting.Config{
+		Plugin:      newDevicePlugin(devfs, 2, 1),
+		Namespace:   namespace,
+		DeviceTypes: []string{deviceTypeEnclave, deviceTypeProvision},
+	})
+}
@@ -51,6 +51,12 @@ type GpuDevicePluginSpec struct {
 	// +kubebuilder:validation:Minimum=0
 	LogLevel int `json:"logLevel,omitempty"`
 
+	// PublishInventory publishes the devices of the nodes as NodeAcceleratorInventory objects.
+	PublishInventory bool `json:"publishInventory,omitempty"`
+
+	// ReportNodeCondition maintains a node condition summarizing the health of the devices.
+	ReportNodeCondition bool `json:"reportNodeCondition,omitempty"`
+
 	// ResourceManager handles the fractional resource management for multi-GPU nodes. Enable only for clusters with GPU Aware Scheduling.
 	ResourceManager bool `json:"resourceManager,omitempty"`
 
@@ -47,6 +47,12 @@ type IaaDevicePluginSpec struct {
 	// LogLevel sets the plugin's log level.
 	// +kubebuilder:validation:Minimum=0
 	LogLevel int `json:"logLevel,omitempty"`
+
+	// PublishInventory publishes the devices of the nodes as NodeAcceleratorInventory objects.
+	PublishInventory bool `json:"publishInventory,omitempty"`
+
+	// ReportNodeCondition maintains a node condition summarizing the health of the devices.
+	ReportNodeCondition bool `json:"reportNodeCondition,omitempty"`
 }
 
 // IaaDevicePluginStatus defines the observed state of IaaDevicePlugin.
@@ -0,0 +1,93 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// AcceleratorDevice describes a device advertised by a device plugin.
+type AcceleratorDevice struct {
+	// Attributes are the details of the device supplied by the plugin, e.g.
+	// the model, the firmware version or the number of tiles.
+	// +optional
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// NUMANode is the NUMA node of the device.
+	// +optional
+	NUMANode *int `json:"numaNode,omitempty"`
+	// Resource is the extended resource of the device, e.g. "gpu.intel.com/i915".
+	Resource string `json:"resource"`
+	// ID is the device ID advertised to kubelet.
+	ID string `json:"id"`
+	// Health is the health of the device.
+	// +kubebuilder:validation:Enum=Healthy;Unhealthy
+	Health string `json:"health"`
+	// HealthReason explains the health of the device, as reported by the
+	// last health check.
+	// +optional
+	HealthReason string `json:"healthReason,omitempty"`
+	// DeviceNodes are the host device nodes of the device.
+	// +optional
+	DeviceNodes []string `json:"deviceNodes,omitempty"`
+}
+
+// NodeAcceleratorInventorySpec contains the devices of a device plugin on a node.
+type NodeAcceleratorInventorySpec struct {
+	// NodeName is the name of the node.
+	NodeName string `json:"nodeName"`
+	// ResourceNamespace is the resource namespace of the device plugin,
+	// e.g. "gpu.intel.com".
+	ResourceNamespace string `json:"resourceNamespace"`
+	// Devices are the devices advertised by the device plugin, sorted by
+	// the resource and the ID.
+	// +optional
+	Devices []AcceleratorDevice `json:"devices,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:path=nodeacceleratorinventories,scope=Cluster,shortName=nai
+// +kubebuilder:printcolumn:name="Node",type=string,JSONPath=`.spec.nodeName`
+// +kubebuilder:printcolumn:name="Resource Namespace",type=string,JSONPath=`.spec.resourceNamespace`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+// +operator-sdk:csv:customresourcedefinitions:displayName="Node Accelerator Inventory"
+
+// NodeAcceleratorInventory is the inventory of the devices a device plugin
+// advertises on a node. The device plugins write one per node when enabled,
+// and the object is removed with the node.
+type NodeAcceleratorInventory struct {
+	Spec NodeAcceleratorInventorySpec `json:"spec,omitempty"`
+
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// NodeAcceleratorInventoryList contains a list of NodeAcceleratorInventory.
+type NodeAcceleratorInventoryList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []NodeAcceleratorInventory `json:"items"`
+}
+
+// InventoryName returns the name of the NodeAcceleratorInventory of a device
+// plugin on a node, e.g. "node1.gpu.intel.com".
+func InventoryName(nodeName, resourceNamespace string) string {
+	return nodeName + "." + resourceNamespace
+}
+
+func init() {
+	SchemeBuilder.Register(&NodeAcceleratorInventory{}, &NodeAcceleratorInventoryList{})
+}
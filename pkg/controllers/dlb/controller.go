@@ -38,6 +38,10 @@ const ownerKey = ".metadata.controller.dlb"
 
 var defaultNodeSelector map[string]string = deployments.DLBPluginDaemonSet().Spec.Template.Spec.NodeSelector
 
+// defaultAutomount tells if the device plugin pods mount a service account
+// token without the reporting.
+var defaultAutomount = deployments.DLBPluginDaemonSet().Spec.Template.Spec.AutomountServiceAccountToken
+
 // +kubebuilder:rbac:groups=deviceplugin.intel.com,resources=dlbdeviceplugins,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=deviceplugin.intel.com,resources=dlbdeviceplugins/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=deviceplugin.intel.com,resources=dlbdeviceplugins/finalizers,verbs=update
@@ -45,6 +49,8 @@ var defaultNodeSelector map[string]string = deployments.DLBPluginDaemonSet().Spe
 // SetupReconciler creates a new reconciler for DlbDevicePlugin objects.
 func SetupReconciler(mgr ctrl.Manager, namespace string, withWebhook bool) error {
 	c := &controller{scheme: mgr.GetScheme(), ns: namespace}
+	c.ReporterObjectsFactory = controllers.ReporterObjectsFactory{Name: "dlb", Namespace: namespace, Reports: reports}
+
 	if err := controllers.SetupWithManager(mgr, c, devicepluginv1.GroupVersion.String(), "DlbDevicePlugin", ownerKey); err != nil {
 		return err
 	}
@@ -57,7 +63,7 @@ func SetupReconciler(mgr ctrl.Manager, namespace string, withWebhook bool) error
 }
 
 type controller struct {
-	controllers.DefaultServiceAccountFactory
+	controllers.ReporterObjectsFactory
 	scheme *runtime.Scheme
 	ns     string
 }
@@ -95,6 +101,7 @@ func (c *controller) NewDaemonSet(rawObj client.Object) *apps.DaemonSet {
 	ds.ObjectMeta.Namespace = c.ns
 
 	ds.Spec.Template.Spec.Containers[0].Args = getPodArgs(devicePlugin)
+	c.SetServiceAccount(&ds.Spec.Template.Spec, reporting(&devicePlugin.Spec), defaultAutomount)
 	ds.Spec.Template.Spec.Containers[0].Image = devicePlugin.Spec.Image
 
 	return ds
@@ -139,6 +146,10 @@ func (c *controller) UpdateDaemonSet(rawObj client.Object, ds *apps.DaemonSet) (
 		updated = true
 	}
 
+	if c.SetServiceAccount(&ds.Spec.Template.Spec, reporting(&dp.Spec), defaultAutomount) {
+		updated = true
+	}
+
 	if controllers.HasTolerationsChanged(ds.Spec.Template.Spec.Tolerations, dp.Spec.Tolerations) {
 		ds.Spec.Template.Spec.Tolerations = dp.Spec.Tolerations
 		updated = true
@@ -246,5 +257,30 @@ func getPodArgs(gdp *devicepluginv1.DlbDevicePlugin) []string {
 	args := make([]string, 0, 4)
 	args = append(args, "-v", strconv.Itoa(gdp.Spec.LogLevel))
 
+	args = append(args, controllers.ReporterArgs(gdp.Spec.PublishInventory, gdp.Spec.ReportNodeCondition)...)
+
 	return args
 }
+
+// reports tells if any DlbDevicePlugin object enables the reporting.
+func reports(ctx context.Context, c client.Client) bool {
+	var list devicepluginv1.DlbDevicePluginList
+
+	if err := c.List(ctx, &list); err != nil {
+		return false
+	}
+
+	for _, cr := range list.Items {
+		if reporting(&cr.Spec) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reporting tells if the device plugin publishes the inventory or reports
+// the node condition.
+func reporting(spec *devicepluginv1.DlbDevicePluginSpec) bool {
+	return spec.PublishInventory || spec.ReportNodeCondition
+}
@@ -17,6 +17,7 @@ package dlb
 
 import (
 	"reflect"
+	"slices"
 	"testing"
 
 	apps "k8s.io/api/apps/v1"
@@ -28,6 +29,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	devicepluginv1 "github.com/intel/intel-device-plugins-for-kubernetes/pkg/apis/deviceplugin/v1"
+	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/controllers"
 )
 
 const appLabel = "intel-dlb-plugin"
@@ -36,6 +38,7 @@ func (c *controller) newDaemonSetExpected(rawObj client.Object) *apps.DaemonSet
 	devicePlugin := rawObj.(*devicepluginv1.DlbDevicePlugin)
 	yes := true
 	no := false
+	directoryOrCreate := v1.HostPathDirectoryOrCreate
 	maxUnavailable := intstr.FromInt(1)
 	maxSurge := intstr.FromInt(0)
 
@@ -118,6 +121,10 @@ func (c *controller) newDaemonSetExpected(rawObj client.Object) *apps.DaemonSet
 									Name:      "kubeletsockets",
 									MountPath: "/var/lib/kubelet/device-plugins",
 								},
+								{
+									Name:      "introspection",
+									MountPath: "/var/run/intel-device-plugins",
+								},
 							},
 						},
 					},
@@ -147,6 +154,15 @@ func (c *controller) newDaemonSetExpected(rawObj client.Object) *apps.DaemonSet
 								},
 							},
 						},
+						{
+							Name: "introspection",
+							VolumeSource: v1.VolumeSource{
+								HostPath: &v1.HostPathVolumeSource{
+									Path: "/var/run/intel-device-plugins",
+									Type: &directoryOrCreate,
+								},
+							},
+						},
 					},
 				},
 			},
@@ -170,3 +186,36 @@ func TestNewDaemonSetDLB(t *testing.T) {
 		t.Errorf("expected and actuall daemonsets differ: %+s", diff.ObjectGoPrintDiff(expected, actual))
 	}
 }
+
+func TestReportingDLB(t *testing.T) {
+	plugin := &devicepluginv1.DlbDevicePlugin{}
+	plugin.Name = "testing"
+	c := &controller{}
+	c.ReporterObjectsFactory = controllers.ReporterObjectsFactory{Name: "dlb"}
+
+	ds := c.NewDaemonSet(plugin)
+
+	plugin.Spec.PublishInventory = true
+
+	if !c.UpdateDaemonSet(plugin, ds) {
+		t.Fatal("daemonset not updated when the inventory is enabled")
+	}
+
+	spec := &ds.Spec.Template.Spec
+	if spec.ServiceAccountName != "dlb-reporter-sa" || !*spec.AutomountServiceAccountToken || !slices.Contains(spec.Containers[0].Args, "-publish-inventory") {
+		t.Errorf("unexpected service account %q and args %v", spec.ServiceAccountName, spec.Containers[0].Args)
+	}
+
+	plugin.Spec.PublishInventory = false
+	plugin.Spec.ReportNodeCondition = true
+
+	if !c.UpdateDaemonSet(plugin, ds) || spec.ServiceAccountName != "dlb-reporter-sa" || !slices.Contains(spec.Containers[0].Args, "-report-node-condition") {
+		t.Errorf("unexpected service account %q and args %v with the node condition", spec.ServiceAccountName, spec.Containers[0].Args)
+	}
+
+	plugin.Spec.ReportNodeCondition = false
+
+	if !c.UpdateDaemonSet(plugin, ds) || spec.ServiceAccountName != "" || *spec.AutomountServiceAccountToken {
+		t.Errorf("unexpected service account %q when the reporting is disabled", spec.ServiceAccountName)
+	}
+}
@@ -42,6 +42,10 @@ const (
 
 var defaultNodeSelector = deployments.DSAPluginDaemonSet().Spec.Template.Spec.NodeSelector
 
+// defaultAutomount tells if the device plugin pods mount a service account
+// token without the reporting.
+var defaultAutomount = deployments.DSAPluginDaemonSet().Spec.Template.Spec.AutomountServiceAccountToken
+
 // +kubebuilder:rbac:groups=deviceplugin.intel.com,resources=dsadeviceplugins,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=deviceplugin.intel.com,resources=dsadeviceplugins/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=deviceplugin.intel.com,resources=dsadeviceplugins/finalizers,verbs=update
@@ -49,6 +53,8 @@ var defaultNodeSelector = deployments.DSAPluginDaemonSet().Spec.Template.Spec.No
 // SetupReconciler creates a new reconciler for DsaDevicePlugin objects.
 func SetupReconciler(mgr ctrl.Manager, namespace string, withWebhook bool) error {
 	c := &controller{scheme: mgr.GetScheme(), ns: namespace}
+	c.ReporterObjectsFactory = controllers.ReporterObjectsFactory{Name: "dsa", Namespace: namespace, Reports: reports}
+
 	if err := controllers.SetupWithManager(mgr, c, devicepluginv1.GroupVersion.String(), "DsaDevicePlugin", ownerKey); err != nil {
 		return err
 	}
@@ -61,7 +67,7 @@ func SetupReconciler(mgr ctrl.Manager, namespace string, withWebhook bool) error
 }
 
 type controller struct {
-	controllers.DefaultServiceAccountFactory
+	controllers.ReporterObjectsFactory
 	scheme *runtime.Scheme
 	ns     string
 }
@@ -202,6 +208,7 @@ func (c *controller) NewDaemonSet(rawObj client.Object) *apps.DaemonSet {
 
 	daemonSet.ObjectMeta.Namespace = c.ns
 	daemonSet.Spec.Template.Spec.Containers[0].Args = getPodArgs(devicePlugin)
+	c.SetServiceAccount(&daemonSet.Spec.Template.Spec, reporting(&devicePlugin.Spec), defaultAutomount)
 	daemonSet.Spec.Template.Spec.Containers[0].Image = devicePlugin.Spec.Image
 
 	if devicePlugin.Spec.InitImage != "" {
@@ -275,6 +282,10 @@ func (c *controller) UpdateDaemonSet(rawObj client.Object, ds *apps.DaemonSet) (
 		updated = true
 	}
 
+	if c.SetServiceAccount(&ds.Spec.Template.Spec, reporting(&dp.Spec), defaultAutomount) {
+		updated = true
+	}
+
 	if controllers.HasTolerationsChanged(ds.Spec.Template.Spec.Tolerations, dp.Spec.Tolerations) {
 		ds.Spec.Template.Spec.Tolerations = dp.Spec.Tolerations
 		updated = true
@@ -324,5 +335,30 @@ func getPodArgs(gdp *devicepluginv1.DsaDevicePlugin) []string {
 		args = append(args, "-shared-dev-num", "1")
 	}
 
+	args = append(args, controllers.ReporterArgs(gdp.Spec.PublishInventory, gdp.Spec.ReportNodeCondition)...)
+
 	return args
 }
+
+// reports tells if any DsaDevicePlugin object enables the reporting.
+func reports(ctx context.Context, c client.Client) bool {
+	var list devicepluginv1.DsaDevicePluginList
+
+	if err := c.List(ctx, &list); err != nil {
+		return false
+	}
+
+	for _, cr := range list.Items {
+		if reporting(&cr.Spec) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reporting tells if the device plugin publishes the inventory or reports
+// the node condition.
+func reporting(spec *devicepluginv1.DsaDevicePluginSpec) bool {
+	return spec.PublishInventory || spec.ReportNodeCondition
+}
@@ -38,6 +38,7 @@ func (c *controller) newDaemonSetExpected(rawObj client.Object) *apps.DaemonSet
 
 	yes := true
 	no := false
+	directoryOrCreate := v1.HostPathDirectoryOrCreate
 	maxUnavailable := intstr.FromInt(1)
 	maxSurge := intstr.FromInt(0)
 
@@ -127,6 +128,10 @@ func (c *controller) newDaemonSetExpected(rawObj client.Object) *apps.DaemonSet
 									Name:      "kubeletsockets",
 									MountPath: "/var/lib/kubelet/device-plugins",
 								},
+								{
+									Name:      "introspection",
+									MountPath: "/var/run/intel-device-plugins",
+								},
 							},
 						},
 					},
@@ -164,6 +169,15 @@ func (c *controller) newDaemonSetExpected(rawObj client.Object) *apps.DaemonSet
 								},
 							},
 						},
+						{
+							Name: "introspection",
+							VolumeSource: v1.VolumeSource{
+								HostPath: &v1.HostPathVolumeSource{
+									Path: "/var/run/intel-device-plugins",
+									Type: &directoryOrCreate,
+								},
+							},
+						},
 					},
 				},
 			},
@@ -37,6 +37,10 @@ const ownerKey = ".metadata.controller.fpga"
 
 var defaultNodeSelector = deployments.FPGAPluginDaemonSet().Spec.Template.Spec.NodeSelector
 
+// defaultAutomount tells if the device plugin pods mount a service account
+// token without the reporting.
+var defaultAutomount = deployments.FPGAPluginDaemonSet().Spec.Template.Spec.AutomountServiceAccountToken
+
 // +kubebuilder:rbac:groups=deviceplugin.intel.com,resources=fpgadeviceplugins,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=deviceplugin.intel.com,resources=fpgadeviceplugins/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=deviceplugin.intel.com,resources=fpgadeviceplugins/finalizers,verbs=update
@@ -44,6 +48,8 @@ var defaultNodeSelector = deployments.FPGAPluginDaemonSet().Spec.Template.Spec.N
 // SetupReconciler creates a new reconciler for FpgaDevicePlugin objects.
 func SetupReconciler(mgr ctrl.Manager, namespace string, withWebhook bool) error {
 	c := &controller{scheme: mgr.GetScheme(), ns: namespace}
+	c.ReporterObjectsFactory = controllers.ReporterObjectsFactory{Name: "fpga", Namespace: namespace, Reports: reports}
+
 	if err := controllers.SetupWithManager(mgr, c, devicepluginv1.GroupVersion.String(), "FpgaDevicePlugin", ownerKey); err != nil {
 		return err
 	}
@@ -56,7 +62,7 @@ func SetupReconciler(mgr ctrl.Manager, namespace string, withWebhook bool) error
 }
 
 type controller struct {
-	controllers.DefaultServiceAccountFactory
+	controllers.ReporterObjectsFactory
 	scheme *runtime.Scheme
 	ns     string
 }
@@ -87,6 +93,7 @@ func (c *controller) NewDaemonSet(rawObj client.Object) *apps.DaemonSet {
 	daemonSet.ObjectMeta.Namespace = c.ns
 
 	daemonSet.Spec.Template.Spec.Containers[0].Args = getPodArgs(devicePlugin)
+	c.SetServiceAccount(&daemonSet.Spec.Template.Spec, reporting(&devicePlugin.Spec), defaultAutomount)
 	daemonSet.Spec.Template.Spec.Containers[0].Image = devicePlugin.Spec.Image
 
 	return daemonSet
@@ -121,6 +128,10 @@ func (c *controller) UpdateDaemonSet(rawObj client.Object, ds *apps.DaemonSet) (
 		updated = true
 	}
 
+	if c.SetServiceAccount(&ds.Spec.Template.Spec, reporting(&dp.Spec), defaultAutomount) {
+		updated = true
+	}
+
 	if controllers.HasTolerationsChanged(ds.Spec.Template.Spec.Tolerations, dp.Spec.Tolerations) {
 		ds.Spec.Template.Spec.Tolerations = dp.Spec.Tolerations
 		updated = true
@@ -170,5 +181,30 @@ func getPodArgs(dp *devicepluginv1.FpgaDevicePlugin) []string {
 		args = append(args, "-mode", "af")
 	}
 
+	args = append(args, controllers.ReporterArgs(dp.Spec.PublishInventory, dp.Spec.ReportNodeCondition)...)
+
 	return args
 }
+
+// reports tells if any FpgaDevicePlugin object enables the reporting.
+func reports(ctx context.Context, c client.Client) bool {
+	var list devicepluginv1.FpgaDevicePluginList
+
+	if err := c.List(ctx, &list); err != nil {
+		return false
+	}
+
+	for _, cr := range list.Items {
+		if reporting(&cr.Spec) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reporting tells if the device plugin publishes the inventory or reports
+// the node condition.
+func reporting(spec *devicepluginv1.FpgaDevicePluginSpec) bool {
+	return spec.PublishInventory || spec.ReportNodeCondition
+}
@@ -120,6 +120,10 @@ func (c *controller) newDaemonSetExpected(rawObj client.Object) *apps.DaemonSet
 									MountPath: "/var/lib/kubelet/device-plugins",
 									Name:      "kubeletsockets",
 								},
+								{
+									MountPath: "/var/run/intel-device-plugins",
+									Name:      "introspection",
+								},
 								{
 									MountPath: "/var/run/cdi",
 									Name:      "cdidir",
@@ -170,6 +174,15 @@ func (c *controller) newDaemonSetExpected(rawObj client.Object) *apps.DaemonSet
 								},
 							},
 						},
+						{
+							Name: "introspection",
+							VolumeSource: v1.VolumeSource{
+								HostPath: &v1.HostPathVolumeSource{
+									Path: "/var/run/intel-device-plugins",
+									Type: &directoryOrCreate,
+								},
+							},
+						},
 						{
 							Name: "intel-fpga-sw",
 							VolumeSource: v1.VolumeSource{
@@ -37,9 +37,10 @@ import (
 )
 
 const (
-	ownerKey           = ".metadata.controller.gpu"
-	serviceAccountName = "gpu-manager-sa"
-	roleBindingName    = "gpu-manager-rolebinding"
+	ownerKey                = ".metadata.controller.gpu"
+	serviceAccountName      = "gpu-manager-sa"
+	roleBindingName         = "gpu-manager-rolebinding"
+	reporterRoleBindingName = "gpu-reporter-rolebinding"
 )
 
 var defaultNodeSelector = deployments.GPUPluginDaemonSet().Spec.Template.Spec.NodeSelector
@@ -85,7 +86,16 @@ func (c *controller) NewSharedServiceAccount() *v1.ServiceAccount {
 	}
 }
 
-func (c *controller) NewSharedClusterRoleBinding() *rbacv1.ClusterRoleBinding {
+// NewSharedClusterRoleBindings binds the resource manager and the reporter
+// roles to the service account shared by both.
+func (c *controller) NewSharedClusterRoleBindings() []*rbacv1.ClusterRoleBinding {
+	return []*rbacv1.ClusterRoleBinding{
+		c.newManagerClusterRoleBinding(),
+		controllers.NewReporterClusterRoleBinding(reporterRoleBindingName, serviceAccountName, c.ns),
+	}
+}
+
+func (c *controller) newManagerClusterRoleBinding() *rbacv1.ClusterRoleBinding {
 	return &rbacv1.ClusterRoleBinding{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      roleBindingName,
@@ -118,7 +128,7 @@ func (c *controller) PluginRequiresSharedObjects(ctx context.Context, client cli
 	}
 
 	for _, cr := range list.Items {
-		if cr.Spec.ResourceManager {
+		if usesServiceAccount(&cr) {
 			return true
 		}
 	}
@@ -151,9 +161,12 @@ func (c *controller) NewDaemonSet(rawObj client.Object) *apps.DaemonSet {
 		setInitContainer(&daemonSet.Spec.Template.Spec, devicePlugin.Spec.InitImage)
 	}
 
-	// add service account if resource manager is enabled
-	if devicePlugin.Spec.ResourceManager {
+	if usesServiceAccount(devicePlugin) {
 		daemonSet.Spec.Template.Spec.ServiceAccountName = serviceAccountName
+	}
+
+	// add volumes if resource manager is enabled
+	if devicePlugin.Spec.ResourceManager {
 		addVolumeIfMissing(&daemonSet.Spec.Template.Spec, "podresources", "/var/lib/kubelet/pod-resources", v1.HostPathDirectory)
 		addVolumeMountIfMissing(&daemonSet.Spec.Template.Spec, "podresources", "/var/lib/kubelet/pod-resources", false)
 		addVolumeIfMissing(&daemonSet.Spec.Template.Spec, "kubeletcrt", "/var/lib/kubelet/pki/kubelet.crt", v1.HostPathFileOrCreate)
@@ -333,7 +346,7 @@ func (c *controller) UpdateDaemonSet(rawObj client.Object, ds *apps.DaemonSet) (
 	}
 
 	newServiceAccountName := "default"
-	if dp.Spec.ResourceManager {
+	if usesServiceAccount(dp) {
 		newServiceAccountName = serviceAccountName
 	}
 
@@ -413,5 +426,13 @@ func getPodArgs(gdp *devicepluginv1.GpuDevicePlugin) []string {
 		args = append(args, "-allocation-policy", "none")
 	}
 
+	args = append(args, controllers.ReporterArgs(gdp.Spec.PublishInventory, gdp.Spec.ReportNodeCondition)...)
+
 	return args
 }
+
+// usesServiceAccount tells if the device plugin needs the permissions of the
+// shared service account, for the resource manager or for the reporting.
+func usesServiceAccount(gdp *devicepluginv1.GpuDevicePlugin) bool {
+	return gdp.Spec.ResourceManager || gdp.Spec.PublishInventory || gdp.Spec.ReportNodeCondition
+}
@@ -132,6 +132,10 @@ func (c *controller) newDaemonSetExpected(rawObj client.Object) *apps.DaemonSet
 									Name:      "kubeletsockets",
 									MountPath: "/var/lib/kubelet/device-plugins",
 								},
+								{
+									Name:      "introspection",
+									MountPath: "/var/run/intel-device-plugins",
+								},
 								{
 									Name:      "cdipath",
 									MountPath: "/var/run/cdi",
@@ -165,6 +169,15 @@ func (c *controller) newDaemonSetExpected(rawObj client.Object) *apps.DaemonSet
 								},
 							},
 						},
+						{
+							Name: "introspection",
+							VolumeSource: v1.VolumeSource{
+								HostPath: &v1.HostPathVolumeSource{
+									Path: "/var/run/intel-device-plugins",
+									Type: &directoryOrCreate,
+								},
+							},
+						},
 						{
 							Name: "cdipath",
 							VolumeSource: v1.VolumeSource{
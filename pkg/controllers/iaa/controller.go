@@ -40,6 +40,10 @@ const (
 	configVolumeName  = "intel-iaa-config-volume"
 )
 
+// defaultAutomount tells if the device plugin pods mount a service account
+// token without the reporting.
+var defaultAutomount = deployments.IAAPluginDaemonSet().Spec.Template.Spec.AutomountServiceAccountToken
+
 // +kubebuilder:rbac:groups=deviceplugin.intel.com,resources=iaadeviceplugins,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=deviceplugin.intel.com,resources=iaadeviceplugins/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=deviceplugin.intel.com,resources=iaadeviceplugins/finalizers,verbs=update
@@ -47,6 +51,8 @@ const (
 // SetupReconciler creates a new reconciler for IaaDevicePlugin objects.
 func SetupReconciler(mgr ctrl.Manager, namespace string, withWebhook bool) error {
 	c := &controller{scheme: mgr.GetScheme(), ns: namespace}
+	c.ReporterObjectsFactory = controllers.ReporterObjectsFactory{Name: "iaa", Namespace: namespace, Reports: reports}
+
 	if err := controllers.SetupWithManager(mgr, c, devicepluginv1.GroupVersion.String(), "IaaDevicePlugin", ownerKey); err != nil {
 		return err
 	}
@@ -59,7 +65,7 @@ func SetupReconciler(mgr ctrl.Manager, namespace string, withWebhook bool) error
 }
 
 type controller struct {
-	controllers.DefaultServiceAccountFactory
+	controllers.ReporterObjectsFactory
 	scheme *runtime.Scheme
 	ns     string
 }
@@ -202,6 +208,7 @@ func (c *controller) NewDaemonSet(rawObj client.Object) *apps.DaemonSet {
 	daemonSet.ObjectMeta.Namespace = c.ns
 
 	daemonSet.Spec.Template.Spec.Containers[0].Args = getPodArgs(devicePlugin)
+	c.SetServiceAccount(&daemonSet.Spec.Template.Spec, reporting(&devicePlugin.Spec), defaultAutomount)
 	daemonSet.Spec.Template.Spec.Containers[0].Image = devicePlugin.Spec.Image
 
 	if devicePlugin.Spec.InitImage != "" {
@@ -277,6 +284,10 @@ func (c *controller) UpdateDaemonSet(rawObj client.Object, ds *apps.DaemonSet) (
 		updated = true
 	}
 
+	if c.SetServiceAccount(&ds.Spec.Template.Spec, reporting(&dp.Spec), defaultAutomount) {
+		updated = true
+	}
+
 	if controllers.HasTolerationsChanged(ds.Spec.Template.Spec.Tolerations, dp.Spec.Tolerations) {
 		ds.Spec.Template.Spec.Tolerations = dp.Spec.Tolerations
 		updated = true
@@ -326,5 +337,30 @@ func getPodArgs(gdp *devicepluginv1.IaaDevicePlugin) []string {
 		args = append(args, "-shared-dev-num", "1")
 	}
 
+	args = append(args, controllers.ReporterArgs(gdp.Spec.PublishInventory, gdp.Spec.ReportNodeCondition)...)
+
 	return args
 }
+
+// reports tells if any IaaDevicePlugin object enables the reporting.
+func reports(ctx context.Context, c client.Client) bool {
+	var list devicepluginv1.IaaDevicePluginList
+
+	if err := c.List(ctx, &list); err != nil {
+		return false
+	}
+
+	for _, cr := range list.Items {
+		if reporting(&cr.Spec) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// reporting tells if the device plugin publishes the inventory or reports
+// the node condition.
+func reporting(spec *devicepluginv1.IaaDevicePluginSpec) bool {
+	return spec.PublishInventory || spec.ReportNodeCondition
+}
@@ -65,7 +65,7 @@ type SharedObjectsFactory interface {
 	// Indicates if plugin currently require shared objects.
 	PluginRequiresSharedObjects(ctx context.Context, client client.Client) bool
 	NewSharedServiceAccount() *v1.ServiceAccount
-	NewSharedClusterRoleBinding() *rbacv1.ClusterRoleBinding
+	NewSharedClusterRoleBindings() []*rbacv1.ClusterRoleBinding
 }
 
 // DefaultServiceAccountFactory is an empty ServiceAccountFactory. "default" will be used for the service account then.
@@ -74,7 +74,7 @@ type DefaultServiceAccountFactory struct{}
 func (d *DefaultServiceAccountFactory) NewSharedServiceAccount() *v1.ServiceAccount {
 	return nil
 }
-func (d *DefaultServiceAccountFactory) NewSharedClusterRoleBinding() *rbacv1.ClusterRoleBinding {
+func (d *DefaultServiceAccountFactory) NewSharedClusterRoleBindings() []*rbacv1.ClusterRoleBinding {
 	return nil
 }
 func (d *DefaultServiceAccountFactory) PluginMayRequireSharedObjects() bool {
@@ -143,11 +143,11 @@ func (r *reconciler) createSharedObjects(ctx context.Context, log logr.Logger) (
 		return result, err
 	}
 
-	rb := r.controller.NewSharedClusterRoleBinding()
-
-	if err := r.Create(ctx, rb); client.IgnoreAlreadyExists(err) != nil {
-		log.Error(err, "unable to create shared ClusterRoleBinding")
-		return ctrl.Result{}, err
+	for _, rb := range r.controller.NewSharedClusterRoleBindings() {
+		if err := r.Create(ctx, rb); client.IgnoreAlreadyExists(err) != nil {
+			log.Error(err, "unable to create shared ClusterRoleBinding")
+			return ctrl.Result{}, err
+		}
 	}
 
 	return result, nil
@@ -430,9 +430,9 @@ func (r *reconciler) maybeDeleteSharedObjects(ctx context.Context, sharedObjects
 		log.Error(err, "unable to delete redundant shared ServiceAccount", "ServiceAccount", sa)
 	}
 
-	crb := r.controller.NewSharedClusterRoleBinding()
-
-	if err := r.Delete(ctx, crb, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
-		log.Error(err, "unable to delete redundant shared ClusterRoleBinding", "ClusterRoleBinding", crb)
+	for _, crb := range r.controller.NewSharedClusterRoleBindings() {
+		if err := r.Delete(ctx, crb, client.PropagationPolicy(metav1.DeletePropagationBackground)); client.IgnoreNotFound(err) != nil {
+			log.Error(err, "unable to delete redundant shared ClusterRoleBinding", "ClusterRoleBinding", crb)
+		}
 	}
 }
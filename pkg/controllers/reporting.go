@@ -0,0 +1,142 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+
+	v1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ReporterRoleName is the ClusterRole with the permissions of the device
+// plugins publishing the inventory of the node or reporting the node
+// condition, see deployments/operator/rbac.
+const ReporterRoleName = "inteldeviceplugins-reporter-role"
+
+// The operator binds the reporter role without holding its permissions.
+// +kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=clusterroles,verbs=bind,resourceNames=inteldeviceplugins-reporter-role
+
+// ReporterObjectsFactory is a SharedObjectsFactory for the device plugins
+// which need a service account only for the reporting. The service account
+// is bound to the reporter role while any device plugin object of the kind
+// enables the reporting.
+type ReporterObjectsFactory struct {
+	// Reports tells if any device plugin object of the kind enables the reporting.
+	Reports func(ctx context.Context, client client.Client) bool
+	// Name prefixes the names of the shared objects, e.g. "dlb".
+	Name      string
+	Namespace string
+}
+
+// ServiceAccountName returns the service account of a device plugin
+// DaemonSet, empty for the default service account.
+func (f *ReporterObjectsFactory) ServiceAccountName(reports bool) string {
+	if !reports {
+		return ""
+	}
+
+	return f.Name + "-reporter-sa"
+}
+
+// SetServiceAccount sets the service account of the pods of a device plugin
+// DaemonSet, and mounts its token when the reporting is enabled. Otherwise,
+// the token is mounted as in the DaemonSet of the deployments, see
+// defaultAutomount. It returns true when the pod spec changed.
+func (f *ReporterObjectsFactory) SetServiceAccount(spec *v1.PodSpec, reports bool, defaultAutomount *bool) bool {
+	name := f.ServiceAccountName(reports)
+
+	var automount *bool
+
+	if reports {
+		automount = ptr.To(true)
+	} else if defaultAutomount != nil {
+		automount = ptr.To(*defaultAutomount)
+	}
+
+	if spec.ServiceAccountName == name && reflect.DeepEqual(spec.AutomountServiceAccountToken, automount) {
+		return false
+	}
+
+	spec.ServiceAccountName = name
+	spec.AutomountServiceAccountToken = automount
+
+	return true
+}
+
+func (f *ReporterObjectsFactory) NewSharedServiceAccount() *v1.ServiceAccount {
+	return &v1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      f.ServiceAccountName(true),
+			Namespace: f.Namespace,
+		},
+	}
+}
+
+func (f *ReporterObjectsFactory) NewSharedClusterRoleBindings() []*rbacv1.ClusterRoleBinding {
+	return []*rbacv1.ClusterRoleBinding{
+		NewReporterClusterRoleBinding(f.Name+"-reporter-rolebinding", f.ServiceAccountName(true), f.Namespace),
+	}
+}
+
+func (f *ReporterObjectsFactory) PluginMayRequireSharedObjects() bool {
+	return true
+}
+
+func (f *ReporterObjectsFactory) PluginRequiresSharedObjects(ctx context.Context, client client.Client) bool {
+	return f.Reports != nil && f.Reports(ctx, client)
+}
+
+// NewReporterClusterRoleBinding binds the reporter role to a service account.
+func NewReporterClusterRoleBinding(name, serviceAccountName, namespace string) *rbacv1.ClusterRoleBinding {
+	return &rbacv1.ClusterRoleBinding{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      "ServiceAccount",
+				Name:      serviceAccountName,
+				Namespace: namespace,
+			},
+		},
+		RoleRef: rbacv1.RoleRef{
+			Kind:     "ClusterRole",
+			Name:     ReporterRoleName,
+			APIGroup: "rbac.authorization.k8s.io",
+		},
+	}
+}
+
+// ReporterArgs returns the command line arguments of the reporting of a
+// device plugin.
+func ReporterArgs(publishInventory, nodeCondition bool) []string {
+	args := []string{}
+
+	if publishInventory {
+		args = append(args, "-publish-inventory")
+	}
+
+	if nodeCondition {
+		args = append(args, "-report-node-condition")
+	}
+
+	return args
+}
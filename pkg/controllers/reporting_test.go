@@ -0,0 +1,71 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestReporterObjectsFactory(t *testing.T) {
+	reports := false
+	f := &ReporterObjectsFactory{
+		Name:      "dlb",
+		Namespace: "inteldeviceplugins-system",
+		Reports:   func(context.Context, client.Client) bool { return reports },
+	}
+
+	if name := f.ServiceAccountName(false); name != "" {
+		t.Errorf("expected the default service account without reporting, got %q", name)
+	}
+
+	if f.PluginRequiresSharedObjects(context.Background(), nil) {
+		t.Error("shared objects required without reporting")
+	}
+
+	reports = true
+
+	if !f.PluginRequiresSharedObjects(context.Background(), nil) {
+		t.Error("shared objects not required with reporting")
+	}
+
+	sa := f.NewSharedServiceAccount()
+	if sa.Name != "dlb-reporter-sa" || sa.Name != f.ServiceAccountName(true) || sa.Namespace != f.Namespace {
+		t.Errorf("unexpected service account %+v", sa.ObjectMeta)
+	}
+
+	crbs := f.NewSharedClusterRoleBindings()
+	if len(crbs) != 1 || crbs[0].RoleRef.Name != ReporterRoleName ||
+		crbs[0].Subjects[0].Name != sa.Name || crbs[0].Subjects[0].Namespace != sa.Namespace {
+		t.Errorf("unexpected cluster role bindings %+v", crbs)
+	}
+}
+
+func TestReporterArgs(t *testing.T) {
+	if args := ReporterArgs(false, false); len(args) != 0 {
+		t.Errorf("unexpected args without reporting: %v", args)
+	}
+
+	if args := ReporterArgs(true, false); !reflect.DeepEqual(args, []string{"-publish-inventory"}) {
+		t.Errorf("unexpected args with the inventory: %v", args)
+	}
+
+	if args := ReporterArgs(true, true); !reflect.DeepEqual(args, []string{"-publish-inventory", "-report-node-condition"}) {
+		t.Errorf("unexpected args with the inventory and the node condition: %v", args)
+	}
+}
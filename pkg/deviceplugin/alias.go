@@ -0,0 +1,370 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	podresourcesv1 "k8s.io/kubelet/pkg/apis/podresources/v1"
+)
+
+const (
+	// aliasReconcilePeriod is the period of checking the devices in use with
+	// the PodResources API.
+	aliasReconcilePeriod = 10 * time.Second
+	// aliasAllocationGrace is how long an allocated device keeps its pool
+	// reserved even if kubelet doesn't report the device in use.
+	aliasAllocationGrace = time.Minute
+)
+
+// aliasPools keeps the devices of different types that share a pool, i.e. the
+// same underlying device, mutually exclusive. Once a device of a pool is
+// allocated, the devices of the pool of other types are withdrawn from
+// kubelet until the allocation is released.
+type aliasPools struct {
+	listPodResources listPodResourcesFunc
+	devices          DeviceTree                      // devices as reported by Scan()
+	withdrawn        map[string]map[string]bool      // devType -> withdrawn device IDs
+	allocated        map[string]map[string]time.Time // devType -> ID -> time of the latest allocation
+	inUse            map[string]map[string]bool      // devType -> IDs in use as reported by kubelet
+	changed          chan struct{}
+	// pooled tells reconcileLoop that the first pooled devices appeared.
+	pooled     chan struct{}
+	namespace  string
+	mutex      sync.Mutex
+	reconciled bool
+}
+
+func newAliasPools(namespace string) *aliasPools {
+	return &aliasPools{
+		namespace: namespace,
+		devices:   NewDeviceTree(),
+		withdrawn: make(map[string]map[string]bool),
+		allocated: make(map[string]map[string]time.Time),
+		inUse:     make(map[string]map[string]bool),
+		changed:   make(chan struct{}, 1),
+		pooled:    make(chan struct{}, 1),
+		listPodResources: func(ctx context.Context) (*podresourcesv1.ListPodResourcesResponse, error) {
+			return listPodResources(ctx, PodResourcesSocket)
+		},
+	}
+}
+
+// hasPooledDevices reports whether an update has pooled devices.
+func hasPooledDevices(update updateInfo) bool {
+	for _, tree := range []DeviceTree{update.Added, update.Updated} {
+		for _, devices := range tree {
+			for _, dev := range devices {
+				if dev.pool != "" {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
+
+// hasPools reports whether any device belongs to a pool. The caller holds
+// the lock.
+func (a *aliasPools) hasPools() bool {
+	for _, devices := range a.devices {
+		for _, dev := range devices {
+			if dev.pool != "" {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// owners returns the device types each pool is allocated as. The caller
+// holds the lock.
+func (a *aliasPools) owners() map[string]map[string]bool {
+	owners := make(map[string]map[string]bool)
+
+	add := func(devType, id string) {
+		pool := a.devices[devType][id].pool
+		if pool == "" {
+			return
+		}
+
+		if owners[pool] == nil {
+			owners[pool] = make(map[string]bool)
+		}
+
+		owners[pool][devType] = true
+	}
+
+	for devType, ids := range a.allocated {
+		for id := range ids {
+			add(devType, id)
+		}
+	}
+
+	for devType, ids := range a.inUse {
+		for id := range ids {
+			add(devType, id)
+		}
+	}
+
+	return owners
+}
+
+// filter returns the devices of a type without the devices whose pool is
+// allocated as another type. Until the devices in use are known, all the
+// pooled devices are withdrawn. The caller holds the lock.
+func (a *aliasPools) filter(devType string, owners map[string]map[string]bool) map[string]DeviceInfo {
+	devices := a.devices[devType]
+	withdrawn := make(map[string]bool)
+
+	for id, dev := range devices {
+		if dev.pool != "" && (!a.reconciled || len(owners[dev.pool]) > 0 && !owners[dev.pool][devType]) {
+			withdrawn[id] = true
+		}
+	}
+
+	a.withdrawn[devType] = withdrawn
+
+	if len(withdrawn) == 0 {
+		return devices
+	}
+
+	filtered := make(map[string]DeviceInfo, len(devices)-len(withdrawn))
+
+	for id, dev := range devices {
+		if !withdrawn[id] {
+			filtered[id] = dev
+		}
+	}
+
+	return filtered
+}
+
+// track records the devices of an update and returns the update without the
+// withdrawn devices. When the first pooled devices appear, reconcileLoop is
+// told to check the devices in use, so that the allocations done before a
+// restart are honored. The pooled devices are advertised after the check.
+func (a *aliasPools) track(update updateInfo) updateInfo {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for _, tree := range []DeviceTree{update.Added, update.Updated} {
+		for devType, devices := range tree {
+			a.devices[devType] = devices
+		}
+	}
+
+	for devType := range update.Removed {
+		delete(a.devices, devType)
+		delete(a.withdrawn, devType)
+	}
+
+	if !a.reconciled && a.hasPools() {
+		select {
+		case a.pooled <- struct{}{}:
+		default:
+		}
+	}
+
+	owners := a.owners()
+	filtered := updateInfo{Added: NewDeviceTree(), Updated: NewDeviceTree(), Removed: update.Removed}
+
+	for devType := range update.Added {
+		filtered.Added[devType] = a.filter(devType, owners)
+	}
+
+	for devType := range update.Updated {
+		filtered.Updated[devType] = a.filter(devType, owners)
+	}
+
+	return filtered
+}
+
+// update returns an update with the device types whose withdrawn devices
+// changed.
+func (a *aliasPools) update() updateInfo {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	update := updateInfo{Added: NewDeviceTree(), Updated: NewDeviceTree(), Removed: NewDeviceTree()}
+	owners := a.owners()
+
+	for devType := range a.devices {
+		withdrawn := a.withdrawn[devType]
+		devices := a.filter(devType, owners)
+
+		if !reflect.DeepEqual(withdrawn, a.withdrawn[devType]) {
+			ids := make([]string, 0, len(a.withdrawn[devType]))
+			for id := range a.withdrawn[devType] {
+				ids = append(ids, id)
+			}
+
+			sort.Strings(ids)
+			klog.V(3).InfoS("Withdrawn aliased devices", LogKeyResource, a.namespace+"/"+devType, LogKeyDeviceID, ids)
+
+			update.Updated[devType] = devices
+		}
+	}
+
+	return update
+}
+
+// reserve records an allocation of devices. It fails if the pool of a device
+// is allocated as another device type already, e.g. when kubelet allocated it
+// before the device was withdrawn. A reservation of a failed allocation is
+// released after aliasAllocationGrace.
+func (a *aliasPools) reserve(devType string, ids []string) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	owners := a.owners()
+
+	for _, id := range ids {
+		pool := a.devices[devType][id].pool
+
+		for owner := range owners[pool] {
+			if owner != devType {
+				return errors.Errorf("device %s is allocated as %s/%s already", id, a.namespace, owner)
+			}
+		}
+	}
+
+	if a.allocated[devType] == nil {
+		a.allocated[devType] = make(map[string]time.Time)
+	}
+
+	now := time.Now()
+	pooled := false
+
+	for _, id := range ids {
+		if a.devices[devType][id].pool != "" {
+			a.allocated[devType][id] = now
+			pooled = true
+		}
+	}
+
+	if pooled {
+		a.notify()
+	}
+
+	return nil
+}
+
+// notify tells Manager that the allocated pools may have changed.
+func (a *aliasPools) notify() {
+	select {
+	case a.changed <- struct{}{}:
+	default:
+	}
+}
+
+// reconcile updates the devices in use from the PodResources API and drops
+// the allocations older than aliasAllocationGrace. The pooled devices
+// withdrawn until the first reconcile are advertised even if it fails.
+func (a *aliasPools) reconcile() error {
+	ctx, cancel := context.WithTimeout(context.Background(), podResourcesTimeout)
+	defer cancel()
+
+	resp, err := a.listPodResources(ctx)
+
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if !a.reconciled {
+		a.reconciled = true
+
+		a.notify()
+	}
+
+	if err != nil {
+		return err
+	}
+
+	inUse := make(map[string]map[string]bool)
+
+	for _, pod := range resp.PodResources {
+		for _, cont := range pod.Containers {
+			for _, dev := range cont.Devices {
+				devType, ok := strings.CutPrefix(dev.ResourceName, a.namespace+"/")
+				if !ok {
+					continue
+				}
+
+				if inUse[devType] == nil {
+					inUse[devType] = make(map[string]bool)
+				}
+
+				for _, id := range dev.DeviceIds {
+					inUse[devType][id] = true
+				}
+			}
+		}
+	}
+
+	a.inUse = inUse
+
+	for devType, ids := range a.allocated {
+		for id, timestamp := range ids {
+			if time.Since(timestamp) > aliasAllocationGrace {
+				delete(ids, id)
+			}
+		}
+
+		if len(ids) == 0 {
+			delete(a.allocated, devType)
+		}
+	}
+
+	a.notify()
+
+	return nil
+}
+
+// reconcileLoop reconciles the devices in use when the first pooled devices
+// appear, and then periodically until ctx is done.
+func (a *aliasPools) reconcileLoop(ctx context.Context) {
+	ticker := time.NewTicker(aliasReconcilePeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-a.pooled:
+		case <-ticker.C:
+		}
+
+		a.mutex.Lock()
+		pooled := a.hasPools()
+		a.mutex.Unlock()
+
+		if !pooled {
+			continue
+		}
+
+		if err := a.reconcile(); err != nil {
+			klog.FromContext(ctx).Error(err, "Failed to get the devices in use")
+		}
+	}
+}
@@ -0,0 +1,206 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	podresourcesv1 "k8s.io/kubelet/pkg/apis/podresources/v1"
+)
+
+const testAliasVF = "0000:01:00.1"
+
+func newTestAliasTree() DeviceTree {
+	tree := NewDeviceTree()
+	info := DeviceInfo{state: pluginapi.Healthy}
+
+	tree.AddPooledDevice("cy", testAliasVF, testAliasVF, info)
+	tree.AddPooledDevice("generic", testAliasVF, testAliasVF, info)
+	tree.AddDevice("generic", "0000:01:00.2", info)
+
+	return tree
+}
+
+func newTestAliasPools(inUse map[string][]string) *aliasPools {
+	a := newAliasPools("qat.intel.com")
+	a.listPodResources = func(context.Context) (*podresourcesv1.ListPodResourcesResponse, error) {
+		devices := []*podresourcesv1.ContainerDevices{}
+
+		for devType, ids := range inUse {
+			devices = append(devices, &podresourcesv1.ContainerDevices{ResourceName: "qat.intel.com/" + devType, DeviceIds: ids})
+		}
+
+		return &podresourcesv1.ListPodResourcesResponse{
+			PodResources: []*podresourcesv1.PodResources{
+				{Name: "pod", Namespace: "default", Containers: []*podresourcesv1.ContainerResources{{Name: "container", Devices: devices}}},
+			},
+		}, nil
+	}
+
+	return a
+}
+
+func TestAliasPools(t *testing.T) {
+	inUse := map[string][]string{}
+	a := newTestAliasPools(inUse)
+
+	update := a.track(updateInfo{Added: newTestAliasTree(), Updated: NewDeviceTree(), Removed: NewDeviceTree()})
+	if len(update.Added["cy"]) != 0 || len(update.Added["generic"]) != 1 {
+		t.Fatalf("pooled devices should be withdrawn until the devices in use are known, got %+v", update.Added)
+	}
+
+	select {
+	case <-a.pooled:
+	default:
+		t.Fatal("the first pooled devices should trigger a reconcile")
+	}
+
+	if err := a.reconcile(); err != nil {
+		t.Fatalf("unable to reconcile: %+v", err)
+	}
+
+	<-a.changed
+
+	if update = a.update(); len(update.Updated["cy"]) != 1 || len(update.Updated["generic"]) != 2 {
+		t.Fatalf("all devices should be advertised after the reconcile, got %+v", update.Updated)
+	}
+
+	if err := a.reserve("cy", []string{testAliasVF}); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	select {
+	case <-a.changed:
+	default:
+		t.Error("reservation should notify about the change")
+	}
+
+	update = a.update()
+	if _, ok := update.Updated["cy"]; ok {
+		t.Error("devices of the allocated type should not be withdrawn")
+	}
+
+	if devices, ok := update.Updated["generic"]; !ok || len(devices) != 1 || devices[testAliasVF].pool != "" {
+		t.Errorf("expected the pooled generic device to be withdrawn, got %+v", update.Updated)
+	}
+
+	if err := a.reserve("generic", []string{testAliasVF}); err == nil {
+		t.Error("expected an error when the pool is allocated as another type")
+	}
+
+	if err := a.reserve("generic", []string{"0000:01:00.2"}); err != nil {
+		t.Errorf("unpooled devices should be allocatable: %+v", err)
+	}
+
+	// The allocation grace period expires while kubelet reports the device in use.
+	a.allocated["cy"][testAliasVF] = time.Now().Add(-2 * aliasAllocationGrace)
+	inUse["cy"] = []string{testAliasVF}
+
+	if err := a.reconcile(); err != nil {
+		t.Fatalf("unable to reconcile: %+v", err)
+	}
+
+	if update = a.update(); len(update.Updated) != 0 {
+		t.Errorf("device in use should stay withdrawn, got %+v", update.Updated)
+	}
+
+	delete(inUse, "cy")
+
+	if err := a.reconcile(); err != nil {
+		t.Fatalf("unable to reconcile: %+v", err)
+	}
+
+	if update = a.update(); len(update.Updated["generic"]) != 2 {
+		t.Errorf("expected the released device to be restored, got %+v", update.Updated)
+	}
+}
+
+func TestAliasPoolsRestart(t *testing.T) {
+	a := newTestAliasPools(map[string][]string{"generic": {testAliasVF}})
+
+	a.track(updateInfo{Added: newTestAliasTree(), Updated: NewDeviceTree(), Removed: NewDeviceTree()})
+
+	if err := a.reconcile(); err != nil {
+		t.Fatalf("unable to reconcile: %+v", err)
+	}
+
+	if update := a.update(); len(update.Updated["generic"]) != 2 {
+		t.Errorf("devices in use before the restart should be honored, got %+v", update.Updated)
+	}
+
+	if devices := a.filter("cy", a.owners()); len(devices) != 0 {
+		t.Errorf("the pool in use should stay withdrawn, got %+v", devices)
+	}
+
+	if err := a.reserve("cy", []string{testAliasVF}); err == nil {
+		t.Error("pool should stay reserved by the device in use")
+	}
+
+	update := a.track(updateInfo{Added: NewDeviceTree(), Updated: NewDeviceTree(), Removed: DeviceTree{"generic": nil}})
+	if len(update.Removed) != 1 {
+		t.Errorf("unexpected update %+v", update)
+	}
+
+	if err := a.reserve("cy", []string{testAliasVF}); err != nil {
+		t.Errorf("pool should be released with the removed device type: %+v", err)
+	}
+}
+
+func TestServerAllocateAlias(t *testing.T) {
+	a := newTestAliasPools(map[string][]string{})
+	a.track(updateInfo{Added: newTestAliasTree(), Updated: NewDeviceTree(), Removed: NewDeviceTree()})
+
+	if err := a.reserve("generic", []string{testAliasVF}); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	srv := newTestServer()
+	srv.devType = "cy"
+	srv.setDevices(newTestAliasTree()["cy"])
+	srv.aliases = a
+
+	rqt := &pluginapi.AllocateRequest{
+		ContainerRequests: []*pluginapi.ContainerAllocateRequest{{DevicesIDs: []string{testAliasVF}}},
+	}
+
+	if _, err := srv.Allocate(context.Background(), rqt); err == nil {
+		t.Error("expected an error when the pool is allocated as another type")
+	}
+}
+
+func TestWithPooledDevices(t *testing.T) {
+	if mgr := NewManager("qat.intel.com", &devicePluginStub{}); mgr.aliases != nil {
+		t.Error("alias pools should be disabled by default")
+	}
+
+	mgr := NewManager("qat.intel.com", &devicePluginStub{}, WithPooledDevices(true))
+	if mgr.aliases == nil {
+		t.Fatal("alias pools should be enabled with the option")
+	}
+
+	mgr = NewManager("qat.intel.com", &devicePluginStub{})
+	mgr.createServer = func(string, string, postAllocateFunc, preStartContainerFunc, getPreferredAllocationFunc, allocateFunc) devicePluginServer {
+		return &serverStub{}
+	}
+
+	mgr.handleUpdate(updateInfo{Added: newTestAliasTree(), Updated: NewDeviceTree(), Removed: NewDeviceTree()})
+
+	if !mgr.pooledWarned {
+		t.Error("pooled devices without the option should be warned about")
+	}
+}
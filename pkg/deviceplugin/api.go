@@ -16,6 +16,8 @@
 package deviceplugin
 
 import (
+	"time"
+
 	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/topology"
 	"k8s.io/klog/v2"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
@@ -27,11 +29,19 @@ type DeviceInfo struct {
 	mounts      []pluginapi.Mount
 	envs        map[string]string
 	annotations map[string]string
-	topology    *pluginapi.TopologyInfo
+	// attributes are the details of the device, see SetAttributes().
+	attributes map[string]string
+	topology   *pluginapi.TopologyInfo
 	// https://github.com/kubernetes/enhancements/tree/master/keps/sig-node/4009-add-cdi-devices-to-device-plugin-api
 	cdiSpec *cdispec.Spec
 	state   string
-	nodes   []pluginapi.DeviceSpec
+	// pool is the underlying device of aliased devices, see AddPooledDevice().
+	pool string
+	// sysfsDevice is the sysfs directory of the device, see SetSysfsDevice().
+	sysfsDevice string
+	// healthReason explains the state set by a health check.
+	healthReason string
+	nodes        []pluginapi.DeviceSpec
 }
 
 // UseDefaultMethodError allows the plugin to request running the default
@@ -68,7 +78,7 @@ func NewDeviceInfo(state string, nodes []pluginapi.DeviceSpec, mounts []pluginap
 	if err == nil {
 		deviceInfo.topology = topologyInfo
 	} else {
-		klog.Warningf("GetTopologyInfo: %v", err)
+		klog.InfoS("Failed to get topology info", "devices", devPaths, "err", err)
 	}
 
 	return deviceInfo
@@ -88,6 +98,19 @@ func NewDeviceInfoWithTopologyHints(state string, nodes []pluginapi.DeviceSpec,
 	}
 }
 
+// SetSysfsDevice sets the sysfs directory of the device, e.g.
+// "/sys/class/drm/card0/device", whose properties DeviceSelector matches.
+// Without it, the sysfs directory is looked up from the device nodes.
+func (info *DeviceInfo) SetSysfsDevice(dir string) {
+	info.sysfsDevice = dir
+}
+
+// SetAttributes sets the details of the device published in the node
+// inventory, e.g. "model" or "firmware", see WithInventory().
+func (info *DeviceInfo) SetAttributes(attributes map[string]string) {
+	info.attributes = attributes
+}
+
 // DeviceTree contains a tree-like structure of device type -> device ID -> device info.
 type DeviceTree map[string]map[string]DeviceInfo
 
@@ -102,22 +125,28 @@ func (tree DeviceTree) AddDevice(devType, id string, info DeviceInfo) {
 		tree[devType] = make(map[string]DeviceInfo)
 	}
 
-	if info.cdiSpec != nil {
-		devLength := len(info.cdiSpec.Devices)
-		if devLength == 0 {
-			klog.Warning("No CDI devices defined in spec, removing spec")
-
-			info.cdiSpec = nil
-		} else if devLength > 1 {
-			klog.Warning("Including more than one CDI device per spec is not supported, using first")
+	if info.cdiSpec != nil && len(info.cdiSpec.Devices) == 0 {
+		klog.InfoS("No CDI devices defined in spec, removing spec", LogKeyDeviceID, id)
 
-			info.cdiSpec.Devices = info.cdiSpec.Devices[:1]
-		}
+		info.cdiSpec = nil
 	}
 
 	tree[devType][id] = info
 }
 
+// AddPooledDevice adds device info of a device, which is an alias of an
+// underlying device in a pool, to DeviceTree. The devices of different types
+// in the same pool are mutually exclusive: once a device of the pool is
+// allocated as one type, the devices of the pool of the other types are
+// withdrawn from kubelet until the allocation is released. E.g. a QAT VF
+// can be advertised both as "cy" and "generic", with its PCI address as the
+// pool.
+func (tree DeviceTree) AddPooledDevice(devType, id, pool string, info DeviceInfo) {
+	info.pool = pool
+
+	tree.AddDevice(devType, id, info)
+}
+
 // DeviceTypeCount returns number of device of given type.
 func (tree DeviceTree) DeviceTypeCount(devType string) int {
 	return len(tree[devType])
@@ -139,6 +168,13 @@ type Scanner interface {
 	Scan(Notifier) error
 }
 
+// ScanStopper is an optional interface implemented by device plugins.
+type ScanStopper interface {
+	// StopScan makes Scan() return. It's called by Manager on shutdown,
+	// possibly after Scan() has already returned.
+	StopScan()
+}
+
 // Allocator is an optional interface implemented by device plugins.
 type Allocator interface {
 	// Allocate allows the plugin to replace the server Allocate(). Plugin can return
@@ -160,9 +196,96 @@ type PreferredAllocator interface {
 	GetPreferredAllocation(*pluginapi.PreferredAllocationRequest) (*pluginapi.PreferredAllocationResponse, error)
 }
 
+// AllocateLimiter is an optional interface implemented by device plugins
+// whose Allocate() and PostAllocate() hooks can't run concurrently, e.g.
+// because they pick the pod being allocated from the API server. Otherwise
+// kubelet's Allocate calls of a device type may run concurrently.
+type AllocateLimiter interface {
+	// MaxConcurrentAllocations returns the number of the hook calls of
+	// a device type allowed to run at the same time: 1 serializes the
+	// calls and 0 doesn't limit them. Calls over the limit wait for their
+	// turn until kubelet gives up the request.
+	MaxConcurrentAllocations(devType string) int
+}
+
+// DeviceSharer is an optional interface implemented by device plugins that
+// advertise each physical device as several devices, so that containers can
+// share it.
+type DeviceSharer interface {
+	// SharingFactor returns the number of devices of a device type advertised
+	// per physical device. Values below 1 are taken as 1.
+	SharingFactor(devType string) int
+}
+
 // ContainerPreStarter is an optional interface implemented by device plugins.
 type ContainerPreStarter interface {
 	// PreStartContainer  defines device initialization function before container is started.
 	// It might include operations like card reset.
 	PreStartContainer(*pluginapi.PreStartContainerRequest) error
 }
+
+// AllocationJournal gives access to the device allocations recorded by Manager.
+type AllocationJournal interface {
+	// Allocations returns the allocations of a resource, e.g. "gpu.intel.com/i915",
+	// oldest first. The allocations survive plugin restarts and the ones of
+	// released devices are dropped when the journal is reconciled with kubelet.
+	Allocations(resourceName string) []Allocation
+}
+
+// AllocationQuerier is an optional interface implemented by device plugins.
+type AllocationQuerier interface {
+	// SetAllocationJournal is called by Manager before Scan() when the
+	// allocation journal is enabled.
+	SetAllocationJournal(AllocationJournal)
+}
+
+// HealthResult is the result of a device health check.
+type HealthResult struct {
+	// Timestamp is the time of the check.
+	Timestamp time.Time `json:"timestamp"`
+	// Metrics are optional measurements backing the result, e.g. temperatures.
+	Metrics map[string]float64 `json:"metrics,omitempty"`
+	// State is either pluginapi.Healthy or pluginapi.Unhealthy.
+	State string `json:"state"`
+	// Reason explains the state, e.g. "memory error".
+	Reason string `json:"reason,omitempty"`
+	// Source names the origin of the result, e.g. "levelzero".
+	Source string `json:"source"`
+}
+
+// HealthChecker is an optional interface implemented by device plugins.
+// Manager calls CheckHealth periodically, independent of the device scans,
+// and the returned states take precedence over the ones set in Scan().
+type HealthChecker interface {
+	// HealthCheckInterval returns the period of the health checks.
+	// The checks are disabled when it is zero.
+	HealthCheckInterval() time.Duration
+	// CheckHealth checks the given devices, passed as device type -> device IDs,
+	// and returns the results as device type -> device ID -> result. Devices
+	// missing from the results keep their previous state.
+	CheckHealth(devices map[string][]string) map[string]map[string]HealthResult
+}
+
+// HealthNotifier is an optional interface implemented by HealthCheckers
+// which learn about the health changes of their devices, e.g. from a stream.
+// Manager checks the health right away when the channel receives, besides
+// the periodic checks.
+type HealthNotifier interface {
+	// HealthChanged returns the channel telling that the health of the
+	// devices may have changed.
+	HealthChanged() <-chan struct{}
+}
+
+// Configurable is an optional interface implemented by device plugins that
+// take settings from the config file, see WithConfigFile().
+type Configurable interface {
+	// ConfigSection returns the name of the plugin section in the config
+	// file, e.g. "gpu".
+	ConfigSection() string
+	// ApplyConfig validates and applies the plugin section of the config
+	// file, see DecodeConfigSection(). The section is empty when the file
+	// has none. It's called before Scan() and whenever the section changes,
+	// and the settings are expected to apply to the next scan. An error
+	// rejects the config and the previous one stays in effect.
+	ApplyConfig(section []byte) error
+}
@@ -0,0 +1,85 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/internal/containers"
+)
+
+// CapacitySummary is the capacity of a resource advertised to kubelet.
+type CapacitySummary struct {
+	// Allocated is the number of allocated devices. It's known only with
+	// the allocation journal, see WithAllocationJournal().
+	Allocated *int `json:"allocated,omitempty"`
+	// Total is the number of advertised devices.
+	Total int `json:"total"`
+	// Healthy is the number of healthy devices.
+	Healthy int `json:"healthy"`
+	// SharingFactor is the number of devices advertised per physical
+	// device, see DeviceSharer.
+	SharingFactor int `json:"sharingFactor"`
+}
+
+// RequestedDevices returns the devices of the resources of a namespace, e.g.
+// "gpu.intel.com", requested by a pod. Like the scheduler, it takes the
+// larger of the sum of the containers and the largest init container. The
+// restartable init containers keep running, so they add to both.
+func RequestedDevices(pod *v1.Pod, namespace string) (map[string]int64, error) {
+	requests := map[string]int64{}
+	sidecars := map[string]int64{}
+	initRequests := map[string]int64{}
+
+	for _, container := range pod.Spec.InitContainers {
+		resources, err := containers.GetRequestedResources(container, namespace+"/")
+		if err != nil {
+			return nil, errors.Wrapf(err, "init container %s", container.Name)
+		}
+
+		restartable := container.RestartPolicy != nil && *container.RestartPolicy == v1.ContainerRestartPolicyAlways
+
+		for resourceName, quantity := range resources {
+			if restartable {
+				sidecars[resourceName] += quantity
+				initRequests[resourceName] = max(initRequests[resourceName], sidecars[resourceName])
+			} else {
+				initRequests[resourceName] = max(initRequests[resourceName], sidecars[resourceName]+quantity)
+			}
+		}
+	}
+
+	for _, container := range pod.Spec.Containers {
+		resources, err := containers.GetRequestedResources(container, namespace+"/")
+		if err != nil {
+			return nil, errors.Wrapf(err, "container %s", container.Name)
+		}
+
+		for resourceName, quantity := range resources {
+			requests[resourceName] += quantity
+		}
+	}
+
+	for resourceName, quantity := range sidecars {
+		requests[resourceName] += quantity
+	}
+
+	for resourceName, quantity := range initRequests {
+		requests[resourceName] = max(requests[resourceName], quantity)
+	}
+
+	return requests, nil
+}
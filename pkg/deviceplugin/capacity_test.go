@@ -0,0 +1,94 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func testContainer(name string, requests map[string]string) v1.Container {
+	list := v1.ResourceList{}
+	for resourceName, quantity := range requests {
+		list[v1.ResourceName(resourceName)] = resource.MustParse(quantity)
+	}
+
+	return v1.Container{Name: name, Resources: v1.ResourceRequirements{Limits: list, Requests: list}}
+}
+
+func TestRequestedDevices(t *testing.T) {
+	always := v1.ContainerRestartPolicyAlways
+	sidecar := testContainer("sidecar", map[string]string{"gpu.intel.com/i915": "1"})
+	sidecar.RestartPolicy = &always
+
+	tcases := []struct {
+		expected    map[string]int64
+		name        string
+		pod         v1.Pod
+		expectedErr bool
+	}{
+		{
+			name: "containers",
+			pod: v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{
+				testContainer("c1", map[string]string{"gpu.intel.com/i915": "1", "cpu": "500m"}),
+				testContainer("c2", map[string]string{"gpu.intel.com/i915": "2", "qat.intel.com/cy": "1"}),
+			}}},
+			expected: map[string]int64{"gpu.intel.com/i915": 3},
+		},
+		{
+			name: "larger init container",
+			pod: v1.Pod{Spec: v1.PodSpec{
+				InitContainers: []v1.Container{testContainer("init", map[string]string{"gpu.intel.com/i915": "4", "gpu.intel.com/xe": "1"})},
+				Containers:     []v1.Container{testContainer("c1", map[string]string{"gpu.intel.com/i915": "1"})},
+			}},
+			expected: map[string]int64{"gpu.intel.com/i915": 4, "gpu.intel.com/xe": 1},
+		},
+		{
+			name: "sidecar",
+			pod: v1.Pod{Spec: v1.PodSpec{
+				InitContainers: []v1.Container{sidecar, testContainer("init", map[string]string{"gpu.intel.com/i915": "2"})},
+				Containers:     []v1.Container{testContainer("c1", map[string]string{"gpu.intel.com/i915": "1"})},
+			}},
+			expected: map[string]int64{"gpu.intel.com/i915": 3},
+		},
+		{
+			name: "overcommitted",
+			pod: v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{{
+				Name: "c1",
+				Resources: v1.ResourceRequirements{
+					Limits:   v1.ResourceList{"gpu.intel.com/i915": resource.MustParse("2")},
+					Requests: v1.ResourceList{"gpu.intel.com/i915": resource.MustParse("1")},
+				},
+			}}}},
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests, err := RequestedDevices(&tc.pod, "gpu.intel.com")
+			if (err != nil) != tc.expectedErr {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			if err == nil && !reflect.DeepEqual(requests, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/mod/semver"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+// CDIOwnerAnnotation is set in the CDI specs written by the device plugins.
+// Its value is the namespace of the device plugin, e.g. "gpu.intel.com".
+// Only the specs owned by a device plugin are ever removed by it.
+const CDIOwnerAnnotation = CDIVendor + "/owner"
+
+// cdiSpecManager keeps the CDI specs in a directory in sync with the
+// advertised devices. The specs are written when the devices are added,
+// rewritten when they change and removed when the devices are removed.
+type cdiSpecManager struct {
+	specs     map[string]map[string]*cdispec.Spec // devType -> spec file name -> spec
+	written   map[string]*cdispec.Spec            // spec file name -> spec
+	dir       string
+	owner     string
+	collected bool
+	mutex     sync.Mutex
+}
+
+func newCdiSpecManager(dir, owner string) *cdiSpecManager {
+	return &cdiSpecManager{
+		specs:   make(map[string]map[string]*cdispec.Spec),
+		written: make(map[string]*cdispec.Spec),
+		dir:     dir,
+		owner:   owner,
+	}
+}
+
+// cdiSpecFileName returns the name of the spec file for the devices of a spec,
+// e.g. "intel.cdi.k8s.io-gpu-card0.yaml".
+func cdiSpecFileName(spec *cdispec.Spec) string {
+	names := make([]string, 0, len(spec.Devices))
+	for _, dev := range spec.Devices {
+		names = append(names, dev.Name)
+	}
+
+	sort.Strings(names)
+
+	return fmt.Sprintf("%s-%s.yaml", strings.ReplaceAll(spec.Kind, "/", "-"), strings.Join(names, "_"))
+}
+
+// cdiDevices returns the fully qualified names of the CDI devices of a spec.
+func cdiDevices(spec *cdispec.Spec) []*pluginapi.CDIDevice {
+	if spec == nil {
+		return nil
+	}
+
+	devices := make([]*pluginapi.CDIDevice, 0, len(spec.Devices))
+	for _, dev := range spec.Devices {
+		devices = append(devices, &pluginapi.CDIDevice{Name: spec.Kind + "=" + dev.Name})
+	}
+
+	return devices
+}
+
+// update applies an update pushed to the servers and syncs the spec files.
+// Owned spec files of devices no longer present are garbage collected on
+// the first update.
+func (c *cdiSpecManager) update(update updateInfo) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, tree := range []DeviceTree{update.Added, update.Updated} {
+		for devType, devices := range tree {
+			files := make(map[string]*cdispec.Spec)
+
+			for _, dev := range devices {
+				if dev.cdiSpec != nil {
+					files[cdiSpecFileName(dev.cdiSpec)] = dev.cdiSpec
+				}
+			}
+
+			c.specs[devType] = files
+		}
+	}
+
+	for devType := range update.Removed {
+		delete(c.specs, devType)
+	}
+
+	errs := []error{c.sync()}
+
+	if !c.collected {
+		errs = append(errs, c.collectGarbage())
+		c.collected = true
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// sync writes the new and changed specs and removes the specs no longer used
+// by any device. The caller holds the lock.
+func (c *cdiSpecManager) sync() error {
+	var errs []error
+
+	used := make(map[string]struct{}, len(c.written))
+
+	for devType, files := range c.specs {
+		for name, spec := range files {
+			used[name] = struct{}{}
+
+			if reflect.DeepEqual(c.written[name], spec) {
+				continue
+			}
+
+			if err := c.write(name, spec); err != nil {
+				cdiSpecWriteFailuresCounter.WithLabelValues(c.owner + "/" + devType).Inc()
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	for name := range c.written {
+		if _, ok := used[name]; !ok {
+			errs = append(errs, c.remove(name))
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// write writes a spec atomically. The caller holds the lock.
+func (c *cdiSpecManager) write(name string, spec *cdispec.Spec) error {
+	owned := *spec
+
+	owned.Annotations = make(map[string]string, len(spec.Annotations)+1)
+	for key, value := range spec.Annotations {
+		owned.Annotations[key] = value
+	}
+
+	owned.Annotations[CDIOwnerAnnotation] = c.owner
+
+	// Spec annotations need CDI spec version 0.6.0, raise the version if needed.
+	minVersion, err := cdi.MinimumRequiredVersion(&owned)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if semver.Compare("v"+minVersion, "v"+owned.Version) > 0 {
+		owned.Version = minVersion
+	}
+
+	cache, err := cdi.NewCache(cdi.WithAutoRefresh(false), cdi.WithSpecDirs(c.dir))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// WriteSpec() writes to a temporary file and renames it.
+	if err := cache.WriteSpec(&owned, name); err != nil {
+		return errors.Wrapf(err, "failed to write CDI spec %s", name)
+	}
+
+	// Fix access issues due to: https://github.com/cncf-tags/container-device-interface/issues/224
+	if err := os.Chmod(filepath.Join(c.dir, name), 0o644); err != nil {
+		return errors.WithStack(err)
+	}
+
+	klog.V(4).InfoS("Wrote CDI spec", "spec", name)
+
+	c.written[name] = spec
+
+	return nil
+}
+
+// remove removes a written spec. The caller holds the lock.
+func (c *cdiSpecManager) remove(name string) error {
+	delete(c.written, name)
+
+	if err := os.Remove(filepath.Join(c.dir, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.WithStack(err)
+	}
+
+	klog.V(4).InfoS("Removed CDI spec", "spec", name)
+
+	return nil
+}
+
+// collectGarbage removes the specs owned by the device plugin, but not
+// written by this instance of it, e.g. specs of devices that disappeared
+// while the plugin was not running. The caller holds the lock.
+func (c *cdiSpecManager) collectGarbage() error {
+	files, err := os.ReadDir(c.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		return errors.WithStack(err)
+	}
+
+	var errs []error
+
+	for _, file := range files {
+		name := file.Name()
+
+		if _, ok := c.written[name]; ok || file.IsDir() ||
+			(filepath.Ext(name) != ".yaml" && filepath.Ext(name) != ".json") {
+			continue
+		}
+
+		// Specs of other vendors may fail to parse, they are left alone.
+		spec, err := cdi.ReadSpec(filepath.Join(c.dir, name), 0)
+		if err != nil || spec.Annotations[CDIOwnerAnnotation] != c.owner {
+			continue
+		}
+
+		klog.V(1).InfoS("Removing stale CDI spec", "spec", name)
+
+		errs = append(errs, c.remove(name))
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// ensure writes a spec unless it's already written and returns the names of
+// its CDI devices.
+func (c *cdiSpecManager) ensure(spec *cdispec.Spec) ([]*pluginapi.CDIDevice, error) {
+	if spec == nil {
+		return nil, nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	name := cdiSpecFileName(spec)
+
+	if !reflect.DeepEqual(c.written[name], spec) {
+		if err := c.write(name, spec); err != nil {
+			return nil, err
+		}
+	}
+
+	return cdiDevices(spec), nil
+}
+
+// removeAll removes all written specs.
+func (c *cdiSpecManager) removeAll() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var errs []error
+
+	for name := range c.written {
+		errs = append(errs, c.remove(name))
+	}
+
+	c.specs = make(map[string]map[string]*cdispec.Spec)
+
+	return utilerrors.NewAggregate(errs)
+}
@@ -0,0 +1,139 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func testCdiSpec(kind string, names ...string) *cdispec.Spec {
+	spec := &cdispec.Spec{
+		Version: CDIVersion,
+		Kind:    kind,
+	}
+
+	for _, name := range names {
+		spec.Devices = append(spec.Devices, cdispec.Device{
+			Name: name,
+			ContainerEdits: cdispec.ContainerEdits{
+				DeviceNodes: []*cdispec.DeviceNode{{Path: "/dev/" + name}},
+			},
+		})
+	}
+
+	return spec
+}
+
+func listSpecFiles(t *testing.T, dir string) []string {
+	t.Helper()
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func TestCdiSpecManager(t *testing.T) {
+	dir := t.TempDir()
+
+	// A stale spec owned by the plugin and a spec of another vendor.
+	stale := newCdiSpecManager(dir, namespace)
+	if _, err := stale.ensure(testCdiSpec("intel.com/test", "gone")); err != nil {
+		t.Fatalf("unable to write spec: %+v", err)
+	}
+
+	foreign := newCdiSpecManager(dir, "other.example.com")
+	if _, err := foreign.ensure(testCdiSpec("example.com/test", "dev1")); err != nil {
+		t.Fatalf("unable to write spec: %+v", err)
+	}
+
+	shared := testCdiSpec("intel.com/test", "card0")
+
+	tree := NewDeviceTree()
+	tree.AddDevice("shared", "card0-0", NewDeviceInfo(pluginapi.Healthy, nil, nil, nil, nil, shared))
+	tree.AddDevice("shared", "card0-1", NewDeviceInfo(pluginapi.Healthy, nil, nil, nil, nil, shared))
+	tree.AddDevice("multi", "dev1", NewDeviceInfo(pluginapi.Healthy, nil, nil, nil, nil, testCdiSpec("intel.com/test", "dev2", "dev1")))
+
+	c := newCdiSpecManager(dir, namespace)
+	if err := c.update(updateInfo{Added: tree}); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	expected := []string{"example.com-test-dev1.yaml", "intel.com-test-card0.yaml", "intel.com-test-dev1_dev2.yaml"}
+	if files := listSpecFiles(t, dir); !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected spec files %v, got %v", expected, files)
+	}
+
+	spec, err := cdi.ReadSpec(filepath.Join(dir, "intel.com-test-dev1_dev2.yaml"), 0)
+	if err != nil {
+		t.Fatalf("unable to read spec: %+v", err)
+	}
+
+	if spec.Annotations[CDIOwnerAnnotation] != namespace || spec.Version != "0.6.0" || len(spec.Devices) != 2 {
+		t.Errorf("unexpected spec %+v", spec.Spec)
+	}
+
+	if names := cdiDevices(tree["multi"]["dev1"].cdiSpec); len(names) != 2 || names[1].Name != "intel.com/test=dev1" {
+		t.Errorf("unexpected CDI devices %v", names)
+	}
+
+	// Updated specs are rewritten.
+	updated := testCdiSpec("intel.com/test", "card0")
+	updated.Devices[0].ContainerEdits.Env = []string{"FOO=bar"}
+
+	if err := c.update(updateInfo{Updated: DeviceTree{"shared": {"card0-0": NewDeviceInfo(pluginapi.Healthy, nil, nil, nil, nil, updated)}}}); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if spec, err := cdi.ReadSpec(filepath.Join(dir, "intel.com-test-card0.yaml"), 0); err != nil || len(spec.Devices[0].ContainerEdits.Env) != 1 {
+		t.Errorf("spec was not updated: %+v", err)
+	}
+
+	// Specs of removed devices are removed.
+	if err := c.update(updateInfo{Removed: DeviceTree{"multi": nil}}); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	expected = []string{"example.com-test-dev1.yaml", "intel.com-test-card0.yaml"}
+	if files := listSpecFiles(t, dir); !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected spec files %v, got %v", expected, files)
+	}
+
+	if err := c.removeAll(); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	expected = []string{"example.com-test-dev1.yaml"}
+	if files := listSpecFiles(t, dir); !reflect.DeepEqual(files, expected) {
+		t.Errorf("expected spec files %v, got %v", expected, files)
+	}
+}
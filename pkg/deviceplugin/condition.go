@@ -0,0 +1,289 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/klog/v2"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+const (
+	// conditionUpdatePeriod is the minimum period between Node condition
+	// updates. Health changes within the period are merged into one update.
+	conditionUpdatePeriod = 10 * time.Second
+	// conditionRetryPeriod is the period of retrying a failed condition update.
+	conditionRetryPeriod = 30 * time.Second
+	// conditionMaxListed is the maximum number of unhealthy devices listed
+	// in the condition message.
+	conditionMaxListed = 5
+	// conditionStopTimeout is the maximum time of writing the condition when
+	// the plugin stops.
+	conditionStopTimeout = 5 * time.Second
+
+	conditionReasonHealthy   = "DevicesHealthy"
+	conditionReasonUnhealthy = "DevicesUnhealthy"
+	conditionReasonNoDevices = "NoDevices"
+	conditionReasonStopped   = "PluginStopped"
+)
+
+// nodeConditionType returns the Node condition type of a resource namespace,
+// e.g. "IntelGPUHealthy" for "gpu.intel.com".
+func nodeConditionType(namespace string) v1.NodeConditionType {
+	prefix, _, _ := strings.Cut(namespace, ".")
+
+	return v1.NodeConditionType("Intel" + strings.ToUpper(prefix) + "Healthy")
+}
+
+// nodeConditionReporter maintains a Node condition that summarizes the health
+// of the advertised devices, see WithNodeCondition().
+type nodeConditionReporter struct {
+	client        kubernetes.Interface
+	limiter       flowcontrol.RateLimiter
+	devices       DeviceTree
+	published     *v1.NodeCondition // nil until written
+	changed       chan struct{}
+	conditionType v1.NodeConditionType
+	namespace     string
+	nodeName      string
+	mutex         sync.Mutex
+}
+
+func newNodeConditionReporter(namespace, nodeName string, client kubernetes.Interface) (*nodeConditionReporter, error) {
+	if nodeName == "" {
+		return nil, errors.New("node name is required for the node condition")
+	}
+
+	return &nodeConditionReporter{
+		client:        client,
+		limiter:       flowcontrol.NewTokenBucketRateLimiter(float32(1/conditionUpdatePeriod.Seconds()), 1),
+		devices:       NewDeviceTree(),
+		changed:       make(chan struct{}, 1),
+		conditionType: nodeConditionType(namespace),
+		namespace:     namespace,
+		nodeName:      nodeName,
+	}, nil
+}
+
+// newNodeConditionReporterInCluster creates a node condition reporter using
+// the in-cluster config and the NODE_NAME environment variable.
+func newNodeConditionReporterInCluster(namespace string) (*nodeConditionReporter, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get in-cluster config")
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create clientset")
+	}
+
+	return newNodeConditionReporter(namespace, os.Getenv("NODE_NAME"), clientset)
+}
+
+// track records the devices of an update pushed to kubelet.
+func (r *nodeConditionReporter) track(update updateInfo) {
+	r.mutex.Lock()
+
+	for _, tree := range []DeviceTree{update.Added, update.Updated} {
+		for devType, devices := range tree {
+			r.devices[devType] = devices
+		}
+	}
+
+	for devType := range update.Removed {
+		delete(r.devices, devType)
+	}
+
+	r.mutex.Unlock()
+
+	select {
+	case r.changed <- struct{}{}:
+	default:
+	}
+}
+
+// condition returns the condition summarizing the tracked devices. Its
+// timestamps are set when written.
+func (r *nodeConditionReporter) condition() v1.NodeCondition {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	total := 0
+	unhealthy := []string{}
+
+	for devType, devices := range r.devices {
+		for id, dev := range devices {
+			total++
+
+			if dev.state == pluginapi.Unhealthy {
+				unhealthy = append(unhealthy, unhealthyDevice(devType, id, dev.healthReason))
+			}
+		}
+	}
+
+	cond := v1.NodeCondition{Type: r.conditionType}
+
+	switch {
+	case total == 0:
+		cond.Status = v1.ConditionUnknown
+		cond.Reason = conditionReasonNoDevices
+		cond.Message = fmt.Sprintf("No %s devices found", r.namespace)
+	case len(unhealthy) == 0:
+		cond.Status = v1.ConditionTrue
+		cond.Reason = conditionReasonHealthy
+		cond.Message = fmt.Sprintf("All %d %s devices are healthy", total, r.namespace)
+	default:
+		sort.Strings(unhealthy)
+
+		listed := unhealthy
+		if len(listed) > conditionMaxListed {
+			listed = append(listed[:conditionMaxListed:conditionMaxListed], "...")
+		}
+
+		cond.Status = v1.ConditionFalse
+		cond.Reason = conditionReasonUnhealthy
+		cond.Message = fmt.Sprintf("%d of %d %s devices are unhealthy: %s",
+			len(unhealthy), total, r.namespace, strings.Join(listed, ", "))
+	}
+
+	return cond
+}
+
+func unhealthyDevice(devType, id, reason string) string {
+	if reason == "" {
+		return devType + "/" + id
+	}
+
+	return fmt.Sprintf("%s/%s (%s)", devType, id, reason)
+}
+
+// run writes the condition whenever the health of the devices changes, at
+// most once per conditionUpdatePeriod, until ctx is done. Failed writes are
+// retried periodically. When ctx is done, the condition is set to Unknown so
+// that it doesn't go stale while the plugin is not running.
+func (r *nodeConditionReporter) run(ctx context.Context) {
+	ticker := time.NewTicker(conditionRetryPeriod)
+	defer ticker.Stop()
+
+	defer r.stop(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.changed:
+		case <-ticker.C:
+		}
+
+		if err := r.limiter.Wait(ctx); err != nil {
+			return
+		}
+
+		if err := r.sync(ctx); err != nil {
+			klog.FromContext(ctx).Error(err, "Failed to update the node condition, retrying", "condition", r.conditionType)
+		}
+	}
+}
+
+// sync writes the condition unless it's the same as the last written one.
+func (r *nodeConditionReporter) sync(ctx context.Context) error {
+	cond := r.condition()
+
+	prev := r.published
+	if prev != nil && prev.Status == cond.Status && prev.Reason == cond.Reason && prev.Message == cond.Message {
+		return nil
+	}
+
+	now := metav1.Now()
+
+	cond.LastHeartbeatTime = now
+	cond.LastTransitionTime = now
+
+	if prev != nil && prev.Status == cond.Status {
+		cond.LastTransitionTime = prev.LastTransitionTime
+	}
+
+	if err := r.publish(ctx, cond); err != nil {
+		return err
+	}
+
+	r.published = &cond
+
+	klog.FromContext(ctx).V(3).Info("Updated node condition", "condition", cond.Type, "status", cond.Status, "message", cond.Message)
+
+	return nil
+}
+
+// stop sets a written condition to Unknown. It uses a context of its own
+// since ctx is done already.
+func (r *nodeConditionReporter) stop(ctx context.Context) {
+	if r.published == nil {
+		return
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), conditionStopTimeout)
+	defer cancel()
+
+	now := metav1.Now()
+	cond := v1.NodeCondition{
+		Type:               r.conditionType,
+		Status:             v1.ConditionUnknown,
+		Reason:             conditionReasonStopped,
+		Message:            fmt.Sprintf("The %s device plugin is not running", r.namespace),
+		LastHeartbeatTime:  now,
+		LastTransitionTime: now,
+	}
+
+	if err := r.publish(stopCtx, cond); err != nil {
+		klog.FromContext(ctx).Error(err, "Failed to update the node condition on stop", "condition", r.conditionType)
+
+		return
+	}
+
+	r.published = &cond
+}
+
+// publish adds or replaces the condition in the Node status.
+func (r *nodeConditionReporter) publish(ctx context.Context, cond v1.NodeCondition) error {
+	// PatchStatus() uses a strategic merge patch, which merges the
+	// conditions of the Node status by type.
+	patch, err := json.Marshal(map[string]any{
+		"status": map[string]any{
+			"conditions": []v1.NodeCondition{cond},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to encode node condition")
+	}
+
+	_, err = r.client.CoreV1().Nodes().PatchStatus(ctx, r.nodeName, patch)
+
+	return errors.Wrapf(err, "failed to patch status of node %s", r.nodeName)
+}
@@ -0,0 +1,188 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+func getTestCondition(t *testing.T, r *nodeConditionReporter) *v1.NodeCondition {
+	t.Helper()
+
+	node, err := r.client.CoreV1().Nodes().Get(context.Background(), r.nodeName, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get node: %+v", err)
+	}
+
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == r.conditionType {
+			return &node.Status.Conditions[i]
+		}
+	}
+
+	return nil
+}
+
+func TestNodeConditionType(t *testing.T) {
+	for namespace, expected := range map[string]v1.NodeConditionType{
+		"gpu.intel.com":  "IntelGPUHealthy",
+		"qat.intel.com":  "IntelQATHealthy",
+		"fpga.intel.com": "IntelFPGAHealthy",
+	} {
+		if conditionType := nodeConditionType(namespace); conditionType != expected {
+			t.Errorf("%s: expected %s, got %s", namespace, expected, conditionType)
+		}
+	}
+}
+
+func TestNodeConditionReporter(t *testing.T) {
+	if _, err := newNodeConditionReporter("test.intel.com", "", fake.NewSimpleClientset()); err == nil {
+		t.Error("expected an error without a node name")
+	}
+
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node1"},
+		Status: v1.NodeStatus{
+			Conditions: []v1.NodeCondition{{Type: v1.NodeReady, Status: v1.ConditionTrue}},
+		},
+	}
+
+	r, err := newNodeConditionReporter("test.intel.com", "node1", fake.NewSimpleClientset(node))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	ctx := context.Background()
+
+	if err := r.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %+v", err)
+	}
+
+	if cond := getTestCondition(t, r); cond == nil || cond.Status != v1.ConditionUnknown || cond.Reason != conditionReasonNoDevices {
+		t.Errorf("unexpected condition without devices: %+v", cond)
+	}
+
+	tree := NewDeviceTree()
+	tree.AddDevice("dev", "dev1", DeviceInfo{state: pluginapi.Healthy})
+	tree.AddDevice("dev", "dev2", DeviceInfo{state: pluginapi.Healthy})
+	r.track(updateInfo{Added: tree})
+
+	if err := r.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %+v", err)
+	}
+
+	healthy := getTestCondition(t, r)
+	if healthy == nil || healthy.Status != v1.ConditionTrue || healthy.Message != "All 2 test.intel.com devices are healthy" {
+		t.Errorf("unexpected condition with healthy devices: %+v", healthy)
+	}
+
+	// The other conditions of the node are kept.
+	if n, _ := r.client.CoreV1().Nodes().Get(ctx, "node1", metav1.GetOptions{}); len(n.Status.Conditions) != 2 {
+		t.Errorf("expected the Ready and device conditions, got %+v", n.Status.Conditions)
+	}
+
+	// An unchanged condition is not written again.
+	client := r.client.(*fake.Clientset)
+	client.ClearActions()
+
+	r.track(updateInfo{Updated: DeviceTree{"dev": tree["dev"]}})
+
+	if err := r.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %+v", err)
+	}
+
+	if actions := client.Actions(); len(actions) != 0 {
+		t.Errorf("unchanged condition should not be written: %+v", actions)
+	}
+
+	unhealthy := DeviceInfo{state: pluginapi.Unhealthy, healthReason: "overheated"}
+	devices := map[string]DeviceInfo{"dev1": unhealthy, "dev2": tree["dev"]["dev2"]}
+
+	for i := range conditionMaxListed + 1 {
+		devices[fmt.Sprintf("hot%d", i)] = unhealthy
+	}
+
+	r.track(updateInfo{Updated: DeviceTree{"dev": devices}})
+
+	if err := r.sync(ctx); err != nil {
+		t.Fatalf("sync failed: %+v", err)
+	}
+
+	cond := getTestCondition(t, r)
+	if cond == nil || cond.Status != v1.ConditionFalse || cond.Reason != conditionReasonUnhealthy {
+		t.Fatalf("unexpected condition with unhealthy devices: %+v", cond)
+	}
+
+	if !strings.HasPrefix(cond.Message, "7 of 8 test.intel.com devices are unhealthy: dev/dev1 (overheated), dev/hot0 (overheated)") ||
+		!strings.HasSuffix(cond.Message, ", ...") {
+		t.Errorf("unexpected message %q", cond.Message)
+	}
+
+	if cond.LastTransitionTime.Before(&healthy.LastTransitionTime) {
+		t.Errorf("transition time was not updated: %+v", cond)
+	}
+}
+
+func TestNodeConditionReporterRun(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1"}}
+
+	r, err := newNodeConditionReporter("test.intel.com", "node1", fake.NewSimpleClientset(node))
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		r.run(ctx)
+		close(done)
+	}()
+
+	tree := NewDeviceTree()
+	tree.AddDevice("dev", "dev1", DeviceInfo{state: pluginapi.Unhealthy})
+	r.track(updateInfo{Added: tree})
+
+	// The first update is not delayed by the rate limiter.
+	for i := 0; ; i++ {
+		if cond := getTestCondition(t, r); cond != nil && cond.Status == v1.ConditionFalse {
+			break
+		}
+
+		if i == 100 {
+			t.Fatal("condition was not written")
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+
+	// The condition doesn't go stale when the plugin stops.
+	cond := getTestCondition(t, r)
+	if cond == nil || cond.Status != v1.ConditionUnknown || cond.Reason != conditionReasonStopped {
+		t.Errorf("unexpected condition after stop: %+v", cond)
+	}
+}
@@ -0,0 +1,213 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ConfigVersion is the version of the config file schema.
+	ConfigVersion = "v1"
+
+	// configReloadDelay coalesces the filesystem events of a config file
+	// update, e.g. the symlink swaps of a ConfigMap volume, into one reload.
+	configReloadDelay = time.Second
+
+	eventReasonInvalidConfig = "InvalidConfig"
+)
+
+// configFile is the schema of the config file shared by the device plugins:
+//
+//	version: v1
+//	plugins:
+//	  gpu:
+//	    sharedDevNum: 2
+//	  qat:
+//	    maxNumDevices: 16
+//	devices:
+//	  gpu.intel.com:
+//	    exclude:
+//	    - pciAddress: "0000:00:02.0"
+//
+// Each plugin decodes its own section, see Configurable. The device
+// selectors are by namespace.
+type configFile struct {
+	Plugins map[string]json.RawMessage `json:"plugins,omitempty"`
+	Devices map[string]*DeviceSelector `json:"devices,omitempty"`
+	Version string                     `json:"version"`
+}
+
+// parseConfig parses a YAML or JSON config file.
+func parseConfig(data []byte) (*configFile, error) {
+	cfg := &configFile{}
+
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, errors.Wrap(err, "failed to parse config")
+	}
+
+	if cfg.Version != ConfigVersion {
+		return nil, errors.Errorf("unsupported config version %q, expected %q", cfg.Version, ConfigVersion)
+	}
+
+	return cfg, nil
+}
+
+// DecodeConfigSection decodes a plugin section of the config file, as passed
+// to Configurable.ApplyConfig(), to v. Unknown fields are rejected. An empty
+// section leaves v as it is.
+func DecodeConfigSection(section []byte, v interface{}) error {
+	if len(section) == 0 {
+		return nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(section))
+	dec.DisallowUnknownFields()
+
+	return errors.Wrap(dec.Decode(v), "invalid config section")
+}
+
+// configLoader applies the plugin section of the config file to a
+// Configurable device plugin, and the device selector of the namespace to
+// Manager, and reapplies them whenever the file changes.
+type configLoader struct {
+	plugin      Configurable // nil when the plugin takes no settings
+	recorder    record.EventRecorder
+	node        *v1.ObjectReference
+	setSelector func(*DeviceSelector)
+	selector    *DeviceSelector // the last applied selector
+	namespace   string
+	path        string
+	section     []byte // the last applied section
+	loaded      bool
+}
+
+func newConfigLoader(path, namespace string, plugin Configurable, setSelector func(*DeviceSelector)) *configLoader {
+	return &configLoader{
+		path:        filepath.Clean(path),
+		namespace:   namespace,
+		plugin:      plugin,
+		setSelector: setSelector,
+	}
+}
+
+// load reads the config file and applies the plugin section and the device
+// selector, unless they are the same as the last applied ones.
+func (c *configLoader) load() error {
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return errors.Wrap(err, "failed to read config")
+	}
+
+	cfg, err := parseConfig(data)
+	if err != nil {
+		return err
+	}
+
+	selector := cfg.Devices[c.namespace]
+	if selector != nil {
+		if err := selector.Validate(); err != nil {
+			return errors.Wrapf(err, "invalid %q device selector", c.namespace)
+		}
+	}
+
+	if c.plugin != nil {
+		name := c.plugin.ConfigSection()
+
+		if section := cfg.Plugins[name]; !c.loaded || !bytes.Equal(section, c.section) {
+			if err := c.plugin.ApplyConfig(section); err != nil {
+				return errors.Wrapf(err, "invalid %q plugin config", name)
+			}
+
+			c.section = section
+
+			klog.V(1).InfoS("Applied plugin config", "section", name, "path", c.path)
+		}
+	}
+
+	if c.setSelector != nil && (!c.loaded || !reflect.DeepEqual(selector, c.selector)) {
+		c.setSelector(selector)
+		c.selector = selector
+
+		klog.V(1).InfoS("Applied device selector", "path", c.path)
+	}
+
+	c.loaded = true
+
+	return nil
+}
+
+// reload loads the changed config file. An invalid config is rejected and
+// reported as a Node event, and the previous config stays in effect.
+func (c *configLoader) reload() {
+	err := c.load()
+	if err == nil {
+		return
+	}
+
+	klog.ErrorS(err, "Rejected config, keeping the previous one", "path", c.path)
+
+	if c.recorder != nil {
+		c.recorder.Eventf(c.node, v1.EventTypeWarning, eventReasonInvalidConfig, "Rejected device plugin config %s: %v", c.path, err)
+	}
+}
+
+// watch reloads the config file on changes until ctx is done. The directory
+// of the file is watched, so that the file can be replaced.
+func (c *configLoader) watch(ctx context.Context) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Wrap(err, "failed to create config watcher")
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(filepath.Dir(c.path)); err != nil {
+		return errors.Wrapf(err, "failed to watch %s", c.path)
+	}
+
+	var delay <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case ev := <-watcher.Events:
+			klog.FromContext(ctx).V(4).Info("Config directory event", "event", ev.String())
+
+			if delay == nil {
+				delay = time.After(configReloadDelay)
+			}
+		case <-delay:
+			delay = nil
+
+			c.reload()
+		case err := <-watcher.Errors:
+			klog.FromContext(ctx).Error(err, "Config watcher error")
+		}
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/tools/record"
+)
+
+type testConfig struct {
+	SharedDevNum *int `json:"sharedDevNum,omitempty"`
+}
+
+type configurablePluginStub struct {
+	devicePluginStub
+	applied chan int
+}
+
+func (p *configurablePluginStub) ConfigSection() string {
+	return "test"
+}
+
+func (p *configurablePluginStub) ApplyConfig(section []byte) error {
+	cfg := testConfig{}
+	if err := DecodeConfigSection(section, &cfg); err != nil {
+		return err
+	}
+
+	sharedDevNum := 1
+	if cfg.SharedDevNum != nil {
+		sharedDevNum = *cfg.SharedDevNum
+	}
+
+	if sharedDevNum < 1 {
+		return errors.New("sharedDevNum must be greater than zero")
+	}
+
+	p.applied <- sharedDevNum
+
+	return nil
+}
+
+func writeTestConfig(t *testing.T, path, data string) {
+	t.Helper()
+
+	// Replace the file like kubelet updates ConfigMap volumes.
+	tmp := path + ".tmp"
+
+	if err := os.WriteFile(tmp, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	tcases := []struct {
+		name        string
+		data        string
+		section     string
+		expectedErr bool
+	}{
+		{
+			name:    "yaml",
+			data:    "version: v1\nplugins:\n  test:\n    sharedDevNum: 2\n",
+			section: `{"sharedDevNum":2}`,
+		},
+		{
+			name:    "json",
+			data:    `{"version": "v1", "plugins": {"test": {"sharedDevNum": 2}}}`,
+			section: `{"sharedDevNum":2}`,
+		},
+		{
+			name: "no plugin sections",
+			data: "version: v1\n",
+		},
+		{
+			name:        "unsupported version",
+			data:        "version: v2\n",
+			expectedErr: true,
+		},
+		{
+			name:        "unknown field",
+			data:        "version: v1\nplugin:\n  test: {}\n",
+			expectedErr: true,
+		},
+		{
+			name:        "invalid yaml",
+			data:        "version: [v1\n",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := parseConfig([]byte(tc.data))
+			if tc.expectedErr != (err != nil) {
+				t.Fatalf("unexpected error state: %+v", err)
+			}
+
+			if !tc.expectedErr && string(cfg.Plugins["test"]) != tc.section {
+				t.Errorf("expected section %s, got %s", tc.section, cfg.Plugins["test"])
+			}
+		})
+	}
+}
+
+func TestDecodeConfigSection(t *testing.T) {
+	cfg := testConfig{}
+
+	if err := DecodeConfigSection(nil, &cfg); err != nil || cfg.SharedDevNum != nil {
+		t.Errorf("empty section should leave the config as it is: %+v", err)
+	}
+
+	if err := DecodeConfigSection([]byte(`{"sharedDevNum": 3}`), &cfg); err != nil || *cfg.SharedDevNum != 3 {
+		t.Errorf("unexpected result %+v: %+v", cfg, err)
+	}
+
+	if err := DecodeConfigSection([]byte(`{"sharedDevNumber": 3}`), &cfg); err == nil {
+		t.Error("expected an error with an unknown field")
+	}
+}
+
+func TestConfigLoader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	plugin := &configurablePluginStub{applied: make(chan int, 10)}
+	recorder := record.NewFakeRecorder(10)
+
+	c := newConfigLoader(path, "testnamespace", plugin, nil)
+	c.recorder = recorder
+
+	if err := c.load(); err == nil {
+		t.Error("expected an error with a missing config")
+	}
+
+	writeTestConfig(t, path, "version: v1\nplugins:\n  other:\n    foo: bar\n")
+
+	if err := c.load(); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if applied := <-plugin.applied; applied != 1 {
+		t.Errorf("expected the defaults without a section, got %d", applied)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		if err := c.watch(ctx); err != nil {
+			t.Errorf("unexpected error: %+v", err)
+		}
+	}()
+
+	// Let the watcher start.
+	time.Sleep(100 * time.Millisecond)
+
+	writeTestConfig(t, path, "version: v1\nplugins:\n  test:\n    sharedDevNum: 0\n")
+
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, eventReasonInvalidConfig) || !strings.Contains(event, "greater than zero") {
+			t.Errorf("unexpected event %q", event)
+		}
+	case <-time.After(5 * configReloadDelay):
+		t.Fatal("invalid config was not reported")
+	}
+
+	writeTestConfig(t, path, "version: v1\nplugins:\n  test:\n    sharedDevNum: 4\n")
+
+	select {
+	case applied := <-plugin.applied:
+		if applied != 4 {
+			t.Errorf("expected the reloaded config, got %d", applied)
+		}
+	case <-time.After(5 * configReloadDelay):
+		t.Fatal("config was not reloaded")
+	}
+
+	// Changes to other sections are not passed to the plugin.
+	writeTestConfig(t, path, "version: v1\nplugins:\n  test:\n    sharedDevNum: 4\n  other: {}\n")
+	time.Sleep(2 * configReloadDelay)
+
+	if len(plugin.applied) != 0 || len(recorder.Events) != 0 {
+		t.Error("unchanged section should not be applied again")
+	}
+}
+
+func TestManagerConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeTestConfig(t, path, "version: v1\ndevices:\n  testnamespace:\n    exclude:\n    - {}\n")
+
+	if err := NewManager("testnamespace", &devicePluginStub{}, WithConfigFile(path)).Run(context.Background()); err == nil {
+		t.Error("expected an error with an invalid device selector")
+	}
+
+	writeTestConfig(t, path, "version: v1\nplugins:\n  test:\n    sharedDevNum: 0\n")
+
+	plugin := &configurablePluginStub{applied: make(chan int, 10)}
+
+	if err := NewManager("testnamespace", plugin, WithConfigFile(path)).Run(context.Background()); err == nil {
+		t.Error("expected an error with an invalid config")
+	}
+
+	writeTestConfig(t, path, "version: v1\nplugins:\n  test:\n    sharedDevNum: 2\n")
+
+	mgr := NewManager("testnamespace", plugin, WithConfigFile(path))
+	mgr.createServer = func(string, string, postAllocateFunc, preStartContainerFunc, getPreferredAllocationFunc, allocateFunc) devicePluginServer {
+		return &serverStub{}
+	}
+
+	if err := mgr.Run(context.Background()); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+
+	if applied := <-plugin.applied; applied != 2 {
+		t.Errorf("expected the config to be applied before the scan, got %d", applied)
+	}
+}
@@ -0,0 +1,686 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	v1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1alpha3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	drapb "k8s.io/kubelet/pkg/apis/dra/v1alpha4"
+	registerapi "k8s.io/kubelet/pkg/apis/pluginregistration/v1"
+	"k8s.io/utils/ptr"
+	"tags.cncf.io/container-device-interface/pkg/cdi"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+const (
+	// DRAPluginRegistryDir is the directory where kubelet looks for plugin registration sockets.
+	DRAPluginRegistryDir = "/var/lib/kubelet/plugins_registry"
+	// DRAPluginDir is the directory where DRA drivers create their kubelet API sockets.
+	DRAPluginDir = "/var/lib/kubelet/plugins"
+
+	// draSupportedVersion is the kubelet DRA plugin API version the driver implements (v1alpha4).
+	draSupportedVersion = "1.0.0"
+
+	rpcNodePrepareResources   = "NodePrepareResources"
+	rpcNodeUnprepareResources = "NodeUnprepareResources"
+
+	draAttributeResource = "resource"
+	draAttributeID       = "id"
+	draAttributeNUMANode = "numaNode"
+
+	draNameMaxLength = 63
+)
+
+var draInvalidNameChars = regexp.MustCompile("[^a-z0-9-]+")
+
+// draName converts a device ID or a device type into a DNS label
+// accepted as a DRA device or pool name.
+func draName(name string) string {
+	name = draInvalidNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	if len(name) > draNameMaxLength {
+		name = name[:draNameMaxLength]
+	}
+
+	return strings.Trim(name, "-")
+}
+
+// draPool holds the devices of one device type published as a ResourceSlice pool.
+type draPool struct {
+	devices    map[string]DeviceInfo // DRA device name -> device info
+	ids        map[string]string     // DRA device name -> device ID
+	devType    string
+	name       string
+	sliceNames []string
+	generation int64
+}
+
+// draClaim holds the result of a prepared ResourceClaim.
+type draClaim struct {
+	specName string
+	devices  []*drapb.Device
+}
+
+// draDriver publishes the devices found by a Scanner as ResourceSlices and
+// prepares ResourceClaims allocated to them over the kubelet DRA plugin API.
+type draDriver struct {
+	clientset   kubernetes.Interface
+	node        *v1.Node
+	grpcServer  *grpc.Server
+	pools       map[string]*draPool  // device type -> pool
+	poolNames   map[string]string    // pool name -> device type
+	claims      map[string]*draClaim // claim UID -> prepared claim
+	driverName  string
+	cdiClass    string
+	cdiDir      string
+	registryDir string
+	pluginDir   string
+	mutex       sync.Mutex
+}
+
+// newDRADriver creates a DRA driver for the given namespace, e.g. "gpu.intel.com",
+// which is used as the driver name.
+func newDRADriver(namespace, nodeName string, clientset kubernetes.Interface) (*draDriver, error) {
+	if nodeName == "" {
+		return nil, errors.New("node name is required for DRA mode")
+	}
+
+	node, err := clientset.CoreV1().Nodes().Get(context.Background(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get node %s", nodeName)
+	}
+
+	return &draDriver{
+		clientset:   clientset,
+		node:        node,
+		driverName:  namespace,
+		cdiClass:    strings.Split(namespace, ".")[0],
+		cdiDir:      CDIDir,
+		registryDir: DRAPluginRegistryDir,
+		pluginDir:   filepath.Join(DRAPluginDir, namespace),
+		pools:       make(map[string]*draPool),
+		poolNames:   make(map[string]string),
+		claims:      make(map[string]*draClaim),
+	}, nil
+}
+
+// newDRADriverInCluster creates a DRA driver using the in-cluster config and
+// the NODE_NAME environment variable.
+func newDRADriverInCluster(namespace string) (*draDriver, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get in-cluster config")
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create clientset")
+	}
+
+	return newDRADriver(namespace, os.Getenv("NODE_NAME"), clientset)
+}
+
+func (d *draDriver) endpoint() string {
+	return filepath.Join(d.pluginDir, "dra.sock")
+}
+
+func (d *draDriver) registrationSocket() string {
+	return filepath.Join(d.registryDir, d.driverName+"-reg.sock")
+}
+
+// Serve removes stale ResourceSlices of the node, starts the kubelet DRA
+// plugin API and makes the driver discoverable by kubelet.
+func (d *draDriver) Serve() error {
+	if err := d.removeStaleSlices(); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(d.pluginDir, 0o750); err != nil {
+		return errors.WithStack(err)
+	}
+
+	d.grpcServer = grpc.NewServer()
+	drapb.RegisterNodeServer(d.grpcServer, d)
+	registerapi.RegisterRegistrationServer(d.grpcServer, d)
+
+	// Kubelet talks to both sockets, it connects to the endpoint only after
+	// finding the registration socket, so the endpoint is started first.
+	for _, socket := range []string{d.endpoint(), d.registrationSocket()} {
+		// We don't care if the socket file doesn't exist.
+		_ = os.Remove(socket)
+
+		lis, err := net.Listen("unix", socket)
+		if err != nil {
+			return errors.Wrapf(err, "failed to listen to socket %s", socket)
+		}
+
+		go func() {
+			if err := d.grpcServer.Serve(lis); err != nil {
+				klog.ErrorS(err, "DRA gRPC server stopped", "driver", d.driverName, "socket", socket)
+			}
+		}()
+
+		if err := waitForServer(socket, 10*time.Second); err != nil {
+			return err
+		}
+	}
+
+	klog.V(1).InfoS("DRA driver serving", "driver", d.driverName, "socket", d.endpoint())
+
+	return nil
+}
+
+// Stop stops the kubelet DRA plugin API and deletes the published ResourceSlices.
+func (d *draDriver) Stop() error {
+	if d.grpcServer == nil {
+		return errors.New("Can't stop non-existing gRPC server. Calling Stop() before Serve()?")
+	}
+
+	d.grpcServer.Stop()
+
+	_ = os.Remove(d.registrationSocket())
+	_ = os.Remove(d.endpoint())
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	for devType := range d.pools {
+		d.removePool(devType)
+	}
+
+	return nil
+}
+
+// GetInfo implements the kubelet plugin registration API.
+func (d *draDriver) GetInfo(ctx context.Context, req *registerapi.InfoRequest) (*registerapi.PluginInfo, error) {
+	return &registerapi.PluginInfo{
+		Type:              registerapi.DRAPlugin,
+		Name:              d.driverName,
+		Endpoint:          d.endpoint(),
+		SupportedVersions: []string{draSupportedVersion},
+	}, nil
+}
+
+// NotifyRegistrationStatus implements the kubelet plugin registration API.
+func (d *draDriver) NotifyRegistrationStatus(ctx context.Context, status *registerapi.RegistrationStatus) (*registerapi.RegistrationStatusResponse, error) {
+	if !status.PluginRegistered {
+		klog.FromContext(ctx).Error(nil, "DRA driver registration failed", "driver", d.driverName, "reason", status.Error)
+
+		return &registerapi.RegistrationStatusResponse{}, nil
+	}
+
+	registrationsCounter.WithLabelValues(d.driverName).Inc()
+
+	klog.FromContext(ctx).V(1).Info("DRA driver registered", "driver", d.driverName)
+
+	return &registerapi.RegistrationStatusResponse{}, nil
+}
+
+// NodePrepareResources implements the kubelet DRA plugin API.
+func (d *draDriver) NodePrepareResources(ctx context.Context, req *drapb.NodePrepareResourcesRequest) (*drapb.NodePrepareResourcesResponse, error) {
+	start := time.Now()
+	resp := &drapb.NodePrepareResourcesResponse{
+		Claims: make(map[string]*drapb.NodePrepareResourceResponse),
+	}
+
+	var lastErr error
+
+	for _, claim := range req.Claims {
+		devices, err := d.prepareClaim(ctx, claim)
+		if err != nil {
+			klog.FromContext(ctx).Error(err, "Failed to prepare claim", "claim", klog.KRef(claim.Namespace, claim.Name))
+
+			resp.Claims[claim.UID] = &drapb.NodePrepareResourceResponse{Error: err.Error()}
+			lastErr = err
+
+			continue
+		}
+
+		resp.Claims[claim.UID] = &drapb.NodePrepareResourceResponse{Devices: devices}
+	}
+
+	observeRPC(d.driverName, rpcNodePrepareResources, start, lastErr)
+
+	return resp, nil
+}
+
+// NodeUnprepareResources implements the kubelet DRA plugin API.
+func (d *draDriver) NodeUnprepareResources(ctx context.Context, req *drapb.NodeUnprepareResourcesRequest) (*drapb.NodeUnprepareResourcesResponse, error) {
+	start := time.Now()
+	resp := &drapb.NodeUnprepareResourcesResponse{
+		Claims: make(map[string]*drapb.NodeUnprepareResourceResponse),
+	}
+
+	var lastErr error
+
+	for _, claim := range req.Claims {
+		result := &drapb.NodeUnprepareResourceResponse{}
+
+		if err := d.unprepareClaim(claim); err != nil {
+			klog.FromContext(ctx).Error(err, "Failed to unprepare claim", "claim", klog.KRef(claim.Namespace, claim.Name))
+
+			result.Error = err.Error()
+			lastErr = err
+		}
+
+		resp.Claims[claim.UID] = result
+	}
+
+	observeRPC(d.driverName, rpcNodeUnprepareResources, start, lastErr)
+
+	return resp, nil
+}
+
+func (d *draDriver) prepareClaim(ctx context.Context, claim *drapb.Claim) ([]*drapb.Device, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if prepared, ok := d.claims[claim.UID]; ok {
+		return prepared.devices, nil
+	}
+
+	resourceClaim, err := d.clientset.ResourceV1alpha3().ResourceClaims(claim.Namespace).Get(ctx, claim.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get ResourceClaim")
+	}
+
+	if string(resourceClaim.UID) != claim.UID {
+		return nil, errors.Errorf("ResourceClaim UID mismatch: expected %s, got %s", claim.UID, resourceClaim.UID)
+	}
+
+	if resourceClaim.Status.Allocation == nil {
+		return nil, errors.New("ResourceClaim is not allocated")
+	}
+
+	spec := &cdispec.Spec{
+		Version: CDIVersion,
+		Kind:    CDIVendor + "/" + d.cdiClass,
+	}
+
+	devices := []*drapb.Device{}
+
+	for _, result := range resourceClaim.Status.Allocation.Devices.Results {
+		if result.Driver != d.driverName {
+			continue
+		}
+
+		info, err := d.lookupDevice(result.Pool, result.Device)
+		if err != nil {
+			return nil, err
+		}
+
+		cdiDevice := cdispec.Device{
+			Name:           claim.UID + "-" + result.Device,
+			ContainerEdits: cdiContainerEdits(info),
+		}
+		spec.Devices = append(spec.Devices, cdiDevice)
+
+		devices = append(devices, &drapb.Device{
+			RequestNames: []string{result.Request},
+			PoolName:     result.Pool,
+			DeviceName:   result.Device,
+			CDIDeviceIDs: []string{cdi.QualifiedName(CDIVendor, d.cdiClass, cdiDevice.Name)},
+		})
+	}
+
+	prepared := &draClaim{devices: devices}
+
+	if len(spec.Devices) > 0 {
+		prepared.specName = d.claimSpecName(claim.UID)
+
+		if err := writeClaimCdiSpec(spec, d.cdiDir, prepared.specName); err != nil {
+			cdiSpecWriteFailuresCounter.WithLabelValues(d.driverName).Inc()
+
+			return nil, err
+		}
+	}
+
+	d.claims[claim.UID] = prepared
+
+	klog.FromContext(ctx).V(2).Info("Prepared claim", "claim", klog.KRef(claim.Namespace, claim.Name), "devices", len(devices))
+
+	return devices, nil
+}
+
+func (d *draDriver) unprepareClaim(claim *drapb.Claim) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	specName := d.claimSpecName(claim.UID)
+
+	// The claim might have been prepared before a plugin restart, so
+	// the spec file is removed even if the claim is not known.
+	if err := os.Remove(filepath.Join(d.cdiDir, specName)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.WithStack(err)
+	}
+
+	delete(d.claims, claim.UID)
+
+	return nil
+}
+
+// claimSpecName returns the name of the CDI spec file of a claim.
+func (d *draDriver) claimSpecName(claimUID string) string {
+	return fmt.Sprintf("%s-%s-%s.yaml", CDIVendor, d.cdiClass, claimUID)
+}
+
+// writeClaimCdiSpec writes the CDI spec of a claim to the given file in cdiDir.
+func writeClaimCdiSpec(spec *cdispec.Spec, cdiDir, specFileName string) error {
+	cache, err := cdi.NewCache(cdi.WithAutoRefresh(false), cdi.WithSpecDirs(cdiDir))
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := cache.WriteSpec(spec, specFileName); err != nil {
+		return errors.WithStack(err)
+	}
+
+	// Fix access issues due to: https://github.com/cncf-tags/container-device-interface/issues/224
+	return errors.WithStack(os.Chmod(filepath.Join(cdiDir, specFileName), 0o644))
+}
+
+func (d *draDriver) lookupDevice(poolName, deviceName string) (DeviceInfo, error) {
+	devType, ok := d.poolNames[poolName]
+	if !ok {
+		return DeviceInfo{}, errors.Errorf("unknown pool %s", poolName)
+	}
+
+	info, ok := d.pools[devType].devices[deviceName]
+	if !ok {
+		return DeviceInfo{}, errors.Errorf("unknown device %s in pool %s", deviceName, poolName)
+	}
+
+	if info.state != pluginapi.Healthy {
+		return DeviceInfo{}, errors.Errorf("device %s in pool %s is unhealthy", deviceName, poolName)
+	}
+
+	return info, nil
+}
+
+// Update publishes the devices of a device type as ResourceSlices.
+func (d *draDriver) Update(devType string, devices map[string]DeviceInfo) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	pool, ok := d.pools[devType]
+	if !ok {
+		pool = &draPool{
+			devType: devType,
+			name:    d.node.Name + "/" + draName(devType),
+		}
+		d.pools[devType] = pool
+		d.poolNames[pool.name] = devType
+	}
+
+	pool.devices = make(map[string]DeviceInfo, len(devices))
+	pool.ids = make(map[string]string, len(devices))
+
+	for id, info := range devices {
+		name := draName(id)
+		if prev, found := pool.ids[name]; found {
+			klog.InfoS("Devices map to the same DRA device name, skipping the latter", LogKeyResource, d.driverName+"/"+devType, LogKeyDeviceID, id, "previous", prev, "name", name)
+
+			continue
+		}
+
+		pool.devices[name] = info
+		pool.ids[name] = id
+	}
+
+	pool.generation++
+
+	if err := d.publishPool(pool); err != nil {
+		klog.ErrorS(err, "Failed to publish ResourceSlices", LogKeyResource, d.driverName+"/"+devType)
+	}
+}
+
+// Remove deletes the ResourceSlices of a device type.
+func (d *draDriver) Remove(devType string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.removePool(devType)
+}
+
+func (d *draDriver) removePool(devType string) {
+	pool, ok := d.pools[devType]
+	if !ok {
+		return
+	}
+
+	for _, name := range pool.sliceNames {
+		if err := d.deleteSlice(name); err != nil {
+			klog.ErrorS(err, "Failed to delete ResourceSlice", "resourceSlice", name)
+		}
+	}
+
+	delete(d.poolNames, pool.name)
+	delete(d.pools, devType)
+}
+
+func (d *draDriver) publishPool(pool *draPool) error {
+	devices := d.resourceSliceDevices(pool)
+
+	sliceCount := (len(devices) + resourceapi.ResourceSliceMaxDevices - 1) / resourceapi.ResourceSliceMaxDevices
+	if sliceCount == 0 {
+		// An empty slice tells the scheduler that the pool exists but has no devices.
+		sliceCount = 1
+	}
+
+	sliceNames := make([]string, 0, sliceCount)
+
+	for i := 0; i < sliceCount; i++ {
+		end := min((i+1)*resourceapi.ResourceSliceMaxDevices, len(devices))
+		slice := d.newResourceSlice(pool, i, int64(sliceCount), devices[i*resourceapi.ResourceSliceMaxDevices:end])
+
+		if err := d.applySlice(slice); err != nil {
+			return err
+		}
+
+		sliceNames = append(sliceNames, slice.Name)
+	}
+
+	for _, name := range pool.sliceNames[min(len(pool.sliceNames), sliceCount):] {
+		if err := d.deleteSlice(name); err != nil {
+			return err
+		}
+	}
+
+	pool.sliceNames = sliceNames
+
+	return nil
+}
+
+func (d *draDriver) resourceSliceDevices(pool *draPool) []resourceapi.Device {
+	names := make([]string, 0, len(pool.devices))
+
+	for name, info := range pool.devices {
+		// DRA has no notion of device health, so only healthy devices are published.
+		if info.state != pluginapi.Healthy {
+			continue
+		}
+
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	devices := make([]resourceapi.Device, 0, len(names))
+
+	for _, name := range names {
+		attributes := map[resourceapi.QualifiedName]resourceapi.DeviceAttribute{
+			draAttributeResource: {StringValue: ptr.To(pool.devType)},
+			draAttributeID:       {StringValue: ptr.To(pool.ids[name])},
+		}
+
+		if topology := pool.devices[name].topology; topology != nil && len(topology.Nodes) > 0 {
+			attributes[draAttributeNUMANode] = resourceapi.DeviceAttribute{IntValue: ptr.To(topology.Nodes[0].ID)}
+		}
+
+		devices = append(devices, resourceapi.Device{
+			Name:  name,
+			Basic: &resourceapi.BasicDevice{Attributes: attributes},
+		})
+	}
+
+	return devices
+}
+
+func (d *draDriver) newResourceSlice(pool *draPool, index int, count int64, devices []resourceapi.Device) *resourceapi.ResourceSlice {
+	return &resourceapi.ResourceSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: fmt.Sprintf("%s-%s-%s-%d", d.node.Name, draName(d.driverName), draName(pool.devType), index),
+			OwnerReferences: []metav1.OwnerReference{
+				{
+					APIVersion: "v1",
+					Kind:       "Node",
+					Name:       d.node.Name,
+					UID:        d.node.UID,
+					Controller: ptr.To(true),
+				},
+			},
+		},
+		Spec: resourceapi.ResourceSliceSpec{
+			Driver:   d.driverName,
+			NodeName: d.node.Name,
+			Pool: resourceapi.ResourcePool{
+				Name:               pool.name,
+				Generation:         pool.generation,
+				ResourceSliceCount: count,
+			},
+			Devices: devices,
+		},
+	}
+}
+
+func (d *draDriver) applySlice(slice *resourceapi.ResourceSlice) error {
+	client := d.clientset.ResourceV1alpha3().ResourceSlices()
+
+	existing, err := client.Get(context.Background(), slice.Name, metav1.GetOptions{})
+
+	switch {
+	case apierrors.IsNotFound(err):
+		_, err = client.Create(context.Background(), slice, metav1.CreateOptions{})
+	case err == nil:
+		slice.ResourceVersion = existing.ResourceVersion
+		_, err = client.Update(context.Background(), slice, metav1.UpdateOptions{})
+	}
+
+	return errors.Wrapf(err, "failed to apply ResourceSlice %s", slice.Name)
+}
+
+func (d *draDriver) deleteSlice(name string) error {
+	err := d.clientset.ResourceV1alpha3().ResourceSlices().Delete(context.Background(), name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return errors.Wrapf(err, "failed to delete ResourceSlice %s", name)
+	}
+
+	return nil
+}
+
+// removeStaleSlices deletes the ResourceSlices a previous instance of
+// the driver left behind for the node.
+func (d *draDriver) removeStaleSlices() error {
+	selector := fields.Set{
+		resourceapi.ResourceSliceSelectorNodeName: d.node.Name,
+		resourceapi.ResourceSliceSelectorDriver:   d.driverName,
+	}.AsSelector().String()
+
+	slices, err := d.clientset.ResourceV1alpha3().ResourceSlices().List(context.Background(), metav1.ListOptions{FieldSelector: selector})
+	if err != nil {
+		return errors.Wrap(err, "failed to list ResourceSlices")
+	}
+
+	for _, slice := range slices.Items {
+		if slice.Spec.NodeName != d.node.Name || slice.Spec.Driver != d.driverName {
+			continue
+		}
+
+		if err := d.deleteSlice(slice.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// cdiContainerEdits converts the device nodes, mounts and environment
+// variables of a device, and the edits of its CDI spec, to CDI container edits.
+func cdiContainerEdits(info DeviceInfo) cdispec.ContainerEdits {
+	edits := cdispec.ContainerEdits{}
+
+	for _, node := range info.nodes {
+		edits.DeviceNodes = append(edits.DeviceNodes, &cdispec.DeviceNode{
+			Path:        node.ContainerPath,
+			HostPath:    node.HostPath,
+			Permissions: node.Permissions,
+		})
+	}
+
+	for _, mount := range info.mounts {
+		options := []string{"bind"}
+		if mount.ReadOnly {
+			options = append(options, "ro")
+		}
+
+		edits.Mounts = append(edits.Mounts, &cdispec.Mount{
+			HostPath:      mount.HostPath,
+			ContainerPath: mount.ContainerPath,
+			Type:          "none",
+			Options:       options,
+		})
+	}
+
+	keys := make([]string, 0, len(info.envs))
+	for key := range info.envs {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		edits.Env = append(edits.Env, key+"="+info.envs[key])
+	}
+
+	if info.cdiSpec != nil {
+		for _, dev := range info.cdiSpec.Devices {
+			edits.Env = append(edits.Env, dev.ContainerEdits.Env...)
+			edits.DeviceNodes = append(edits.DeviceNodes, dev.ContainerEdits.DeviceNodes...)
+			edits.Hooks = append(edits.Hooks, dev.ContainerEdits.Hooks...)
+			edits.Mounts = append(edits.Mounts, dev.ContainerEdits.Mounts...)
+		}
+	}
+
+	return edits
+}
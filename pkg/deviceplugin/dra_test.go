@@ -0,0 +1,415 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	v1 "k8s.io/api/core/v1"
+	resourceapi "k8s.io/api/resource/v1alpha3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	drapb "k8s.io/kubelet/pkg/apis/dra/v1alpha4"
+	registerapi "k8s.io/kubelet/pkg/apis/pluginregistration/v1"
+)
+
+const (
+	testDRANode   = "node1"
+	testDRADriver = "test.intel.com"
+)
+
+func newTestDRADriver(t *testing.T, objects ...runtime.Object) *draDriver {
+	t.Helper()
+
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: testDRANode, UID: "node-uid"}}
+	clientset := fake.NewSimpleClientset(append(objects, node)...)
+
+	d, err := newDRADriver(testDRADriver, testDRANode, clientset)
+	if err != nil {
+		t.Fatalf("unable to create DRA driver: %+v", err)
+	}
+
+	tmpdir := t.TempDir()
+	d.cdiDir = filepath.Join(tmpdir, "cdi")
+	d.registryDir = filepath.Join(tmpdir, "plugins_registry")
+	d.pluginDir = filepath.Join(tmpdir, "plugins", testDRADriver)
+
+	for _, dir := range []string{d.cdiDir, d.registryDir} {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return d
+}
+
+func listSlices(t *testing.T, d *draDriver) []resourceapi.ResourceSlice {
+	t.Helper()
+
+	slices, err := d.clientset.ResourceV1alpha3().ResourceSlices().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("unable to list ResourceSlices: %+v", err)
+	}
+
+	return slices.Items
+}
+
+func TestNewDRADriver(t *testing.T) {
+	if _, err := newDRADriver(testDRADriver, "", fake.NewSimpleClientset()); err == nil {
+		t.Error("expected an error for an empty node name")
+	}
+
+	if _, err := newDRADriver(testDRADriver, testDRANode, fake.NewSimpleClientset()); err == nil {
+		t.Error("expected an error for a missing node")
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	tcases := []struct {
+		input       string
+		expected    Mode
+		expectedErr bool
+	}{
+		{input: "classic", expected: ModeClassic},
+		{input: "dra", expected: ModeDRA},
+		{input: "both", expected: ModeBoth},
+		{input: "", expectedErr: true},
+		{input: "DRA", expectedErr: true},
+	}
+
+	for _, tc := range tcases {
+		mode, err := ParseMode(tc.input)
+		if tc.expectedErr != (err != nil) {
+			t.Errorf("%q: unexpected error state: %+v", tc.input, err)
+		}
+
+		if mode != tc.expected {
+			t.Errorf("%q: expected %q, got %q", tc.input, tc.expected, mode)
+		}
+	}
+}
+
+func TestDRAName(t *testing.T) {
+	tcases := []struct {
+		input    string
+		expected string
+	}{
+		{input: "card0", expected: "card0"},
+		{input: "0000:00:02.0", expected: "0000-00-02-0"},
+		{input: "dlb0-vf", expected: "dlb0-vf"},
+		{input: "/dev/dsa/wq0.1", expected: "dev-dsa-wq0-1"},
+		{input: "Region_1", expected: "region-1"},
+		{input: string(make([]byte, 70)), expected: ""},
+	}
+
+	for _, tc := range tcases {
+		if name := draName(tc.input); name != tc.expected {
+			t.Errorf("%q: expected %q, got %q", tc.input, tc.expected, name)
+		}
+	}
+}
+
+func TestDRAUpdate(t *testing.T) {
+	d := newTestDRADriver(t)
+
+	topology := &pluginapi.TopologyInfo{Nodes: []*pluginapi.NUMANode{{ID: 1}}}
+
+	d.Update("gpu", map[string]DeviceInfo{
+		"card0": NewDeviceInfoWithTopologyHints(pluginapi.Healthy, nil, nil, nil, nil, topology, nil),
+		"card1": NewDeviceInfo(pluginapi.Unhealthy, nil, nil, nil, nil, nil),
+	})
+
+	slices := listSlices(t, d)
+	if len(slices) != 1 {
+		t.Fatalf("expected 1 ResourceSlice, got %d", len(slices))
+	}
+
+	slice := slices[0]
+	if slice.Spec.Driver != testDRADriver || slice.Spec.NodeName != testDRANode {
+		t.Errorf("unexpected driver %q or node %q", slice.Spec.Driver, slice.Spec.NodeName)
+	}
+
+	if slice.Spec.Pool.Name != testDRANode+"/gpu" || slice.Spec.Pool.Generation != 1 {
+		t.Errorf("unexpected pool %+v", slice.Spec.Pool)
+	}
+
+	if len(slice.OwnerReferences) != 1 || slice.OwnerReferences[0].UID != "node-uid" {
+		t.Errorf("unexpected owner references %+v", slice.OwnerReferences)
+	}
+
+	if len(slice.Spec.Devices) != 1 || slice.Spec.Devices[0].Name != "card0" {
+		t.Fatalf("expected only the healthy device to be published, got %+v", slice.Spec.Devices)
+	}
+
+	attrs := slice.Spec.Devices[0].Basic.Attributes
+	if *attrs[draAttributeID].StringValue != "card0" || *attrs[draAttributeResource].StringValue != "gpu" || *attrs[draAttributeNUMANode].IntValue != 1 {
+		t.Errorf("unexpected attributes %+v", attrs)
+	}
+
+	devices := make(map[string]DeviceInfo)
+	for i := 0; i < resourceapi.ResourceSliceMaxDevices+2; i++ {
+		devices[fmt.Sprintf("card%d", i)] = NewDeviceInfo(pluginapi.Healthy, nil, nil, nil, nil, nil)
+	}
+
+	d.Update("gpu", devices)
+
+	slices = listSlices(t, d)
+	if len(slices) != 2 {
+		t.Fatalf("expected 2 ResourceSlices, got %d", len(slices))
+	}
+
+	for _, slice := range slices {
+		if slice.Spec.Pool.Generation != 2 || slice.Spec.Pool.ResourceSliceCount != 2 {
+			t.Errorf("unexpected pool %+v", slice.Spec.Pool)
+		}
+	}
+
+	d.Update("gpu", map[string]DeviceInfo{
+		"card0": NewDeviceInfo(pluginapi.Healthy, nil, nil, nil, nil, nil),
+	})
+
+	if slices = listSlices(t, d); len(slices) != 1 {
+		t.Errorf("expected the extra ResourceSlice to be deleted, got %d", len(slices))
+	}
+
+	d.Remove("gpu")
+
+	if slices = listSlices(t, d); len(slices) != 0 {
+		t.Errorf("expected all ResourceSlices to be deleted, got %d", len(slices))
+	}
+}
+
+func newTestClaim(uid string, results ...resourceapi.DeviceRequestAllocationResult) *resourceapi.ResourceClaim {
+	claim := &resourceapi.ResourceClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "claim-" + uid, Namespace: "default", UID: k8stypes.UID("uid-" + uid)},
+	}
+
+	if len(results) > 0 {
+		claim.Status.Allocation = &resourceapi.AllocationResult{
+			Devices: resourceapi.DeviceAllocationResult{Results: results},
+		}
+	}
+
+	return claim
+}
+
+func TestDRAPrepare(t *testing.T) {
+	pool := testDRANode + "/gpu"
+	d := newTestDRADriver(t,
+		newTestClaim("ok",
+			resourceapi.DeviceRequestAllocationResult{Request: "gpu", Driver: testDRADriver, Pool: pool, Device: "card0"},
+			resourceapi.DeviceRequestAllocationResult{Request: "other", Driver: "other.intel.com", Pool: "other", Device: "other0"}),
+		newTestClaim("unhealthy",
+			resourceapi.DeviceRequestAllocationResult{Request: "gpu", Driver: testDRADriver, Pool: pool, Device: "card1"}),
+		newTestClaim("unknown",
+			resourceapi.DeviceRequestAllocationResult{Request: "gpu", Driver: testDRADriver, Pool: pool, Device: "card2"}),
+		newTestClaim("unallocated"),
+	)
+
+	d.Update("gpu", map[string]DeviceInfo{
+		"card0": NewDeviceInfo(pluginapi.Healthy,
+			[]pluginapi.DeviceSpec{{HostPath: "/dev/dri/card0", ContainerPath: "/dev/dri/card0", Permissions: "rw"}},
+			[]pluginapi.Mount{{HostPath: "/sys/class/drm", ContainerPath: "/sys/class/drm", ReadOnly: true}},
+			map[string]string{"B": "2", "A": "1"}, nil, nil),
+		"card1": NewDeviceInfo(pluginapi.Unhealthy, nil, nil, nil, nil, nil),
+	})
+
+	claims := []*drapb.Claim{
+		{Namespace: "default", Name: "claim-ok", UID: "uid-ok"},
+		{Namespace: "default", Name: "claim-unhealthy", UID: "uid-unhealthy"},
+		{Namespace: "default", Name: "claim-unknown", UID: "uid-unknown"},
+		{Namespace: "default", Name: "claim-unallocated", UID: "uid-unallocated"},
+		{Namespace: "default", Name: "claim-ok", UID: "uid-mismatch"},
+		{Namespace: "default", Name: "claim-missing", UID: "uid-missing"},
+	}
+
+	resp, err := d.NodePrepareResources(context.Background(), &drapb.NodePrepareResourcesRequest{Claims: claims})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	ok := resp.Claims["uid-ok"]
+	if ok.Error != "" || len(ok.Devices) != 1 {
+		t.Fatalf("unexpected response %+v", ok)
+	}
+
+	if ids := ok.Devices[0].CDIDeviceIDs; len(ids) != 1 || ids[0] != CDIVendor+"/test=uid-ok-card0" {
+		t.Errorf("unexpected CDI device IDs %v", ids)
+	}
+
+	for _, uid := range []string{"uid-unhealthy", "uid-unknown", "uid-unallocated", "uid-mismatch", "uid-missing"} {
+		if resp.Claims[uid].Error == "" {
+			t.Errorf("%s: expected an error", uid)
+		}
+	}
+
+	specFile := filepath.Join(d.cdiDir, d.claimSpecName("uid-ok"))
+
+	content, err := os.ReadFile(specFile)
+	if err != nil {
+		t.Fatalf("CDI spec was not written: %+v", err)
+	}
+
+	expected := cdiContainerEdits(d.pools["gpu"].devices["card0"])
+	if len(expected.DeviceNodes) != 1 || len(expected.Mounts) != 1 || len(expected.Env) != 2 || expected.Env[0] != "A=1" {
+		t.Errorf("unexpected container edits %+v", expected)
+	}
+
+	if len(content) == 0 {
+		t.Error("empty CDI spec")
+	}
+
+	// Preparing again returns the same result.
+	resp, _ = d.NodePrepareResources(context.Background(), &drapb.NodePrepareResourcesRequest{Claims: claims[:1]})
+	if len(resp.Claims["uid-ok"].Devices) != 1 {
+		t.Errorf("unexpected response %+v", resp.Claims["uid-ok"])
+	}
+
+	uresp, err := d.NodeUnprepareResources(context.Background(), &drapb.NodeUnprepareResourcesRequest{Claims: claims[:2]})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	for uid, result := range uresp.Claims {
+		if result.Error != "" {
+			t.Errorf("%s: unexpected error %s", uid, result.Error)
+		}
+	}
+
+	if _, err := os.Stat(specFile); !os.IsNotExist(err) {
+		t.Errorf("CDI spec was not removed: %+v", err)
+	}
+
+	if _, ok := d.claims["uid-ok"]; ok {
+		t.Error("claim is still prepared")
+	}
+}
+
+func TestDRAServe(t *testing.T) {
+	d := newTestDRADriver(t,
+		&resourceapi.ResourceSlice{
+			ObjectMeta: metav1.ObjectMeta{Name: "stale"},
+			Spec:       resourceapi.ResourceSliceSpec{Driver: testDRADriver, NodeName: testDRANode},
+		},
+		&resourceapi.ResourceSlice{
+			ObjectMeta: metav1.ObjectMeta{Name: "other-node"},
+			Spec:       resourceapi.ResourceSliceSpec{Driver: testDRADriver, NodeName: "node2"},
+		},
+	)
+
+	if err := d.Stop(); err == nil {
+		t.Error("expected an error when stopping a driver which is not served")
+	}
+
+	if err := d.Serve(); err != nil {
+		t.Fatalf("unable to serve: %+v", err)
+	}
+
+	if slices := listSlices(t, d); len(slices) != 1 || slices[0].Name != "other-node" {
+		t.Errorf("expected only the stale ResourceSlice to be removed, got %+v", slices)
+	}
+
+	conn, err := grpc.NewClient(filepath.Join("unix://", d.registrationSocket()),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("unable to connect to the registration socket: %+v", err)
+	}
+	defer conn.Close()
+
+	info, err := registerapi.NewRegistrationClient(conn).GetInfo(context.Background(), &registerapi.InfoRequest{})
+	if err != nil {
+		t.Fatalf("GetInfo failed: %+v", err)
+	}
+
+	if info.Type != registerapi.DRAPlugin || info.Name != testDRADriver || info.Endpoint != d.endpoint() {
+		t.Errorf("unexpected plugin info %+v", info)
+	}
+
+	if _, err := registerapi.NewRegistrationClient(conn).NotifyRegistrationStatus(context.Background(),
+		&registerapi.RegistrationStatus{PluginRegistered: true}); err != nil {
+		t.Errorf("NotifyRegistrationStatus failed: %+v", err)
+	}
+
+	draConn, err := grpc.NewClient(filepath.Join("unix://", info.Endpoint),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("unable to connect to the DRA socket: %+v", err)
+	}
+	defer draConn.Close()
+
+	if _, err := drapb.NewNodeClient(draConn).NodePrepareResources(context.Background(),
+		&drapb.NodePrepareResourcesRequest{}); err != nil {
+		t.Errorf("NodePrepareResources failed: %+v", err)
+	}
+
+	d.Update("gpu", map[string]DeviceInfo{"card0": NewDeviceInfo(pluginapi.Healthy, nil, nil, nil, nil, nil)})
+
+	if err := d.Stop(); err != nil {
+		t.Errorf("unable to stop: %+v", err)
+	}
+
+	if slices := listSlices(t, d); len(slices) != 1 {
+		t.Errorf("expected the published ResourceSlices to be removed, got %+v", slices)
+	}
+
+	if _, err := os.Stat(d.registrationSocket()); !os.IsNotExist(err) {
+		t.Errorf("registration socket was not removed: %+v", err)
+	}
+}
+
+func TestManagerDRAMode(t *testing.T) {
+	tcases := []struct {
+		mode            Mode
+		expectedServers int
+		expectedSlices  int
+	}{
+		{mode: ModeClassic, expectedServers: 1, expectedSlices: 0},
+		{mode: ModeDRA, expectedServers: 0, expectedSlices: 1},
+		{mode: ModeBoth, expectedServers: 1, expectedSlices: 1},
+	}
+
+	for _, tc := range tcases {
+		t.Run(string(tc.mode), func(t *testing.T) {
+			mgr := NewManager(testDRADriver, nil, WithMode(tc.mode))
+			mgr.createServer = func(string, string, postAllocateFunc, preStartContainerFunc, getPreferredAllocationFunc, allocateFunc) devicePluginServer {
+				return &serverStub{}
+			}
+			mgr.dra = newTestDRADriver(t)
+
+			mgr.handleUpdate(updateInfo{
+				Added: map[string]map[string]DeviceInfo{
+					"gpu": {"card0": NewDeviceInfo(pluginapi.Healthy, nil, nil, nil, nil, nil)},
+				},
+			})
+
+			if len(mgr.servers) != tc.expectedServers {
+				t.Errorf("expected %d servers, got %d", tc.expectedServers, len(mgr.servers))
+			}
+
+			if slices := listSlices(t, mgr.dra); len(slices) != tc.expectedSlices {
+				t.Errorf("expected %d ResourceSlices, got %d", tc.expectedSlices, len(slices))
+			}
+		})
+	}
+}
@@ -0,0 +1,335 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/flowcontrol"
+	"k8s.io/klog/v2"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+const (
+	healthDebugPath = "/debug/health"
+
+	eventReasonDeviceHealthy   = "DeviceHealthy"
+	eventReasonDeviceUnhealthy = "DeviceUnhealthy"
+
+	// A flapping device gets healthEventBurst events, and then one event
+	// per healthEventPeriod.
+	healthEventBurst  = 4
+	healthEventPeriod = 5 * time.Minute
+)
+
+type healthResults map[string]map[string]HealthResult
+
+// healthMonitor runs the checks of a HealthChecker and applies their results
+// to the devices reported by Scan().
+type healthMonitor struct {
+	checker   HealthChecker
+	recorder  record.EventRecorder
+	node      *v1.ObjectReference
+	devices   DeviceTree                         // devices as reported by Scan()
+	results   healthResults                      // devType -> ID -> latest result
+	limiters  map[string]flowcontrol.RateLimiter // resource/ID -> event rate limiter
+	namespace string
+	mutex     sync.RWMutex
+}
+
+func newHealthMonitor(namespace string, checker HealthChecker) *healthMonitor {
+	return &healthMonitor{
+		checker:   checker,
+		namespace: namespace,
+		devices:   NewDeviceTree(),
+		results:   make(healthResults),
+		limiters:  make(map[string]flowcontrol.RateLimiter),
+	}
+}
+
+// newNodeEventRecorder creates an event recorder using the in-cluster config
+// and a reference to the Node named by the NODE_NAME environment variable.
+func newNodeEventRecorder(component string) (record.EventRecorder, *v1.ObjectReference, error) {
+	nodeName := os.Getenv("NODE_NAME")
+	if nodeName == "" {
+		return nil, nil, errors.New("NODE_NAME is not set")
+	}
+
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to get in-cluster config")
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to create clientset")
+	}
+
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: clientset.CoreV1().Events("")})
+
+	recorder := broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: component, Host: nodeName})
+
+	// Kubelet uses the node name as the UID of Node events, do the same so
+	// that the events are shown by "kubectl describe node".
+	node := &v1.ObjectReference{
+		Kind: "Node",
+		Name: nodeName,
+		UID:  types.UID(nodeName),
+	}
+
+	return recorder, node, nil
+}
+
+// run checks the health of the devices periodically, and when a
+// HealthNotifier tells about changes, and sends the results to resultsCh
+// until ctx is done.
+func (h *healthMonitor) run(ctx context.Context, resultsCh chan<- healthResults) {
+	ticker := time.NewTicker(h.checker.HealthCheckInterval())
+	defer ticker.Stop()
+
+	var changed <-chan struct{}
+	if notifier, ok := h.checker.(HealthNotifier); ok {
+		changed = notifier.HealthChanged()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-changed:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case resultsCh <- h.check():
+		}
+	}
+}
+
+// check runs the health checks for the devices currently reported by Scan().
+func (h *healthMonitor) check() healthResults {
+	h.mutex.RLock()
+
+	devices := make(map[string][]string, len(h.devices))
+
+	for devType, devs := range h.devices {
+		for id := range devs {
+			devices[devType] = append(devices[devType], id)
+		}
+
+		sort.Strings(devices[devType])
+	}
+
+	h.mutex.RUnlock()
+
+	return h.checker.CheckHealth(devices)
+}
+
+// track records the devices of an update received from Scan() and returns
+// the update with the health results applied.
+func (h *healthMonitor) track(update updateInfo) updateInfo {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for devType, devices := range update.Added {
+		h.devices[devType] = devices
+		update.Added[devType] = h.apply(devType)
+	}
+
+	for devType, devices := range update.Updated {
+		h.devices[devType] = devices
+
+		for id := range h.results[devType] {
+			if _, ok := devices[id]; !ok {
+				delete(h.results[devType], id)
+			}
+		}
+
+		update.Updated[devType] = h.apply(devType)
+	}
+
+	for devType := range update.Removed {
+		delete(h.devices, devType)
+		delete(h.results, devType)
+	}
+
+	return update
+}
+
+// update stores the results of a health check. It returns an update with the
+// device types whose device states changed.
+func (h *healthMonitor) update(results healthResults) updateInfo {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	update := updateInfo{
+		Added:   NewDeviceTree(),
+		Updated: NewDeviceTree(),
+		Removed: NewDeviceTree(),
+	}
+
+	for devType, devResults := range results {
+		devices, ok := h.devices[devType]
+		if !ok {
+			continue
+		}
+
+		if _, ok := h.results[devType]; !ok {
+			h.results[devType] = make(map[string]HealthResult)
+		}
+
+		changed := false
+
+		for id, result := range devResults {
+			dev, ok := devices[id]
+			if !ok {
+				continue
+			}
+
+			prevState := dev.state
+			if prev, ok := h.results[devType][id]; ok {
+				prevState = prev.State
+			}
+
+			if result.Timestamp.IsZero() {
+				result.Timestamp = time.Now()
+			}
+
+			h.results[devType][id] = result
+
+			if prevState != result.State {
+				h.transition(devType, id, prevState, result)
+
+				changed = true
+			}
+		}
+
+		if changed {
+			update.Updated[devType] = h.apply(devType)
+		}
+	}
+
+	return update
+}
+
+// apply returns the devices of a type with the health results applied.
+// The caller holds the lock.
+func (h *healthMonitor) apply(devType string) map[string]DeviceInfo {
+	devices := h.devices[devType]
+	if len(h.results[devType]) == 0 {
+		return devices
+	}
+
+	applied := make(map[string]DeviceInfo, len(devices))
+
+	for id, dev := range devices {
+		if result, ok := h.results[devType][id]; ok {
+			dev.state = result.State
+			dev.healthReason = result.Reason
+		}
+
+		applied[id] = dev
+	}
+
+	return applied
+}
+
+func (h *healthMonitor) transition(devType, id, prevState string, result HealthResult) {
+	resourceName := h.namespace + "/" + devType
+
+	healthTransitionsCounter.WithLabelValues(resourceName, result.State).Inc()
+
+	eventType, eventReason := v1.EventTypeNormal, eventReasonDeviceHealthy
+
+	if result.State == pluginapi.Unhealthy {
+		eventType, eventReason = v1.EventTypeWarning, eventReasonDeviceUnhealthy
+
+		klog.InfoS("Device became unhealthy", LogKeyResource, resourceName, LogKeyDeviceID, id, LogKeyHealth, result.State, "previous", prevState, "source", result.Source, "reason", result.Reason)
+	} else {
+		klog.InfoS("Device health changed", LogKeyResource, resourceName, LogKeyDeviceID, id, LogKeyHealth, result.State, "previous", prevState, "source", result.Source, "reason", result.Reason)
+	}
+
+	if h.recorder != nil && h.allowEvent(resourceName, id) {
+		h.recorder.Eventf(h.node, eventType, eventReason, "%s device %s is %s: %s (source: %s)",
+			resourceName, id, result.State, result.Reason, result.Source)
+	}
+}
+
+// allowEvent rate limits the events of a device, so that a flapping device
+// doesn't flood the Node with events. The caller holds the lock.
+func (h *healthMonitor) allowEvent(resourceName, id string) bool {
+	key := resourceName + "/" + id
+
+	limiter, ok := h.limiters[key]
+	if !ok {
+		limiter = flowcontrol.NewTokenBucketRateLimiter(float32(1/healthEventPeriod.Seconds()), healthEventBurst)
+		h.limiters[key] = limiter
+	}
+
+	if !limiter.TryAccept() {
+		klog.V(2).InfoS("Too many health changes, not reporting them as events", LogKeyResource, resourceName, LogKeyDeviceID, id)
+
+		return false
+	}
+
+	return true
+}
+
+// ServeHTTP responds with the latest health results as resource name ->
+// device ID -> result.
+func (h *healthMonitor) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	results := make(healthResults, len(h.results))
+	for devType, devResults := range h.results {
+		results[h.namespace+"/"+devType] = devResults
+	}
+
+	writeJSON(w, results)
+}
+
+// serveHealthDebug serves the health results over HTTP at the given address
+// until ctx is done.
+func serveHealthDebug(ctx context.Context, addr string, h *healthMonitor) error {
+	mux := http.NewServeMux()
+	mux.Handle(healthDebugPath, h)
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Wrapf(err, "failed to listen on %s", addr)
+	}
+
+	klog.FromContext(ctx).V(1).Info("Serving device health", "address", addr, "path", healthDebugPath)
+
+	return serveHTTP(ctx, lis, mux)
+}
@@ -0,0 +1,226 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"k8s.io/client-go/tools/record"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+type healthCheckerStub struct {
+	devicePluginStub
+	checked map[string][]string
+	results healthResults
+}
+
+func (*healthCheckerStub) HealthCheckInterval() time.Duration {
+	return time.Second
+}
+
+func (p *healthCheckerStub) CheckHealth(devices map[string][]string) map[string]map[string]HealthResult {
+	p.checked = devices
+
+	return p.results
+}
+
+type healthNotifierStub struct {
+	healthCheckerStub
+	changed chan struct{}
+}
+
+func (p *healthNotifierStub) HealthCheckInterval() time.Duration {
+	return time.Hour
+}
+
+func (p *healthNotifierStub) HealthChanged() <-chan struct{} {
+	return p.changed
+}
+
+type updateRecorderStub struct {
+	serverStub
+	devices map[string]DeviceInfo
+}
+
+func (s *updateRecorderStub) Update(devices map[string]DeviceInfo) {
+	s.devices = devices
+}
+
+func unhealthyResult(reason string) map[string]map[string]HealthResult {
+	return map[string]map[string]HealthResult{
+		"testdevice": {
+			"dev1":    {State: pluginapi.Unhealthy, Reason: reason, Source: "test"},
+			"missing": {State: pluginapi.Unhealthy, Reason: reason, Source: "test"},
+		},
+	}
+}
+
+func TestHealthMonitor(t *testing.T) {
+	plugin := &healthCheckerStub{}
+	srv := &updateRecorderStub{}
+	recorder := record.NewFakeRecorder(10)
+
+	mgr := NewManager("test.intel.com", plugin)
+	mgr.createServer = func(string, string, postAllocateFunc, preStartContainerFunc, getPreferredAllocationFunc, allocateFunc) devicePluginServer {
+		return srv
+	}
+
+	if !mgr.setupHealth(context.Background()) {
+		t.Fatal("health checks should be enabled")
+	}
+
+	mgr.health.recorder = recorder
+
+	tree := NewDeviceTree()
+	tree.AddDevice("testdevice", "dev1", DeviceInfo{state: pluginapi.Healthy})
+	tree.AddDevice("testdevice", "dev2", DeviceInfo{state: pluginapi.Healthy})
+
+	mgr.handleUpdate(updateInfo{Added: tree})
+
+	plugin.results = unhealthyResult("overheated")
+	mgr.handleHealthResults(mgr.health.check())
+
+	if !reflect.DeepEqual(plugin.checked, map[string][]string{"testdevice": {"dev1", "dev2"}}) {
+		t.Errorf("unexpected devices checked: %+v", plugin.checked)
+	}
+
+	if srv.devices["dev1"].state != pluginapi.Unhealthy || srv.devices["dev2"].state != pluginapi.Healthy {
+		t.Errorf("health result was not applied: %+v", srv.devices)
+	}
+
+	if event := <-recorder.Events; !strings.Contains(event, eventReasonDeviceUnhealthy) || !strings.Contains(event, "overheated") {
+		t.Errorf("unexpected event %q", event)
+	}
+
+	// Unchanged states are not pushed again.
+	srv.devices = nil
+	plugin.results = unhealthyResult("still overheated")
+	mgr.handleHealthResults(mgr.health.check())
+
+	if srv.devices != nil || len(recorder.Events) != 0 {
+		t.Error("unchanged health state should not cause an update")
+	}
+
+	// The health results take precedence over the states set in Scan().
+	mgr.handleUpdate(updateInfo{Updated: DeviceTree{"testdevice": tree["testdevice"]}})
+
+	if srv.devices["dev1"].state != pluginapi.Unhealthy {
+		t.Errorf("health result was not applied to scan results: %+v", srv.devices)
+	}
+
+	rec := httptest.NewRecorder()
+	mgr.health.ServeHTTP(rec, httptest.NewRequest("GET", healthDebugPath, nil))
+
+	results := healthResults{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &results); err != nil {
+		t.Fatalf("unable to decode health results: %+v", err)
+	}
+
+	if result := results["test.intel.com/testdevice"]["dev1"]; result.Reason != "still overheated" || result.Timestamp.IsZero() {
+		t.Errorf("unexpected health results %+v", results)
+	}
+
+	plugin.results = map[string]map[string]HealthResult{
+		"testdevice": {"dev1": {State: pluginapi.Healthy, Source: "test"}},
+	}
+	mgr.handleHealthResults(mgr.health.check())
+
+	if srv.devices["dev1"].state != pluginapi.Healthy {
+		t.Errorf("device did not recover: %+v", srv.devices)
+	}
+
+	if event := <-recorder.Events; !strings.Contains(event, eventReasonDeviceHealthy) {
+		t.Errorf("unexpected event %q", event)
+	}
+
+	mgr.health.track(updateInfo{Removed: DeviceTree{"testdevice": tree["testdevice"]}})
+	mgr.health.check()
+
+	if len(mgr.health.results) != 0 || len(plugin.checked) != 0 {
+		t.Error("health results of removed devices should be dropped")
+	}
+}
+
+func TestHealthCheckDisabled(t *testing.T) {
+	if NewManager("test.intel.com", &devicePluginStub{}, WithHealthDebug("127.0.0.1:0")).setupHealth(context.Background()) {
+		t.Error("health checks should be disabled without HealthChecker")
+	}
+}
+
+func TestHealthNotifier(t *testing.T) {
+	plugin := &healthNotifierStub{changed: make(chan struct{}, 1)}
+	plugin.results = unhealthyResult("memory error")
+
+	h := newHealthMonitor("test.intel.com", plugin)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resultsCh := make(chan healthResults)
+
+	go h.run(ctx, resultsCh)
+
+	// The change is checked without waiting for the hourly check.
+	plugin.changed <- struct{}{}
+
+	select {
+	case results := <-resultsCh:
+		if results["testdevice"]["dev1"].Reason != "memory error" {
+			t.Errorf("unexpected results %+v", results)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("health change was not checked")
+	}
+}
+
+func TestHealthEventRateLimit(t *testing.T) {
+	recorder := record.NewFakeRecorder(20)
+
+	h := newHealthMonitor("test.intel.com", &healthCheckerStub{})
+	h.recorder = recorder
+
+	tree := NewDeviceTree()
+	tree.AddDevice("testdevice", "dev1", DeviceInfo{state: pluginapi.Healthy})
+	tree.AddDevice("testdevice", "dev2", DeviceInfo{state: pluginapi.Healthy})
+	h.track(updateInfo{Added: tree})
+
+	// dev1 flaps, dev2 changes once.
+	for i := range 10 {
+		state := pluginapi.Unhealthy
+		if i%2 == 1 {
+			state = pluginapi.Healthy
+		}
+
+		results := healthResults{"testdevice": {"dev1": {State: state, Source: "test"}}}
+		if i == 0 {
+			results["testdevice"]["dev2"] = HealthResult{State: pluginapi.Unhealthy, Source: "test"}
+		}
+
+		if update := h.update(results); len(update.Updated) != 1 {
+			t.Fatalf("every state change should be applied, got %+v", update)
+		}
+	}
+
+	if len(recorder.Events) != healthEventBurst+1 {
+		t.Errorf("expected %d events, got %d", healthEventBurst+1, len(recorder.Events))
+	}
+}
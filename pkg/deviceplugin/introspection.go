@@ -0,0 +1,264 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+const (
+	// IntrospectionSocketDir is the default directory of the introspection sockets.
+	IntrospectionSocketDir = "/var/run/intel-device-plugins"
+	// IntrospectionDevicesPath serves the advertised devices as resource name -> []DeviceState.
+	IntrospectionDevicesPath = "/devices"
+	// IntrospectionAllocationsPath serves the recent Allocate calls as []AllocateRecord, oldest first.
+	IntrospectionAllocationsPath = "/allocations"
+	// IntrospectionCapacityPath serves the capacity of the resources as resource name -> CapacitySummary.
+	IntrospectionCapacityPath = "/capacity"
+
+	// introspectionMaxAllocations is the number of Allocate calls kept.
+	introspectionMaxAllocations = 64
+)
+
+// DeviceState is a device as advertised to kubelet.
+type DeviceState struct {
+	Topology    *pluginapi.TopologyInfo `json:"topology,omitempty"`
+	Envs        map[string]string       `json:"envs,omitempty"`
+	Annotations map[string]string       `json:"annotations,omitempty"`
+	ID          string                  `json:"id"`
+	Health      string                  `json:"health"`
+	Nodes       []pluginapi.DeviceSpec  `json:"nodes,omitempty"`
+	Mounts      []pluginapi.Mount       `json:"mounts,omitempty"`
+	CDIDevices  []string                `json:"cdiDevices,omitempty"`
+}
+
+// AllocateRecord is an Allocate call received from kubelet.
+type AllocateRecord struct {
+	Timestamp    time.Time                   `json:"timestamp"`
+	Request      *pluginapi.AllocateRequest  `json:"request"`
+	Response     *pluginapi.AllocateResponse `json:"response,omitempty"`
+	ResourceName string                      `json:"resourceName"`
+	Error        string                      `json:"error,omitempty"`
+}
+
+// IntrospectionSocket returns the path of the introspection socket of the
+// device plugins of a namespace, e.g. "gpu.intel.com", in a directory.
+func IntrospectionSocket(dir, namespace string) string {
+	return filepath.Join(dir, namespace+".sock")
+}
+
+// introspector keeps the devices advertised by a Manager and its recent
+// Allocate calls for the read-only introspection endpoint.
+type introspector struct {
+	devices     map[string][]DeviceState // resource name -> devices
+	journal     AllocationJournal        // nil without the allocation journal
+	sharer      DeviceSharer             // nil when the devices are not shared
+	namespace   string
+	socket      string
+	allocations []AllocateRecord
+	mutex       sync.RWMutex
+}
+
+func newIntrospector(namespace, socket string) *introspector {
+	return &introspector{
+		devices:   make(map[string][]DeviceState),
+		namespace: namespace,
+		socket:    socket,
+	}
+}
+
+func newDeviceState(id string, dev DeviceInfo) DeviceState {
+	state := DeviceState{
+		ID:          id,
+		Health:      dev.state,
+		Topology:    dev.topology,
+		Nodes:       dev.nodes,
+		Mounts:      dev.mounts,
+		Envs:        dev.envs,
+		Annotations: dev.annotations,
+	}
+
+	for _, cdiDevice := range cdiDevices(dev.cdiSpec) {
+		state.CDIDevices = append(state.CDIDevices, cdiDevice.Name)
+	}
+
+	return state
+}
+
+// update applies an update pushed to the servers.
+func (in *introspector) update(update updateInfo) {
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+
+	for _, tree := range []DeviceTree{update.Added, update.Updated} {
+		for devType, devices := range tree {
+			states := make([]DeviceState, 0, len(devices))
+
+			for id, dev := range devices {
+				states = append(states, newDeviceState(id, dev))
+			}
+
+			sort.Slice(states, func(i, k int) bool {
+				return states[i].ID < states[k].ID
+			})
+
+			in.devices[in.namespace+"/"+devType] = states
+		}
+	}
+
+	for devType := range update.Removed {
+		delete(in.devices, in.namespace+"/"+devType)
+	}
+}
+
+// recordAllocate keeps an Allocate call. Only the most recent calls are kept.
+func (in *introspector) recordAllocate(resourceName string, rqt *pluginapi.AllocateRequest, resp *pluginapi.AllocateResponse, err error) {
+	record := AllocateRecord{
+		Timestamp:    time.Now(),
+		Request:      rqt,
+		Response:     resp,
+		ResourceName: resourceName,
+	}
+
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	in.mutex.Lock()
+	defer in.mutex.Unlock()
+
+	in.allocations = append(in.allocations, record)
+	if len(in.allocations) > introspectionMaxAllocations {
+		in.allocations = in.allocations[len(in.allocations)-introspectionMaxAllocations:]
+	}
+}
+
+// capacity summarizes the advertised devices per resource.
+func (in *introspector) capacity() map[string]CapacitySummary {
+	in.mutex.RLock()
+	defer in.mutex.RUnlock()
+
+	capacity := make(map[string]CapacitySummary, len(in.devices))
+
+	for resourceName, states := range in.devices {
+		summary := CapacitySummary{Total: len(states), SharingFactor: 1}
+
+		for _, state := range states {
+			if state.Health == pluginapi.Healthy {
+				summary.Healthy++
+			}
+		}
+
+		if in.sharer != nil {
+			summary.SharingFactor = max(in.sharer.SharingFactor(strings.TrimPrefix(resourceName, in.namespace+"/")), 1)
+		}
+
+		if in.journal != nil {
+			allocated := make(map[string]struct{})
+
+			for _, allocation := range in.journal.Allocations(resourceName) {
+				for _, id := range allocation.DeviceIDs {
+					allocated[id] = struct{}{}
+				}
+			}
+
+			summary.Allocated = new(int)
+			*summary.Allocated = len(allocated)
+		}
+
+		capacity[resourceName] = summary
+	}
+
+	return capacity
+}
+
+func (in *introspector) handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(IntrospectionDevicesPath, func(w http.ResponseWriter, r *http.Request) {
+		in.mutex.RLock()
+		defer in.mutex.RUnlock()
+
+		writeJSON(w, in.devices)
+	})
+
+	mux.HandleFunc(IntrospectionAllocationsPath, func(w http.ResponseWriter, r *http.Request) {
+		in.mutex.RLock()
+		defer in.mutex.RUnlock()
+
+		writeJSON(w, in.allocations)
+	})
+
+	mux.HandleFunc(IntrospectionCapacityPath, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, in.capacity())
+	})
+
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if _, err := w.Write(data); err != nil {
+		klog.V(4).InfoS("Failed to write response", "err", err)
+	}
+}
+
+// serve serves the introspection endpoint over HTTP on the unix socket
+// until ctx is done. The socket is removed when the endpoint is closed.
+func (in *introspector) serve(ctx context.Context) error {
+	if err := os.MkdirAll(filepath.Dir(in.socket), 0o750); err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := os.Remove(in.socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.WithStack(err)
+	}
+
+	lis, err := net.Listen("unix", in.socket)
+	if err != nil {
+		return errors.Wrapf(err, "failed to listen on %s", in.socket)
+	}
+
+	if err := os.Chmod(in.socket, 0o600); err != nil {
+		lis.Close()
+
+		return errors.WithStack(err)
+	}
+
+	klog.FromContext(ctx).V(1).Info("Serving introspection endpoint", "socket", in.socket)
+
+	return serveHTTP(ctx, lis, in.handler())
+}
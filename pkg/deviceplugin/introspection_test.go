@@ -0,0 +1,203 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	cdispec "tags.cncf.io/container-device-interface/specs-go"
+)
+
+func queryIntrospection(t *testing.T, socket, path string, v interface{}) {
+	t.Helper()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+
+				return d.DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+
+	resp, err := client.Get("http://localhost" + path)
+	if err != nil {
+		t.Fatalf("unable to query %s: %+v", path, err)
+	}
+
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("unable to decode %s: %+v", path, err)
+	}
+}
+
+func TestIntrospection(t *testing.T) {
+	dir, err := os.MkdirTemp("", "introspection")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	mgr := NewManager("test.intel.com", &devicePluginStub{}, WithIntrospection(dir))
+	if mgr.introspection == nil || mgr.introspection.socket != IntrospectionSocket(dir, "test.intel.com") {
+		t.Fatalf("unexpected introspection socket: %+v", mgr.introspection)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		if err := mgr.introspection.serve(ctx); err != nil {
+			t.Errorf("unable to serve introspection endpoint: %+v", err)
+		}
+	}()
+
+	spec := &cdispec.Spec{Kind: "test.intel.com/device", Devices: []cdispec.Device{{Name: "dev1"}}}
+
+	tree := NewDeviceTree()
+	tree.AddDevice("testdevice", "dev2", NewDeviceInfo(pluginapi.Unhealthy, nil, nil, nil, nil, nil))
+	tree.AddDevice("testdevice", "dev1", NewDeviceInfoWithTopologyHints(pluginapi.Healthy,
+		[]pluginapi.DeviceSpec{{HostPath: "/dev/dev1", ContainerPath: "/dev/dev1"}}, nil, map[string]string{"DEV": "1"}, nil,
+		&pluginapi.TopologyInfo{Nodes: []*pluginapi.NUMANode{{ID: 1}}}, spec))
+	tree.AddDevice("removed", "dev1", NewDeviceInfo(pluginapi.Healthy, nil, nil, nil, nil, nil))
+
+	mgr.introspection.update(updateInfo{Added: tree})
+	mgr.introspection.update(updateInfo{Removed: DeviceTree{"removed": tree["removed"]}})
+
+	srv := newTestServer()
+	srv.resourceName = "test.intel.com/testdevice"
+	srv.cdiSpecs = newCdiSpecManager(t.TempDir(), namespace)
+	srv.introspection = mgr.introspection
+
+	for i := 0; i < introspectionMaxAllocations+1; i++ {
+		rqt, _ := testAllocation("dev1")
+		if _, err := srv.Allocate(context.Background(), rqt); err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+	}
+
+	rqt, _ := testAllocation("missing")
+	if _, err := srv.Allocate(context.Background(), rqt); err == nil {
+		t.Fatal("expected an error for a missing device")
+	}
+
+	// Wait for the socket to be served.
+	for i := 0; i < 50; i++ {
+		if _, err := os.Stat(mgr.introspection.socket); err == nil {
+			break
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	devices := map[string][]DeviceState{}
+	queryIntrospection(t, mgr.introspection.socket, IntrospectionDevicesPath, &devices)
+
+	states := devices["test.intel.com/testdevice"]
+	if len(devices) != 1 || len(states) != 2 {
+		t.Fatalf("unexpected devices %+v", devices)
+	}
+
+	if dev := states[0]; dev.ID != "dev1" || dev.Health != pluginapi.Healthy || dev.Topology.Nodes[0].ID != 1 ||
+		dev.Nodes[0].HostPath != "/dev/dev1" || dev.Envs["DEV"] != "1" || dev.CDIDevices[0] != "test.intel.com/device=dev1" {
+		t.Errorf("unexpected device %+v", dev)
+	}
+
+	if dev := states[1]; dev.ID != "dev2" || dev.Health != pluginapi.Unhealthy {
+		t.Errorf("unexpected device %+v", dev)
+	}
+
+	allocations := []AllocateRecord{}
+	queryIntrospection(t, mgr.introspection.socket, IntrospectionAllocationsPath, &allocations)
+
+	if len(allocations) != introspectionMaxAllocations {
+		t.Fatalf("expected %d allocations, got %d", introspectionMaxAllocations, len(allocations))
+	}
+
+	if last := allocations[len(allocations)-1]; last.Error == "" || last.Request.ContainerRequests[0].DevicesIDs[0] != "missing" {
+		t.Errorf("unexpected last allocation %+v", last)
+	}
+
+	if first := allocations[0]; first.Error != "" || first.Response == nil || first.ResourceName != "test.intel.com/testdevice" {
+		t.Errorf("unexpected allocation %+v", first)
+	}
+
+	capacity := map[string]CapacitySummary{}
+	queryIntrospection(t, mgr.introspection.socket, IntrospectionCapacityPath, &capacity)
+
+	if summary := capacity["test.intel.com/testdevice"]; len(capacity) != 1 || summary.Total != 2 || summary.Healthy != 1 || summary.Allocated != nil {
+		t.Errorf("unexpected capacity %+v", capacity)
+	}
+}
+
+type sharedDevicePluginStub struct {
+	devicePluginStub
+}
+
+func (*sharedDevicePluginStub) SharingFactor(devType string) int {
+	if devType == "shared" {
+		return 2
+	}
+
+	return 0
+}
+
+func TestIntrospectionCapacity(t *testing.T) {
+	dir := t.TempDir()
+
+	mgr := NewManager("test.intel.com", &sharedDevicePluginStub{}, WithIntrospection(dir), WithAllocationJournal(dir))
+
+	tree := NewDeviceTree()
+	tree.AddDevice("shared", "dev1-0", NewDeviceInfo(pluginapi.Healthy, nil, nil, nil, nil, nil))
+	tree.AddDevice("shared", "dev1-1", NewDeviceInfo(pluginapi.Healthy, nil, nil, nil, nil, nil))
+	tree.AddDevice("shared", "dev2-0", NewDeviceInfo(pluginapi.Unhealthy, nil, nil, nil, nil, nil))
+	tree.AddDevice("shared", "dev2-1", NewDeviceInfo(pluginapi.Unhealthy, nil, nil, nil, nil, nil))
+	tree.AddDevice("exclusive", "dev1", NewDeviceInfo(pluginapi.Healthy, nil, nil, nil, nil, nil))
+
+	mgr.introspection.update(updateInfo{Added: tree})
+
+	recordAllocation(mgr.journal, "test.intel.com/shared", "dev1-0")
+	recordAllocation(mgr.journal, "test.intel.com/shared", "dev1-1", "dev1-0")
+
+	capacity := mgr.introspection.capacity()
+
+	if shared := capacity["test.intel.com/shared"]; shared.Total != 4 || shared.Healthy != 2 || shared.SharingFactor != 2 ||
+		shared.Allocated == nil || *shared.Allocated != 2 {
+		t.Errorf("unexpected capacity of shared devices: %+v", shared)
+	}
+
+	if exclusive := capacity["test.intel.com/exclusive"]; exclusive.Total != 1 || exclusive.Healthy != 1 || exclusive.SharingFactor != 1 ||
+		exclusive.Allocated == nil || *exclusive.Allocated != 0 {
+		t.Errorf("unexpected capacity of exclusive devices: %+v", exclusive)
+	}
+
+	// The allocations are unknown without the journal.
+	mgr = NewManager("test.intel.com", &devicePluginStub{}, WithIntrospection(dir))
+	mgr.introspection.update(updateInfo{Added: tree})
+
+	if exclusive := mgr.introspection.capacity()["test.intel.com/exclusive"]; exclusive.Allocated != nil || exclusive.SharingFactor != 1 {
+		t.Errorf("unexpected capacity without journal: %+v", exclusive)
+	}
+}
@@ -0,0 +1,248 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/klog/v2"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/apis/deviceplugin/v1alpha1"
+)
+
+// inventoryRetryPeriod is the period of retrying a failed inventory update.
+const inventoryRetryPeriod = 30 * time.Second
+
+var (
+	inventoryResource = v1alpha1.GroupVersion.WithResource("nodeacceleratorinventories")
+	nodeResource      = schema.GroupVersionResource{Version: "v1", Resource: "nodes"}
+)
+
+// inventoryPublisher writes the devices pushed to kubelet to the
+// NodeAcceleratorInventory of the node, see WithInventory().
+type inventoryPublisher struct {
+	client    dynamic.Interface
+	devices   DeviceTree
+	published *v1alpha1.NodeAcceleratorInventorySpec // nil until written
+	changed   chan struct{}
+	node      metav1.OwnerReference
+	namespace string
+	name      string
+	mutex     sync.Mutex
+}
+
+// newInventoryPublisher creates an inventory publisher for the given
+// namespace, e.g. "gpu.intel.com". The inventory is owned by the node, so
+// that it's removed with the node.
+func newInventoryPublisher(namespace, nodeName string, client dynamic.Interface) (*inventoryPublisher, error) {
+	if nodeName == "" {
+		return nil, errors.New("node name is required for the inventory")
+	}
+
+	node, err := client.Resource(nodeResource).Get(context.Background(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get node %s", nodeName)
+	}
+
+	return &inventoryPublisher{
+		client:    client,
+		devices:   NewDeviceTree(),
+		changed:   make(chan struct{}, 1),
+		namespace: namespace,
+		name:      v1alpha1.InventoryName(nodeName, namespace),
+		node: metav1.OwnerReference{
+			APIVersion: "v1",
+			Kind:       "Node",
+			Name:       nodeName,
+			UID:        node.GetUID(),
+		},
+	}, nil
+}
+
+// newInventoryPublisherInCluster creates an inventory publisher using the
+// in-cluster config and the NODE_NAME environment variable.
+func newInventoryPublisherInCluster(namespace string) (*inventoryPublisher, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get in-cluster config")
+	}
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create dynamic client")
+	}
+
+	return newInventoryPublisher(namespace, os.Getenv("NODE_NAME"), client)
+}
+
+// track records the devices of an update pushed to kubelet.
+func (p *inventoryPublisher) track(update updateInfo) {
+	p.mutex.Lock()
+
+	for _, tree := range []DeviceTree{update.Added, update.Updated} {
+		for devType, devices := range tree {
+			p.devices[devType] = devices
+		}
+	}
+
+	for devType := range update.Removed {
+		delete(p.devices, devType)
+	}
+
+	p.mutex.Unlock()
+
+	select {
+	case p.changed <- struct{}{}:
+	default:
+	}
+}
+
+// spec returns the inventory of the tracked devices.
+func (p *inventoryPublisher) spec() *v1alpha1.NodeAcceleratorInventorySpec {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	spec := &v1alpha1.NodeAcceleratorInventorySpec{
+		NodeName:          p.node.Name,
+		ResourceNamespace: p.namespace,
+	}
+
+	for devType, devices := range p.devices {
+		for id, dev := range devices {
+			spec.Devices = append(spec.Devices, newAcceleratorDevice(p.namespace+"/"+devType, id, dev))
+		}
+	}
+
+	sort.Slice(spec.Devices, func(i, k int) bool {
+		if spec.Devices[i].Resource != spec.Devices[k].Resource {
+			return spec.Devices[i].Resource < spec.Devices[k].Resource
+		}
+
+		return spec.Devices[i].ID < spec.Devices[k].ID
+	})
+
+	return spec
+}
+
+func newAcceleratorDevice(resourceName, id string, dev DeviceInfo) v1alpha1.AcceleratorDevice {
+	device := v1alpha1.AcceleratorDevice{
+		Resource:     resourceName,
+		ID:           id,
+		Health:       dev.state,
+		HealthReason: dev.healthReason,
+	}
+
+	if len(dev.attributes) > 0 {
+		device.Attributes = dev.attributes
+	}
+
+	if dev.topology != nil && len(dev.topology.Nodes) > 0 {
+		node := int(dev.topology.Nodes[0].ID)
+		device.NUMANode = &node
+	}
+
+	for _, node := range dev.nodes {
+		if !slices.Contains(device.DeviceNodes, node.HostPath) {
+			device.DeviceNodes = append(device.DeviceNodes, node.HostPath)
+		}
+	}
+
+	return device
+}
+
+// run writes the inventory whenever the devices change, until ctx is done.
+// Failed writes are retried periodically.
+func (p *inventoryPublisher) run(ctx context.Context) {
+	ticker := time.NewTicker(inventoryRetryPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-p.changed:
+		case <-ticker.C:
+		}
+
+		if err := p.sync(ctx); err != nil {
+			klog.FromContext(ctx).Error(err, "Failed to update the inventory, retrying", "inventory", p.name)
+		}
+	}
+}
+
+// sync writes the inventory unless it's the same as the last written one.
+func (p *inventoryPublisher) sync(ctx context.Context) error {
+	spec := p.spec()
+	if p.published != nil && reflect.DeepEqual(spec, p.published) {
+		return nil
+	}
+
+	if err := p.publish(ctx, spec); err != nil {
+		return err
+	}
+
+	p.published = spec
+
+	klog.FromContext(ctx).V(3).Info("Updated NodeAcceleratorInventory", "inventory", p.name)
+
+	return nil
+}
+
+// publish creates or updates the inventory with the given spec.
+func (p *inventoryPublisher) publish(ctx context.Context, spec *v1alpha1.NodeAcceleratorInventorySpec) error {
+	client := p.client.Resource(inventoryResource)
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(spec)
+	if err != nil {
+		return errors.Wrap(err, "failed to convert inventory")
+	}
+
+	obj, err := client.Get(ctx, p.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		obj = &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(v1alpha1.GroupVersion.WithKind("NodeAcceleratorInventory"))
+		obj.SetName(p.name)
+		obj.SetOwnerReferences([]metav1.OwnerReference{p.node})
+		obj.Object["spec"] = content
+
+		_, err = client.Create(ctx, obj, metav1.CreateOptions{})
+
+		return errors.Wrapf(err, "failed to create NodeAcceleratorInventory %s", p.name)
+	}
+
+	if err != nil {
+		return errors.Wrapf(err, "failed to get NodeAcceleratorInventory %s", p.name)
+	}
+
+	obj.Object["spec"] = content
+
+	_, err = client.Update(ctx, obj, metav1.UpdateOptions{})
+
+	return errors.Wrapf(err, "failed to update NodeAcceleratorInventory %s", p.name)
+}
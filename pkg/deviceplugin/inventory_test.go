@@ -0,0 +1,150 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/apis/deviceplugin/v1alpha1"
+)
+
+func newFakeInventoryClient(objects ...runtime.Object) *dynamicfake.FakeDynamicClient {
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(scheme.Scheme,
+		map[schema.GroupVersionResource]string{inventoryResource: "NodeAcceleratorInventoryList"}, objects...)
+}
+
+func getTestInventory(t *testing.T, p *inventoryPublisher) *v1alpha1.NodeAcceleratorInventory {
+	t.Helper()
+
+	obj, err := p.client.Resource(inventoryResource).Get(context.Background(), p.name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("failed to get inventory: %+v", err)
+	}
+
+	inventory := &v1alpha1.NodeAcceleratorInventory{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, inventory); err != nil {
+		t.Fatal(err)
+	}
+
+	return inventory
+}
+
+func TestNewInventoryPublisher(t *testing.T) {
+	if _, err := newInventoryPublisher("test.intel.com", "", newFakeInventoryClient()); err == nil {
+		t.Error("expected an error without a node name")
+	}
+
+	if _, err := newInventoryPublisher("test.intel.com", "node1", newFakeInventoryClient()); err == nil {
+		t.Error("expected an error with a missing node")
+	}
+}
+
+func TestInventoryPublisher(t *testing.T) {
+	node := &v1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node1", UID: "node1-uid"}}
+	client := newFakeInventoryClient(node)
+
+	p, err := newInventoryPublisher("test.intel.com", "node1", client)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	dev := NewDeviceInfoWithTopologyHints(pluginapi.Healthy,
+		[]pluginapi.DeviceSpec{{HostPath: "/dev/card0"}, {HostPath: "/dev/renderD128"}}, nil, nil, nil,
+		&pluginapi.TopologyInfo{Nodes: []*pluginapi.NUMANode{{ID: 1}}}, nil)
+	dev.SetAttributes(map[string]string{"tiles": "2"})
+
+	unhealthy := NewDeviceInfoWithTopologyHints(pluginapi.Unhealthy, []pluginapi.DeviceSpec{{HostPath: "/dev/card1"}}, nil, nil, nil, nil, nil)
+	unhealthy.healthReason = "memory error"
+
+	p.track(updateInfo{
+		Added:   DeviceTree{"gpu": {"card1": unhealthy, "card0": dev}, "monitoring": {"all": dev}},
+		Updated: NewDeviceTree(),
+		Removed: NewDeviceTree(),
+	})
+
+	if err := p.sync(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	inventory := getTestInventory(t, p)
+
+	numaNode := 1
+	expected := v1alpha1.NodeAcceleratorInventorySpec{
+		NodeName:          "node1",
+		ResourceNamespace: "test.intel.com",
+		Devices: []v1alpha1.AcceleratorDevice{
+			{
+				Resource:    "test.intel.com/gpu",
+				ID:          "card0",
+				Health:      pluginapi.Healthy,
+				NUMANode:    &numaNode,
+				DeviceNodes: []string{"/dev/card0", "/dev/renderD128"},
+				Attributes:  map[string]string{"tiles": "2"},
+			},
+			{
+				Resource:     "test.intel.com/gpu",
+				ID:           "card1",
+				Health:       pluginapi.Unhealthy,
+				HealthReason: "memory error",
+				DeviceNodes:  []string{"/dev/card1"},
+			},
+			{
+				Resource:    "test.intel.com/monitoring",
+				ID:          "all",
+				Health:      pluginapi.Healthy,
+				NUMANode:    &numaNode,
+				DeviceNodes: []string{"/dev/card0", "/dev/renderD128"},
+				Attributes:  map[string]string{"tiles": "2"},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(inventory.Spec, expected) {
+		t.Errorf("expected inventory %+v, got %+v", expected, inventory.Spec)
+	}
+
+	if owners := inventory.GetOwnerReferences(); len(owners) != 1 || owners[0].Kind != "Node" || owners[0].UID != node.UID {
+		t.Errorf("expected the node to own the inventory, got %+v", owners)
+	}
+
+	// Unchanged devices are not written again.
+	client.ClearActions()
+
+	p.track(updateInfo{Added: NewDeviceTree(), Updated: DeviceTree{"gpu": {"card1": unhealthy, "card0": dev}}, Removed: NewDeviceTree()})
+
+	if err := p.sync(context.Background()); err != nil || len(client.Actions()) != 0 {
+		t.Errorf("unchanged inventory should not be written: %+v, %v", err, client.Actions())
+	}
+
+	p.track(updateInfo{Added: NewDeviceTree(), Updated: NewDeviceTree(), Removed: DeviceTree{"monitoring": {}}})
+
+	if err := p.sync(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if inventory := getTestInventory(t, p); len(inventory.Spec.Devices) != 2 {
+		t.Errorf("expected the removed devices to be dropped, got %+v", inventory.Spec.Devices)
+	}
+}
@@ -0,0 +1,301 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"k8s.io/klog/v2"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	podresourcesv1 "k8s.io/kubelet/pkg/apis/podresources/v1"
+)
+
+const (
+	// PodResourcesSocket is the kubelet PodResources API socket.
+	PodResourcesSocket = "/var/lib/kubelet/pod-resources/kubelet.sock"
+
+	podResourcesMaxMsgSize = 4 * 1024 * 1024
+	podResourcesTimeout    = 5 * time.Second
+
+	// journalReconcilePeriod is the period of journal reconciliations. Allocations
+	// younger than this are kept even if kubelet doesn't report them yet.
+	journalReconcilePeriod = 5 * time.Minute
+)
+
+// Allocation is a device allocation recorded by Manager.
+type Allocation struct {
+	// Timestamp is the time of the allocation.
+	Timestamp time.Time `json:"timestamp"`
+	// Response is the response returned to kubelet for the container.
+	Response *pluginapi.ContainerAllocateResponse `json:"response"`
+	// ResourceName is the full name of the allocated resource, e.g. "gpu.intel.com/i915".
+	ResourceName string `json:"resourceName"`
+	// Namespace, Pod and Container identify the container using the devices.
+	// They are empty until kubelet reports the allocation.
+	Namespace string `json:"namespace,omitempty"`
+	Pod       string `json:"pod,omitempty"`
+	Container string `json:"container,omitempty"`
+	// DeviceIDs are the sorted IDs of the allocated devices.
+	DeviceIDs []string `json:"deviceIDs"`
+}
+
+type listPodResourcesFunc func(ctx context.Context) (*podresourcesv1.ListPodResourcesResponse, error)
+
+// allocationJournal keeps the allocations done by the servers of a Manager
+// in a file on the host, so that they survive plugin restarts.
+type allocationJournal struct {
+	allocations      map[string]Allocation // resource name and device IDs -> allocation
+	listPodResources listPodResourcesFunc
+	path             string
+	mutex            sync.RWMutex
+}
+
+func newAllocationJournal(path string) *allocationJournal {
+	return &allocationJournal{
+		path:        path,
+		allocations: make(map[string]Allocation),
+		listPodResources: func(ctx context.Context) (*podresourcesv1.ListPodResourcesResponse, error) {
+			return listPodResources(ctx, PodResourcesSocket)
+		},
+	}
+}
+
+func listPodResources(ctx context.Context, socket string) (*podresourcesv1.ListPodResourcesResponse, error) {
+	conn, err := grpc.NewClient(filepath.Join("unix://", socket),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(podResourcesMaxMsgSize)))
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot create a PodResources client")
+	}
+
+	defer conn.Close()
+
+	resp, err := podresourcesv1.NewPodResourcesListerClient(conn).List(ctx, &podresourcesv1.ListPodResourcesRequest{})
+
+	return resp, errors.Wrap(err, "cannot list pod resources")
+}
+
+func allocationKey(resourceName string, deviceIDs []string) string {
+	return resourceName + "/" + strings.Join(deviceIDs, ",")
+}
+
+func sortedIDs(ids []string) []string {
+	sorted := append([]string{}, ids...)
+	sort.Strings(sorted)
+
+	return sorted
+}
+
+// Allocations returns the recorded allocations of a resource, oldest first.
+func (j *allocationJournal) Allocations(resourceName string) []Allocation {
+	j.mutex.RLock()
+	defer j.mutex.RUnlock()
+
+	allocations := []Allocation{}
+
+	for _, allocation := range j.allocations {
+		if allocation.ResourceName == resourceName {
+			allocations = append(allocations, allocation)
+		}
+	}
+
+	sort.Slice(allocations, func(i, k int) bool {
+		return allocations[i].Timestamp.Before(allocations[k].Timestamp)
+	})
+
+	return allocations
+}
+
+// record adds the allocations of an Allocate call to the journal. A previous
+// allocation of the same devices is replaced.
+func (j *allocationJournal) record(resourceName string, rqt *pluginapi.AllocateRequest, resp *pluginapi.AllocateResponse) {
+	if len(rqt.ContainerRequests) != len(resp.ContainerResponses) {
+		klog.InfoS("Not journaling allocation with a different number of requests and responses",
+			LogKeyResource, resourceName, "requests", len(rqt.ContainerRequests), "responses", len(resp.ContainerResponses))
+
+		return
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	now := time.Now()
+
+	for i, crqt := range rqt.ContainerRequests {
+		ids := sortedIDs(crqt.DevicesIDs)
+
+		j.allocations[allocationKey(resourceName, ids)] = Allocation{
+			Timestamp:    now,
+			Response:     resp.ContainerResponses[i],
+			ResourceName: resourceName,
+			DeviceIDs:    ids,
+		}
+	}
+
+	if err := j.save(); err != nil {
+		klog.ErrorS(err, "Failed to save allocation journal", "path", j.path)
+	}
+}
+
+// load reads the journal from the host. A missing journal is not an error.
+func (j *allocationJournal) load() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	data, err := os.ReadFile(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	allocations := []Allocation{}
+	if err := json.Unmarshal(data, &allocations); err != nil {
+		return errors.Wrapf(err, "corrupted allocation journal %s", j.path)
+	}
+
+	for _, allocation := range allocations {
+		j.allocations[allocationKey(allocation.ResourceName, allocation.DeviceIDs)] = allocation
+	}
+
+	klog.V(1).InfoS("Loaded allocation journal", "allocations", len(allocations), "path", j.path)
+
+	return nil
+}
+
+// save writes the journal atomically to the host. The caller holds the lock.
+func (j *allocationJournal) save() error {
+	allocations := make([]Allocation, 0, len(j.allocations))
+	for _, allocation := range j.allocations {
+		allocations = append(allocations, allocation)
+	}
+
+	data, err := json.Marshal(allocations)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o750); err != nil {
+		return errors.WithStack(err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(j.path), filepath.Base(j.path)+".*")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+
+		return errors.WithStack(err)
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+
+		return errors.WithStack(err)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(os.Rename(tmp.Name(), j.path))
+}
+
+// reconcile matches the journal against the containers kubelet reports via
+// the PodResources API. Matched allocations get the container identity and
+// allocations of released devices are dropped.
+func (j *allocationJournal) reconcile() error {
+	ctx, cancel := context.WithTimeout(context.Background(), podResourcesTimeout)
+	defer cancel()
+
+	resp, err := j.listPodResources(ctx)
+	if err != nil {
+		return err
+	}
+
+	type container struct {
+		namespace, pod, name string
+	}
+
+	containers := make(map[string]container)
+
+	for _, pod := range resp.PodResources {
+		for _, cont := range pod.Containers {
+			for _, dev := range cont.Devices {
+				containers[allocationKey(dev.ResourceName, sortedIDs(dev.DeviceIds))] = container{
+					namespace: pod.Namespace,
+					pod:       pod.Name,
+					name:      cont.Name,
+				}
+			}
+		}
+	}
+
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	for key, allocation := range j.allocations {
+		cont, found := containers[key]
+
+		switch {
+		case found:
+			allocation.Namespace = cont.namespace
+			allocation.Pod = cont.pod
+			allocation.Container = cont.name
+			j.allocations[key] = allocation
+		case time.Since(allocation.Timestamp) > journalReconcilePeriod:
+			klog.V(3).InfoS("Devices were released", LogKeyResource, allocation.ResourceName, LogKeyDeviceID, allocation.DeviceIDs,
+				LogKeyPod, klog.KRef(allocation.Namespace, allocation.Pod), LogKeyContainer, allocation.Container)
+
+			delete(j.allocations, key)
+		}
+	}
+
+	return j.save()
+}
+
+// reconcileLoop reconciles the journal periodically until ctx is done.
+func (j *allocationJournal) reconcileLoop(ctx context.Context) {
+	ticker := time.NewTicker(journalReconcilePeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := j.reconcile(); err != nil {
+			klog.FromContext(ctx).Error(err, "Failed to reconcile allocation journal")
+		}
+	}
+}
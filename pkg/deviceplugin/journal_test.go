@@ -0,0 +1,208 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+	podresourcesv1 "k8s.io/kubelet/pkg/apis/podresources/v1"
+)
+
+const testJournalResource = "test.intel.com/journal"
+
+func newTestJournal(t *testing.T, podResources ...*podresourcesv1.PodResources) *allocationJournal {
+	t.Helper()
+
+	j := newAllocationJournal(filepath.Join(t.TempDir(), "journal", "test.intel.com-allocations.json"))
+	j.listPodResources = func(context.Context) (*podresourcesv1.ListPodResourcesResponse, error) {
+		return &podresourcesv1.ListPodResourcesResponse{PodResources: podResources}, nil
+	}
+
+	return j
+}
+
+func testAllocation(ids ...string) (*pluginapi.AllocateRequest, *pluginapi.AllocateResponse) {
+	return &pluginapi.AllocateRequest{
+		ContainerRequests: []*pluginapi.ContainerAllocateRequest{{DevicesIDs: ids}},
+	}, &pluginapi.AllocateResponse{
+		ContainerResponses: []*pluginapi.ContainerAllocateResponse{{Envs: map[string]string{"IDS": ids[0]}}},
+	}
+}
+
+func recordAllocation(j *allocationJournal, resourceName string, ids ...string) {
+	rqt, resp := testAllocation(ids...)
+	j.record(resourceName, rqt, resp)
+}
+
+func TestJournalRecordAndLoad(t *testing.T) {
+	j := newTestJournal(t)
+
+	if err := j.load(); err != nil {
+		t.Errorf("a missing journal should not be an error: %+v", err)
+	}
+
+	recordAllocation(j, testJournalResource, "dev2", "dev1")
+	recordAllocation(j, testJournalResource, "dev3")
+	recordAllocation(j, "other.intel.com/other", "dev1")
+
+	// Allocating the same devices again replaces the previous allocation.
+	recordAllocation(j, testJournalResource, "dev3")
+
+	rqt, resp := testAllocation("dev4")
+	resp.ContainerResponses = nil
+	j.record(testJournalResource, rqt, resp)
+
+	allocations := j.Allocations(testJournalResource)
+	if len(allocations) != 2 {
+		t.Fatalf("expected 2 allocations, got %+v", allocations)
+	}
+
+	if !reflect.DeepEqual(allocations[0].DeviceIDs, []string{"dev1", "dev2"}) || allocations[0].Response.Envs["IDS"] != "dev2" {
+		t.Errorf("unexpected allocation %+v", allocations[0])
+	}
+
+	restored := newAllocationJournal(j.path)
+	if err := restored.load(); err != nil {
+		t.Fatalf("unable to load journal: %+v", err)
+	}
+
+	if loaded := restored.Allocations(testJournalResource); !reflect.DeepEqual(loaded[0].DeviceIDs, allocations[0].DeviceIDs) ||
+		!reflect.DeepEqual(loaded[1].Response, allocations[1].Response) || len(restored.allocations) != 3 {
+		t.Errorf("journal was not restored: %+v", restored.allocations)
+	}
+
+	if err := os.WriteFile(j.path, []byte("{"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := newAllocationJournal(j.path).load(); err == nil {
+		t.Error("expected an error for a corrupted journal")
+	}
+}
+
+func TestJournalReconcile(t *testing.T) {
+	j := newTestJournal(t, &podresourcesv1.PodResources{
+		Name:      "pod1",
+		Namespace: "default",
+		Containers: []*podresourcesv1.ContainerResources{
+			{
+				Name: "container1",
+				Devices: []*podresourcesv1.ContainerDevices{
+					{ResourceName: testJournalResource, DeviceIds: []string{"dev2", "dev1"}},
+				},
+			},
+		},
+	})
+
+	recordAllocation(j, testJournalResource, "dev1", "dev2")
+	recordAllocation(j, testJournalResource, "released")
+	recordAllocation(j, testJournalResource, "pending")
+
+	// Pretend the released devices were allocated before the previous reconciliation.
+	key := allocationKey(testJournalResource, []string{"released"})
+	released := j.allocations[key]
+	released.Timestamp = time.Now().Add(-2 * journalReconcilePeriod)
+	j.allocations[key] = released
+
+	if err := j.reconcile(); err != nil {
+		t.Fatalf("unable to reconcile: %+v", err)
+	}
+
+	allocations := j.Allocations(testJournalResource)
+	if len(allocations) != 2 {
+		t.Fatalf("expected the released allocation to be dropped, got %+v", allocations)
+	}
+
+	if a := allocations[0]; a.Namespace != "default" || a.Pod != "pod1" || a.Container != "container1" {
+		t.Errorf("allocation was not matched with its container: %+v", a)
+	}
+
+	if a := allocations[1]; a.Pod != "" {
+		t.Errorf("pending allocation should not have a container: %+v", a)
+	}
+
+	j.listPodResources = func(context.Context) (*podresourcesv1.ListPodResourcesResponse, error) {
+		return nil, errors.New("kubelet is not available")
+	}
+
+	if err := j.reconcile(); err == nil {
+		t.Error("expected an error when kubelet is not available")
+	}
+
+	if len(j.Allocations(testJournalResource)) != 2 {
+		t.Error("allocations should be kept when kubelet is not available")
+	}
+}
+
+func TestServerJournal(t *testing.T) {
+	srv := newTestServer()
+	srv.resourceName = testJournalResource
+	srv.cdiSpecs = newCdiSpecManager(t.TempDir(), namespace)
+	srv.journal = newTestJournal(t)
+
+	rqt, _ := testAllocation("dev1")
+
+	if _, err := srv.Allocate(context.Background(), rqt); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	rqt, _ = testAllocation("missing")
+
+	if _, err := srv.Allocate(context.Background(), rqt); err == nil {
+		t.Fatal("expected an error for a missing device")
+	}
+
+	if allocations := srv.journal.Allocations(testJournalResource); len(allocations) != 1 || allocations[0].DeviceIDs[0] != "dev1" {
+		t.Errorf("expected only the successful allocation to be recorded, got %+v", allocations)
+	}
+}
+
+type journalPluginStub struct {
+	devicePluginStub
+	journal AllocationJournal
+}
+
+func (p *journalPluginStub) SetAllocationJournal(journal AllocationJournal) {
+	p.journal = journal
+}
+
+func TestManagerJournal(t *testing.T) {
+	plugin := &journalPluginStub{}
+
+	if NewManager("test.intel.com", plugin, WithAllocationJournal("")).journal != nil {
+		t.Error("journal should be disabled with an empty directory")
+	}
+
+	dir := t.TempDir()
+
+	mgr := NewManager("test.intel.com", plugin, WithAllocationJournal(dir))
+	if mgr.journal == nil || mgr.journal.path != filepath.Join(dir, "test.intel.com-allocations.json") {
+		t.Fatalf("unexpected journal %+v", mgr.journal)
+	}
+
+	mgr.journal.listPodResources = newTestJournal(t).listPodResources
+	mgr.setupJournal()
+
+	if plugin.journal != mgr.journal {
+		t.Error("journal was not handed to the plugin")
+	}
+}
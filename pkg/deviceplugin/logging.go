@@ -0,0 +1,32 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+// Keys of the structured log messages of the framework. Plugins use the same
+// keys, so that log pipelines can extract the values from all plugins.
+const (
+	// LogKeyResource is the key of resource names, e.g. "gpu.intel.com/i915".
+	LogKeyResource = "resource"
+	// LogKeyDeviceID is the key of device IDs, e.g. "card0-1".
+	LogKeyDeviceID = "deviceID"
+	// LogKeyBDF is the key of PCI addresses, e.g. "0000:00:02.0".
+	LogKeyBDF = "bdf"
+	// LogKeyPod is the key of pods, given as klog.KObj() or klog.KRef().
+	LogKeyPod = "pod"
+	// LogKeyContainer is the key of container names.
+	LogKeyContainer = "container"
+	// LogKeyHealth is the key of device health states, e.g. "Unhealthy".
+	LogKeyHealth = "health"
+)
@@ -15,9 +15,19 @@
 package deviceplugin
 
 import (
+	"context"
+	"maps"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"reflect"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/pkg/errors"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	"k8s.io/klog/v2"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 )
@@ -27,6 +37,9 @@ type postAllocateFunc func(*pluginapi.AllocateResponse) error
 type preStartContainerFunc func(*pluginapi.PreStartContainerRequest) error
 type getPreferredAllocationFunc func(*pluginapi.PreferredAllocationRequest) (*pluginapi.PreferredAllocationResponse, error)
 
+// scanStopTimeout is the time Manager waits for Scan() to return on shutdown.
+const scanStopTimeout = 5 * time.Second
+
 // updateInfo contains info for added, updated and deleted devices.
 type updateInfo struct {
 	Added   DeviceTree
@@ -37,83 +50,704 @@ type updateInfo struct {
 // notifier implements Notifier interface.
 type notifier struct {
 	deviceTree DeviceTree
-	updatesCh  chan<- updateInfo
+	// scanned is the latest device tree from Scan(), before the selector.
+	scanned   DeviceTree
+	selector  *DeviceSelector
+	updatesCh chan<- updateInfo
+	done      <-chan struct{}
+	filtered  []string
+	mutex     sync.Mutex
 }
 
-func newNotifier(updatesCh chan<- updateInfo) *notifier {
+// newNotifier creates a notifier sending the updates to updatesCh. The
+// updates are dropped once done is closed.
+func newNotifier(done <-chan struct{}, updatesCh chan<- updateInfo) *notifier {
 	return &notifier{
 		updatesCh: updatesCh,
+		done:      done,
 	}
 }
 
 func (n *notifier) Notify(newDeviceTree DeviceTree) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.scanned = newDeviceTree
+
+	n.notify()
+}
+
+// setSelector changes the device selector and applies it to the latest
+// device tree from Scan().
+func (n *notifier) setSelector(selector *DeviceSelector) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.selector = selector
+
+	if n.scanned != nil {
+		n.notify()
+	}
+}
+
+// notify sends the changes of the selected devices. The caller holds the lock.
+func (n *notifier) notify() {
+	newDeviceTree, filtered := n.selector.Filter(n.scanned)
+
+	if !slices.Equal(filtered, n.filtered) {
+		klog.InfoS("Devices filtered out by the device selector", "devices", filtered)
+
+		n.filtered = filtered
+	}
+
 	added := NewDeviceTree()
 	updated := NewDeviceTree()
+	// The previous tree may be the scanned one, which is selected again
+	// when the selector changes.
+	removed := maps.Clone(n.deviceTree)
 
 	for devType, new := range newDeviceTree {
-		if old, ok := n.deviceTree[devType]; ok {
+		if old, ok := removed[devType]; ok {
 			if !reflect.DeepEqual(old, new) {
 				updated[devType] = new
 			}
 
-			delete(n.deviceTree, devType)
+			delete(removed, devType)
 		} else {
 			added[devType] = new
 		}
 	}
 
-	if len(added) > 0 || len(updated) > 0 || len(n.deviceTree) > 0 {
-		n.updatesCh <- updateInfo{
+	if len(added) > 0 || len(updated) > 0 || len(removed) > 0 {
+		select {
+		case n.updatesCh <- updateInfo{
 			Added:   added,
 			Updated: updated,
-			Removed: n.deviceTree,
+			Removed: removed,
+		}:
+		case <-n.done:
 		}
 	}
 
 	n.deviceTree = newDeviceTree
 }
 
+// Mode selects the kubelet API the devices are advertised with.
+type Mode string
+
+const (
+	// ModeClassic advertises devices with the device plugin API.
+	ModeClassic Mode = "classic"
+	// ModeDRA publishes devices as ResourceSlices and prepares them with the DRA plugin API.
+	ModeDRA Mode = "dra"
+	// ModeBoth advertises devices with both APIs. The APIs don't share
+	// allocation accounting, so a device can be handed out twice.
+	ModeBoth Mode = "both"
+)
+
+// ParseMode converts a string to a Mode.
+func ParseMode(mode string) (Mode, error) {
+	switch m := Mode(mode); m {
+	case ModeClassic, ModeDRA, ModeBoth:
+		return m, nil
+	}
+
+	return "", errors.Errorf("unknown resource API mode %q", mode)
+}
+
+func (m Mode) classic() bool {
+	return m != ModeDRA
+}
+
+func (m Mode) dra() bool {
+	return m == ModeDRA || m == ModeBoth
+}
+
 // Manager manages life cycle of device plugins and handles the scan results
 // received from them.
 type Manager struct {
-	devicePlugin Scanner
-	servers      map[string]devicePluginServer
-	createServer func(string, postAllocateFunc, preStartContainerFunc, getPreferredAllocationFunc, allocateFunc) devicePluginServer
-	namespace    string
+	devicePlugin  Scanner
+	servers       map[string]devicePluginServer
+	createServer  func(string, string, postAllocateFunc, preStartContainerFunc, getPreferredAllocationFunc, allocateFunc) devicePluginServer
+	dra           *draDriver
+	policy        *AllocationPolicy
+	selector      *DeviceSelector
+	journal       *allocationJournal
+	aliases       *aliasPools
+	health        *healthMonitor
+	introspection *introspector
+	inventory     *inventoryPublisher
+	conditions    *nodeConditionReporter
+	cdiSpecs      *cdiSpecManager
+	errCh         chan error
+	logger        klog.Logger // named after the namespace
+	namespace     string
+	pluginPath    string
+	configPath    string
+	metricsAddr   string
+	debugAddr     string
+	mode          Mode
+	withInventory bool
+	withCondition bool
+	// pooledWarned tells that the pooled devices without WithPooledDevices()
+	// have been warned about.
+	pooledWarned bool
+}
+
+// ManagerOption configures optional features of Manager.
+type ManagerOption func(*Manager)
+
+// WithMetrics enables serving Prometheus metrics at the given address,
+// e.g. ":8080". The metrics are not served when the address is empty.
+func WithMetrics(addr string) ManagerOption {
+	return func(m *Manager) {
+		m.metricsAddr = addr
+	}
+}
+
+// WithMode selects the kubelet API the devices are advertised with.
+// The default is ModeClassic.
+func WithMode(mode Mode) ManagerOption {
+	return func(m *Manager) {
+		m.mode = mode
+	}
+}
+
+// WithAllocationJournal enables recording the device allocations to a journal
+// in the given host directory. The journal is reconciled with the kubelet
+// PodResources API on startup and periodically after that. The journal is
+// not kept when the directory is empty.
+func WithAllocationJournal(dir string) ManagerOption {
+	return func(m *Manager) {
+		if dir != "" {
+			m.journal = newAllocationJournal(filepath.Join(dir, m.namespace+"-allocations.json"))
+		}
+	}
+}
+
+// WithHealthDebug enables serving the latest results of the HealthChecker
+// device plugins at the given address, e.g. "127.0.0.1:8081", under
+// /debug/health. The results are not served when the address is empty.
+func WithHealthDebug(addr string) ManagerOption {
+	return func(m *Manager) {
+		m.debugAddr = addr
+	}
+}
+
+// WithAllocationPolicy enables the preferred allocation of devices with the
+// given policy. The policy is not used when the device plugin implements
+// PreferredAllocator.
+func WithAllocationPolicy(policy *AllocationPolicy) ManagerOption {
+	return func(m *Manager) {
+		m.policy = policy
+	}
+}
+
+// WithIntrospection enables serving the advertised devices, their capacity and
+// the recent Allocate calls over HTTP on a unix socket in the given directory, see
+// IntrospectionSocket(). The endpoint is not served when the directory is empty.
+func WithIntrospection(dir string) ManagerOption {
+	return func(m *Manager) {
+		if dir != "" {
+			m.introspection = newIntrospector(m.namespace, IntrospectionSocket(dir, m.namespace))
+		}
+	}
+}
+
+// WithDevicePluginPath sets the directory of the device plugin sockets and
+// the kubelet registration socket. The default is pluginapi.DevicePluginPath.
+func WithDevicePluginPath(dir string) ManagerOption {
+	return func(m *Manager) {
+		m.pluginPath = dir
+	}
+}
+
+// WithCDIDir sets the directory of the CDI spec files written by Manager.
+// The default is CDIDir.
+func WithCDIDir(dir string) ManagerOption {
+	return func(m *Manager) {
+		m.cdiSpecs = newCdiSpecManager(dir, m.namespace)
+	}
+}
+
+// WithConfigFile makes Manager apply the config file at the given path to a
+// Configurable device plugin and to the device selector before Scan(), and
+// whenever the file changes. An invalid config fails Run() at startup, and is
+// rejected with a Node event later. The config file is not used when the path
+// is empty.
+func WithConfigFile(path string) ManagerOption {
+	return func(m *Manager) {
+		m.configPath = path
+	}
+}
+
+// WithDeviceSelector makes Manager advertise only the devices the selector
+// selects, see DeviceSelector. The selector overrides the one of the config
+// file, see WithConfigFile(). All devices are advertised when the selector
+// is nil.
+func WithDeviceSelector(selector *DeviceSelector) ManagerOption {
+	return func(m *Manager) {
+		m.selector = selector
+	}
+}
+
+// WithInventory enables writing the advertised devices, their health and
+// attributes to the NodeAcceleratorInventory of the node, using the in-cluster
+// config and the NODE_NAME environment variable. The inventory is updated only
+// when the devices change.
+func WithInventory(enabled bool) ManagerOption {
+	return func(m *Manager) {
+		m.withInventory = enabled
+	}
+}
+
+// WithNodeCondition enables maintaining a Node condition, e.g. IntelGPUHealthy
+// for "gpu.intel.com", that summarizes the health of the advertised devices,
+// using the in-cluster config and the NODE_NAME environment variable. Health
+// changes are merged into at most one Node update per 10 seconds.
+func WithNodeCondition(enabled bool) ManagerOption {
+	return func(m *Manager) {
+		m.withCondition = enabled
+	}
+}
+
+// WithPooledDevices enables the mutual exclusion of the devices added with
+// DeviceTree.AddPooledDevice(). The devices in use are checked periodically
+// with the kubelet PodResources API.
+func WithPooledDevices(enabled bool) ManagerOption {
+	return func(m *Manager) {
+		if enabled {
+			m.aliases = newAliasPools(m.namespace)
+		} else {
+			m.aliases = nil
+		}
+	}
 }
 
 // NewManager creates a new instance of Manager.
-func NewManager(namespace string, devicePlugin Scanner) *Manager {
-	return &Manager{
+func NewManager(namespace string, devicePlugin Scanner, opts ...ManagerOption) *Manager {
+	m := &Manager{
 		devicePlugin: devicePlugin,
 		namespace:    namespace,
 		servers:      make(map[string]devicePluginServer),
 		createServer: newServer,
+		cdiSpecs:     newCdiSpecManager(CDIDir, namespace),
+		pluginPath:   pluginapi.DevicePluginPath,
+		errCh:        make(chan error, 1),
+		mode:         ModeClassic,
+		logger:       klog.LoggerWithName(klog.Background(), namespace),
+	}
+
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if m.introspection != nil {
+		m.introspection.sharer, _ = devicePlugin.(DeviceSharer)
+
+		// A nil journal must not become a non-nil interface.
+		if m.journal != nil {
+			m.introspection.journal = m.journal
+		}
 	}
+
+	return m
 }
 
-// Run prepares and launches event loop for updates from Scanner.
-func (m *Manager) Run() {
+// Run prepares and launches event loop for updates from Scanner. It returns
+// when ctx is done, the process receives SIGTERM or SIGINT, Scan() returns or
+// serving the devices fails. Before returning, the devices are withdrawn from
+// kubelet, and the plugin sockets and the CDI spec files written by Manager
+// are removed. The returned error aggregates the errors that caused or
+// occurred during the shutdown.
+func (m *Manager) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	// The helpers started by Run() log with the logger of the Manager.
+	ctx = klog.NewContext(ctx, m.logger)
+
 	updatesCh := make(chan updateInfo)
+	notifier := newNotifier(ctx.Done(), updatesCh)
+	notifier.selector = m.selector
+
+	if m.configPath != "" {
+		if err := m.setupConfig(ctx, notifier); err != nil {
+			return err
+		}
+	}
+
+	if m.metricsAddr != "" {
+		go func() {
+			if err := serveMetrics(ctx, m.metricsAddr); err != nil {
+				m.logger.Error(err, "Failed to serve metrics")
+			}
+		}()
+	}
+
+	if m.introspection != nil {
+		go func() {
+			if err := m.introspection.serve(ctx); err != nil {
+				m.logger.Error(err, "Introspection endpoint is not available, is its directory writable?")
+			}
+		}()
+	}
+
+	if m.journal != nil {
+		m.setupJournal()
+
+		go m.journal.reconcileLoop(ctx)
+	}
+
+	if m.mode.dra() {
+		if err := m.setupDRA(); err != nil {
+			return err
+		}
+	}
+
+	if m.setupInventory() {
+		go m.inventory.run(ctx)
+	}
+
+	if m.setupNodeCondition() {
+		go m.conditions.run(ctx)
+	}
+
+	var (
+		healthCh chan healthResults
+		aliasCh  chan struct{}
+	)
+
+	if m.mode.classic() && m.aliases != nil {
+		aliasCh = m.aliases.changed
+
+		go m.aliases.reconcileLoop(ctx)
+	}
+
+	if m.setupHealth(ctx) {
+		healthCh = make(chan healthResults)
+
+		go m.health.run(ctx, healthCh)
+	}
+
+	scanDone := make(chan struct{})
 
 	go func() {
-		err := m.devicePlugin.Scan(newNotifier(updatesCh))
-		if err != nil {
-			klog.Errorf("Device scan failed: %+v", err)
-			os.Exit(1)
+		defer close(scanDone)
+
+		if err := m.devicePlugin.Scan(notifier); err != nil {
+			m.fail(errors.Wrap(err, "device scan failed"))
 		}
 
 		close(updatesCh)
 	}()
 
-	for update := range updatesCh {
-		m.handleUpdate(update)
+	for {
+		select {
+		case <-ctx.Done():
+			m.logger.V(1).Info("Shutting down device plugins")
+
+			return m.shutdown(stop, scanDone)
+		case err := <-m.errCh:
+			return m.shutdown(stop, scanDone, err)
+		case update, ok := <-updatesCh:
+			if !ok {
+				return m.shutdown(stop, scanDone)
+			}
+
+			m.handleUpdate(update)
+		case results := <-healthCh:
+			m.handleHealthResults(results)
+		case <-aliasCh:
+			m.handleAliasUpdate()
+		}
+	}
+}
+
+// fail makes Run() return with the error. Only the first error is returned,
+// the rest are logged.
+func (m *Manager) fail(err error) {
+	select {
+	case m.errCh <- err:
+	default:
+		m.logger.Error(err, "Device plugin failed")
+	}
+}
+
+// shutdown stops the device scan and the servers, and withdraws the devices
+// from kubelet. It returns the given errors aggregated with the errors of
+// the shutdown.
+func (m *Manager) shutdown(cancel context.CancelFunc, scanDone <-chan struct{}, errs ...error) error {
+	// Stop the helper servers and loops, and drop the updates still sent by Scan().
+	cancel()
+
+	if stopper, ok := m.devicePlugin.(ScanStopper); ok {
+		stopper.StopScan()
+
+		select {
+		case <-scanDone:
+		case <-time.After(scanStopTimeout):
+			m.logger.Info("Timeout waiting for the device scan to stop")
+		}
+	}
+
+	for devType, srv := range m.servers {
+		if err := srv.Stop(); err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to stop %s", m.resourceName(devType)))
+		}
+
+		deleteDeviceMetrics(m.resourceName(devType))
+		delete(m.servers, devType)
+	}
+
+	if m.cdiSpecs != nil {
+		if err := m.cdiSpecs.removeAll(); err != nil {
+			errs = append(errs, errors.Wrap(err, "failed to remove CDI specs"))
+		}
+	}
+
+	if m.dra != nil {
+		if err := m.dra.Stop(); err != nil {
+			errs = append(errs, errors.Wrapf(err, "failed to stop DRA driver %s", m.namespace))
+		}
+	}
+
+	select {
+	case err := <-m.errCh:
+		errs = append(errs, err)
+	default:
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// setupDRA creates the DRA driver, unless one is set already, and serves it.
+func (m *Manager) setupDRA() error {
+	if m.dra == nil {
+		dra, err := newDRADriverInCluster(m.namespace)
+		if err != nil {
+			return errors.Wrap(err, "failed to create DRA driver")
+		}
+
+		m.dra = dra
+	}
+
+	return errors.Wrapf(m.dra.Serve(), "failed to serve DRA driver %s", m.namespace)
+}
+
+// setupInventory creates the inventory publisher, unless one is set already.
+// It returns false when the inventory is disabled or not available.
+func (m *Manager) setupInventory() bool {
+	if m.inventory == nil && m.withInventory {
+		inventory, err := newInventoryPublisherInCluster(m.namespace)
+		if err != nil {
+			m.logger.Error(err, "Devices are not published in the node inventory")
+
+			return false
+		}
+
+		m.inventory = inventory
+	}
+
+	return m.inventory != nil
+}
+
+// setupNodeCondition creates the node condition reporter, unless one is set
+// already. It returns false when the condition is disabled or not available.
+func (m *Manager) setupNodeCondition() bool {
+	if m.conditions == nil && m.withCondition {
+		conditions, err := newNodeConditionReporterInCluster(m.namespace)
+		if err != nil {
+			m.logger.Error(err, "Device health is not reported as a node condition")
+
+			return false
+		}
+
+		m.conditions = conditions
+	}
+
+	return m.conditions != nil
+}
+
+// setupHealth creates the health monitor when the device plugin implements
+// HealthChecker. It returns false when the health checks are disabled.
+func (m *Manager) setupHealth(ctx context.Context) bool {
+	checker, ok := m.devicePlugin.(HealthChecker)
+	if !ok || checker.HealthCheckInterval() <= 0 {
+		if m.debugAddr != "" {
+			m.logger.Info("Device health checks are disabled, not serving them", "address", m.debugAddr)
+		}
+
+		return false
+	}
+
+	m.health = newHealthMonitor(m.namespace, checker)
+
+	recorder, node, err := newNodeEventRecorder(m.namespace + "-device-plugin")
+	if err != nil {
+		m.logger.Error(err, "Device health changes are not reported as Node events")
+	} else {
+		m.health.recorder, m.health.node = recorder, node
+	}
+
+	if m.debugAddr != "" {
+		go func() {
+			if err := serveHealthDebug(ctx, m.debugAddr, m.health); err != nil {
+				m.logger.Error(err, "Failed to serve device health")
+			}
+		}()
+	}
+
+	return true
+}
+
+// setupConfig applies the config file to the device plugin and to the device
+// selector of the notifier, and starts watching the file for changes.
+func (m *Manager) setupConfig(ctx context.Context, n *notifier) error {
+	// A plugin that takes no settings can still use the device selector.
+	plugin, _ := m.devicePlugin.(Configurable)
+
+	config := newConfigLoader(m.configPath, m.namespace, plugin, func(selector *DeviceSelector) {
+		if m.selector != nil {
+			m.logger.V(1).Info("The device selector of the config file is overridden on the command line")
+			return
+		}
+
+		n.setSelector(selector)
+	})
+	if err := config.load(); err != nil {
+		return errors.Wrapf(err, "failed to load config %s", m.configPath)
+	}
+
+	recorder, node, err := newNodeEventRecorder(m.namespace + "-device-plugin")
+	if err != nil {
+		m.logger.Error(err, "Rejected configs are not reported as Node events")
+	} else {
+		config.recorder, config.node = recorder, node
+	}
+
+	go func() {
+		if err := config.watch(ctx); err != nil {
+			m.logger.Error(err, "Config changes are not applied")
+		}
+	}()
+
+	return nil
+}
+
+// setupJournal restores the allocation journal and hands it to the device plugin.
+func (m *Manager) setupJournal() {
+	if err := m.journal.load(); err != nil {
+		m.logger.Error(err, "Failed to load allocation journal, starting with an empty one")
+	}
+
+	if err := m.journal.reconcile(); err != nil {
+		m.logger.Error(err, "Failed to reconcile allocation journal")
+	}
+
+	if querier, ok := m.devicePlugin.(AllocationQuerier); ok {
+		querier.SetAllocationJournal(m.journal)
 	}
 }
 
 func (m *Manager) handleUpdate(update updateInfo) {
-	klog.V(4).Info("Received dev updates:", update)
+	m.logger.V(4).Info("Received device updates", "update", update)
+
+	if m.health != nil {
+		update = m.health.track(update)
+	}
+
+	if m.mode.classic() && m.aliases != nil {
+		update = m.aliases.track(update)
+	} else if !m.pooledWarned && hasPooledDevices(update) {
+		m.pooledWarned = true
+
+		m.logger.Info("Pooled devices are not mutually exclusive without WithPooledDevices()")
+	}
+
+	m.applyUpdate(update)
+}
+
+// handleHealthResults pushes the device state changes caused by health
+// check results to kubelet.
+func (m *Manager) handleHealthResults(results healthResults) {
+	update := m.health.update(results)
+	if len(update.Updated) == 0 {
+		return
+	}
+
+	if m.mode.classic() && m.aliases != nil {
+		update = m.aliases.track(update)
+	}
+
+	m.applyUpdate(update)
+}
+
+// handleAliasUpdate withdraws or restores the devices whose pools were
+// allocated or released.
+func (m *Manager) handleAliasUpdate() {
+	update := m.aliases.update()
+	if len(update.Updated) > 0 {
+		m.applyUpdate(update)
+	}
+}
+
+func (m *Manager) applyUpdate(update updateInfo) {
+	if m.introspection != nil {
+		m.introspection.update(update)
+	}
+
+	if m.inventory != nil {
+		m.inventory.track(update)
+	}
+
+	if m.conditions != nil {
+		m.conditions.track(update)
+	}
+
+	if m.mode.dra() {
+		m.handleDRAUpdate(update)
+	}
 
+	for devType, devices := range update.Added {
+		updateDeviceMetrics(m.resourceName(devType), devices)
+	}
+
+	for devType, devices := range update.Updated {
+		updateDeviceMetrics(m.resourceName(devType), devices)
+	}
+
+	for devType := range update.Removed {
+		deleteDeviceMetrics(m.resourceName(devType))
+	}
+
+	if m.mode.classic() {
+		// The CDI specs are written before the devices are advertised.
+		if m.cdiSpecs != nil {
+			if err := m.cdiSpecs.update(update); err != nil {
+				m.logger.Error(err, "Failed to update CDI specs")
+			}
+		}
+
+		m.handleClassicUpdate(update)
+	}
+}
+
+func (m *Manager) handleDRAUpdate(update updateInfo) {
+	for devType, devices := range update.Added {
+		m.dra.Update(devType, devices)
+	}
+
+	for devType, devices := range update.Updated {
+		m.dra.Update(devType, devices)
+	}
+
+	for devType := range update.Removed {
+		m.dra.Remove(devType)
+	}
+}
+
+func (m *Manager) handleClassicUpdate(update updateInfo) {
 	for devType, devices := range update.Added {
 		var (
 			allocate               allocateFunc
@@ -138,15 +772,29 @@ func (m *Manager) handleUpdate(update updateInfo) {
 			allocate = allocator.Allocate
 		}
 
-		m.servers[devType] = m.createServer(devType, postAllocate, preStartContainer, getPreferredAllocation, allocate)
+		m.servers[devType] = m.createServer(m.namespace, devType, postAllocate, preStartContainer, getPreferredAllocation, allocate)
+
+		if srv, ok := m.servers[devType].(*server); ok {
+			srv.journal = m.journal
+			srv.introspection = m.introspection
+			srv.cdiSpecs = m.cdiSpecs
+			srv.aliases = m.aliases
+			srv.devicePluginPath = m.pluginPath
 
-		go func(dt string) {
-			err := m.servers[dt].Serve(m.namespace)
-			if err != nil {
-				klog.Errorf("Failed to serve %s/%s: %+v", m.namespace, dt, err)
-				os.Exit(1)
+			if limiter, ok := m.devicePlugin.(AllocateLimiter); ok {
+				srv.setAllocateLimit(limiter.MaxConcurrentAllocations(devType))
 			}
-		}(devType)
+
+			if getPreferredAllocation == nil {
+				srv.allocationPolicy = m.policy
+			}
+		}
+
+		go func(srv devicePluginServer, resourceName string) {
+			if err := srv.Serve(m.namespace); err != nil {
+				m.fail(errors.Wrapf(err, "failed to serve %s", resourceName))
+			}
+		}(m.servers[devType], m.resourceName(devType))
 		m.servers[devType].Update(devices)
 	}
 
@@ -156,9 +804,13 @@ func (m *Manager) handleUpdate(update updateInfo) {
 
 	for devType := range update.Removed {
 		if err := m.servers[devType].Stop(); err != nil {
-			klog.Errorf("Unable to stop gRPC server for %q: %+v", devType, err)
+			m.logger.Error(err, "Unable to stop gRPC server", LogKeyResource, m.resourceName(devType))
 		}
 
 		delete(m.servers, devType)
 	}
 }
+
+func (m *Manager) resourceName(devType string) string {
+	return m.namespace + "/" + devType
+}
@@ -15,9 +15,13 @@
 package deviceplugin
 
 import (
+	"context"
 	"flag"
+	"strings"
 	"testing"
 
+	"github.com/pkg/errors"
+
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 )
 
@@ -109,7 +113,7 @@ func TestNotify(t *testing.T) {
 
 	for _, tcase := range tcases {
 		ch := make(chan updateInfo, 1)
-		n := newNotifier(ch)
+		n := newNotifier(nil, ch)
 		n.deviceTree = tcase.oldmap
 
 		n.Notify(tcase.newmap)
@@ -274,7 +278,7 @@ func TestHandleUpdate(t *testing.T) {
 		mgr := Manager{
 			devicePlugin: &devicePluginStub{},
 			servers:      tt.servers,
-			createServer: func(string, postAllocateFunc, preStartContainerFunc, getPreferredAllocationFunc, allocateFunc) devicePluginServer {
+			createServer: func(string, string, postAllocateFunc, preStartContainerFunc, getPreferredAllocationFunc, allocateFunc) devicePluginServer {
 				return &serverStub{}
 			},
 		}
@@ -290,8 +294,124 @@ func TestHandleUpdate(t *testing.T) {
 
 func TestRun(t *testing.T) {
 	mgr := NewManager("testnamespace", &devicePluginStub{})
-	mgr.createServer = func(string, postAllocateFunc, preStartContainerFunc, getPreferredAllocationFunc, allocateFunc) devicePluginServer {
+	mgr.createServer = func(string, string, postAllocateFunc, preStartContainerFunc, getPreferredAllocationFunc, allocateFunc) devicePluginServer {
 		return &serverStub{}
 	}
-	mgr.Run()
+
+	if err := mgr.Run(context.Background()); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+}
+
+type lifecycleServerStub struct {
+	serveErr error
+	updated  chan struct{}
+	stopped  bool
+}
+
+func (s *lifecycleServerStub) Serve(string) error {
+	return s.serveErr
+}
+
+func (s *lifecycleServerStub) Update(map[string]DeviceInfo) {
+	s.updated <- struct{}{}
+}
+
+func (s *lifecycleServerStub) Stop() error {
+	s.stopped = true
+
+	return nil
+}
+
+type scanStopperStub struct {
+	devicePluginStub
+	scanErr  error
+	scanDone chan bool
+}
+
+func (p *scanStopperStub) Scan(n Notifier) error {
+	_ = p.devicePluginStub.Scan(n)
+
+	if p.scanErr != nil {
+		return p.scanErr
+	}
+
+	<-p.scanDone
+
+	return nil
+}
+
+func (p *scanStopperStub) StopScan() {
+	select {
+	case p.scanDone <- true:
+	default:
+	}
+}
+
+func TestRunShutdown(t *testing.T) {
+	tcases := []struct {
+		serveErr    error
+		scanErr     error
+		name        string
+		expectedErr string
+	}{
+		{
+			name: "context canceled",
+		},
+		{
+			name:        "serve failure",
+			serveErr:    errors.New("socket in use"),
+			expectedErr: "failed to serve testnamespace/testdevice: socket in use",
+		},
+		{
+			name:        "scan failure",
+			scanErr:     errors.New("no devices"),
+			expectedErr: "device scan failed: no devices",
+		},
+	}
+
+	for _, tt := range tcases {
+		t.Run(tt.name, func(t *testing.T) {
+			plugin := &scanStopperStub{scanErr: tt.scanErr, scanDone: make(chan bool, 1)}
+			srv := &lifecycleServerStub{serveErr: tt.serveErr, updated: make(chan struct{}, 1)}
+
+			mgr := NewManager("testnamespace", plugin)
+			mgr.createServer = func(string, string, postAllocateFunc, preStartContainerFunc, getPreferredAllocationFunc, allocateFunc) devicePluginServer {
+				return srv
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			errCh := make(chan error)
+
+			go func() {
+				errCh <- mgr.Run(ctx)
+			}()
+
+			<-srv.updated
+
+			if tt.expectedErr == "" {
+				cancel()
+			}
+
+			err := <-errCh
+
+			if tt.expectedErr == "" && err != nil {
+				t.Errorf("unexpected error: %+v", err)
+			}
+
+			if tt.expectedErr != "" && (err == nil || !strings.Contains(err.Error(), tt.expectedErr)) {
+				t.Errorf("expected error %q, got %v", tt.expectedErr, err)
+			}
+
+			if !srv.stopped || len(mgr.servers) != 0 {
+				t.Error("servers were not stopped")
+			}
+
+			if tt.scanErr == nil && len(plugin.scanDone) != 0 {
+				t.Error("scan was not stopped")
+			}
+		})
+	}
 }
@@ -0,0 +1,179 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"k8s.io/klog/v2"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+const (
+	metricsNamespace = "intel"
+	metricsSubsystem = "device_plugin"
+	metricsPath      = "/metrics"
+
+	rpcAllocate               = "Allocate"
+	rpcGetPreferredAllocation = "GetPreferredAllocation"
+	rpcPreStartContainer      = "PreStartContainer"
+
+	rpcResultSuccess = "success"
+	rpcResultError   = "error"
+)
+
+var (
+	metricsRegistry = prometheus.NewRegistry()
+
+	devicesGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "devices",
+		Help:      "Number of devices advertised to kubelet per resource and health state.",
+	}, []string{"resource", "health"})
+
+	rpcRequestsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "rpc_requests_total",
+		Help:      "Number of device plugin RPC calls received from kubelet.",
+	}, []string{"resource", "method", "result"})
+
+	rpcDurationHistogram = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "rpc_duration_seconds",
+		Help:      "Latency of device plugin RPC calls received from kubelet.",
+		Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+	}, []string{"resource", "method"})
+
+	cdiSpecWriteFailuresCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "cdi_spec_write_failures_total",
+		Help:      "Number of failed CDI spec writes to the filesystem.",
+	}, []string{"resource"})
+
+	registrationsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "kubelet_registrations_total",
+		Help:      "Number of successful registrations with kubelet.",
+	}, []string{"resource"})
+
+	serverRestartsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "server_restarts_total",
+		Help:      "Number of gRPC server restarts caused by kubelet removing the plugin socket.",
+	}, []string{"resource"})
+
+	healthTransitionsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: metricsSubsystem,
+		Name:      "health_transitions_total",
+		Help:      "Number of device health state changes reported by health checks per resource and new state.",
+	}, []string{"resource", "health"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		devicesGauge,
+		rpcRequestsCounter,
+		rpcDurationHistogram,
+		cdiSpecWriteFailuresCounter,
+		registrationsCounter,
+		serverRestartsCounter,
+		healthTransitionsCounter,
+	)
+}
+
+// updateDeviceMetrics sets the device count gauges of a resource.
+func updateDeviceMetrics(resourceName string, devices map[string]DeviceInfo) {
+	counts := map[string]float64{
+		pluginapi.Healthy:   0,
+		pluginapi.Unhealthy: 0,
+	}
+
+	for _, dev := range devices {
+		counts[dev.state]++
+	}
+
+	for health, count := range counts {
+		devicesGauge.WithLabelValues(resourceName, health).Set(count)
+	}
+}
+
+// deleteDeviceMetrics drops the device count gauges of a removed resource.
+func deleteDeviceMetrics(resourceName string) {
+	devicesGauge.DeletePartialMatch(prometheus.Labels{"resource": resourceName})
+}
+
+// observeRPC records the result and the latency of a single RPC call.
+func observeRPC(resourceName, method string, start time.Time, err error) {
+	result := rpcResultSuccess
+	if err != nil {
+		result = rpcResultError
+	}
+
+	rpcRequestsCounter.WithLabelValues(resourceName, method, result).Inc()
+	rpcDurationHistogram.WithLabelValues(resourceName, method).Observe(time.Since(start).Seconds())
+}
+
+// serveMetrics serves the framework metrics over HTTP at the given address
+// until ctx is done.
+func serveMetrics(ctx context.Context, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{}))
+
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return errors.Wrapf(err, "failed to listen on %s", addr)
+	}
+
+	klog.FromContext(ctx).V(1).Info("Serving metrics", "address", addr, "path", metricsPath)
+
+	return serveHTTP(ctx, lis, mux)
+}
+
+// serveHTTP serves HTTP requests on the listener until ctx is done. It only
+// returns an error when the HTTP server fails.
+func serveHTTP(ctx context.Context, lis net.Listener, handler http.Handler) error {
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		_ = srv.Close()
+	}()
+
+	if err := srv.Serve(lis); !errors.Is(err, http.ErrServerClosed) {
+		return errors.WithStack(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,122 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+func TestUpdateDeviceMetrics(t *testing.T) {
+	const resource = "test.intel.com/metrics"
+
+	updateDeviceMetrics(resource, map[string]DeviceInfo{
+		"dev1": {state: pluginapi.Healthy},
+		"dev2": {state: pluginapi.Healthy},
+		"dev3": {state: pluginapi.Unhealthy},
+	})
+
+	if val := testutil.ToFloat64(devicesGauge.WithLabelValues(resource, pluginapi.Healthy)); val != 2 {
+		t.Errorf("expected 2 healthy devices, got %v", val)
+	}
+
+	if val := testutil.ToFloat64(devicesGauge.WithLabelValues(resource, pluginapi.Unhealthy)); val != 1 {
+		t.Errorf("expected 1 unhealthy device, got %v", val)
+	}
+
+	updateDeviceMetrics(resource, map[string]DeviceInfo{
+		"dev1": {state: pluginapi.Healthy},
+	})
+
+	if val := testutil.ToFloat64(devicesGauge.WithLabelValues(resource, pluginapi.Unhealthy)); val != 0 {
+		t.Errorf("expected 0 unhealthy devices, got %v", val)
+	}
+
+	before := testutil.CollectAndCount(devicesGauge)
+
+	deleteDeviceMetrics(resource)
+
+	if after := testutil.CollectAndCount(devicesGauge); before-after != 2 {
+		t.Errorf("expected both health states to be removed, got %d -> %d metrics", before, after)
+	}
+}
+
+func TestRPCMetrics(t *testing.T) {
+	srv := newTestServer()
+	srv.resourceName = "test.intel.com/rpc"
+	srv.cdiSpecs = newCdiSpecManager(t.TempDir(), namespace)
+
+	// The counters are global, so the calls of the previous runs are dropped.
+	rpcRequestsCounter.Reset()
+
+	rqt := &pluginapi.AllocateRequest{
+		ContainerRequests: []*pluginapi.ContainerAllocateRequest{
+			{DevicesIDs: []string{"dev1"}},
+		},
+	}
+
+	if _, err := srv.Allocate(context.Background(), rqt); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	rqt.ContainerRequests[0].DevicesIDs = []string{"missing"}
+
+	if _, err := srv.Allocate(context.Background(), rqt); err == nil {
+		t.Fatal("expected an error for a missing device")
+	}
+
+	if _, err := srv.GetPreferredAllocation(context.Background(), nil); err == nil {
+		t.Fatal("expected an error for a missing preferred allocation hook")
+	}
+
+	tcases := []struct {
+		method   string
+		result   string
+		expected float64
+	}{
+		{method: rpcAllocate, result: rpcResultSuccess, expected: 1},
+		{method: rpcAllocate, result: rpcResultError, expected: 1},
+		{method: rpcGetPreferredAllocation, result: rpcResultError, expected: 1},
+		{method: rpcPreStartContainer, result: rpcResultSuccess, expected: 0},
+	}
+
+	for _, tc := range tcases {
+		val := testutil.ToFloat64(rpcRequestsCounter.WithLabelValues(srv.resourceName, tc.method, tc.result))
+		if val != tc.expected {
+			t.Errorf("%s/%s: expected %v calls, got %v", tc.method, tc.result, tc.expected, val)
+		}
+	}
+}
+
+func TestObserveRPC(t *testing.T) {
+	const resource = "test.intel.com/observe"
+
+	observeRPC(resource, rpcPreStartContainer, time.Now().Add(-time.Second), nil)
+
+	if count := testutil.CollectAndCount(rpcDurationHistogram, "intel_device_plugin_rpc_duration_seconds"); count == 0 {
+		t.Error("no latency observations recorded")
+	}
+}
+
+func TestWithMetrics(t *testing.T) {
+	mgr := NewManager("testnamespace", &devicePluginStub{}, WithMetrics(":12345"))
+	if mgr.metricsAddr != ":12345" {
+		t.Errorf("unexpected metrics address %q", mgr.metricsAddr)
+	}
+}
@@ -0,0 +1,454 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// DeviceKey returns a property of a device, e.g. its parent device or NUMA
+// node, which AllocationRule groups the devices by. The DeviceInfo is empty
+// when the policy is used without the advertised devices.
+type DeviceKey func(id string, dev DeviceInfo) string
+
+// ParentKey groups the devices by the part of their ID before the last sep,
+// e.g. "card0" of "card0-1". IDs without sep are their own parents.
+func ParentKey(sep string) DeviceKey {
+	return func(id string, _ DeviceInfo) string {
+		if i := strings.LastIndex(id, sep); i >= 0 {
+			return id[:i]
+		}
+
+		return id
+	}
+}
+
+// PCIBusKey groups devices with PCI address IDs, e.g. "0000:03:00.4", by
+// their domain and bus, e.g. "0000:03".
+func PCIBusKey(id string, _ DeviceInfo) string {
+	if i := strings.LastIndex(id, ":"); i >= 0 {
+		return id[:i]
+	}
+
+	return id
+}
+
+// PCIRootComplexKey groups devices with PCI address IDs by their root
+// complex, e.g. "pci0000:00", read from the device links in
+// sysfsPCIDevicesDir, e.g. "/sys/bus/pci/devices".
+func PCIRootComplexKey(sysfsPCIDevicesDir string) DeviceKey {
+	return func(id string, _ DeviceInfo) string {
+		link, err := os.Readlink(filepath.Join(sysfsPCIDevicesDir, id))
+		if err != nil {
+			return ""
+		}
+
+		// ../../../devices/pci0000:00/0000:00:01.0/0000:03:00.4
+		for _, elem := range strings.Split(link, "/") {
+			if strings.HasPrefix(elem, "pci") {
+				return elem
+			}
+		}
+
+		return ""
+	}
+}
+
+// NUMANodeKey groups the devices by the NUMA nodes of their topology.
+func NUMANodeKey(_ string, dev DeviceInfo) string {
+	if dev.topology == nil {
+		return ""
+	}
+
+	nodes := make([]string, 0, len(dev.topology.Nodes))
+	for _, node := range dev.topology.Nodes {
+		nodes = append(nodes, fmt.Sprint(node.ID))
+	}
+
+	return strings.Join(nodes, ",")
+}
+
+var (
+	pciRootRe    = regexp.MustCompile(`^pci[0-9a-f]{4}:[0-9a-f]{2}$`)
+	pciAddressRe = regexp.MustCompile(`^[0-9a-f]{4}:[0-9a-f]{2}:[0-9a-f]{2}\.[0-7]$`)
+)
+
+// pciPath returns the PCI hierarchy of a resolved sysfs device path, e.g.
+// "pci0000:00/0000:00:01.0/0000:03:00.0" for
+// "/sys/devices/pci0000:00/0000:00:01.0/0000:03:00.0/drm/card0", and the
+// sysfs directory of the last PCI device in it.
+func pciPath(sysfsPath string) (string, string) {
+	elems := strings.Split(sysfsPath, "/")
+
+	for i, elem := range elems {
+		if !pciRootRe.MatchString(elem) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(elems) && pciAddressRe.MatchString(elems[end]) {
+			end++
+		}
+
+		return strings.Join(elems[i:end], "/"), strings.Join(elems[:end], "/")
+	}
+
+	return "", ""
+}
+
+// TopologyKey returns the NUMA node and the PCI hierarchy of the devices for
+// Tightest, e.g. "0/pci0000:00/0000:00:01.0/0000:01:00.0/0000:02:01.0/0000:03:00.0".
+// The devices behind the same PCIe switch or root port share the groups of
+// the upstream bridges. sysfsPath returns the sysfs path of a device ID, e.g.
+// "/sys/class/drm/card0" for "card0-1". The NUMA node comes from the topology
+// of the device or, when it's not known, from sysfs.
+func TopologyKey(sysfsPath func(id string) string) DeviceKey {
+	return func(id string, dev DeviceInfo) string {
+		var pci, dir string
+
+		if path, err := filepath.EvalSymlinks(sysfsPath(id)); err == nil {
+			pci, dir = pciPath(path)
+		}
+
+		node := NUMANodeKey(id, dev)
+		if node == "" && dir != "" {
+			if data, err := os.ReadFile(filepath.Join(dir, "numa_node")); err == nil {
+				if node = strings.TrimSpace(string(data)); node == "-1" {
+					node = ""
+				}
+			}
+		}
+
+		if node == "" || pci == "" {
+			return node + pci
+		}
+
+		return node + "/" + pci
+	}
+}
+
+type ruleMode int
+
+const (
+	modeByID ruleMode = iota
+	modeSpread
+	modeBalance
+	modePack
+	modeTightest
+)
+
+// AllocationRule ranks the candidate devices by the groups given by a key.
+type AllocationRule struct {
+	key  DeviceKey
+	mode ruleMode
+}
+
+// ByID prefers the devices with the lowest IDs.
+var ByID = AllocationRule{mode: modeByID}
+
+// Spread prefers the groups with the fewest devices selected for the
+// container, i.e. it keeps the devices of a container apart.
+func Spread(key DeviceKey) AllocationRule {
+	return AllocationRule{key: key, mode: modeSpread}
+}
+
+// Balance prefers the least loaded groups. The load of a group is the number
+// of its devices which are allocated or selected for the container. Without
+// the advertised devices, the groups with the most free devices are preferred.
+func Balance(key DeviceKey) AllocationRule {
+	return AllocationRule{key: key, mode: modeBalance}
+}
+
+// Pack prefers the groups with devices already selected for the container,
+// then the groups which can fit the rest of the request and then the groups
+// with the fewest free devices, i.e. it keeps the devices of a container
+// together and the allocations unfragmented.
+func Pack(key DeviceKey) AllocationRule {
+	return AllocationRule{key: key, mode: modePack}
+}
+
+// Tightest prefers the devices in the smallest group that has the devices
+// already selected for the container and enough available devices for the
+// whole request. The key returns the path of nested groups of a device,
+// separated by "/", e.g. "0/pci0000:00/0000:00:01.0" for a device on NUMA
+// node 0 behind the root port 0000:00:01.0.
+func Tightest(key DeviceKey) AllocationRule {
+	return AllocationRule{key: key, mode: modeTightest}
+}
+
+// AllocationPolicy selects the preferred devices for containers. The devices
+// are selected one by one. Each time, the candidates are ranked by the rules
+// in order and the first candidate in the request wins the ties.
+type AllocationPolicy struct {
+	rules []AllocationRule
+}
+
+// NewAllocationPolicy creates an AllocationPolicy with the rules in order of
+// precedence. Without rules, the devices are selected in the order of the
+// request.
+func NewAllocationPolicy(rules ...AllocationRule) *AllocationPolicy {
+	return &AllocationPolicy{rules: rules}
+}
+
+// groupCounts has the device counts of the groups of a rule.
+type groupCounts struct {
+	total     map[string]int // advertised devices
+	available map[string]int
+	selected  map[string]int
+}
+
+// free returns the number of available devices not selected in a group.
+func (g *groupCounts) free(key string) int {
+	return g.available[key] - g.selected[key]
+}
+
+// selection is the state of a Select call.
+type selection struct {
+	candidates []string
+	selected   []string
+	keys       [][]string    // rule -> candidate -> key
+	nested     [][][]string  // Tightest rule -> candidate -> nested groups
+	counts     []groupCounts // rule -> group counts
+	taken      []bool
+	size       int
+	need       int
+	loadKnown  bool
+}
+
+func (p *AllocationPolicy) newSelection(req *pluginapi.ContainerPreferredAllocationRequest, devices map[string]DeviceInfo) *selection {
+	s := &selection{
+		candidates: req.AvailableDeviceIDs,
+		selected:   make([]string, 0, req.AllocationSize),
+		keys:       make([][]string, len(p.rules)),
+		nested:     make([][][]string, len(p.rules)),
+		counts:     make([]groupCounts, len(p.rules)),
+		taken:      make([]bool, len(req.AvailableDeviceIDs)),
+		size:       int(req.AllocationSize),
+		need:       int(req.AllocationSize),
+		loadKnown:  devices != nil,
+	}
+
+	for r, rule := range p.rules {
+		if rule.key == nil {
+			continue
+		}
+
+		s.keys[r] = make([]string, len(s.candidates))
+		s.counts[r] = groupCounts{
+			total:     make(map[string]int),
+			available: make(map[string]int),
+			selected:  make(map[string]int),
+		}
+
+		if rule.mode == modeTightest {
+			s.nested[r] = make([][]string, len(s.candidates))
+		}
+
+		for id, dev := range devices {
+			s.counts[r].total[rule.key(id, dev)]++
+		}
+
+		for c, id := range s.candidates {
+			s.keys[r][c] = rule.key(id, devices[id])
+
+			if s.nested[r] != nil {
+				s.nested[r][c] = nestedGroups(s.keys[r][c])
+			}
+
+			for _, group := range s.groups(r, c) {
+				s.counts[r].available[group]++
+			}
+		}
+	}
+
+	return s
+}
+
+// nestedGroups returns the groups of a path from the outermost, e.g. "", "0",
+// "0/pci0000:00" for "0/pci0000:00".
+func nestedGroups(path string) []string {
+	groups := []string{""}
+
+	for i, c := range path {
+		if c == '/' {
+			groups = append(groups, path[:i])
+		}
+	}
+
+	if path != "" {
+		groups = append(groups, path)
+	}
+
+	return groups
+}
+
+// groups returns the groups of a candidate by a rule.
+func (s *selection) groups(r, c int) []string {
+	if s.nested[r] != nil {
+		return s.nested[r][c]
+	}
+
+	return s.keys[r][c : c+1]
+}
+
+// tightest returns the number of available devices in the smallest group of
+// a candidate that has the selected devices and fits the request.
+func (s *selection) tightest(r, c int) int {
+	g, groups := &s.counts[r], s.nested[r][c]
+
+	for i := len(groups) - 1; i > 0; i-- {
+		if g.selected[groups[i]] == len(s.selected) && g.available[groups[i]] >= s.size {
+			return g.available[groups[i]]
+		}
+	}
+
+	return g.available[""]
+}
+
+func (s *selection) take(c int) {
+	s.taken[c] = true
+	s.selected = append(s.selected, s.candidates[c])
+	s.need--
+
+	for r := range s.keys {
+		if s.keys[r] == nil {
+			continue
+		}
+
+		for _, group := range s.groups(r, c) {
+			s.counts[r].selected[group]++
+		}
+	}
+}
+
+// Select returns the preferred devices for a container. The devices must
+// include the MustIncludeDeviceIDs of the request. devices are the advertised
+// devices, they are needed by the NUMANodeKey and the load of Balance. The
+// returned slice has less than AllocationSize IDs only when there are not
+// enough available devices.
+func (p *AllocationPolicy) Select(req *pluginapi.ContainerPreferredAllocationRequest, devices map[string]DeviceInfo) []string {
+	s := p.newSelection(req, devices)
+
+	for _, id := range req.MustIncludeDeviceIDs {
+		if c := slices.Index(s.candidates, id); c >= 0 && !s.taken[c] {
+			s.take(c)
+		}
+	}
+
+	for s.need > 0 {
+		best := -1
+
+		for c := range s.candidates {
+			if !s.taken[c] && (best < 0 || p.less(s, c, best)) {
+				best = c
+			}
+		}
+
+		if best < 0 {
+			break
+		}
+
+		s.take(best)
+	}
+
+	klog.V(3).InfoS("Preferred devices", LogKeyDeviceID, s.selected)
+
+	return s.selected
+}
+
+// less reports whether candidate a ranks before candidate b.
+func (p *AllocationPolicy) less(s *selection, a, b int) bool {
+	for r, rule := range p.rules {
+		if rule.mode == modeByID {
+			if s.candidates[a] != s.candidates[b] {
+				return s.candidates[a] < s.candidates[b]
+			}
+
+			continue
+		}
+
+		g, ka, kb := &s.counts[r], s.keys[r][a], s.keys[r][b]
+
+		var sa, sb int
+
+		switch rule.mode {
+		case modeSpread:
+			sa, sb = -g.selected[ka], -g.selected[kb]
+		case modeBalance:
+			// Higher for less loaded groups.
+			sa, sb = g.free(ka), g.free(kb)
+			if s.loadKnown {
+				sa, sb = sa-g.total[ka], sb-g.total[kb]
+			}
+		case modePack:
+			if sa, sb = g.selected[ka], g.selected[kb]; sa != sb {
+				return sa > sb
+			}
+
+			if fa, fb := g.free(ka) >= s.need, g.free(kb) >= s.need; fa != fb {
+				return fa
+			}
+
+			sa, sb = -g.free(ka), -g.free(kb)
+		case modeTightest:
+			sa, sb = -s.tightest(r, a), -s.tightest(r, b)
+		}
+
+		if sa != sb {
+			return sa > sb
+		}
+	}
+
+	return a < b
+}
+
+// NewTopologyPolicy creates an AllocationPolicy which prefers the devices on
+// the same NUMA node and behind the same PCIe switch. See TopologyKey for
+// sysfsPath.
+func NewTopologyPolicy(sysfsPath func(id string) string) *AllocationPolicy {
+	return NewAllocationPolicy(Tightest(TopologyKey(sysfsPath)), ByID)
+}
+
+// SelectPreferred returns the preferred allocation of all container requests
+// selected by the policy. It fails when a request can't be satisfied.
+func (p *AllocationPolicy) SelectPreferred(rqt *pluginapi.PreferredAllocationRequest, devices map[string]DeviceInfo) (*pluginapi.PreferredAllocationResponse, error) {
+	response := &pluginapi.PreferredAllocationResponse{}
+
+	for _, req := range rqt.ContainerRequests {
+		klog.V(3).InfoS("Preferred allocation requested", "available", req.AvailableDeviceIDs,
+			"mustInclude", req.MustIncludeDeviceIDs, "size", req.AllocationSize)
+
+		// This should never happen unless kubelet misbehaves.
+		if int(req.AllocationSize) > len(req.AvailableDeviceIDs) {
+			return nil, errors.Errorf("AllocationSize (%d) is greater than the number of available device IDs (%d)",
+				req.AllocationSize, len(req.AvailableDeviceIDs))
+		}
+
+		response.ContainerResponses = append(response.ContainerResponses, &pluginapi.ContainerPreferredAllocationResponse{
+			DeviceIDs: p.Select(req, devices),
+		})
+	}
+
+	return response, nil
+}
@@ -0,0 +1,303 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+func numaDevice(node int64) DeviceInfo {
+	return NewDeviceInfoWithTopologyHints(pluginapi.Healthy, nil, nil, nil, nil,
+		&pluginapi.TopologyInfo{Nodes: []*pluginapi.NUMANode{{ID: node}}}, nil)
+}
+
+func TestAllocationPolicySelect(t *testing.T) {
+	cards := []string{"card1-0", "card0-1", "card1-1", "card0-0", "card2-0", "card1-2"}
+	parent := ParentKey("-")
+	// NUMA node 0 has switches sw0 and sw1, node 1 has sw2.
+	paths := map[string]string{
+		"a1": "0/sw0/a1", "a2": "0/sw0/a2",
+		"b1": "0/sw1/b1", "b2": "0/sw1/b2", "b3": "0/sw1/b3",
+		"c1": "1/sw2/c1",
+	}
+	topology := func(id string, _ DeviceInfo) string { return paths[id] }
+	nodes := []string{"c1", "b1", "a1", "b2", "a2", "b3"}
+
+	tcases := []struct {
+		devices     map[string]DeviceInfo
+		name        string
+		available   []string
+		mustInclude []string
+		rules       []AllocationRule
+		expectedIDs []string
+		size        int32
+	}{
+		{
+			name:        "no rules selects in request order",
+			available:   cards,
+			size:        3,
+			expectedIDs: []string{"card1-0", "card0-1", "card1-1"},
+		},
+		{
+			name:        "by ID",
+			available:   cards,
+			rules:       []AllocationRule{ByID},
+			size:        3,
+			expectedIDs: []string{"card0-0", "card0-1", "card1-0"},
+		},
+		{
+			name:        "spread",
+			available:   cards,
+			rules:       []AllocationRule{Spread(parent)},
+			size:        4,
+			expectedIDs: []string{"card1-0", "card0-1", "card2-0", "card1-1"},
+		},
+		{
+			name:        "balance by free devices",
+			available:   cards,
+			rules:       []AllocationRule{Balance(parent), ByID},
+			size:        3,
+			expectedIDs: []string{"card1-0", "card0-0", "card1-1"},
+		},
+		{
+			name: "balance by load",
+			devices: map[string]DeviceInfo{
+				"card0-0": {}, "card0-1": {}, "card0-2": {}, "card0-3": {},
+				"card1-0": {}, "card1-1": {},
+			},
+			// card0-2 and card0-3 are allocated.
+			available:   []string{"card0-0", "card0-1", "card1-0", "card1-1"},
+			rules:       []AllocationRule{Balance(parent), ByID},
+			size:        1,
+			expectedIDs: []string{"card1-0"},
+		},
+		{
+			name:        "pack",
+			available:   cards,
+			rules:       []AllocationRule{Pack(parent), ByID},
+			size:        3,
+			expectedIDs: []string{"card1-0", "card1-1", "card1-2"},
+		},
+		{
+			name:        "pack prefers the fullest group that fits",
+			available:   cards,
+			rules:       []AllocationRule{Pack(parent), ByID},
+			size:        2,
+			expectedIDs: []string{"card0-0", "card0-1"},
+		},
+		{
+			name:        "pack prefers the fullest group",
+			available:   cards,
+			rules:       []AllocationRule{Pack(parent), ByID},
+			size:        1,
+			expectedIDs: []string{"card2-0"},
+		},
+		{
+			name:        "pack uses up the fullest group when none fits",
+			available:   cards,
+			rules:       []AllocationRule{Pack(parent), ByID},
+			size:        4,
+			expectedIDs: []string{"card2-0", "card1-0", "card1-1", "card1-2"},
+		},
+		{
+			name:        "must include",
+			available:   cards,
+			mustInclude: []string{"card0-1"},
+			rules:       []AllocationRule{Pack(parent), ByID},
+			size:        2,
+			expectedIDs: []string{"card0-1", "card0-0"},
+		},
+		{
+			name: "NUMA aligned",
+			devices: map[string]DeviceInfo{
+				"0000:03:00.1": numaDevice(0), "0000:04:00.1": numaDevice(1),
+				"0000:05:00.1": numaDevice(0), "0000:06:00.1": numaDevice(1),
+				"0000:04:00.2": numaDevice(1),
+			},
+			available:   []string{"0000:03:00.1", "0000:04:00.1", "0000:05:00.1", "0000:06:00.1", "0000:04:00.2"},
+			rules:       []AllocationRule{Pack(NUMANodeKey), Spread(PCIBusKey), ByID},
+			size:        3,
+			expectedIDs: []string{"0000:04:00.1", "0000:06:00.1", "0000:04:00.2"},
+		},
+		{
+			name:        "anti-affinity across PFs",
+			available:   []string{"0000:03:00.1", "0000:03:00.2", "0000:04:00.1", "0000:04:00.2"},
+			rules:       []AllocationRule{Spread(PCIBusKey)},
+			size:        3,
+			expectedIDs: []string{"0000:03:00.1", "0000:04:00.1", "0000:03:00.2"},
+		},
+		{
+			name:        "tightest switch",
+			available:   nodes,
+			rules:       []AllocationRule{Tightest(topology), ByID},
+			size:        2,
+			expectedIDs: []string{"a1", "a2"},
+		},
+		{
+			name:        "tightest larger switch",
+			available:   nodes,
+			rules:       []AllocationRule{Tightest(topology), ByID},
+			size:        3,
+			expectedIDs: []string{"b1", "b2", "b3"},
+		},
+		{
+			name:        "tightest NUMA node",
+			available:   nodes,
+			rules:       []AllocationRule{Tightest(topology), ByID},
+			size:        4,
+			expectedIDs: []string{"a1", "a2", "b1", "b2"},
+		},
+		{
+			name:        "tightest with must include",
+			available:   nodes,
+			mustInclude: []string{"b3"},
+			rules:       []AllocationRule{Tightest(topology), ByID},
+			size:        2,
+			expectedIDs: []string{"b3", "b1"},
+		},
+		{
+			name:        "tightest across NUMA nodes",
+			available:   nodes,
+			mustInclude: []string{"c1"},
+			rules:       []AllocationRule{Tightest(topology), ByID},
+			size:        2,
+			expectedIDs: []string{"c1", "a1"},
+		},
+		{
+			name:        "not enough devices",
+			available:   []string{"card0-0", "card1-0"},
+			rules:       []AllocationRule{Spread(parent)},
+			size:        3,
+			expectedIDs: []string{"card0-0", "card1-0"},
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := &pluginapi.ContainerPreferredAllocationRequest{
+				AvailableDeviceIDs:   tc.available,
+				MustIncludeDeviceIDs: tc.mustInclude,
+				AllocationSize:       tc.size,
+			}
+
+			ids := NewAllocationPolicy(tc.rules...).Select(req, tc.devices)
+			if !reflect.DeepEqual(ids, tc.expectedIDs) {
+				t.Errorf("expected %v, got %v", tc.expectedIDs, ids)
+			}
+		})
+	}
+}
+
+func TestPCIRootComplexKey(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.Symlink("../../../devices/pci0000:80/0000:80:01.0/0000:81:00.1", filepath.Join(dir, "0000:81:00.1")); err != nil {
+		t.Fatal(err)
+	}
+
+	key := PCIRootComplexKey(dir)
+
+	if root := key("0000:81:00.1", DeviceInfo{}); root != "pci0000:80" {
+		t.Errorf("expected pci0000:80, got %q", root)
+	}
+
+	if root := key("0000:82:00.1", DeviceInfo{}); root != "" {
+		t.Errorf("expected no root complex, got %q", root)
+	}
+}
+
+func TestTopologyKey(t *testing.T) {
+	root := t.TempDir()
+	pci := "pci0000:00/0000:00:01.0/0000:01:00.0/0000:02:01.0/0000:03:00.0"
+	devices := filepath.Join(root, "devices", pci)
+
+	if err := os.MkdirAll(filepath.Join(devices, "drm", "card0"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(devices, "numa_node"), []byte("1\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(root, "class", "drm"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Symlink(filepath.Join(devices, "drm", "card0"), filepath.Join(root, "class", "drm", "card0")); err != nil {
+		t.Fatal(err)
+	}
+
+	key := TopologyKey(func(id string) string {
+		return filepath.Join(root, "class", "drm", strings.Split(id, "-")[0])
+	})
+
+	tcases := []struct {
+		name     string
+		id       string
+		expected string
+		dev      DeviceInfo
+	}{
+		{name: "NUMA node from sysfs", id: "card0-1", expected: "1/" + pci},
+		{name: "NUMA node from topology", id: "card0-1", dev: numaDevice(0), expected: "0/" + pci},
+		{name: "NUMA node only", id: "card1-0", dev: numaDevice(0), expected: "0"},
+		{name: "unknown device", id: "card1-0"},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if path := key(tc.id, tc.dev); path != tc.expected {
+				t.Errorf("expected %q, got %q", tc.expected, path)
+			}
+		})
+	}
+}
+
+func TestGetPreferredAllocationWithPolicy(t *testing.T) {
+	srv := newServer(namespace, "test", nil, nil, nil, nil).(*server)
+
+	if srv.getDevicePluginOptions().GetPreferredAllocationAvailable {
+		t.Error("preferred allocation available without a policy")
+	}
+
+	srv.allocationPolicy = NewAllocationPolicy(ByID)
+
+	if !srv.getDevicePluginOptions().GetPreferredAllocationAvailable {
+		t.Error("preferred allocation not available with a policy")
+	}
+
+	rqt := &pluginapi.PreferredAllocationRequest{
+		ContainerRequests: []*pluginapi.ContainerPreferredAllocationRequest{
+			{AvailableDeviceIDs: []string{"dev2", "dev1"}, AllocationSize: 1},
+		},
+	}
+
+	resp, err := srv.GetPreferredAllocation(context.Background(), rqt)
+	if err != nil || !reflect.DeepEqual(resp.ContainerResponses[0].DeviceIDs, []string{"dev1"}) {
+		t.Errorf("unexpected response %v: %+v", resp, err)
+	}
+
+	rqt.ContainerRequests[0].AllocationSize = 3
+
+	if _, err := srv.GetPreferredAllocation(context.Background(), rqt); err == nil {
+		t.Error("expected an error for too large allocation size")
+	}
+}
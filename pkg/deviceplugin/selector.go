@@ -0,0 +1,342 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/intel/intel-device-plugins-for-kubernetes/pkg/topology"
+	"github.com/pkg/errors"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+const attributeMatchPrefix = "attr:"
+
+// DeviceMatch matches devices by their properties. A device matches when all
+// the set properties match. The PCI properties are read from the PCI device
+// of the sysfs device, see DeviceInfo.SetSysfsDevice().
+type DeviceMatch struct {
+	// Attributes are sysfs attributes of the device, relative to its sysfs
+	// directory, and their values, e.g. "sriov_numvfs": "0".
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// NUMANode is the NUMA node of the PCI device.
+	NUMANode *int `json:"numaNode,omitempty"`
+	// PCIAddress is a glob pattern of the PCI address, e.g. "0000:00:02.0".
+	PCIAddress string `json:"pciAddress,omitempty"`
+	// DeviceID is the PCI device ID, e.g. "0x56a0".
+	DeviceID string `json:"deviceID,omitempty"`
+	// Driver is the driver the PCI device is bound to, e.g. "i915".
+	Driver string `json:"driver,omitempty"`
+	// DeviceNode is a glob pattern of a device node, e.g. "/dev/dri/card1".
+	DeviceNode string `json:"deviceNode,omitempty"`
+}
+
+// DeviceSelector selects the devices advertised to kubelet: a device is
+// advertised when it matches any of the Include matches, or Include is
+// empty, and none of the Exclude matches.
+type DeviceSelector struct {
+	Include []DeviceMatch `json:"include,omitempty"`
+	Exclude []DeviceMatch `json:"exclude,omitempty"`
+}
+
+// ParseDeviceMatches parses device matches separated by ";", each with
+// properties separated by ",", e.g. "driver=i915,numa=1;pci=0000:03:00.0".
+// The properties are pci, deviceid, driver, numa, node and attr:<name>.
+func ParseDeviceMatches(s string) ([]DeviceMatch, error) {
+	var matches []DeviceMatch
+
+	for _, str := range strings.Split(s, ";") {
+		if strings.TrimSpace(str) == "" {
+			continue
+		}
+
+		match := DeviceMatch{}
+
+		for _, prop := range strings.Split(str, ",") {
+			key, value, ok := strings.Cut(strings.TrimSpace(prop), "=")
+			if !ok || value == "" {
+				return nil, errors.Errorf("invalid device match property %q, expected <name>=<value>", prop)
+			}
+
+			if err := match.set(key, value); err != nil {
+				return nil, err
+			}
+		}
+
+		matches = append(matches, match)
+	}
+
+	return matches, nil
+}
+
+func (m *DeviceMatch) set(key, value string) error {
+	switch key {
+	case "pci":
+		m.PCIAddress = value
+	case "deviceid":
+		m.DeviceID = value
+	case "driver":
+		m.Driver = value
+	case "node":
+		m.DeviceNode = value
+	case "numa":
+		node, err := strconv.Atoi(value)
+		if err != nil {
+			return errors.Wrapf(err, "invalid NUMA node %q", value)
+		}
+
+		m.NUMANode = &node
+	default:
+		name, ok := strings.CutPrefix(key, attributeMatchPrefix)
+		if !ok || name == "" {
+			return errors.Errorf("unknown device match property %q", key)
+		}
+
+		if m.Attributes == nil {
+			m.Attributes = make(map[string]string)
+		}
+
+		m.Attributes[name] = value
+	}
+
+	return nil
+}
+
+func (m *DeviceMatch) validate() error {
+	if m.PCIAddress == "" && m.DeviceID == "" && m.Driver == "" && m.DeviceNode == "" &&
+		m.NUMANode == nil && len(m.Attributes) == 0 {
+		return errors.New("device match without properties")
+	}
+
+	for _, pattern := range []string{m.PCIAddress, m.DeviceNode} {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return errors.Wrapf(err, "invalid pattern %q", pattern)
+		}
+	}
+
+	return nil
+}
+
+// Validate checks that the matches of the selector have properties and valid
+// patterns.
+func (s *DeviceSelector) Validate() error {
+	for _, matches := range [][]DeviceMatch{s.Include, s.Exclude} {
+		for i := range matches {
+			if err := matches[i].validate(); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Filter returns the device tree without the devices the selector doesn't
+// select, and the filtered devices as sorted "<type>/<ID>" strings. The
+// tree is returned as it is when the selector is nil.
+func (s *DeviceSelector) Filter(tree DeviceTree) (DeviceTree, []string) {
+	if s == nil || (len(s.Include) == 0 && len(s.Exclude) == 0) {
+		return tree, nil
+	}
+
+	props := make(map[string]*deviceProperties)
+	selected := NewDeviceTree()
+	filtered := []string{}
+
+	for devType, devices := range tree {
+		// A type stays even if all its devices are filtered out, so that
+		// the selector doesn't remove it.
+		selected[devType] = make(map[string]DeviceInfo, len(devices))
+
+		for id, dev := range devices {
+			if s.selects(lookupDeviceProperties(props, dev), dev) {
+				selected[devType][id] = dev
+			} else {
+				filtered = append(filtered, devType+"/"+id)
+			}
+		}
+	}
+
+	sort.Strings(filtered)
+
+	return selected, filtered
+}
+
+func (s *DeviceSelector) selects(p *deviceProperties, dev DeviceInfo) bool {
+	for i := range s.Exclude {
+		if p.matches(&s.Exclude[i], dev) {
+			return false
+		}
+	}
+
+	if len(s.Include) == 0 {
+		return true
+	}
+
+	for i := range s.Include {
+		if p.matches(&s.Include[i], dev) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// deviceProperties are the sysfs properties of a device, read when needed.
+type deviceProperties struct {
+	attributes map[string]string
+	numaNode   *int
+	sysfs      string // the sysfs directory of the device
+	pci        string // the sysfs directory of the PCI device
+	deviceID   string
+	driver     string
+	pciRead    bool
+}
+
+// lookupDeviceProperties returns the properties of a device, shared by the
+// devices with the same sysfs directory.
+func lookupDeviceProperties(props map[string]*deviceProperties, dev DeviceInfo) *deviceProperties {
+	sysfs := dev.sysfsDevice
+
+	for i := 0; sysfs == "" && i < len(dev.nodes); i++ {
+		// FindSysFsDevice() returns the storage device of other files.
+		if fi, err := os.Stat(dev.nodes[i].HostPath); err != nil || fi.Mode()&os.ModeDevice == 0 {
+			continue
+		}
+
+		if path, err := topology.FindSysFsDevice(dev.nodes[i].HostPath); err == nil {
+			sysfs = path
+		}
+	}
+
+	if sysfs == "" {
+		return &deviceProperties{}
+	}
+
+	if p, ok := props[sysfs]; ok {
+		return p
+	}
+
+	p := &deviceProperties{sysfs: sysfs, attributes: make(map[string]string)}
+	props[sysfs] = p
+
+	return p
+}
+
+func readSysfsValue(path string) (string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+
+	return strings.TrimSpace(string(data)), true
+}
+
+// readPCI reads the properties of the PCI device of the sysfs device.
+func (p *deviceProperties) readPCI() {
+	if p.pciRead || p.sysfs == "" {
+		return
+	}
+
+	p.pciRead = true
+
+	path, err := filepath.EvalSymlinks(p.sysfs)
+	if err != nil {
+		return
+	}
+
+	if _, p.pci = pciPath(path); p.pci == "" {
+		return
+	}
+
+	p.deviceID, _ = readSysfsValue(filepath.Join(p.pci, "device"))
+
+	if link, err := os.Readlink(filepath.Join(p.pci, "driver")); err == nil {
+		p.driver = filepath.Base(link)
+	}
+
+	if value, ok := readSysfsValue(filepath.Join(p.pci, "numa_node")); ok {
+		if node, err := strconv.Atoi(value); err == nil && node >= 0 {
+			p.numaNode = &node
+		}
+	}
+}
+
+func (p *deviceProperties) attribute(name string) (string, bool) {
+	if value, ok := p.attributes[name]; ok {
+		return value, true
+	}
+
+	if p.sysfs == "" {
+		return "", false
+	}
+
+	value, ok := readSysfsValue(filepath.Join(p.sysfs, name))
+	if ok {
+		p.attributes[name] = value
+	}
+
+	return value, ok
+}
+
+func normalizeDeviceID(id string) string {
+	return strings.TrimPrefix(strings.ToLower(id), "0x")
+}
+
+func (p *deviceProperties) matches(m *DeviceMatch, dev DeviceInfo) bool {
+	if m.DeviceNode != "" && !slices.ContainsFunc(dev.nodes, func(node pluginapi.DeviceSpec) bool {
+		ok, _ := filepath.Match(m.DeviceNode, node.HostPath)
+		return ok
+	}) {
+		return false
+	}
+
+	if m.PCIAddress != "" || m.DeviceID != "" || m.Driver != "" || m.NUMANode != nil {
+		p.readPCI()
+
+		if p.pci == "" {
+			return false
+		}
+
+		if ok, _ := filepath.Match(m.PCIAddress, filepath.Base(p.pci)); m.PCIAddress != "" && !ok {
+			return false
+		}
+
+		if m.DeviceID != "" && normalizeDeviceID(m.DeviceID) != normalizeDeviceID(p.deviceID) {
+			return false
+		}
+
+		if m.Driver != "" && m.Driver != p.driver {
+			return false
+		}
+
+		if m.NUMANode != nil && (p.numaNode == nil || *m.NUMANode != *p.numaNode) {
+			return false
+		}
+	}
+
+	for name, expected := range m.Attributes {
+		if value, ok := p.attribute(name); !ok || value != expected {
+			return false
+		}
+	}
+
+	return true
+}
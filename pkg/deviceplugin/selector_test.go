@@ -0,0 +1,254 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func TestParseDeviceMatches(t *testing.T) {
+	tcases := []struct {
+		name        string
+		input       string
+		expected    []DeviceMatch
+		expectedErr bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:  "all properties",
+			input: "pci=0000:00:02.0,deviceid=0x56a0,driver=i915,numa=1,node=/dev/dri/card*,attr:device/sriov_numvfs=0",
+			expected: []DeviceMatch{{
+				PCIAddress: "0000:00:02.0",
+				DeviceID:   "0x56a0",
+				Driver:     "i915",
+				NUMANode:   intPtr(1),
+				DeviceNode: "/dev/dri/card*",
+				Attributes: map[string]string{"device/sriov_numvfs": "0"},
+			}},
+		},
+		{
+			name:     "several matches",
+			input:    "driver=i915; driver=xe;",
+			expected: []DeviceMatch{{Driver: "i915"}, {Driver: "xe"}},
+		},
+		{
+			name:        "unknown property",
+			input:       "bdf=0000:00:02.0",
+			expectedErr: true,
+		},
+		{
+			name:        "missing value",
+			input:       "driver",
+			expectedErr: true,
+		},
+		{
+			name:        "invalid NUMA node",
+			input:       "numa=first",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			matches, err := ParseDeviceMatches(tc.input)
+			if tc.expectedErr != (err != nil) {
+				t.Fatalf("unexpected error state: %+v", err)
+			}
+
+			if !reflect.DeepEqual(matches, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, matches)
+			}
+		})
+	}
+}
+
+func TestDeviceSelectorValidate(t *testing.T) {
+	for _, selector := range []DeviceSelector{
+		{Include: []DeviceMatch{{}}},
+		{Exclude: []DeviceMatch{{PCIAddress: "0000:00:0[2.0"}}},
+	} {
+		if err := selector.Validate(); err == nil {
+			t.Errorf("expected an error with %+v", selector)
+		}
+	}
+
+	selector := DeviceSelector{Exclude: []DeviceMatch{{PCIAddress: "0000:00:*"}}}
+	if err := selector.Validate(); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+}
+
+// createSelectorTestSysfs creates PCI devices with a card each, and returns
+// the sysfs directories of the cards.
+func createSelectorTestSysfs(t *testing.T) map[string]string {
+	t.Helper()
+
+	root := t.TempDir()
+	cards := make(map[string]string)
+
+	for _, dev := range []struct {
+		card, bdf, deviceID, driver, numa, numVFs string
+	}{
+		{"card0", "0000:00:02.0", "0x56a0", "i915", "0", "0"},
+		{"card1", "0000:03:00.0", "0x0bda", "xe", "1", "1"},
+	} {
+		pci := filepath.Join(root, "devices", "pci0000:00", dev.bdf)
+		card := filepath.Join(pci, "drm", dev.card)
+		driver := filepath.Join(root, "bus", "pci", "drivers", dev.driver)
+
+		for _, dir := range []string{card, driver} {
+			if err := os.MkdirAll(dir, 0750); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for name, value := range map[string]string{"device": dev.deviceID, "numa_node": dev.numa, "sriov_numvfs": dev.numVFs} {
+			if err := os.WriteFile(filepath.Join(pci, name), []byte(value+"\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for link, target := range map[string]string{
+			filepath.Join(pci, "driver"):  driver,
+			filepath.Join(card, "device"): pci,
+		} {
+			if err := os.Symlink(target, link); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		cards[dev.card] = card
+	}
+
+	return cards
+}
+
+func TestDeviceSelectorFilter(t *testing.T) {
+	cards := createSelectorTestSysfs(t)
+	tree := NewDeviceTree()
+
+	for _, card := range []string{"card0", "card1"} {
+		info := NewDeviceInfoWithTopologyHints(pluginapi.Healthy, []pluginapi.DeviceSpec{{HostPath: "/dev/dri/" + card}}, nil, nil, nil, nil, nil)
+		info.SetSysfsDevice(cards[card])
+
+		for _, id := range []string{card + "-0", card + "-1"} {
+			tree.AddDevice("gpu", id, info)
+		}
+	}
+
+	tree.AddDevice("monitoring", "all", NewDeviceInfoWithTopologyHints(pluginapi.Healthy, nil, nil, nil, nil, nil, nil))
+
+	tcases := []struct {
+		selector *DeviceSelector
+		name     string
+		expected []string
+	}{
+		{
+			name: "no selector",
+		},
+		{
+			name:     "exclude PCI address",
+			selector: &DeviceSelector{Exclude: []DeviceMatch{{PCIAddress: "0000:00:02.*"}}},
+			expected: []string{"gpu/card0-0", "gpu/card0-1"},
+		},
+		{
+			name:     "include driver",
+			selector: &DeviceSelector{Include: []DeviceMatch{{Driver: "xe"}}},
+			expected: []string{"gpu/card0-0", "gpu/card0-1", "monitoring/all"},
+		},
+		{
+			name:     "include device ID and NUMA node",
+			selector: &DeviceSelector{Include: []DeviceMatch{{DeviceID: "56A0", NUMANode: intPtr(0)}}},
+			expected: []string{"gpu/card1-0", "gpu/card1-1", "monitoring/all"},
+		},
+		{
+			name:     "exclude device node",
+			selector: &DeviceSelector{Exclude: []DeviceMatch{{DeviceNode: "/dev/dri/card1"}}},
+			expected: []string{"gpu/card1-0", "gpu/card1-1"},
+		},
+		{
+			name: "include attribute, exclude NUMA node",
+			selector: &DeviceSelector{
+				Include: []DeviceMatch{{Attributes: map[string]string{"device/sriov_numvfs": "1"}}},
+				Exclude: []DeviceMatch{{NUMANode: intPtr(1)}},
+			},
+			expected: []string{"gpu/card0-0", "gpu/card0-1", "gpu/card1-0", "gpu/card1-1", "monitoring/all"},
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			selected, filtered := tc.selector.Filter(tree)
+			if !reflect.DeepEqual(filtered, tc.expected) {
+				t.Errorf("expected filtered devices %v, got %v", tc.expected, filtered)
+			}
+
+			count := 0
+			for devType, devices := range selected {
+				if _, ok := tree[devType]; !ok {
+					t.Errorf("unexpected device type %s", devType)
+				}
+
+				count += len(devices)
+			}
+
+			if count+len(filtered) != 5 || len(selected) != len(tree) {
+				t.Errorf("expected all device types and the unfiltered devices, got %v", selected)
+			}
+		})
+	}
+}
+
+func TestNotifierSelector(t *testing.T) {
+	ch := make(chan updateInfo, 1)
+	n := newNotifier(nil, ch)
+
+	tree := NewDeviceTree()
+	tree.AddDevice("dev", "dev0", NewDeviceInfoWithTopologyHints(pluginapi.Healthy, []pluginapi.DeviceSpec{{HostPath: "/dev/dev0"}}, nil, nil, nil, nil, nil))
+	tree.AddDevice("dev", "dev1", NewDeviceInfoWithTopologyHints(pluginapi.Healthy, []pluginapi.DeviceSpec{{HostPath: "/dev/dev1"}}, nil, nil, nil, nil, nil))
+
+	n.setSelector(&DeviceSelector{Exclude: []DeviceMatch{{DeviceNode: "/dev/dev1"}}})
+
+	if len(ch) != 0 {
+		t.Fatal("unexpected update before a scan")
+	}
+
+	n.Notify(tree)
+
+	if update := <-ch; len(update.Added["dev"]) != 1 {
+		t.Errorf("expected one selected device, got %v", update.Added)
+	}
+
+	n.setSelector(nil)
+
+	if update := <-ch; len(update.Updated["dev"]) != 2 || len(update.Removed) != 0 {
+		t.Errorf("expected both devices when the selector is removed, got %+v", update)
+	}
+
+	if len(tree["dev"]) != 2 {
+		t.Error("the scanned device tree should not be changed")
+	}
+}
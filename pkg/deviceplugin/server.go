@@ -16,13 +16,13 @@ package deviceplugin
 
 import (
 	"context"
-	"fmt"
+	"maps"
 	"net"
 	"os"
 	"path"
 	"path/filepath"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
@@ -30,8 +30,6 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
-	"tags.cncf.io/container-device-interface/pkg/cdi"
-	cdispec "tags.cncf.io/container-device-interface/specs-go"
 
 	"k8s.io/klog/v2"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
@@ -45,6 +43,14 @@ const (
 	serving
 	terminating
 
+	// serverStopTimeout is the time Stop() waits for the device updates to be
+	// delivered to kubelet.
+	serverStopTimeout = 5 * time.Second
+
+	// kubeletSocketName is the name of the kubelet registration socket in the
+	// device plugin directory.
+	kubeletSocketName = "kubelet.sock"
+
 	CDIVersion = "0.5.0" // Kubernetes 1.27 / CRI-O 1.27 / Containerd 1.7 use this version.
 	CDIDir     = "/var/run/cdi"
 	CDIVendor  = "intel.cdi.k8s.io"
@@ -59,44 +65,93 @@ type devicePluginServer interface {
 	Update(devices map[string]DeviceInfo)
 }
 
+// deviceSnapshot is an immutable set of the advertised devices. The RPCs
+// handle a request against the snapshot current at its start, and
+// ListAndWatch swaps in a new one on every update.
+type deviceSnapshot struct {
+	devices map[string]DeviceInfo
+}
+
 // server implements devicePluginServer and pluginapi.PluginInterfaceServer interfaces.
 type server struct {
-	grpcServer             *grpc.Server
+	grpcServer             *grpc.Server // guarded by stateMutex
 	updatesCh              chan map[string]DeviceInfo
-	devices                map[string]DeviceInfo
+	snapshot               atomic.Pointer[deviceSnapshot]
+	allocateSlots          chan struct{} // limits the concurrent allocate hooks, nil when unlimited
 	allocate               allocateFunc
 	postAllocate           postAllocateFunc
 	preStartContainer      preStartContainerFunc
 	getPreferredAllocation getPreferredAllocationFunc
+	allocationPolicy       *AllocationPolicy
+	journal                *allocationJournal
+	aliases                *aliasPools
+	introspection          *introspector
+	cdiSpecs               *cdiSpecManager
 	devType                string
-	cdiDir                 string
+	resourceName           string
+	devicePluginPath       string
+	socket                 string // guarded by stateMutex
 	state                  serverState
 	stateMutex             sync.Mutex
+	// updatesMutex guards the sends to updatesCh against closing it.
+	updatesMutex  sync.Mutex
+	updatesClosed bool
 }
 
 // newServer creates a new server satisfying the devicePluginServer interface.
-func newServer(devType string,
+// The resource name of the server is fixed here, as the RPCs and the
+// registration read it concurrently.
+func newServer(namespace, devType string,
 	postAllocate postAllocateFunc,
 	preStartContainer preStartContainerFunc,
 	getPreferredAllocation getPreferredAllocationFunc,
 	allocate allocateFunc) devicePluginServer {
 	return &server{
 		devType:                devType,
-		updatesCh:              make(chan map[string]DeviceInfo, 1), // TODO: is 1 needed?
-		devices:                make(map[string]DeviceInfo),
+		resourceName:           namespace + "/" + devType,
+		updatesCh:              make(chan map[string]DeviceInfo, 1), // holds the latest update, see replaceUpdate()
 		allocate:               allocate,
 		postAllocate:           postAllocate,
 		preStartContainer:      preStartContainer,
 		getPreferredAllocation: getPreferredAllocation,
+		devicePluginPath:       pluginapi.DevicePluginPath,
 		state:                  uninitialized,
-		cdiDir:                 CDIDir,
+	}
+}
+
+// devices returns the devices of the current snapshot, none before the first
+// update. The returned map must not be modified.
+func (srv *server) devices() map[string]DeviceInfo {
+	if snapshot := srv.snapshot.Load(); snapshot != nil {
+		return snapshot.devices
+	}
+
+	return nil
+}
+
+// setDevices swaps in a snapshot of the devices. The devices are copied, so
+// the caller may reuse the map.
+func (srv *server) setDevices(devices map[string]DeviceInfo) {
+	snapshot := &deviceSnapshot{devices: make(map[string]DeviceInfo, len(devices))}
+	maps.Copy(snapshot.devices, devices)
+
+	srv.snapshot.Store(snapshot)
+}
+
+// setAllocateLimit limits the number of concurrent Allocate and PostAllocate
+// hook calls, see AllocateLimiter. Zero means no limit.
+func (srv *server) setAllocateLimit(limit int) {
+	srv.allocateSlots = nil
+
+	if limit > 0 {
+		srv.allocateSlots = make(chan struct{}, limit)
 	}
 }
 
 func (srv *server) getDevicePluginOptions() *pluginapi.DevicePluginOptions {
 	return &pluginapi.DevicePluginOptions{
 		PreStartRequired:                srv.preStartContainer != nil,
-		GetPreferredAllocationAvailable: srv.getPreferredAllocation != nil,
+		GetPreferredAllocationAvailable: srv.getPreferredAllocation != nil || srv.allocationPolicy != nil,
 	}
 }
 
@@ -106,7 +161,7 @@ func (srv *server) GetDevicePluginOptions(ctx context.Context, empty *pluginapi.
 
 func (srv *server) sendDevices(stream pluginapi.DevicePlugin_ListAndWatchServer) error {
 	resp := new(pluginapi.ListAndWatchResponse)
-	for id, device := range srv.devices {
+	for id, device := range srv.devices() {
 		resp.Devices = append(resp.Devices, &pluginapi.Device{
 			ID:       id,
 			Health:   device.state,
@@ -114,10 +169,12 @@ func (srv *server) sendDevices(stream pluginapi.DevicePlugin_ListAndWatchServer)
 		})
 	}
 
-	klog.V(4).Info("Sending to kubelet", resp.Devices)
+	klog.V(4).InfoS("Sending devices to kubelet", LogKeyResource, srv.resourceName, "devices", resp.Devices)
 
 	if err := stream.Send(resp); err != nil {
-		_ = srv.Stop()
+		// Stop() waits for this stream to end, so don't block it.
+		go func() { _ = srv.Stop() }()
+
 		return errors.Wrapf(err, "Cannot update device list")
 	}
 
@@ -125,13 +182,15 @@ func (srv *server) sendDevices(stream pluginapi.DevicePlugin_ListAndWatchServer)
 }
 
 func (srv *server) ListAndWatch(empty *pluginapi.Empty, stream pluginapi.DevicePlugin_ListAndWatchServer) error {
-	klog.V(4).Info("Started ListAndWatch for", srv.devType)
+	klog.V(4).InfoS("Started ListAndWatch", LogKeyResource, srv.resourceName)
 
 	if err := srv.sendDevices(stream); err != nil {
 		return err
 	}
 
-	for srv.devices = range srv.updatesCh {
+	for devices := range srv.updatesCh {
+		srv.setDevices(devices)
+
 		if err := srv.sendDevices(stream); err != nil {
 			return err
 		}
@@ -141,6 +200,50 @@ func (srv *server) ListAndWatch(empty *pluginapi.Empty, stream pluginapi.DeviceP
 }
 
 func (srv *server) Allocate(ctx context.Context, rqt *pluginapi.AllocateRequest) (*pluginapi.AllocateResponse, error) {
+	start := time.Now()
+
+	response, err := srv.reserveAndAllocate(ctx, rqt)
+	observeRPC(srv.resourceName, rpcAllocate, start, err)
+
+	if err == nil && srv.journal != nil {
+		srv.journal.record(srv.resourceName, rqt, response)
+	}
+
+	if srv.introspection != nil {
+		srv.introspection.recordAllocate(srv.resourceName, rqt, response, err)
+	}
+
+	return response, err
+}
+
+// reserveAndAllocate reserves the pools of the requested devices before
+// allocating them, so that an aliased device is never allocated twice.
+// The request is handled against one device snapshot throughout, and waits
+// for a free slot when the concurrent allocations are limited.
+func (srv *server) reserveAndAllocate(ctx context.Context, rqt *pluginapi.AllocateRequest) (*pluginapi.AllocateResponse, error) {
+	devices := srv.devices()
+
+	if srv.allocateSlots != nil {
+		select {
+		case srv.allocateSlots <- struct{}{}:
+			defer func() { <-srv.allocateSlots }()
+		case <-ctx.Done():
+			return nil, errors.Wrapf(ctx.Err(), "gave up waiting for the previous %s allocations", srv.devType)
+		}
+	}
+
+	if srv.aliases != nil {
+		for _, crqt := range rqt.ContainerRequests {
+			if err := srv.aliases.reserve(srv.devType, crqt.DevicesIDs); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return srv.allocateDevices(rqt, devices)
+}
+
+func (srv *server) allocateDevices(rqt *pluginapi.AllocateRequest, devices map[string]DeviceInfo) (*pluginapi.AllocateResponse, error) {
 	if srv.allocate != nil {
 		response, err := srv.allocate(rqt)
 
@@ -159,7 +262,7 @@ func (srv *server) Allocate(ctx context.Context, rqt *pluginapi.AllocateRequest)
 		cresp.CDIDevices = []*pluginapi.CDIDevice{}
 
 		for _, id := range crqt.DevicesIDs {
-			dev, ok := srv.devices[id]
+			dev, ok := devices[id]
 			if !ok {
 				return nil, errors.Errorf("Invalid allocation request with non-existing device %s", id)
 			}
@@ -184,10 +287,15 @@ func (srv *server) Allocate(ctx context.Context, rqt *pluginapi.AllocateRequest)
 				cresp.Annotations[key] = value
 			}
 
-			if names, err := writeCdiSpecToFilesystem(dev.cdiSpec, srv.cdiDir); err == nil {
+			if srv.cdiSpecs == nil {
+				continue
+			}
+
+			if names, err := srv.cdiSpecs.ensure(dev.cdiSpec); err == nil {
 				cresp.CDIDevices = append(cresp.CDIDevices, names...)
 			} else {
-				klog.Errorf("CDI spec write failed: %+v", err)
+				klog.ErrorS(err, "CDI spec write failed", LogKeyResource, srv.resourceName, LogKeyDeviceID, id)
+				cdiSpecWriteFailuresCounter.WithLabelValues(srv.resourceName).Inc()
 			}
 		}
 
@@ -204,7 +312,9 @@ func (srv *server) Allocate(ctx context.Context, rqt *pluginapi.AllocateRequest)
 	return response, nil
 }
 
-func (srv *server) PreStartContainer(ctx context.Context, rqt *pluginapi.PreStartContainerRequest) (*pluginapi.PreStartContainerResponse, error) {
+func (srv *server) PreStartContainer(ctx context.Context, rqt *pluginapi.PreStartContainerRequest) (resp *pluginapi.PreStartContainerResponse, err error) {
+	defer func(start time.Time) { observeRPC(srv.resourceName, rpcPreStartContainer, start, err) }(time.Now())
+
 	if srv.preStartContainer != nil {
 		return new(pluginapi.PreStartContainerResponse), srv.preStartContainer(rqt)
 	}
@@ -212,63 +322,136 @@ func (srv *server) PreStartContainer(ctx context.Context, rqt *pluginapi.PreStar
 	return nil, errors.New("PreStartContainer() should not be called as this device plugin doesn't implement it")
 }
 
-func (srv *server) GetPreferredAllocation(ctx context.Context, rqt *pluginapi.PreferredAllocationRequest) (*pluginapi.PreferredAllocationResponse, error) {
+func (srv *server) GetPreferredAllocation(ctx context.Context, rqt *pluginapi.PreferredAllocationRequest) (resp *pluginapi.PreferredAllocationResponse, err error) {
+	defer func(start time.Time) { observeRPC(srv.resourceName, rpcGetPreferredAllocation, start, err) }(time.Now())
+
 	if srv.getPreferredAllocation != nil {
 		return srv.getPreferredAllocation(rqt)
 	}
 
+	if srv.allocationPolicy != nil {
+		return srv.allocationPolicy.SelectPreferred(rqt, srv.devices())
+	}
+
 	return nil, errors.New("GetPreferredAllocation should not be called as this device plugin doesn't implement it")
 }
 
 // Serve starts a gRPC server to serve pluginapi.PluginInterfaceServer interface.
 func (srv *server) Serve(namespace string) error {
-	return srv.setupAndServe(namespace, pluginapi.DevicePluginPath, pluginapi.KubeletSocket)
+	return srv.setupAndServe(namespace, srv.devicePluginPath, filepath.Join(srv.devicePluginPath, kubeletSocketName))
 }
 
-// Stop stops serving pluginapi.PluginInterfaceServer interface.
+// Stop stops serving pluginapi.PluginInterfaceServer interface. Kubelet is
+// told that there are no devices before the gRPC server is stopped, and the
+// plugin socket is removed. Once stopped, the server is not served nor
+// registered again, also when Serve() is still setting it up.
 func (srv *server) Stop() error {
-	if srv.grpcServer == nil {
-		return errors.New("Can't stop non-existing gRPC server. Calling Stop() before Serve()?")
+	srv.stateMutex.Lock()
+	stopped := srv.state == terminating
+	srv.state = terminating
+	grpcServer, socket := srv.grpcServer, srv.socket
+	srv.stateMutex.Unlock()
+
+	if stopped {
+		return nil
 	}
 
-	srv.setState(terminating)
-	srv.grpcServer.Stop()
+	// Replace a pending update with an empty device list. ListAndWatch sends
+	// it to kubelet before it returns because of the closed channel.
+	srv.updatesMutex.Lock()
+	srv.replaceUpdate(map[string]DeviceInfo{})
 	close(srv.updatesCh)
 
+	srv.updatesClosed = true
+	srv.updatesMutex.Unlock()
+
+	if grpcServer == nil {
+		return nil
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		grpcServer.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(serverStopTimeout):
+		klog.InfoS("Timeout waiting for kubelet to receive the device updates", LogKeyResource, srv.resourceName)
+		grpcServer.Stop()
+	}
+
+	// Removing the socket also makes setupAndServe() return.
+	if err := os.Remove(socket); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.WithStack(err)
+	}
+
 	return nil
 }
 
-// Update sends updates from Manager to ListAndWatch's event loop.
+// Update sends updates from Manager to ListAndWatch's event loop. The
+// updates after Stop() are dropped.
 func (srv *server) Update(devices map[string]DeviceInfo) {
+	srv.updatesMutex.Lock()
+	defer srv.updatesMutex.Unlock()
+
+	if !srv.updatesClosed {
+		srv.replaceUpdate(devices)
+	}
+}
+
+// replaceUpdate replaces the update ListAndWatch has not received yet, if
+// any, as every update has all the devices. The caller holds updatesMutex,
+// so the send doesn't block.
+func (srv *server) replaceUpdate(devices map[string]DeviceInfo) {
+	select {
+	case <-srv.updatesCh:
+	default:
+	}
+
 	srv.updatesCh <- devices
 }
 
-func (srv *server) setState(state serverState) {
+func (srv *server) getState() serverState {
 	srv.stateMutex.Lock()
 	defer srv.stateMutex.Unlock()
-	srv.state = state
+
+	return srv.state
 }
 
-func (srv *server) getState() serverState {
+// startServing sets the gRPC server and the socket of the server, unless
+// it's stopped. The fields are set together with the check, so that Stop()
+// either stops them or makes setupAndServe() return before serving.
+func (srv *server) startServing(grpcServer *grpc.Server, socket string) bool {
 	srv.stateMutex.Lock()
 	defer srv.stateMutex.Unlock()
 
-	return srv.state
+	if srv.state == terminating {
+		return false
+	}
+
+	srv.state = serving
+	srv.grpcServer = grpcServer
+	srv.socket = socket
+
+	return true
 }
 
 // setupAndServe binds given gRPC server to device manager, starts it and registers it with kubelet.
+// It returns without serving nor registering once the server is stopped.
 func (srv *server) setupAndServe(namespace string, devicePluginPath string, kubeletSocket string) error {
-	resourceName := namespace + "/" + srv.devType
 	pluginPrefix := namespace + "-" + srv.devType
-	srv.setState(serving)
 
-	for srv.getState() == serving {
+	for srv.getState() != terminating {
 		pluginEndpoint := pluginPrefix + ".sock"
 		pluginSocket := path.Join(devicePluginPath, pluginEndpoint)
 
 		if err := waitForServer(pluginSocket, time.Second); err == nil {
 			return errors.Errorf("Socket %s is already in use", pluginSocket)
 		}
+
 		// We don't care if the plugin's socket file doesn't exist.
 		_ = os.Remove(pluginSocket)
 
@@ -277,30 +460,48 @@ func (srv *server) setupAndServe(namespace string, devicePluginPath string, kube
 			return errors.Wrap(err, "Failed to listen to plugin socket")
 		}
 
-		srv.grpcServer = grpc.NewServer()
-		pluginapi.RegisterDevicePluginServer(srv.grpcServer, srv)
+		grpcServer := grpc.NewServer()
+		pluginapi.RegisterDevicePluginServer(grpcServer, srv)
+
+		if !srv.startServing(grpcServer, pluginSocket) {
+			// Closing the listener removes the socket.
+			lis.Close()
+
+			break
+		}
 
 		// Starts device plugin service.
 		go func() {
-			klog.V(1).Infof("Start server for %s at: %s", srv.devType, pluginSocket)
+			klog.V(1).InfoS("Starting device plugin server", LogKeyResource, srv.resourceName, "socket", pluginSocket)
 
-			if serveErr := srv.grpcServer.Serve(lis); serveErr != nil {
-				klog.Errorf("unable to start gRPC server: %+v", serveErr)
+			if serveErr := grpcServer.Serve(lis); serveErr != nil {
+				klog.ErrorS(serveErr, "Unable to start gRPC server", LogKeyResource, srv.resourceName)
 			}
 		}()
 
 		// Wait for the server to start
 		if err = waitForServer(pluginSocket, 10*time.Second); err != nil {
+			if srv.getState() == terminating {
+				break
+			}
+
 			return err
 		}
 
+		// Don't register a server stopped meanwhile.
+		if srv.getState() == terminating {
+			break
+		}
+
 		// Register with Kubelet.
-		err = srv.registerWithKubelet(kubeletSocket, pluginEndpoint, resourceName)
+		err = srv.registerWithKubelet(kubeletSocket, pluginEndpoint, srv.resourceName)
 		if err != nil {
 			return err
 		}
 
-		klog.V(1).Infof("Device plugin for %s registered", srv.devType)
+		registrationsCounter.WithLabelValues(srv.resourceName).Inc()
+
+		klog.V(1).InfoS("Device plugin registered", LogKeyResource, srv.resourceName)
 
 		// Kubelet removes plugin socket when it (re)starts
 		// plugin must restart in this case
@@ -309,10 +510,11 @@ func (srv *server) setupAndServe(namespace string, devicePluginPath string, kube
 		}
 
 		if srv.getState() == serving {
-			srv.grpcServer.Stop()
-			klog.V(1).Infof("Socket %s removed, restarting", pluginSocket)
+			grpcServer.Stop()
+			serverRestartsCounter.WithLabelValues(srv.resourceName).Inc()
+			klog.V(1).InfoS("Socket removed, restarting", LogKeyResource, srv.resourceName, "socket", pluginSocket)
 		} else {
-			klog.V(1).Infof("Socket %s shut down", pluginSocket)
+			klog.V(1).InfoS("Socket shut down", LogKeyResource, srv.resourceName, "socket", pluginSocket)
 		}
 	}
 
@@ -331,6 +533,11 @@ func watchFile(file string) error {
 		return errors.Wrapf(err, "Failed to add %s to watcher", file)
 	}
 
+	// The file may be removed before the watch starts, e.g. by Stop().
+	if _, err = os.Stat(file); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
 	for {
 		select {
 		case ev := <-watcher.Events:
@@ -400,48 +607,3 @@ func waitForServer(socket string, timeout time.Duration) error {
 		}
 	}
 }
-
-// Writes CDI spec to filesystem if not found from the CDI cache.
-// Returns a list of CDI device names.
-func writeCdiSpecToFilesystem(spec *cdispec.Spec, cdiDir string) ([]*pluginapi.CDIDevice, error) {
-	names := []*pluginapi.CDIDevice{}
-
-	if spec == nil {
-		return names, nil
-	}
-
-	cache, err := cdi.NewCache(cdi.WithAutoRefresh(false), cdi.WithSpecDirs(cdiDir))
-	if err != nil {
-		return nil, err
-	}
-
-	// It's expected to have one device per spec
-	if len(spec.Devices) != 1 {
-		return nil, os.ErrNotExist
-	}
-
-	deviceName := spec.Devices[0].Name
-	fqName := fmt.Sprintf("%s=%s", spec.Kind, deviceName)
-
-	names = append(names, &pluginapi.CDIDevice{Name: fqName})
-
-	// The device is found in the cache.
-	if cache.GetDevice(fqName) != nil {
-		return names, nil
-	}
-
-	// Generate filename with '/' and '=' replaced with '-'.
-	specFileName := fmt.Sprintf("%s-%s.yaml", strings.ReplaceAll(spec.Kind, "/", "-"), deviceName)
-
-	// Write spec to filesystem.
-	if err := cache.WriteSpec(spec, specFileName); err != nil {
-		return nil, err
-	}
-
-	// Fix access issues due to: https://github.com/cncf-tags/container-device-interface/issues/224
-	if err := os.Chmod(filepath.Join(cdiDir, specFileName), 0o644); err != nil {
-		return nil, err
-	}
-
-	return names, nil
-}
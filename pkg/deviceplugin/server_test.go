@@ -23,7 +23,9 @@ import (
 	"path"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -71,18 +73,22 @@ func newKubeletStub(socket string) *kubeletStub {
 
 // newTestServer returns a server with devices for testing purposes.
 func newTestServer() *server {
-	return &server{
-		devType: "testtype",
-		devices: map[string]DeviceInfo{
-			"dev1": {
-				state: pluginapi.Healthy,
-			},
-			"dev2": {
-				state: pluginapi.Healthy,
-			},
-		},
-		updatesCh: make(chan map[string]DeviceInfo, 1),
+	srv := &server{
+		devType:      "testtype",
+		resourceName: namespace + "/testtype",
+		updatesCh:    make(chan map[string]DeviceInfo, 1),
 	}
+
+	srv.setDevices(map[string]DeviceInfo{
+		"dev1": {
+			state: pluginapi.Healthy,
+		},
+		"dev2": {
+			state: pluginapi.Healthy,
+		},
+	})
+
+	return srv
 }
 
 // Minimal implementation of deviceplugin.RegistrationServer interface
@@ -253,9 +259,151 @@ func TestSetupAndServe(t *testing.T) {
 }
 
 func TestStop(t *testing.T) {
+	dir := t.TempDir()
+
+	srv := newTestServer()
+	if err := srv.Stop(); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+
+	// A server stopped before Serve() is neither served nor registered.
+	if err := srv.setupAndServe(namespace, dir, filepath.Join(dir, "kubelet.sock")); err != nil {
+		t.Errorf("unexpected serve error: %+v", err)
+	}
+
+	if srv.grpcServer != nil {
+		t.Error("a stopped server was served")
+	}
+}
+
+func TestStopWithdrawsDevices(t *testing.T) {
+	dir := t.TempDir()
+
+	kubelet := newKubeletStub(filepath.Join(dir, "kubelet.sock"))
+	if err := kubelet.start(); err != nil {
+		t.Fatalf("unable to start kubelet stub: %+v", err)
+	}
+
+	defer kubelet.server.Stop()
+
+	srv := newTestServer()
+
+	serveErr := make(chan error, 1)
+
+	go func() {
+		serveErr <- srv.setupAndServe(namespace, dir, kubelet.socket)
+	}()
+
+	var pEndpoint string
+
+	for pEndpoint == "" {
+		time.Sleep(10 * time.Millisecond)
+
+		kubelet.Lock()
+		pEndpoint = kubelet.pluginEndpoint
+		kubelet.Unlock()
+	}
+
+	pluginSocket := filepath.Join(dir, pEndpoint)
+
+	conn, err := grpc.NewClient(filepath.Join("unix://", pluginSocket),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to get connection: %+v", err)
+	}
+
+	defer conn.Close()
+
+	stream, err := pluginapi.NewDevicePluginClient(conn).ListAndWatch(context.Background(), &pluginapi.Empty{})
+	if err != nil {
+		t.Fatalf("ListAndWatch failed: %+v", err)
+	}
+
+	if resp, err := stream.Recv(); err != nil || len(resp.Devices) != 2 {
+		t.Fatalf("expected 2 devices, got %v (%v)", resp, err)
+	}
+
+	if err := srv.Stop(); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+
+	if resp, err := stream.Recv(); err != nil || len(resp.Devices) != 0 {
+		t.Errorf("expected no devices after Stop(), got %v (%v)", resp, err)
+	}
+
+	if err := <-serveErr; err != nil {
+		t.Errorf("unexpected serve error: %+v", err)
+	}
+
+	if _, err := os.Stat(pluginSocket); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("%s was not removed", pluginSocket)
+	}
+
+	if err := srv.Stop(); err != nil {
+		t.Errorf("Stop() should be idempotent: %+v", err)
+	}
+}
+
+func TestAllocateDuringServe(t *testing.T) {
+	dir := t.TempDir()
+
+	kubelet := newKubeletStub(filepath.Join(dir, "kubelet.sock"))
+	if err := kubelet.start(); err != nil {
+		t.Fatalf("unable to start kubelet stub: %+v", err)
+	}
+
+	defer kubelet.server.Stop()
+
 	srv := newTestServer()
-	if err := srv.Stop(); err == nil {
-		t.Error("Calling Stop() before Serve() is successful")
+	srv.cdiSpecs = newCdiSpecManager(t.TempDir(), namespace)
+
+	done := make(chan struct{})
+	allocated := make(chan struct{})
+	rqt := &pluginapi.AllocateRequest{
+		ContainerRequests: []*pluginapi.ContainerAllocateRequest{{DevicesIDs: []string{"dev1"}}},
+	}
+
+	// The RPCs read the resource name of the server while it registers.
+	go func() {
+		defer close(allocated)
+
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			if _, err := srv.Allocate(context.Background(), rqt); err != nil {
+				t.Errorf("unexpected error: %+v", err)
+				return
+			}
+		}
+	}()
+
+	serveErr := make(chan error, 1)
+
+	go func() {
+		serveErr <- srv.setupAndServe(namespace, dir, kubelet.socket)
+	}()
+
+	for registered := false; !registered; {
+		time.Sleep(10 * time.Millisecond)
+
+		kubelet.Lock()
+		registered = kubelet.pluginEndpoint != ""
+		kubelet.Unlock()
+	}
+
+	close(done)
+	<-allocated
+
+	if err := srv.Stop(); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+
+	if err := <-serveErr; err != nil {
+		t.Errorf("unexpected serve error: %+v", err)
 	}
 }
 
@@ -269,7 +417,7 @@ func TestAllocate(t *testing.T) {
 
 	defer os.RemoveAll(tmpRoot)
 
-	srv.cdiDir = tmpRoot
+	srv.cdiSpecs = newCdiSpecManager(tmpRoot, namespace)
 
 	tcases := []struct {
 		devices           map[string]DeviceInfo
@@ -545,7 +693,7 @@ func TestAllocate(t *testing.T) {
 			rqt.ContainerRequests[0].DevicesIDs = devNames
 		}
 
-		srv.devices = tt.devices
+		srv.setDevices(tt.devices)
 		srv.postAllocate = tt.postAllocate
 		resp, err := srv.Allocate(context.Background(), rqt)
 
@@ -685,6 +833,9 @@ func TestListAndWatch(t *testing.T) {
 		devCh := make(chan map[string]DeviceInfo, len(tt.updates))
 		testServer := newTestServer()
 		testServer.updatesCh = devCh
+		// The test closes the updates as Stop() would, so the Stop() of a
+		// failed Send() must not close them again.
+		testServer.state = terminating
 
 		server := &listAndWatchServerStub{
 			testServer:  testServer,
@@ -710,6 +861,41 @@ func TestListAndWatch(t *testing.T) {
 	}
 }
 
+func TestUpdateDuringStop(t *testing.T) {
+	srv := newTestServer()
+	srv.grpcServer = grpc.NewServer()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			srv.Update(map[string]DeviceInfo{"dev1": {state: pluginapi.Healthy}})
+		}()
+	}
+
+	if err := srv.Stop(); err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+
+	wg.Wait()
+
+	// The updates after Stop() are dropped, and ListAndWatch gets the
+	// empty device list.
+	srv.Update(map[string]DeviceInfo{"dev1": {state: pluginapi.Healthy}})
+
+	if devices, ok := <-srv.updatesCh; !ok || len(devices) != 0 {
+		t.Errorf("expected an empty device list, got %v", devices)
+	}
+
+	if _, ok := <-srv.updatesCh; ok {
+		t.Error("expected the updates to be closed")
+	}
+}
+
 func TestGetDevicePluginOptions(t *testing.T) {
 	srv := newTestServer()
 	if _, err := srv.GetDevicePluginOptions(context.Background(), nil); err != nil {
@@ -782,7 +968,7 @@ func TestGetPreferredAllocation(t *testing.T) {
 }
 
 func TestNewServer(t *testing.T) {
-	_ = newServer("test", nil, nil, nil, nil)
+	_ = newServer(namespace, "test", nil, nil, nil, nil)
 }
 
 func TestUpdate(t *testing.T) {
@@ -790,6 +976,215 @@ func TestUpdate(t *testing.T) {
 	srv.Update(make(map[string]DeviceInfo))
 }
 
+// versionedDevices returns devices with their snapshot version in the envs.
+func versionedDevices(version int) map[string]DeviceInfo {
+	devices := make(map[string]DeviceInfo)
+
+	for _, id := range []string{"dev1", "dev2"} {
+		devices[id] = DeviceInfo{
+			state: pluginapi.Healthy,
+			nodes: []pluginapi.DeviceSpec{{HostPath: "/dev/" + id, ContainerPath: "/dev/" + id, Permissions: "rw"}},
+			envs:  map[string]string{id + "_VERSION": strconv.Itoa(version)},
+		}
+	}
+
+	// Every other snapshot withdraws a device.
+	if version%2 == 1 {
+		delete(devices, "dev2")
+	}
+
+	return devices
+}
+
+func TestConcurrentAllocate(t *testing.T) {
+	const updates = 200
+
+	srv := newServer(namespace, "testtype", nil, nil, nil, nil).(*server)
+	srv.allocationPolicy = NewAllocationPolicy()
+	srv.setDevices(versionedDevices(0))
+
+	stream := &listAndWatchServerStub{
+		testServer: srv,
+		cdata:      make(chan []*pluginapi.Device, updates+1),
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		if err := srv.ListAndWatch(&pluginapi.Empty{}, stream); err != nil {
+			t.Errorf("unexpected error: %+v", err)
+		}
+	}()
+
+	done := make(chan struct{})
+	rqt := &pluginapi.AllocateRequest{
+		ContainerRequests: []*pluginapi.ContainerAllocateRequest{{DevicesIDs: []string{"dev1", "dev2"}}},
+	}
+	preferredRqt := &pluginapi.PreferredAllocationRequest{
+		ContainerRequests: []*pluginapi.ContainerPreferredAllocationRequest{{AvailableDeviceIDs: []string{"dev1", "dev2"}, AllocationSize: 1}},
+	}
+
+	for range 4 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+
+				// dev2 is missing from every other snapshot, but the
+				// allocated devices have to come from the same one.
+				if resp, err := srv.Allocate(context.Background(), rqt); err == nil {
+					envs := resp.ContainerResponses[0].Envs
+					if envs["dev1_VERSION"] != envs["dev2_VERSION"] {
+						t.Errorf("allocated devices from different snapshots: %v", envs)
+					}
+				}
+
+				if _, err := srv.GetPreferredAllocation(context.Background(), preferredRqt); err != nil {
+					t.Errorf("unexpected error: %+v", err)
+				}
+			}
+		}()
+	}
+
+	for version := 1; version <= updates; version++ {
+		srv.Update(versionedDevices(version))
+	}
+
+	close(srv.updatesCh)
+	close(done)
+	wg.Wait()
+
+	if devices := srv.devices(); len(devices) != 2 || devices["dev1"].envs["dev1_VERSION"] != strconv.Itoa(updates) {
+		t.Errorf("expected the last update in the snapshot, got %v", devices)
+	}
+}
+
+func TestSetDevicesCopiesDevices(t *testing.T) {
+	srv := newTestServer()
+	devices := versionedDevices(0)
+
+	srv.setDevices(devices)
+	delete(devices, "dev1")
+
+	if _, ok := srv.devices()["dev1"]; !ok {
+		t.Error("modifying the updated devices changed the snapshot")
+	}
+}
+
+func TestAllocateLimit(t *testing.T) {
+	const calls = 8
+
+	for _, limit := range []int{0, 1, 2} {
+		t.Run(fmt.Sprintf("limit %d", limit), func(t *testing.T) {
+			var inflight, maxInflight atomic.Int32
+
+			// Without a limit, all the calls wait for each other.
+			allStarted := make(chan struct{})
+
+			srv := newTestServer()
+			srv.setAllocateLimit(limit)
+			srv.allocate = func(*pluginapi.AllocateRequest) (*pluginapi.AllocateResponse, error) {
+				n := inflight.Add(1)
+				defer inflight.Add(-1)
+
+				for {
+					prev := maxInflight.Load()
+					if n <= prev || maxInflight.CompareAndSwap(prev, n) {
+						break
+					}
+				}
+
+				if limit == 0 {
+					if n == calls {
+						close(allStarted)
+					}
+
+					<-allStarted
+				} else {
+					time.Sleep(10 * time.Millisecond)
+				}
+
+				return new(pluginapi.AllocateResponse), nil
+			}
+
+			rqt := &pluginapi.AllocateRequest{
+				ContainerRequests: []*pluginapi.ContainerAllocateRequest{{DevicesIDs: []string{"dev1"}}},
+			}
+
+			var wg sync.WaitGroup
+
+			for range calls {
+				wg.Add(1)
+
+				go func() {
+					defer wg.Done()
+
+					if _, err := srv.Allocate(context.Background(), rqt); err != nil {
+						t.Errorf("unexpected error: %+v", err)
+					}
+				}()
+			}
+
+			wg.Wait()
+
+			if limit > 0 && maxInflight.Load() > int32(limit) {
+				t.Errorf("expected at most %d concurrent allocations, got %d", limit, maxInflight.Load())
+			}
+		})
+	}
+}
+
+func TestAllocateLimitCanceled(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	srv := newTestServer()
+	srv.setAllocateLimit(1)
+	srv.allocate = func(*pluginapi.AllocateRequest) (*pluginapi.AllocateResponse, error) {
+		close(started)
+		<-release
+
+		return new(pluginapi.AllocateResponse), nil
+	}
+
+	rqt := &pluginapi.AllocateRequest{
+		ContainerRequests: []*pluginapi.ContainerAllocateRequest{{DevicesIDs: []string{"dev1"}}},
+	}
+
+	errCh := make(chan error)
+
+	go func() {
+		_, err := srv.Allocate(context.Background(), rqt)
+		errCh <- err
+	}()
+
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := srv.Allocate(ctx, rqt); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the waiting allocation to time out, got %+v", err)
+	}
+
+	close(release)
+
+	if err := <-errCh; err != nil {
+		t.Errorf("unexpected error: %+v", err)
+	}
+}
+
 func maybeLogError(f func() error, message string) {
 	if err := f(); err != nil {
 		klog.Errorf(message+":%+v", err)
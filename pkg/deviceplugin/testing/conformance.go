@@ -0,0 +1,313 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testing
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+)
+
+// defaultTimeout is the default time the suite waits for the device plugin.
+const defaultTimeout = 10 * time.Second
+
+// Config describes the device plugin under test.
+type Config struct {
+	// Plugin is the device plugin, usually scanning a fake sysfs tree.
+	Plugin dpapi.Scanner
+	// Namespace is the resource namespace, e.g. "gpu.intel.com".
+	Namespace string
+	// DeviceTypes are the device types the plugin is expected to advertise.
+	DeviceTypes []string
+	// Options are passed to deviceplugin.NewManager() in addition to the
+	// ones pointing Manager to the fake kubelet.
+	Options []dpapi.ManagerOption
+	// Timeout is the time the suite waits for the registrations and the
+	// device lists. The default is 10 seconds.
+	Timeout time.Duration
+}
+
+// Run serves the device plugin with deviceplugin.Manager against a fake
+// kubelet and checks that for every device type:
+//   - the plugin registers with the device plugin API version and options
+//     it serves,
+//   - ListAndWatch() streams unique devices with a valid health,
+//   - every healthy device is allocatable, and unhealthy and unknown ones
+//     are not, which the plugins implementing deviceplugin.Allocator check
+//     themselves,
+//   - GetPreferredAllocation() and PreStartContainer() work when the
+//     plugin advertises them,
+//   - the plugin registers again with the same devices after kubelet
+//     removes its socket,
+//
+// and that on shutdown Manager.Run() returns without an error and removes
+// the plugin sockets.
+func Run(t *testing.T, cfg Config) {
+	t.Helper()
+
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultTimeout
+	}
+
+	kubelet := NewFakeKubelet(t)
+
+	opts := append(slices.Clone(cfg.Options), dpapi.WithDevicePluginPath(kubelet.Dir()), dpapi.WithCDIDir(t.TempDir()))
+	mgr := dpapi.NewManager(cfg.Namespace, cfg.Plugin, opts...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runErr := make(chan error, 1)
+
+	go func() { runErr <- mgr.Run(ctx) }()
+
+	devices := make(map[string][]string)
+
+	for _, devType := range cfg.DeviceTypes {
+		t.Run(devType, func(t *testing.T) {
+			devices[devType] = checkDeviceType(t, kubelet, cfg, devType)
+		})
+	}
+
+	t.Run("kubelet restart", func(t *testing.T) {
+		kubelet.Restart(t)
+
+		for _, devType := range cfg.DeviceTypes {
+			client, _ := connect(t, kubelet, cfg, devType)
+
+			ids, _ := healthyDevices(listDevices(t, client, cfg.Timeout))
+			if !slices.Equal(ids, devices[devType]) {
+				t.Errorf("%s: expected healthy devices %v after restart, got %v", devType, devices[devType], ids)
+			}
+		}
+	})
+
+	t.Run("shutdown", func(t *testing.T) {
+		cancel()
+
+		select {
+		case err := <-runErr:
+			if err != nil {
+				t.Errorf("Run() failed: %+v", err)
+			}
+		case <-time.After(cfg.Timeout):
+			t.Fatalf("Run() did not return in %v", cfg.Timeout)
+		}
+
+		if endpoints := kubelet.Endpoints(t); len(endpoints) > 0 {
+			t.Errorf("plugin sockets were not removed: %v", endpoints)
+		}
+	})
+}
+
+// connect waits for the registration of a device type and connects to it.
+func connect(t *testing.T, kubelet *FakeKubelet, cfg Config, devType string) (pluginapi.DevicePluginClient, *pluginapi.RegisterRequest) {
+	t.Helper()
+
+	r := kubelet.WaitForRegistration(t, cfg.Namespace+"/"+devType, cfg.Timeout)
+
+	if endpoint := cfg.Namespace + "-" + devType + ".sock"; r.Endpoint != endpoint {
+		t.Errorf("expected endpoint %s, got %s", endpoint, r.Endpoint)
+	}
+
+	return kubelet.Connect(t, r.Endpoint), r
+}
+
+// checkDeviceType checks the RPCs of a device type and returns its healthy devices.
+func checkDeviceType(t *testing.T, kubelet *FakeKubelet, cfg Config, devType string) []string {
+	t.Helper()
+
+	client, r := connect(t, kubelet, cfg, devType)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+
+	options, err := client.GetDevicePluginOptions(ctx, &pluginapi.Empty{})
+	if err != nil {
+		t.Fatalf("GetDevicePluginOptions() failed: %+v", err)
+	}
+
+	if options.PreStartRequired != r.Options.GetPreStartRequired() ||
+		options.GetPreferredAllocationAvailable != r.Options.GetGetPreferredAllocationAvailable() {
+		t.Errorf("registered options %+v differ from the served ones %+v", r.Options, options)
+	}
+
+	healthy, unhealthy := healthyDevices(listDevices(t, client, cfg.Timeout))
+	if len(healthy) == 0 {
+		t.Fatal("no healthy devices")
+	}
+
+	for _, id := range healthy {
+		resp, err := client.Allocate(ctx, allocateRequest(id))
+		if err != nil {
+			t.Errorf("Allocate(%s) failed: %+v", id, err)
+			continue
+		}
+
+		checkAllocateResponse(t, id, resp)
+	}
+
+	for _, id := range append(unhealthy, "non-existing-device") {
+		if _, err := client.Allocate(ctx, allocateRequest(id)); err == nil {
+			t.Errorf("Allocate(%s) succeeded with an unhealthy or unknown device", id)
+		}
+	}
+
+	if options.GetPreferredAllocationAvailable {
+		checkPreferredAllocation(ctx, t, client, healthy)
+	}
+
+	if options.PreStartRequired {
+		rqt := &pluginapi.PreStartContainerRequest{DevicesIDs: healthy[:1]}
+		if _, err := client.PreStartContainer(ctx, rqt); err != nil {
+			t.Errorf("PreStartContainer() failed: %+v", err)
+		}
+	}
+
+	return healthy
+}
+
+// listDevices returns the first non-empty device list streamed by
+// ListAndWatch(). The list is empty until Manager passes the devices to
+// the registered plugin.
+func listDevices(t *testing.T, client pluginapi.DevicePluginClient, timeout time.Duration) []*pluginapi.Device {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	stream, err := client.ListAndWatch(ctx, &pluginapi.Empty{})
+	if err != nil {
+		t.Fatalf("ListAndWatch() failed: %+v", err)
+	}
+
+	resp := &pluginapi.ListAndWatchResponse{}
+
+	for len(resp.Devices) == 0 {
+		if resp, err = stream.Recv(); err != nil {
+			t.Fatalf("unable to receive devices: %+v", err)
+		}
+	}
+
+	ids := make(map[string]bool)
+
+	for _, dev := range resp.Devices {
+		if ids[dev.ID] {
+			t.Errorf("device %s is listed twice", dev.ID)
+		}
+
+		ids[dev.ID] = true
+
+		if dev.Health != pluginapi.Healthy && dev.Health != pluginapi.Unhealthy {
+			t.Errorf("device %s has invalid health %q", dev.ID, dev.Health)
+		}
+	}
+
+	return resp.Devices
+}
+
+// healthyDevices splits the devices to sorted healthy and unhealthy device IDs.
+func healthyDevices(devices []*pluginapi.Device) (healthy, unhealthy []string) {
+	for _, dev := range devices {
+		if dev.Health == pluginapi.Healthy {
+			healthy = append(healthy, dev.ID)
+		} else {
+			unhealthy = append(unhealthy, dev.ID)
+		}
+	}
+
+	sort.Strings(healthy)
+	sort.Strings(unhealthy)
+
+	return healthy, unhealthy
+}
+
+func allocateRequest(ids ...string) *pluginapi.AllocateRequest {
+	return &pluginapi.AllocateRequest{
+		ContainerRequests: []*pluginapi.ContainerAllocateRequest{{DevicesIDs: ids}},
+	}
+}
+
+func checkAllocateResponse(t *testing.T, id string, resp *pluginapi.AllocateResponse) {
+	t.Helper()
+
+	if len(resp.ContainerResponses) != 1 {
+		t.Errorf("Allocate(%s) returned %d container responses", id, len(resp.ContainerResponses))
+		return
+	}
+
+	for _, spec := range resp.ContainerResponses[0].Devices {
+		if spec.HostPath == "" || spec.ContainerPath == "" {
+			t.Errorf("Allocate(%s) returned an incomplete device node %+v", id, spec)
+		}
+	}
+
+	for _, mount := range resp.ContainerResponses[0].Mounts {
+		if mount.HostPath == "" || mount.ContainerPath == "" {
+			t.Errorf("Allocate(%s) returned an incomplete mount %+v", id, mount)
+		}
+	}
+}
+
+// checkPreferredAllocation checks that the preferred devices are unique
+// available devices, and include the required ones.
+func checkPreferredAllocation(ctx context.Context, t *testing.T, client pluginapi.DevicePluginClient, available []string) {
+	t.Helper()
+
+	size := min(2, len(available))
+	rqt := &pluginapi.PreferredAllocationRequest{
+		ContainerRequests: []*pluginapi.ContainerPreferredAllocationRequest{{
+			AvailableDeviceIDs:   available,
+			MustIncludeDeviceIDs: available[len(available)-1:],
+			AllocationSize:       int32(size),
+		}},
+	}
+
+	resp, err := client.GetPreferredAllocation(ctx, rqt)
+	if err != nil {
+		t.Errorf("GetPreferredAllocation() failed: %+v", err)
+		return
+	}
+
+	if len(resp.ContainerResponses) != 1 {
+		t.Errorf("GetPreferredAllocation() returned %d container responses", len(resp.ContainerResponses))
+		return
+	}
+
+	ids := resp.ContainerResponses[0].DeviceIDs
+	if len(ids) != size {
+		t.Errorf("GetPreferredAllocation() returned %v, expected %d devices", ids, size)
+	}
+
+	seen := make(map[string]bool)
+
+	for _, id := range ids {
+		if seen[id] || !slices.Contains(available, id) {
+			t.Errorf("GetPreferredAllocation() returned a duplicate or unavailable device %s", id)
+		}
+
+		seen[id] = true
+	}
+
+	if !seen[available[len(available)-1]] {
+		t.Errorf("GetPreferredAllocation() returned %v without the required device %s", ids, available[len(available)-1])
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package testing
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+
+	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+)
+
+// stubPlugin advertises a healthy and an unhealthy device of two types and
+// allocates the healthy ones itself.
+type stubPlugin struct {
+	stop chan struct{}
+}
+
+func (p *stubPlugin) Scan(notifier dpapi.Notifier) error {
+	tree := dpapi.NewDeviceTree()
+
+	for _, devType := range []string{"type1", "type2"} {
+		nodes := []pluginapi.DeviceSpec{{HostPath: "/dev/null", ContainerPath: "/dev/null", Permissions: "rw"}}
+
+		tree.AddDevice(devType, devType+"-0", dpapi.NewDeviceInfoWithTopologyHints(pluginapi.Healthy, nodes, nil, nil, nil, nil, nil))
+		tree.AddDevice(devType, devType+"-1", dpapi.NewDeviceInfoWithTopologyHints(pluginapi.Unhealthy, nodes, nil, nil, nil, nil, nil))
+	}
+
+	notifier.Notify(tree)
+
+	<-p.stop
+
+	return nil
+}
+
+func (p *stubPlugin) StopScan() {
+	close(p.stop)
+}
+
+func (p *stubPlugin) Allocate(rqt *pluginapi.AllocateRequest) (*pluginapi.AllocateResponse, error) {
+	resp := &pluginapi.AllocateResponse{}
+
+	for _, crqt := range rqt.ContainerRequests {
+		for _, id := range crqt.DevicesIDs {
+			if !strings.HasSuffix(id, "-0") {
+				return nil, errors.Errorf("device %s is not allocatable", id)
+			}
+		}
+
+		resp.ContainerResponses = append(resp.ContainerResponses, &pluginapi.ContainerAllocateResponse{})
+	}
+
+	return resp, nil
+}
+
+func (p *stubPlugin) PreStartContainer(*pluginapi.PreStartContainerRequest) error {
+	return nil
+}
+
+func TestConformance(t *testing.T) {
+	Run(t, Config{
+		Plugin:      &stubPlugin{stop: make(chan struct{})},
+		Namespace:   "conformance.intel.com",
+		DeviceTypes: []string{"type1", "type2"},
+		Options:     []dpapi.ManagerOption{dpapi.WithAllocationPolicy(dpapi.NewAllocationPolicy(dpapi.ByID))},
+	})
+}
+
+func TestFakeKubelet(t *testing.T) {
+	kubelet := NewFakeKubelet(t)
+
+	if endpoints := kubelet.Endpoints(t); len(endpoints) != 0 {
+		t.Errorf("unexpected endpoints %v", endpoints)
+	}
+
+	if _, err := kubelet.Register(nil, &pluginapi.RegisterRequest{Version: "v1alpha", Endpoint: "test.sock"}); err == nil {
+		t.Error("expected an error with an unsupported version")
+	}
+
+	if _, err := kubelet.Register(nil, &pluginapi.RegisterRequest{Version: pluginapi.Version, Endpoint: "test.sock"}); err == nil {
+		t.Error("expected an error with a missing endpoint")
+	}
+
+	registered := make(chan *pluginapi.RegisterRequest)
+
+	go func() { registered <- kubelet.WaitForRegistration(t, "test.intel.com/test", 10*time.Second) }()
+
+	if _, err := kubelet.Register(nil, &pluginapi.RegisterRequest{Version: pluginapi.Version, Endpoint: kubeletSocketName, ResourceName: "test.intel.com/test"}); err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	if r := <-registered; r.Endpoint != kubeletSocketName {
+		t.Errorf("unexpected registration %+v", r)
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package testing provides a fake kubelet and a conformance suite for
+// testing device plugins over the device plugin gRPC API.
+package testing
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// kubeletSocketName is the name of the kubelet registration socket.
+const kubeletSocketName = "kubelet.sock"
+
+// FakeKubelet serves the kubelet Registration service in a temporary device
+// plugin directory, see deviceplugin.WithDevicePluginPath().
+type FakeKubelet struct {
+	server        *grpc.Server
+	registrations map[string]*pluginapi.RegisterRequest
+	registered    chan struct{}
+	dir           string
+	mutex         sync.Mutex
+}
+
+// NewFakeKubelet starts a fake kubelet. It is stopped and its directory is
+// removed when the test ends.
+func NewFakeKubelet(t testing.TB) *FakeKubelet {
+	t.Helper()
+
+	// Unix socket paths are short, so t.TempDir() can't be used.
+	dir, err := os.MkdirTemp("", "kubelet")
+	if err != nil {
+		t.Fatalf("unable to create device plugin directory: %+v", err)
+	}
+
+	k := &FakeKubelet{
+		dir:           dir,
+		registrations: make(map[string]*pluginapi.RegisterRequest),
+		registered:    make(chan struct{}),
+	}
+
+	lis, err := net.Listen("unix", filepath.Join(dir, kubeletSocketName))
+	if err != nil {
+		_ = os.RemoveAll(dir)
+
+		t.Fatalf("unable to listen to kubelet socket: %+v", err)
+	}
+
+	k.server = grpc.NewServer()
+	pluginapi.RegisterRegistrationServer(k.server, k)
+
+	go func() { _ = k.server.Serve(lis) }()
+
+	t.Cleanup(func() {
+		k.server.Stop()
+		_ = os.RemoveAll(dir)
+	})
+
+	return k
+}
+
+// Dir returns the device plugin directory of the kubelet.
+func (k *FakeKubelet) Dir() string {
+	return k.dir
+}
+
+// Register implements pluginapi.RegistrationServer.
+func (k *FakeKubelet) Register(ctx context.Context, r *pluginapi.RegisterRequest) (*pluginapi.Empty, error) {
+	if r.Version != pluginapi.Version {
+		return nil, errors.Errorf("unsupported API version %s", r.Version)
+	}
+
+	if _, err := os.Stat(filepath.Join(k.dir, r.Endpoint)); err != nil {
+		return nil, errors.Wrapf(err, "invalid endpoint %s", r.Endpoint)
+	}
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	k.registrations[r.ResourceName] = r
+
+	close(k.registered)
+	k.registered = make(chan struct{})
+
+	return &pluginapi.Empty{}, nil
+}
+
+// WaitForRegistration returns the registration of a resource, e.g.
+// "gpu.intel.com/i915", after waiting at most timeout for it.
+func (k *FakeKubelet) WaitForRegistration(t testing.TB, resourceName string, timeout time.Duration) *pluginapi.RegisterRequest {
+	t.Helper()
+
+	deadline := time.After(timeout)
+
+	for {
+		k.mutex.Lock()
+
+		r, registered := k.registrations[resourceName], k.registered
+		k.mutex.Unlock()
+
+		if r != nil {
+			return r
+		}
+
+		select {
+		case <-registered:
+		case <-deadline:
+			t.Fatalf("%s was not registered in %v", resourceName, timeout)
+		}
+	}
+}
+
+// Connect returns a client of the device plugin registered at the endpoint.
+// The connection is closed when the test ends.
+func (k *FakeKubelet) Connect(t testing.TB, endpoint string) pluginapi.DevicePluginClient {
+	t.Helper()
+
+	conn, err := grpc.NewClient("unix://"+filepath.Join(k.dir, endpoint), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("unable to connect to %s: %+v", endpoint, err)
+	}
+
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return pluginapi.NewDevicePluginClient(conn)
+}
+
+// Restart simulates a kubelet restart by removing the device plugin sockets
+// and forgetting the registrations. The device plugins are expected to
+// register again.
+func (k *FakeKubelet) Restart(t testing.TB) {
+	t.Helper()
+
+	k.mutex.Lock()
+	k.registrations = make(map[string]*pluginapi.RegisterRequest)
+	k.mutex.Unlock()
+
+	for _, endpoint := range k.Endpoints(t) {
+		if err := os.Remove(filepath.Join(k.dir, endpoint)); err != nil {
+			t.Fatalf("unable to remove plugin socket: %+v", err)
+		}
+	}
+}
+
+// Endpoints returns the device plugin sockets in the device plugin directory.
+func (k *FakeKubelet) Endpoints(t testing.TB) []string {
+	t.Helper()
+
+	entries, err := os.ReadDir(k.dir)
+	if err != nil {
+		t.Fatalf("unable to read device plugin directory: %+v", err)
+	}
+
+	endpoints := []string{}
+
+	for _, entry := range entries {
+		if name := entry.Name(); name != kubeletSocketName && strings.HasSuffix(name, ".sock") {
+			endpoints = append(endpoints, name)
+		}
+	}
+
+	return endpoints
+}
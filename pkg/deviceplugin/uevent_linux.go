@@ -0,0 +1,84 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// Netlink multicast group of the uevents sent by the kernel.
+	ueventKernelGroup = 1
+	// Uevent messages are limited to a few kilobytes by the kernel.
+	ueventBufferSize = 16 * 1024
+)
+
+// watchUEvents subscribes to kernel uevents on a netlink socket.
+func (w *DeviceWatcher) watchUEvents() error {
+	fd, err := unix.Socket(unix.AF_NETLINK, unix.SOCK_RAW|unix.SOCK_CLOEXEC|unix.SOCK_NONBLOCK, unix.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return errors.Wrap(err, "failed to create uevent socket")
+	}
+
+	if err := unix.Bind(fd, &unix.SockaddrNetlink{Family: unix.AF_NETLINK, Groups: ueventKernelGroup}); err != nil {
+		unix.Close(fd)
+
+		return errors.Wrap(err, "failed to bind uevent socket")
+	}
+
+	// The non-blocking socket is handled by the runtime poller, so
+	// closing the file unblocks the reader goroutine.
+	socket := os.NewFile(uintptr(fd), "uevent")
+	w.closers = append(w.closers, socket.Close)
+
+	go func() {
+		buf := make([]byte, ueventBufferSize)
+
+		for {
+			n, err := socket.Read(buf)
+			if errors.Is(err, unix.ENOBUFS) {
+				// Uevents were dropped, so rescan to catch up.
+				w.notifyChange()
+
+				continue
+			}
+
+			if err != nil {
+				if !errors.Is(err, os.ErrClosed) {
+					klog.ErrorS(err, "Failed to read uevents, relying on periodic scans")
+				}
+
+				return
+			}
+
+			w.ueventReceived()
+
+			ev, err := parseUEvent(buf[:n])
+			if err != nil {
+				klog.V(4).InfoS("Skipping uevent", "err", err)
+
+				continue
+			}
+
+			w.handleUEvent(ev)
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,344 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+)
+
+const (
+	// DefaultScanDebounce is the default time DeviceWatcher waits for more
+	// events after the first one before triggering a scan.
+	DefaultScanDebounce = 500 * time.Millisecond
+	// DefaultScanResyncPeriod is the default period of the safety-net scans
+	// triggered by DeviceWatcher even when no events are received.
+	DefaultScanResyncPeriod = time.Minute
+	// ueventFallbackResyncPeriod is the period of the scans until the first
+	// uevent is received, so that the changes missed by fsnotify are found
+	// soon when the uevents don't reach the plugin.
+	ueventFallbackResyncPeriod = 5 * time.Second
+)
+
+// UEvent actions which trigger a scan.
+var scanActions = map[string]bool{
+	"add":    true,
+	"remove": true,
+	"bind":   true,
+	"unbind": true,
+}
+
+// UEvent is a kernel kobject uevent.
+type UEvent struct {
+	Env       map[string]string
+	Action    string
+	DevPath   string
+	Subsystem string
+}
+
+// parseUEvent parses a kernel uevent netlink message of the form
+// "action@devpath\0KEY=value\0...".
+func parseUEvent(msg []byte) (UEvent, error) {
+	fields := bytes.Split(bytes.TrimRight(msg, "\x00"), []byte{0})
+
+	header := strings.SplitN(string(fields[0]), "@", 2)
+	if len(header) != 2 {
+		return UEvent{}, errors.Errorf("malformed uevent header %q", fields[0])
+	}
+
+	ev := UEvent{
+		Action:  header[0],
+		DevPath: header[1],
+		Env:     make(map[string]string, len(fields)-1),
+	}
+
+	for _, field := range fields[1:] {
+		if key, value, found := strings.Cut(string(field), "="); found {
+			ev.Env[key] = value
+		}
+	}
+
+	ev.Subsystem = ev.Env["SUBSYSTEM"]
+
+	return ev, nil
+}
+
+// DeviceWatcherOption configures optional features of DeviceWatcher.
+type DeviceWatcherOption func(*DeviceWatcher)
+
+// WithSubsystems limits the uevents triggering a scan to the given
+// subsystems, e.g. "drm" or "pci". By default uevents of all subsystems
+// trigger a scan.
+func WithSubsystems(subsystems ...string) DeviceWatcherOption {
+	return func(w *DeviceWatcher) {
+		for _, subsystem := range subsystems {
+			w.subsystems[subsystem] = true
+		}
+	}
+}
+
+// WithWatchPaths sets the devfs directories watched with fsnotify in
+// addition to the kernel uevents.
+func WithWatchPaths(paths ...string) DeviceWatcherOption {
+	return func(w *DeviceWatcher) {
+		w.paths = append(w.paths, paths...)
+	}
+}
+
+// WithDebounce sets the time to wait for more events after the first one
+// before triggering a scan.
+func WithDebounce(debounce time.Duration) DeviceWatcherOption {
+	return func(w *DeviceWatcher) {
+		w.debounce = debounce
+	}
+}
+
+// WithResyncPeriod sets the period of the safety-net scans.
+func WithResyncPeriod(period time.Duration) DeviceWatcherOption {
+	return func(w *DeviceWatcher) {
+		w.resyncPeriod = period
+	}
+}
+
+// DeviceWatcher triggers device scans on kernel uevents and on the files
+// created or removed in the configured paths. Bursts of events are coalesced
+// into a single scan and a scan is also triggered periodically as a safety
+// net.
+//
+// The kernel sends the uevents only to the initial network namespace, so a
+// plugin receives them only with hostNetwork. Elsewhere, the uevent socket
+// works but stays silent, so the periodic scans are triggered every five
+// seconds until the first uevent is received.
+//
+// A Scanner typically creates a DeviceWatcher in its constructor, calls
+// Start() in Scan() and rescans whenever C() delivers a value.
+type DeviceWatcher struct {
+	subsystems     map[string]bool
+	changes        chan struct{}
+	scans          chan struct{}
+	stop           chan struct{}
+	ueventSeen     chan struct{} // closed on the first uevent received
+	closers        []func() error
+	paths          []string
+	debounce       time.Duration
+	resyncPeriod   time.Duration
+	fallbackPeriod time.Duration
+	startOnce      sync.Once
+	stopOnce       sync.Once
+	seenOnce       sync.Once
+}
+
+// NewDeviceWatcher creates a new DeviceWatcher.
+func NewDeviceWatcher(opts ...DeviceWatcherOption) *DeviceWatcher {
+	w := &DeviceWatcher{
+		subsystems:     make(map[string]bool),
+		changes:        make(chan struct{}, 1),
+		scans:          make(chan struct{}, 1),
+		stop:           make(chan struct{}),
+		ueventSeen:     make(chan struct{}),
+		debounce:       DefaultScanDebounce,
+		resyncPeriod:   DefaultScanResyncPeriod,
+		fallbackPeriod: ueventFallbackResyncPeriod,
+	}
+
+	for _, opt := range opts {
+		opt(w)
+	}
+
+	return w
+}
+
+// C returns the channel which delivers a value whenever a scan is due.
+func (w *DeviceWatcher) C() <-chan struct{} {
+	return w.scans
+}
+
+// Start starts listening to uevents and to filesystem events. Event sources
+// that can't be set up are logged and the watcher keeps triggering the
+// periodic scans. Start is a no-op when called more than once.
+func (w *DeviceWatcher) Start() {
+	w.startOnce.Do(func() {
+		if err := w.watchUEvents(); err != nil {
+			klog.ErrorS(err, "Kernel uevents are not available, relying on the paths and periodic scans", "paths", w.paths)
+		}
+
+		// The uevent socket may be silent without hostNetwork, so
+		// the paths are watched in any case.
+		if len(w.paths) > 0 {
+			if err := w.watchPaths(); err != nil {
+				klog.ErrorS(err, "Failed to watch the paths", "paths", w.paths)
+			}
+		}
+
+		go w.run()
+	})
+}
+
+// Stop stops the watcher and releases its event sources.
+func (w *DeviceWatcher) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+
+		for _, closer := range w.closers {
+			if err := closer(); err != nil {
+				klog.ErrorS(err, "Failed to close device watcher")
+			}
+		}
+	})
+}
+
+// Rescan triggers a scan, e.g. after the plugin settings have changed.
+func (w *DeviceWatcher) Rescan() {
+	w.triggerScan()
+}
+
+// Inject handles a uevent as if it was received from the kernel. It is
+// meant for testing Scanners.
+func (w *DeviceWatcher) Inject(ev UEvent) {
+	w.handleUEvent(ev)
+}
+
+func (w *DeviceWatcher) handleUEvent(ev UEvent) {
+	if !scanActions[ev.Action] {
+		return
+	}
+
+	if len(w.subsystems) > 0 && !w.subsystems[ev.Subsystem] {
+		return
+	}
+
+	klog.V(4).InfoS("Scan triggered by uevent", "action", ev.Action, "devPath", ev.DevPath, "subsystem", ev.Subsystem)
+
+	w.notifyChange()
+}
+
+func (w *DeviceWatcher) notifyChange() {
+	select {
+	case w.changes <- struct{}{}:
+	default:
+		// A change is already pending.
+	}
+}
+
+func (w *DeviceWatcher) triggerScan() {
+	select {
+	case w.scans <- struct{}{}:
+	default:
+		// A scan is already pending.
+	}
+}
+
+// ueventReceived records that the uevents reach the watcher.
+func (w *DeviceWatcher) ueventReceived() {
+	w.seenOnce.Do(func() {
+		close(w.ueventSeen)
+	})
+}
+
+// run coalesces the changes received within the debounce window into one
+// scan and triggers the periodic scans, more often until the first uevent
+// is received.
+func (w *DeviceWatcher) run() {
+	resync := time.NewTicker(min(w.resyncPeriod, w.fallbackPeriod))
+	defer resync.Stop()
+
+	var debounce <-chan time.Time
+
+	seen := w.ueventSeen
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-seen:
+			seen = nil
+
+			resync.Reset(w.resyncPeriod)
+		case <-w.changes:
+			if debounce == nil {
+				debounce = time.After(w.debounce)
+			}
+		case <-debounce:
+			debounce = nil
+
+			w.triggerScan()
+		case <-resync.C:
+			w.triggerScan()
+		}
+	}
+}
+
+func (w *DeviceWatcher) watchPaths() error {
+	if len(w.paths) == 0 {
+		return errors.New("no paths to watch")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return errors.Wrap(err, "failed to create fsnotify watcher")
+	}
+
+	watched := 0
+
+	for _, path := range w.paths {
+		// Device directories, e.g. /dev/dsa, may appear only later.
+		if err := watcher.Add(path); err != nil {
+			klog.V(2).InfoS("Not watching path", "path", path, "err", err)
+
+			continue
+		}
+
+		watched++
+	}
+
+	if watched == 0 {
+		watcher.Close()
+
+		return errors.Errorf("none of %v can be watched", w.paths)
+	}
+
+	w.closers = append(w.closers, watcher.Close)
+
+	go func() {
+		for {
+			select {
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+
+				if ev.Has(fsnotify.Create) || ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename) {
+					klog.V(4).InfoS("Scan triggered by file event", "event", ev.String())
+
+					w.notifyChange()
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+
+				klog.ErrorS(err, "fsnotify watcher error")
+			}
+		}
+	}()
+
+	return nil
+}
@@ -0,0 +1,192 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deviceplugin
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const (
+	testDebounce = 20 * time.Millisecond
+	testTimeout  = time.Second
+)
+
+func expectScans(t *testing.T, w *DeviceWatcher, expected int) {
+	t.Helper()
+
+	received := 0
+	timeout := time.After(5 * testDebounce)
+
+	for {
+		select {
+		case <-w.C():
+			received++
+		case <-timeout:
+			if received != expected {
+				t.Errorf("expected %d scans, got %d", expected, received)
+			}
+
+			return
+		}
+	}
+}
+
+func TestParseUEvent(t *testing.T) {
+	tcases := []struct {
+		expected    UEvent
+		name        string
+		msg         string
+		expectedErr bool
+	}{
+		{
+			name: "add",
+			msg:  "add@/devices/pci0000:00/0000:00:02.0/drm/card0\x00ACTION=add\x00DEVPATH=/devices/pci0000:00/0000:00:02.0/drm/card0\x00SUBSYSTEM=drm\x00DEVNAME=dri/card0\x00",
+			expected: UEvent{
+				Action:    "add",
+				DevPath:   "/devices/pci0000:00/0000:00:02.0/drm/card0",
+				Subsystem: "drm",
+				Env: map[string]string{
+					"ACTION":    "add",
+					"DEVPATH":   "/devices/pci0000:00/0000:00:02.0/drm/card0",
+					"SUBSYSTEM": "drm",
+					"DEVNAME":   "dri/card0",
+				},
+			},
+		},
+		{
+			name: "unbind without subsystem",
+			msg:  "unbind@/devices/pci0000:6a/0000:6a:01.0\x00",
+			expected: UEvent{
+				Action:  "unbind",
+				DevPath: "/devices/pci0000:6a/0000:6a:01.0",
+				Env:     map[string]string{},
+			},
+		},
+		{
+			name:        "udev message",
+			msg:         "libudev\x00\xfe\xed\xca\xfe",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			ev, err := parseUEvent([]byte(tc.msg))
+			if tc.expectedErr != (err != nil) {
+				t.Fatalf("unexpected error state: %+v", err)
+			}
+
+			if !tc.expectedErr && !reflect.DeepEqual(ev, tc.expected) {
+				t.Errorf("expected %+v, got %+v", tc.expected, ev)
+			}
+		})
+	}
+}
+
+func TestDeviceWatcherInject(t *testing.T) {
+	w := NewDeviceWatcher(WithSubsystems("drm", "pci"), WithDebounce(testDebounce), WithResyncPeriod(time.Hour))
+	w.Start()
+	defer w.Stop()
+
+	w.Inject(UEvent{Action: "change", Subsystem: "drm"})
+	w.Inject(UEvent{Action: "add", Subsystem: "net"})
+	expectScans(t, w, 0)
+
+	for i := 0; i < 10; i++ {
+		w.Inject(UEvent{Action: "add", Subsystem: "drm"})
+	}
+
+	w.Inject(UEvent{Action: "bind", Subsystem: "pci"})
+	expectScans(t, w, 1)
+
+	w.Inject(UEvent{Action: "remove", Subsystem: "drm"})
+	expectScans(t, w, 1)
+
+	w.Rescan()
+	expectScans(t, w, 1)
+}
+
+func TestDeviceWatcherResync(t *testing.T) {
+	w := NewDeviceWatcher(WithResyncPeriod(testDebounce))
+	w.Start()
+	defer w.Stop()
+
+	select {
+	case <-w.C():
+	case <-time.After(testTimeout):
+		t.Error("no periodic scan triggered")
+	}
+}
+
+func TestDeviceWatcherUEventFallback(t *testing.T) {
+	w := NewDeviceWatcher(WithResyncPeriod(time.Hour))
+	w.fallbackPeriod = testDebounce
+
+	go w.run()
+	defer w.Stop()
+
+	// The scans are frequent until the uevents are known to arrive.
+	select {
+	case <-w.C():
+	case <-time.After(testTimeout):
+		t.Error("no scan triggered before the first uevent")
+	}
+
+	w.ueventReceived()
+	w.ueventReceived()
+
+	time.Sleep(3 * testDebounce)
+
+	select {
+	case <-w.C():
+	default:
+	}
+
+	expectScans(t, w, 0)
+}
+
+func TestDeviceWatcherPaths(t *testing.T) {
+	root := t.TempDir()
+
+	w := NewDeviceWatcher(WithWatchPaths(root), WithDebounce(testDebounce), WithResyncPeriod(time.Hour))
+	if err := w.watchPaths(); err != nil {
+		t.Fatalf("unable to watch paths: %+v", err)
+	}
+
+	go w.run()
+	defer w.Stop()
+
+	if err := os.WriteFile(filepath.Join(root, "card0"), nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-w.C():
+	case <-time.After(testTimeout):
+		t.Error("no scan triggered by a new file")
+	}
+
+	if err := NewDeviceWatcher().watchPaths(); err == nil {
+		t.Error("expected an error without paths")
+	}
+
+	if err := NewDeviceWatcher(WithWatchPaths(filepath.Join(root, "missing"))).watchPaths(); err == nil {
+		t.Error("expected an error for a missing path")
+	}
+}
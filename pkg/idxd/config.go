@@ -0,0 +1,95 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package idxd
+
+import (
+	"github.com/pkg/errors"
+	"k8s.io/klog/v2"
+
+	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+)
+
+// config is the section of the config file of the idxd device plugins. The
+// settings are overridden by the corresponding flags given on the command
+// line, see EnableConfig().
+type config struct {
+	SharedDevNum     *int    `json:"sharedDevNum,omitempty"`     // -shared-dev-num
+	AllocationPolicy *string `json:"allocationPolicy,omitempty"` // -allocation-policy
+}
+
+// EnableConfig sets the section of the config file of the plugin, e.g. "dsa",
+// and sysfsDir as in NewAllocationPolicy(). commandLine has the flags set on
+// the command line, which override the config file.
+func (dp *DevicePlugin) EnableConfig(section, sysfsDir string, commandLine map[string]bool) {
+	dp.configSection = section
+	dp.sysfsDir = sysfsDir
+	dp.commandLine = commandLine
+}
+
+// settings returns the settings the config file can change.
+func (dp *DevicePlugin) settings() (int, *dpapi.AllocationPolicy) {
+	dp.mutex.RLock()
+	defer dp.mutex.RUnlock()
+
+	return dp.sharedDevNum, dp.policy
+}
+
+// ConfigSection implements the Configurable interface.
+func (dp *DevicePlugin) ConfigSection() string {
+	return dp.configSection
+}
+
+// ApplyConfig implements the Configurable interface. The new settings are
+// applied on a rescan of the devices.
+func (dp *DevicePlugin) ApplyConfig(section []byte) error {
+	cfg := config{}
+	if err := dpapi.DecodeConfigSection(section, &cfg); err != nil {
+		return err
+	}
+
+	sharedDevNum, policy := dp.flagSharedDevNum, dp.flagPolicy
+
+	if cfg.SharedDevNum != nil && !dp.commandLine["shared-dev-num"] {
+		sharedDevNum = *cfg.SharedDevNum
+	}
+
+	if sharedDevNum < 1 {
+		return errors.New("the number of containers sharing the same work queue must be greater than zero")
+	}
+
+	if cfg.AllocationPolicy != nil && !dp.commandLine["allocation-policy"] {
+		var err error
+
+		if policy, err = NewAllocationPolicy(*cfg.AllocationPolicy, dp.sysfsDir); err != nil {
+			return err
+		}
+
+		if policy == nil {
+			policy = dpapi.NewAllocationPolicy()
+		}
+	}
+
+	klog.V(1).Infof("%s config: share count = %d", dp.configSection, sharedDevNum)
+
+	dp.mutex.Lock()
+
+	dp.sharedDevNum, dp.policy = sharedDevNum, policy
+
+	dp.mutex.Unlock()
+
+	dp.scanWatcher.Rescan()
+
+	return nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 Intel Corporation. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package idxd
+
+import (
+	"reflect"
+	"testing"
+
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+func TestApplyConfig(t *testing.T) {
+	rqt := &pluginapi.PreferredAllocationRequest{
+		ContainerRequests: []*pluginapi.ContainerPreferredAllocationRequest{{
+			AvailableDeviceIDs: []string{
+				"wq-user-shared-wq0.0-0", "wq-user-shared-wq0.0-1",
+				"wq-user-shared-wq1.0-0", "wq-user-shared-wq1.0-1",
+			},
+			AllocationSize: 2,
+		}},
+	}
+
+	tcases := []struct {
+		commandLine          map[string]bool
+		name                 string
+		section              string
+		expectedDeviceIDs    []string
+		expectedSharedDevNum int
+		expectedErr          bool
+	}{
+		{
+			name:                 "empty section keeps the flags",
+			expectedSharedDevNum: 2,
+			expectedDeviceIDs:    []string{"wq-user-shared-wq0.0-0", "wq-user-shared-wq0.0-1"},
+		},
+		{
+			name:                 "config overrides flag defaults",
+			section:              `{"sharedDevNum": 4, "allocationPolicy": "balanced"}`,
+			expectedSharedDevNum: 4,
+			expectedDeviceIDs:    []string{"wq-user-shared-wq0.0-0", "wq-user-shared-wq1.0-0"},
+		},
+		{
+			name:                 "command line overrides config",
+			section:              `{"sharedDevNum": 4, "allocationPolicy": "balanced"}`,
+			commandLine:          map[string]bool{"shared-dev-num": true, "allocation-policy": true},
+			expectedSharedDevNum: 2,
+			expectedDeviceIDs:    []string{"wq-user-shared-wq0.0-0", "wq-user-shared-wq0.0-1"},
+		},
+		{
+			name:                 "invalid share count",
+			section:              `{"sharedDevNum": 0}`,
+			expectedSharedDevNum: 2,
+			expectedDeviceIDs:    []string{"wq-user-shared-wq0.0-0", "wq-user-shared-wq0.0-1"},
+			expectedErr:          true,
+		},
+		{
+			name:                 "invalid policy",
+			section:              `{"allocationPolicy": "random"}`,
+			expectedSharedDevNum: 2,
+			expectedDeviceIDs:    []string{"wq-user-shared-wq0.0-0", "wq-user-shared-wq0.0-1"},
+			expectedErr:          true,
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			plugin := NewDevicePlugin("", t.TempDir(), 2, nil)
+			plugin.EnableConfig("dsa", t.TempDir(), tc.commandLine)
+
+			err := plugin.ApplyConfig([]byte(tc.section))
+			if tc.expectedErr != (err != nil) {
+				t.Fatalf("unexpected error state: %+v", err)
+			}
+
+			if sharedDevNum, _ := plugin.settings(); sharedDevNum != tc.expectedSharedDevNum {
+				t.Errorf("expected share count %d, got %d", tc.expectedSharedDevNum, sharedDevNum)
+			}
+
+			resp, err := plugin.GetPreferredAllocation(rqt)
+			if err != nil {
+				t.Fatalf("unexpected error: %+v", err)
+			}
+
+			if deviceIDs := resp.ContainerResponses[0].DeviceIDs; !reflect.DeepEqual(deviceIDs, tc.expectedDeviceIDs) {
+				t.Errorf("expected preferred devices %v, got %v", tc.expectedDeviceIDs, deviceIDs)
+			}
+		})
+	}
+}
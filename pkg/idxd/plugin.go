@@ -20,8 +20,8 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"syscall"
-	"time"
 
 	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
 	"github.com/pkg/errors"
@@ -33,40 +33,113 @@ import (
 const (
 	// Character devices directory.
 	charDevDir = "/dev/char"
-	// Frequency of device scans.
-	scanFrequency = 5 * time.Second
 )
 
 // getDevNodesFunc type allows overriding filesystem APIs (os.Stat, stat.Sys, etc) in tests.
 type getDevNodesFunc func(devDir, charDevDir, wqName string) ([]pluginapi.DeviceSpec, error)
 
+// workQueueKey groups the device IDs, e.g. "wq-user-shared-wq0.1-2", by
+// their work queue, e.g. "wq-user-shared-wq0.1".
+var workQueueKey = dpapi.ParentKey("-")
+
+// idxdDeviceKey groups the device IDs by the idxd device of their work queue,
+// e.g. "wq-user-shared-wq0".
+func idxdDeviceKey(id string, dev dpapi.DeviceInfo) string {
+	wq := workQueueKey(id, dev)
+	if i := strings.LastIndex(wq, "."); i >= 0 {
+		return wq[:i]
+	}
+
+	return wq
+}
+
+// NewAllocationPolicy returns the preferred allocation policy of work queues
+// by name: "balanced" spreads the containers among the idxd devices and their
+// work queues, "packed" fills one work queue before moving to the next,
+// "topology" prefers the work queues of devices close to each other and
+// "none" leaves the selection to kubelet. sysfsDir is the directory of the
+// work queue devices, e.g. "/sys/bus/dsa/devices".
+func NewAllocationPolicy(name, sysfsDir string) (*dpapi.AllocationPolicy, error) {
+	switch name {
+	case "balanced":
+		return dpapi.NewAllocationPolicy(dpapi.Balance(idxdDeviceKey), dpapi.Balance(workQueueKey), dpapi.ByID), nil
+	case "packed":
+		return dpapi.NewAllocationPolicy(dpapi.Pack(idxdDeviceKey), dpapi.Pack(workQueueKey), dpapi.ByID), nil
+	case "topology":
+		return dpapi.NewTopologyPolicy(func(id string) string {
+			wq := workQueueKey(id, dpapi.DeviceInfo{})
+
+			return filepath.Join(sysfsDir, wq[strings.LastIndex(wq, "-")+1:])
+		}), nil
+	case "none":
+		return nil, nil
+	}
+
+	return nil, errors.Errorf("unknown allocation policy %q", name)
+}
+
 // DevicePlugin defines properties of the idxd device plugin.
 type DevicePlugin struct {
-	scanTicker   *time.Ticker
-	scanDone     chan bool
-	getDevNodes  getDevNodesFunc
-	statePattern string
-	devDir       string
-	charDevDir   string
-	sharedDevNum int
+	scanWatcher *dpapi.DeviceWatcher
+	scanDone    chan bool
+	getDevNodes getDevNodesFunc
+	commandLine map[string]bool
+	// Note: If restarting the plugin with a new policy, the allocations for existing pods remain with old policy.
+	policy *dpapi.AllocationPolicy
+	// flagPolicy and flagSharedDevNum are the settings before the config
+	// file is applied.
+	flagPolicy       *dpapi.AllocationPolicy
+	statePattern     string
+	devDir           string
+	charDevDir       string
+	sysfsDir         string
+	configSection    string
+	sharedDevNum     int
+	flagSharedDevNum int
+	// mutex protects the settings the config file can change.
+	mutex sync.RWMutex
 }
 
-// NewDevicePlugin creates DevicePlugin.
-func NewDevicePlugin(statePattern, devDir string, sharedDevNum int) *DevicePlugin {
+// NewDevicePlugin creates DevicePlugin. The preferred allocation policy is
+// as returned by NewAllocationPolicy(), nil leaves the selection to kubelet.
+func NewDevicePlugin(statePattern, devDir string, sharedDevNum int, policy *dpapi.AllocationPolicy) *DevicePlugin {
+	if policy == nil {
+		policy = dpapi.NewAllocationPolicy()
+	}
+
 	return &DevicePlugin{
-		statePattern: statePattern,
-		devDir:       devDir,
-		charDevDir:   charDevDir,
-		sharedDevNum: sharedDevNum,
-		scanTicker:   time.NewTicker(scanFrequency),
-		scanDone:     make(chan bool, 1),
-		getDevNodes:  getDevNodes,
+		statePattern:     statePattern,
+		devDir:           devDir,
+		charDevDir:       charDevDir,
+		sharedDevNum:     sharedDevNum,
+		flagSharedDevNum: sharedDevNum,
+		policy:           policy,
+		flagPolicy:       policy,
+		scanWatcher:      dpapi.NewDeviceWatcher(dpapi.WithSubsystems("dsa"), dpapi.WithWatchPaths(devDir, filepath.Dir(devDir))),
+		scanDone:         make(chan bool, 1),
+		getDevNodes:      getDevNodes,
+	}
+}
+
+// GetPreferredAllocation implements the PreferredAllocator interface.
+func (dp *DevicePlugin) GetPreferredAllocation(rqt *pluginapi.PreferredAllocationRequest) (*pluginapi.PreferredAllocationResponse, error) {
+	_, policy := dp.settings()
+
+	return policy.SelectPreferred(rqt, nil)
+}
+
+// StopScan makes Scan() return. It implements dpapi.ScanStopper.
+func (dp *DevicePlugin) StopScan() {
+	select {
+	case dp.scanDone <- true:
+	default:
 	}
 }
 
 // Scan discovers devices and reports them to the upper level API.
 func (dp *DevicePlugin) Scan(notifier dpapi.Notifier) error {
-	defer dp.scanTicker.Stop()
+	dp.scanWatcher.Start()
+	defer dp.scanWatcher.Stop()
 
 	for {
 		devTree, err := dp.scan()
@@ -79,7 +152,7 @@ func (dp *DevicePlugin) Scan(notifier dpapi.Notifier) error {
 		select {
 		case <-dp.scanDone:
 			return nil
-		case <-dp.scanTicker.C:
+		case <-dp.scanWatcher.C():
 		}
 	}
 }
@@ -154,6 +227,7 @@ func (dp *DevicePlugin) scan() (dpapi.DeviceTree, error) {
 	}
 
 	devTree := dpapi.NewDeviceTree()
+	sharedDevNum, _ := dp.settings()
 
 	for _, fpath := range matches {
 		// Read queue state entry
@@ -190,17 +264,24 @@ func (dp *DevicePlugin) scan() (dpapi.DeviceTree, error) {
 			}
 		}
 
-		amount := dp.sharedDevNum
+		amount := sharedDevNum
 		if wqMode != "shared" {
 			amount = 1
 		}
 
-		klog.V(4).Infof("%s: amount: %d, type: %s, mode: %s, nodes: %+v", wqName, amount, wqType, wqMode, devNodes)
+		klog.V(4).InfoS("Work queue found", "wq", wqName, "amount", amount, "type", wqType, "mode", wqMode, "nodes", devNodes)
 
 		for i := 0; i < amount; i++ {
 			deviceType := fmt.Sprintf("wq-%s-%s", wqType, wqMode)
 			deviceID := fmt.Sprintf("%s-%s-%d", deviceType, wqName, i)
-			devTree.AddDevice(deviceType, deviceID, dpapi.NewDeviceInfo(pluginapi.Healthy, devNodes, nil, nil, nil, nil))
+			deviceInfo := dpapi.NewDeviceInfo(pluginapi.Healthy, devNodes, nil, nil, nil, nil)
+			deviceInfo.SetSysfsDevice(queueDir)
+			deviceInfo.SetAttributes(map[string]string{
+				"type": wqType,
+				"mode": wqMode,
+			})
+
+			devTree.AddDevice(deviceType, deviceID, deviceInfo)
 		}
 	}
 
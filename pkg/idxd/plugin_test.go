@@ -20,9 +20,11 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"reflect"
 	"testing"
 
 	dpapi "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin"
+	dptesting "github.com/intel/intel-device-plugins-for-kubernetes/pkg/deviceplugin/testing"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 )
 
@@ -223,7 +225,7 @@ func genTest(sysfs, statePattern string, tc testCase) func(t *testing.T) {
 			}
 		}
 
-		plugin := NewDevicePlugin(statePattern, "", tc.sharedDevNum)
+		plugin := NewDevicePlugin(statePattern, "", tc.sharedDevNum, nil)
 		plugin.getDevNodes = getFakeDevNodes
 
 		notifier := &fakeNotifier{
@@ -245,6 +247,83 @@ func genTest(sysfs, statePattern string, tc testCase) func(t *testing.T) {
 	}
 }
 
+func TestNewAllocationPolicy(t *testing.T) {
+	sysfs := t.TempDir()
+
+	for wq, device := range map[string]string{
+		"wq0.0": "pci0000:6a/0000:6a:01.0/dsa0",
+		"wq1.0": "pci0000:e7/0000:e7:01.0/dsa1",
+		"wq1.1": "pci0000:e7/0000:e7:01.0/dsa1",
+	} {
+		dir := path.Join(sysfs, "devices", device, wq)
+		if err := os.MkdirAll(dir, 0750); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Symlink(dir, path.Join(sysfs, wq)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	available := []string{
+		"wq-user-shared-wq1.0-0", "wq-user-shared-wq1.0-1",
+		"wq-user-shared-wq0.0-0", "wq-user-shared-wq0.0-1",
+		"wq-user-shared-wq1.1-0", "wq-user-shared-wq1.1-1",
+	}
+
+	tcases := []struct {
+		name          string
+		expectedIDs   []string
+		size          int32
+		expectedError bool
+	}{
+		{
+			name:        "balanced",
+			size:        2,
+			expectedIDs: []string{"wq-user-shared-wq1.0-0", "wq-user-shared-wq1.1-0"},
+		},
+		{
+			name:        "packed",
+			size:        2,
+			expectedIDs: []string{"wq-user-shared-wq0.0-0", "wq-user-shared-wq0.0-1"},
+		},
+		{
+			name:        "topology",
+			size:        3,
+			expectedIDs: []string{"wq-user-shared-wq1.0-0", "wq-user-shared-wq1.0-1", "wq-user-shared-wq1.1-0"},
+		},
+		{
+			name: "none",
+		},
+		{
+			name:          "unknown",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range tcases {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := NewAllocationPolicy(tc.name, sysfs)
+			if tc.expectedError != (err != nil) {
+				t.Fatalf("unexpected error state: %+v", err)
+			}
+
+			if policy == nil {
+				if tc.expectedIDs != nil {
+					t.Error("expected a policy")
+				}
+
+				return
+			}
+
+			ids := policy.Select(&pluginapi.ContainerPreferredAllocationRequest{AvailableDeviceIDs: available, AllocationSize: tc.size}, nil)
+			if !reflect.DeepEqual(ids, tc.expectedIDs) {
+				t.Errorf("expected %v, got %v", tc.expectedIDs, ids)
+			}
+		})
+	}
+}
+
 // checkDeviceTree checks discovered device types and number of discovered devices.
 func checkDeviceTree(deviceTree dpapi.DeviceTree, expectedResult map[string]int, expectedError bool) error {
 	if !expectedError && deviceTree != nil {
@@ -269,3 +348,35 @@ func checkDeviceTree(deviceTree dpapi.DeviceTree, expectedResult map[string]int,
 
 	return nil
 }
+
+func TestConformance(t *testing.T) {
+	sysfs := t.TempDir()
+
+	for _, wq := range []string{"dsa0/wq0.0", "dsa0/wq0.1"} {
+		files := map[string]string{"state": "enabled", "mode": "shared", "type": "user"}
+
+		if err := os.MkdirAll(path.Join(sysfs, wq), 0750); err != nil {
+			t.Fatalf("Failed to create fake sysfs directory: %+v", err)
+		}
+
+		for name, body := range files {
+			if err := os.WriteFile(path.Join(sysfs, wq, name), []byte(body), 0600); err != nil {
+				t.Fatalf("Failed to create fake sysfs entry: %+v", err)
+			}
+		}
+	}
+
+	policy, err := NewAllocationPolicy("balanced", sysfs)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+
+	plugin := NewDevicePlugin(path.Join(sysfs, "dsa*/wq*/state"), "", 2, policy)
+	plugin.getDevNodes = getFakeDevNodes
+
+	dptesting.Run(t, dptesting.Config{
+		Plugin:      plugin,
+		Namespace:   "dsa.intel.com",
+		DeviceTypes: []string{"wq-user-shared"},
+	})
+}